@@ -0,0 +1,4 @@
+// Package shmanagev1 gRPC 接口定义，*.pb.go 由 proto 文件生成，不要手动修改
+package shmanagev1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative shmanage/v1/common.proto shmanage/v1/user.proto shmanage/v1/post.proto shmanage/v1/comment.proto
@@ -0,0 +1,365 @@
+// Package app 组装服务、处理器和路由，供 serve 和 routes 等子命令共用
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sh-manage/cache"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/events"
+	"sh-manage/graph"
+	"sh-manage/handlers"
+	"sh-manage/live"
+	"sh-manage/logging"
+	"sh-manage/middleware"
+	"sh-manage/moderation"
+	"sh-manage/passwords"
+	"sh-manage/rpc"
+	"sh-manage/services"
+	"sh-manage/storage"
+	"sh-manage/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+type App struct {
+	cfg    *config.Config
+	db     *gorm.DB
+	Router *gin.Engine
+	// GRPC 与 Router 共用服务层，Serve 在 server.grpc_port 上启动
+	GRPC *grpc.Server
+
+	health            *services.HealthService
+	hub               *live.Hub
+	oidcService       *services.OIDCService
+	webhookService    *services.WebhookService
+	moderationService *services.ModerationService
+}
+
+// New 创建所有服务并注册路由，不会访问外部依赖，后台任务在 Serve 中启动
+func New(cfg *config.Config, db *gorm.DB) (*App, error) {
+	cacheStore, err := cache.New(cfg.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("create cache: %w", err)
+	}
+
+	if level, err := logging.ParseLevel(cfg.Log.Level); err == nil {
+		logging.SetLevel(level)
+	}
+	jwtKeys := utils.NewJWTKeys([]byte(cfg.JWT.Secret), config.Duration(cfg.JWT.Expire, 24*time.Hour))
+	// 租户解析和租户级的限流、跨域配置
+	tenants := middleware.NewTenants(services.NewTenantService(db, cacheStore), cfg)
+	moderationPipeline := moderation.New(cfg.Moderation)
+	passwordManager, err := passwords.New(cfg.Password)
+	if err != nil {
+		return nil, fmt.Errorf("create password manager: %w", err)
+	}
+
+	// 配置文件修改后，日志级别、租户解析、限流、跨域白名单、内容审核规则、密码策略和 JWT 密钥立即生效，其余配置需要重启
+	config.OnChange(func(old, next *config.Config) {
+		if level, err := logging.ParseLevel(next.Log.Level); err == nil {
+			logging.SetLevel(level)
+		}
+		tenants.Update(next)
+		moderationPipeline.Update(next.Moderation)
+		if err := passwordManager.Update(next.Password); err != nil {
+			logging.Warnf("Password settings not reloaded: %v", err)
+		}
+
+		expire := config.Duration(next.JWT.Expire, 24*time.Hour)
+		jwtKeys.SetExpire(expire)
+		if next.JWT.Secret != old.JWT.Secret {
+			// 宽限期内旧密钥签发的令牌仍可使用，默认覆盖一个完整的令牌有效期
+			jwtKeys.Rotate([]byte(next.JWT.Secret), config.Duration(next.JWT.RotationGrace, expire))
+			logging.Infof("JWT secret rotated")
+		}
+		logging.Infof("Config reloaded")
+	})
+
+	userService := services.NewUserService(db, cacheStore)
+	// 新的哈希参数只用于新密码，旧哈希在用户下次登录时重新计算
+	userService.SetPasswords(passwordManager)
+	// 删除用户时一并删除其文章附件的文件
+	fileStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("create storage: %w", err)
+	}
+	userService.SetStorage(fileStorage)
+	userHandler := handlers.NewUserHandler(userService, jwtKeys)
+
+	oidcService := services.NewOIDCService(db, userService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, jwtKeys)
+
+	apiKeyService := services.NewApiKeyService(db)
+	apiKeyHandler := handlers.NewApiKeyHandler(apiKeyService)
+
+	eventBus := events.NewBus()
+	webhookService := services.NewWebhookService(db, cfg.Webhook)
+	eventBus.Subscribe(webhookService.HandleEvent)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+	// 文章和评论发布前经过内容审核，命中规则的内容进入审核队列
+	moderationService := services.NewModerationService(db, moderationPipeline, cacheStore)
+	moderationService.SetPublisher(eventBus)
+	moderationHandler := handlers.NewModerationHandler(moderationService)
+	// 用户举报由审核员处理，可以删除被举报的内容并警告、停用或封禁作者
+	reportService := services.NewReportService(db, userService, cacheStore)
+	reportService.SetPublisher(eventBus)
+	reportHandler := handlers.NewReportHandler(reportService)
+	postService := services.NewPostService(db, userService, cacheStore, nil)
+	postService.SetPublisher(eventBus)
+	postService.SetModerator(moderationService)
+	postHandler := handlers.NewPostHandler(postService)
+	commentService := services.NewCommentService(db, userService, nil)
+	commentService.SetPublisher(eventBus)
+	commentService.SetModerator(moderationService)
+	commentHandler := handlers.NewCommentHandler(commentService)
+	// 文章和评论事件经事件总线推送给 SSE 和 WebSocket 订阅者
+	hub := live.NewHub(live.DefaultBuffer)
+	eventBus.Subscribe(hub.Publish)
+	streamHandler := handlers.NewStreamHandler(hub, postService)
+	schema, err := graph.NewSchema(userService, postService, commentService)
+	if err != nil {
+		return nil, fmt.Errorf("parse graphql schema: %w", err)
+	}
+	graphQLHandler := handlers.NewGraphQLHandler(schema)
+	grpcServer := rpc.NewServer(rpc.Services{Users: userService, Posts: postService, Comments: commentService}, jwtKeys, tenants)
+	auditHandler := handlers.NewAuditHandler(services.NewAuditService(db))
+	attachmentHandler := handlers.NewAttachmentHandler(services.NewAttachmentService(db, fileStorage, postService, cfg.Storage))
+	statsHandler := handlers.NewStatsHandler(services.NewStatsService(db, cacheStore))
+	feedHandler := handlers.NewFeedHandler(services.NewFeedService(db, cacheStore, cfg.Feed))
+	postTransferService := services.NewPostTransferService(db, cacheStore)
+	postTransferService.SetModerator(moderationService)
+	postTransferHandler := handlers.NewPostTransferHandler(postTransferService)
+
+	healthService := services.NewHealthService()
+	healthService.AddCheck("database", services.DatabaseHealthCheck(db))
+	if pinger, ok := cacheStore.(cache.Pinger); ok {
+		healthService.AddCheck("cache", pinger.Ping)
+	}
+	healthHandler := handlers.NewHealthHandler(healthService)
+
+	// 不使用 gin 的默认访问日志，它在中间件执行前记录完整查询串，会把 access_token 写进日志
+	// 访问日志由 middleware.Logger 记录，只包含路径
+	r := gin.New()
+	r.Use(gin.Recovery())
+	// 只信任配置的反向代理传来的 X-Forwarded-For，防止客户端伪造 IP 绕过限流
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("set trusted proxies: %w", err)
+	}
+
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger())
+
+	// 本地存储的附件通过静态文件路由访问
+	r.Static("/static", "web/static")
+
+	r.GET("/health", healthHandler.Livez)
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+
+	// gin 的 Use 只作用于之后注册的路由：健康检查和静态文件不解析租户，数据库不可用时存活检查仍然正常
+	// 未匹配路由（包括跨域预检的 OPTIONS 请求）也会经过这些中间件
+	r.Use(middleware.Tenant(tenants))
+	r.Use(middleware.TenantCORS(tenants))
+	r.Use(middleware.TenantRateLimit(tenants))
+
+	feeds := r.Group("/feeds")
+	{
+		feeds.GET("/rss.xml", feedHandler.RSS)
+		feeds.GET("/atom.xml", feedHandler.Atom)
+		feeds.GET("/authors/:username/rss.xml", feedHandler.AuthorRSS)
+		feeds.GET("/authors/:username/atom.xml", feedHandler.AuthorAtom)
+		feeds.GET("/tags/:tag/rss.xml", feedHandler.TagRSS)
+		feeds.GET("/tags/:tag/atom.xml", feedHandler.TagAtom)
+	}
+
+	// 未登录时只能查询，修改在解析函数中检查登录状态和 API Key 的 scope
+	r.POST("/graphql", middleware.OptionalAuth(jwtKeys, apiKeyService, userService), graphQLHandler.Query)
+
+	// REST 接口按 Accept 协商响应格式，请求体按 Content-Type 解析
+	public := r.Group("/api/v1", middleware.Negotiate())
+	{
+		public.POST("/users/register", userHandler.Register)
+		public.POST("/users/login", userHandler.Login)
+		public.GET("/auth/oidc/:provider/login", oidcHandler.Login)
+		public.GET("/auth/oidc/:provider/callback", oidcHandler.Callback)
+
+		public.GET("/posts", postHandler.List)
+		public.GET("/posts/:id", postHandler.Get)
+		public.GET("/posts/:id/comments", commentHandler.ListByPost)
+		public.GET("/comments/:id", commentHandler.Get)
+		public.GET("/posts/:id/attachments", attachmentHandler.ListByPost)
+	}
+
+	// 需要认证的路由
+	protected := r.Group("/api/v1")
+	protected.Use(middleware.Negotiate(), middleware.Auth(jwtKeys, apiKeyService, userService))
+	{
+		protected.GET("/users/me", middleware.RequireScope(consts.ScopeUsersRead), userHandler.GetProfile)
+		protected.PUT("/users/me", middleware.RequireScope(consts.ScopeUsersWrite), userHandler.UpdateProfile)
+
+		apiKeys := protected.Group("/users/me/api-keys", middleware.RequireScope(consts.ScopeApiKeysManage))
+		{
+			apiKeys.POST("", apiKeyHandler.Create)
+			apiKeys.GET("", apiKeyHandler.List)
+			apiKeys.GET("/:id", apiKeyHandler.Get)
+			apiKeys.PUT("/:id", apiKeyHandler.Update)
+			apiKeys.DELETE("/:id", apiKeyHandler.Delete)
+		}
+
+		protected.POST("/posts", middleware.RequireScope(consts.ScopePostsWrite), postHandler.Create)
+		protected.PUT("/posts/:id", middleware.RequireScope(consts.ScopePostsWrite), postHandler.Update)
+		protected.DELETE("/posts/:id", middleware.RequireScope(consts.ScopePostsWrite), postHandler.Delete)
+
+		protected.POST("/posts/:id/attachments", middleware.RequireScope(consts.ScopePostsWrite), attachmentHandler.Upload)
+		protected.DELETE("/attachments/:id", middleware.RequireScope(consts.ScopePostsWrite), attachmentHandler.Delete)
+
+		protected.POST("/posts/:id/comments", middleware.RequireScope(consts.ScopeCommentsWrite), commentHandler.Create)
+		protected.PUT("/comments/:id", middleware.RequireScope(consts.ScopeCommentsWrite), commentHandler.Update)
+		protected.DELETE("/comments/:id", middleware.RequireScope(consts.ScopeCommentsWrite), commentHandler.Delete)
+
+		protected.POST("/reports", middleware.RequireScope(consts.ScopeReportsWrite), reportHandler.Create)
+	}
+
+	// 评论实时推送，令牌可以放在 access_token 查询参数中
+	streams := r.Group("/api/v1")
+	streams.Use(middleware.QueryToken(), middleware.Auth(jwtKeys, apiKeyService, userService))
+	{
+		streams.GET("/posts/:id/comments/stream", streamHandler.CommentStream)
+		streams.GET("/ws/comments", streamHandler.WebSocket)
+	}
+
+	// 审核队列，审核员和管理员可以访问
+	moderationQueue := r.Group("/api/v1/moderation")
+	moderationQueue.Use(middleware.Negotiate(), middleware.Auth(jwtKeys, apiKeyService, userService), middleware.RequireScope(consts.ScopeAdmin), middleware.RequireModerator(userService))
+	{
+		moderationQueue.GET("/queue", moderationHandler.Queue)
+		moderationQueue.POST("/queue/:id/approve", moderationHandler.Approve)
+		moderationQueue.POST("/queue/:id/reject", moderationHandler.Reject)
+
+		moderationQueue.GET("/reports", reportHandler.List)
+		moderationQueue.GET("/reports/:id", reportHandler.Get)
+		moderationQueue.POST("/reports/:id/resolve", reportHandler.Resolve)
+	}
+
+	// 管理员路由
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.Auth(jwtKeys, apiKeyService, userService), middleware.RequireScope(consts.ScopeAdmin), middleware.RequireAdmin(userService))
+	{
+		admin.DELETE("/users/:id", userHandler.Delete)
+
+		admin.GET("/audit-logs", auditHandler.List)
+		admin.GET("/audit-logs/export", auditHandler.Export)
+		admin.GET("/posts/export", postTransferHandler.Export)
+		admin.POST("/posts/import", postTransferHandler.Import)
+
+		admin.GET("/stats/overview", statsHandler.Overview)
+		admin.GET("/stats/timeseries", statsHandler.TimeSeries)
+		admin.GET("/stats/top-authors", statsHandler.TopAuthors)
+		admin.GET("/stats/top-posts", statsHandler.TopPosts)
+		admin.GET("/stats/storage", statsHandler.Storage)
+
+		admin.POST("/webhooks", webhookHandler.Create)
+		admin.GET("/webhooks", webhookHandler.List)
+		admin.GET("/webhooks/:id", webhookHandler.Get)
+		admin.PUT("/webhooks/:id", webhookHandler.Update)
+		admin.DELETE("/webhooks/:id", webhookHandler.Delete)
+		admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+		admin.POST("/webhook-deliveries/:id/redeliver", webhookHandler.Redeliver)
+	}
+
+	return &App{
+		cfg:               cfg,
+		db:                db,
+		Router:            r,
+		GRPC:              grpcServer,
+		health:            healthService,
+		hub:               hub,
+		oidcService:       oidcService,
+		webhookService:    webhookService,
+		moderationService: moderationService,
+	}, nil
+}
+
+// Serve 启动后台任务、HTTP 和 gRPC 服务，ctx 取消后停止接收新请求，等待进行中的请求完成后返回
+func (a *App) Serve(ctx context.Context) error {
+	for name, providerCfg := range a.cfg.OIDC.Providers {
+		if err := a.oidcService.RegisterProvider(ctx, name, providerCfg); err != nil {
+			log.Printf("Skip OIDC provider %s: %v", name, err)
+		}
+	}
+	a.webhookService.Start(ctx)
+	if err := a.moderationService.LoadTraining(ctx); err != nil {
+		log.Printf("Skip training spam classifier: %v", err)
+	}
+
+	srv := &http.Server{
+		Addr:              a.cfg.Server.Host + ":" + a.cfg.Server.Port,
+		Handler:           a.Router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// 关闭时通知 SSE 和 WebSocket 长连接结束，否则 Shutdown 要等到超时
+	srv.RegisterOnShutdown(a.hub.Shutdown)
+
+	serverErr := make(chan error, 2)
+	go func() {
+		log.Printf("Server starting on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	if a.cfg.Server.GRPCPort != "" {
+		listener, err := net.Listen("tcp", a.cfg.Server.Host+":"+a.cfg.Server.GRPCPort)
+		if err != nil {
+			_ = srv.Close()
+			return fmt.Errorf("listen grpc: %w", err)
+		}
+		go func() {
+			log.Printf("gRPC server starting on %s", listener.Addr())
+			if err := a.GRPC.Serve(listener); err != nil {
+				serverErr <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		_ = srv.Close()
+		a.GRPC.Stop()
+		return fmt.Errorf("start server: %w", err)
+	case <-ctx.Done():
+	}
+
+	// 先让就绪检查失败，负载均衡摘除流量后再等待进行中的请求完成
+	// 摘除前仍会有新请求进来，等待 shutdown_delay 期间继续正常处理
+	log.Println("Shutting down server...")
+	a.health.SetDraining()
+	if delay := config.Duration(a.cfg.Server.ShutdownDelay, 0); delay > 0 {
+		log.Printf("Waiting %s for load balancers to stop routing traffic", delay)
+		time.Sleep(delay)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Duration(a.cfg.Server.ShutdownTimeout, 15*time.Second))
+	defer cancel()
+	go func() {
+		// GracefulStop 等待进行中的调用结束，超时后强制关闭连接
+		<-shutdownCtx.Done()
+		a.GRPC.Stop()
+	}()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	a.GRPC.GracefulStop()
+	log.Println("Server exited")
+	return nil
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss 缓存未命中
+var ErrMiss = errors.New("cache: miss")
+
+// Cache 缓存抽象，值为序列化后的字节；ttl 为 0 时使用实现的默认过期时间
+type Cache interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
+	// Incr 原子自增并返回新值，用于列表缓存的版本号
+	Incr(key string) (int64, error)
+}
+
+// Nop 不缓存任何数据，用于关闭缓存
+type Nop struct{}
+
+func (Nop) Get(string) ([]byte, error)              { return nil, ErrMiss }
+func (Nop) Set(string, []byte, time.Duration) error { return nil }
+func (Nop) Delete(...string) error                  { return nil }
+func (Nop) Incr(string) (int64, error)              { return 0, nil }
+
+// Pinger 依赖外部服务的缓存实现，用于就绪检查
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
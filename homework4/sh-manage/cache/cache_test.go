@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 两种实现共用的行为测试
+func testCacheBehavior(t *testing.T, c Cache) {
+	t.Helper()
+
+	if _, err := c.Get("missing"); err != ErrMiss {
+		t.Fatalf("Get missing = %v, want ErrMiss", err)
+	}
+
+	if err := c.Set("a", []byte("1"), 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if value, err := c.Get("a"); err != nil || string(value) != "1" {
+		t.Fatalf("Get a = %q, %v", value, err)
+	}
+
+	if err := c.Set("a", []byte("2"), 0); err != nil {
+		t.Fatalf("Set overwrite: %v", err)
+	}
+	if value, _ := c.Get("a"); string(value) != "2" {
+		t.Fatalf("Get a after overwrite = %q", value)
+	}
+
+	_ = c.Set("b", []byte("x"), 0)
+	if err := c.Delete("a", "b", "never-set"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, err := c.Get(key); err != ErrMiss {
+			t.Fatalf("Get %s after delete = %v, want ErrMiss", key, err)
+		}
+	}
+
+	first, err := c.Incr("gen")
+	if err != nil {
+		t.Fatalf("Incr: %v", err)
+	}
+	second, _ := c.Incr("gen")
+	if second != first+1 {
+		t.Fatalf("Incr = %d then %d", first, second)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	testCacheBehavior(t, NewLRU(100, time.Minute))
+}
+
+func TestWithPrefix(t *testing.T) {
+	inner := NewLRU(100, time.Minute)
+	acme := WithPrefix(inner, "tenant:1:")
+	testCacheBehavior(t, acme)
+
+	_ = acme.Set("post:1", []byte("acme"), 0)
+	if _, err := WithPrefix(inner, "tenant:2:").Get("post:1"); err != ErrMiss {
+		t.Fatalf("other prefix Get = %v, want ErrMiss", err)
+	}
+	// 重新设置前缀时替换而不是叠加
+	if value, err := WithPrefix(WithPrefix(inner, "tenant:2:"), "tenant:1:").Get("post:1"); err != nil || string(value) != "acme" {
+		t.Fatalf("rewrapped Get = %q, %v", value, err)
+	}
+	if value, err := inner.Get("tenant:1:post:1"); err != nil || string(value) != "acme" {
+		t.Fatalf("inner Get = %q, %v", value, err)
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2, time.Minute)
+	_ = c.Set("a", []byte("a"), 0)
+	_ = c.Set("b", []byte("b"), 0)
+	_, _ = c.Get("a") // a 变为最近使用
+	_ = c.Set("c", []byte("c"), 0)
+
+	if _, err := c.Get("b"); err != ErrMiss {
+		t.Fatalf("b should be evicted, got %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(key); err != nil {
+			t.Fatalf("%s should be kept, got %v", key, err)
+		}
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	_ = c.Set("default", []byte("v"), 0)
+	_ = c.Set("short", []byte("v"), time.Second)
+	_ = c.Set("forever", []byte("v"), -1)
+
+	now = now.Add(2 * time.Second)
+	if _, err := c.Get("short"); err != ErrMiss {
+		t.Fatalf("short should expire, got %v", err)
+	}
+	if _, err := c.Get("default"); err != nil {
+		t.Fatalf("default should be alive, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := c.Get("default"); err != ErrMiss {
+		t.Fatalf("default should expire, got %v", err)
+	}
+	if _, err := c.Get("forever"); err != nil {
+		t.Fatalf("forever should be alive, got %v", err)
+	}
+}
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedis(client, "test:", time.Minute), server
+}
+
+func TestRedis(t *testing.T) {
+	c, _ := newTestRedis(t)
+	testCacheBehavior(t, c)
+}
+
+func TestRedisUsesPrefixAndTTL(t *testing.T) {
+	c, server := newTestRedis(t)
+	_ = c.Set("a", []byte("v"), 0)
+	_ = c.Set("short", []byte("v"), time.Second)
+
+	if !server.Exists("test:a") {
+		t.Fatalf("key should be stored with prefix")
+	}
+	if ttl := server.TTL("test:a"); ttl != time.Minute {
+		t.Fatalf("default ttl = %v, want 1m", ttl)
+	}
+
+	server.FastForward(2 * time.Second)
+	if _, err := c.Get("short"); err != ErrMiss {
+		t.Fatalf("short should expire, got %v", err)
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	c, server := newTestRedis(t)
+	server.Close()
+	if _, err := c.Get("a"); err == nil || err == ErrMiss {
+		t.Fatalf("expected connection error, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"fmt"
+	"sh-manage/config"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// New 根据配置创建缓存，driver 为空或 none 时关闭缓存
+func New(cfg config.CacheConfig) (Cache, error) {
+	ttl := 5 * time.Minute
+	if cfg.TTL != "" {
+		parsed, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache ttl %q: %w", cfg.TTL, err)
+		}
+		ttl = parsed
+	}
+
+	switch cfg.Driver {
+	case "", "none":
+		return Nop{}, nil
+	case "memory":
+		return NewLRU(cfg.MaxEntries, ttl), nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		return NewRedis(client, cfg.Redis.Prefix, ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LRU 进程内缓存，按最近最少使用淘汰，并支持按条目过期
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	defaultTTL time.Duration
+	ll         *list.List
+	items      map[string]*list.Element
+	now        func() time.Time
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewLRU(maxEntries int, defaultTTL time.Duration) *LRU {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &LRU{
+		maxEntries: maxEntries,
+		defaultTTL: defaultTTL,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *LRU) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*lruEntry)
+	if c.expired(entry) {
+		c.removeElement(elem)
+		return nil, ErrMiss
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, nil
+}
+
+func (c *LRU) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, ttl)
+	return nil
+}
+
+func (c *LRU) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := c.items[key]; ok {
+			c.removeElement(elem)
+		}
+	}
+	return nil
+}
+
+func (c *LRU) Incr(key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 不存在时以当前时间为起点，避免被淘汰后重新从1开始与旧值重复
+	n := c.now().UnixNano()
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		if !c.expired(entry) {
+			n, _ = strconv.ParseInt(string(entry.value), 10, 64)
+		}
+	}
+	n++
+	// 版本号不过期
+	c.set(key, []byte(strconv.FormatInt(n, 10)), -1)
+	return n, nil
+}
+
+// Len 当前条目数（包含尚未清理的过期条目）
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// set ttl 为 0 使用默认过期时间，小于 0 表示不过期；调用方需持有锁
+func (c *LRU) set(key string, value []byte, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *LRU) expired(entry *lruEntry) bool {
+	return !entry.expiresAt.IsZero() && c.now().After(entry.expiresAt)
+}
+
+func (c *LRU) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,37 @@
+package cache
+
+import "time"
+
+// prefixed 为所有 key 加上前缀，同一个底层缓存可以按租户等维度隔离
+type prefixed struct {
+	inner  Cache
+	prefix string
+}
+
+// WithPrefix 返回为 key 加上 prefix 的缓存；c 已经带前缀时替换原前缀，不会叠加
+func WithPrefix(c Cache, prefix string) Cache {
+	if p, ok := c.(*prefixed); ok {
+		c = p.inner
+	}
+	return &prefixed{inner: c, prefix: prefix}
+}
+
+func (p *prefixed) Get(key string) ([]byte, error) {
+	return p.inner.Get(p.prefix + key)
+}
+
+func (p *prefixed) Set(key string, value []byte, ttl time.Duration) error {
+	return p.inner.Set(p.prefix+key, value, ttl)
+}
+
+func (p *prefixed) Delete(keys ...string) error {
+	prefixedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		prefixedKeys[i] = p.prefix + key
+	}
+	return p.inner.Delete(prefixedKeys...)
+}
+
+func (p *prefixed) Incr(key string) (int64, error) {
+	return p.inner.Incr(p.prefix + key)
+}
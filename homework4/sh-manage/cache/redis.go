@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 单次 Redis 操作的超时时间
+const redisTimeout = 500 * time.Millisecond
+
+// Redis 基于 Redis 协议的缓存实现，可以连接 Redis 或兼容协议的服务
+type Redis struct {
+	client     redis.UniversalClient
+	prefix     string
+	defaultTTL time.Duration
+}
+
+func NewRedis(client redis.UniversalClient, prefix string, defaultTTL time.Duration) *Redis {
+	return &Redis{client: client, prefix: prefix, defaultTTL: defaultTTL}
+}
+
+func (c *Redis) Get(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return value, err
+}
+
+func (c *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+	if ttl < 0 {
+		ttl = 0 // go-redis 中 0 表示不过期
+	}
+	return c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+}
+
+func (c *Redis) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, c.prefix+key)
+	}
+	return c.client.Del(ctx, prefixed...).Err()
+}
+
+func (c *Redis) Incr(key string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return c.client.Incr(ctx, c.prefix+key).Result()
+}
+
+// Ping 检查 Redis 是否可用
+func (c *Redis) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
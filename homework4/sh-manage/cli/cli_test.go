@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sh-manage/consts"
+	"sh-manage/database"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/tenant"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	content = strings.ReplaceAll(content, "UPLOAD_DIR", filepath.Join(dir, "uploads"))
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+const testConfig = `
+server:
+  port: "8080"
+jwt:
+  secret: "test-secret-0123456789"
+  expire: "1h"
+cache:
+  driver: "memory"
+  ttl: "1m"
+storage:
+  driver: "local"
+  local_dir: "UPLOAD_DIR"
+  base_url: "/static/uploads"
+`
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetArgs(args)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestConfigValidate(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	out, err := run(t, "--config", path, "config", "validate")
+	if err != nil || !strings.Contains(out, "is valid") {
+		t.Fatalf("validate valid config: out = %q, err = %v", out, err)
+	}
+
+	bad := writeConfig(t, strings.Replace(testConfig, "test-secret-0123456789", "short", 1))
+	if _, err := run(t, "--config", bad, "config", "validate"); err == nil || !strings.Contains(err.Error(), "jwt.secret") {
+		t.Fatalf("validate invalid config: err = %v", err)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	out, err := run(t, "--config", path, "routes")
+	if err != nil {
+		t.Fatalf("routes: %v", err)
+	}
+	for _, want := range []string{"METHOD", "/readyz", "/api/v1/users/login", "/api/v1/admin/webhooks/:id", "/api/v1/admin/users/:id", "/api/v1/moderation/queue/:id/approve", "/api/v1/moderation/reports/:id/resolve", "/api/v1/reports"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("routes output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSeedIsIdempotent(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatalf("use tenant plugin: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	def, err := resolveTenant(db, consts.DefaultTenant)
+	if err != nil {
+		t.Fatalf("resolve default tenant: %v", err)
+	}
+	defDB := db.WithContext(tenant.WithID(context.Background(), def.ID))
+
+	opts := seedOptions{password: "password123", postsPerUser: 2, commentsPerPost: 2}
+	result, err := seed(defDB, opts)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if result != (seedResult{Users: 3, Posts: 6, Comments: 12}) {
+		t.Fatalf("first seed = %+v", result)
+	}
+
+	result, err = seed(defDB, opts)
+	if err != nil || result != (seedResult{}) {
+		t.Fatalf("second seed = %+v, %v", result, err)
+	}
+
+	var posts int64
+	defDB.Model(&models.Post{}).Count(&posts)
+	if posts != 6 {
+		t.Fatalf("posts = %d, want 6", posts)
+	}
+
+	// 同名演示用户可以写入另一个租户，且互不可见
+	acme, appErr := services.NewTenantService(db, nil).CreateTenant("acme", "Acme")
+	if appErr != nil {
+		t.Fatalf("create tenant: %v", appErr)
+	}
+	acmeDB := db.WithContext(tenant.WithID(context.Background(), acme.ID))
+	result, err = seed(acmeDB, opts)
+	if err != nil || result.Users != 3 {
+		t.Fatalf("seed acme = %+v, %v", result, err)
+	}
+	defDB.Model(&models.Post{}).Count(&posts)
+	if posts != 6 {
+		t.Fatalf("default tenant posts = %d, want 6", posts)
+	}
+	db.Model(&models.Post{}).Count(&posts)
+	if posts != 12 {
+		t.Fatalf("unscoped posts = %d, want 12", posts)
+	}
+}
+
+func TestTransferRequiresTenant(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	for _, args := range [][]string{{"export"}, {"import", "--file", "posts.json"}} {
+		if _, err := run(t, append([]string{"--config", path}, args...)...); err == nil || !strings.Contains(err.Error(), `"tenant" not set`) {
+			t.Fatalf("%s without --tenant: err = %v", args[0], err)
+		}
+	}
+}
+
+func TestErrorsReferenceIsUpToDate(t *testing.T) {
+	out, err := run(t, "errors")
+	if err != nil {
+		t.Fatalf("errors: %v", err)
+	}
+	committed, err := os.ReadFile("../docs/errors.md")
+	if err != nil {
+		t.Fatalf("read docs/errors.md: %v", err)
+	}
+	if out != string(committed) {
+		t.Fatal("docs/errors.md is out of date, run go generate ./cli")
+	}
+	if !strings.Contains(out, "| `USER_EXISTS` | 409 |") {
+		t.Fatalf("reference missing USER_EXISTS:\n%s", out)
+	}
+}
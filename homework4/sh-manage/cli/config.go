@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"fmt"
+	"sh-manage/config"
+
+	"github.com/spf13/cobra"
+)
+
+func newConfigCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "配置文件相关操作",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "读取并校验配置文件，失败时以状态码 1 退出",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, path, err := config.Read(opts.configPath)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Config %s is valid\n", path)
+			return nil
+		},
+	})
+	return cmd
+}
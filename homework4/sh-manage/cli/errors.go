@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"os"
+	"sh-manage/utils"
+
+	"github.com/spf13/cobra"
+)
+
+//go:generate go run .. errors --output ../docs/errors.md
+
+func newErrorsCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "errors",
+		Short: "生成错误码参考文档",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				return utils.WriteErrorReference(cmd.OutOrStdout())
+			}
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if err := utils.WriteErrorReference(file); err != nil {
+				file.Close()
+				return err
+			}
+			return file.Close()
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "写入的文件，默认输出到标准输出")
+	return cmd
+}
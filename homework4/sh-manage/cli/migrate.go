@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"fmt"
+	"sh-manage/database"
+	"sh-manage/models"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "根据模型自动迁移数据库表结构",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, db, err := opts.openDB(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if err := database.Migrate(db); err != nil {
+				return fmt.Errorf("migrate database: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Migrated %d models\n", len(models.GetModels()))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,84 @@
+// Package cli 定义 sh-manage 的子命令
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sh-manage/config"
+	"sh-manage/database"
+	"sh-manage/models"
+	"sh-manage/services"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// options 所有子命令共用的全局参数
+type options struct {
+	configPath string
+}
+
+// NewRootCommand 创建根命令，不带子命令运行时等同于 serve
+func NewRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:           "sh-manage",
+		Short:         "博客管理服务",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&opts.configPath, "config", "", "配置文件路径，默认在 ./config、../config 中查找 config.yaml")
+
+	serve := newServeCommand(opts)
+	root.RunE = serve.RunE
+	root.AddCommand(
+		serve,
+		newMigrateCommand(opts),
+		newUserCommand(opts),
+		newSeedCommand(opts),
+		newExportCommand(opts),
+		newImportCommand(opts),
+		newTenantCommand(opts),
+		newConfigCommand(opts),
+		newRoutesCommand(opts),
+		newErrorsCommand(),
+	)
+	return root
+}
+
+// Execute 运行命令行，出错时输出到标准错误并以状态码 1 退出
+func Execute() {
+	if err := NewRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// loadConfig 读取并校验配置，不监听文件变化
+func (o *options) loadConfig() (*config.Config, error) {
+	cfg, _, err := config.Read(o.configPath)
+	return cfg, err
+}
+
+// openDB 读取配置并连接数据库
+func (o *options) openDB(ctx context.Context) (*config.Config, *gorm.DB, error) {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := database.Open(ctx, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect database: %w", err)
+	}
+	return cfg, db, nil
+}
+
+// resolveTenant 按标识查找租户，租户由 migrate 或 tenant create 创建
+func resolveTenant(db *gorm.DB, slug string) (*models.Tenant, error) {
+	t, err := services.NewTenantService(db, nil).Resolve(slug)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %q: %w", slug, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"sh-manage/app"
+	"sh-manage/config"
+	"text/tabwriter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func newRoutesCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "routes",
+		Short: "打印路由表",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := opts.loadConfig()
+			if err != nil {
+				return err
+			}
+			// 只注册路由不处理请求，不需要真正连接数据库
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				DSN:                       config.GetMySQLDSN(cfg),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{DisableAutomaticPing: true})
+			if err != nil {
+				return err
+			}
+
+			gin.SetMode(gin.ReleaseMode)
+			application, err := app.New(cfg, db)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "METHOD\tPATH\tHANDLER")
+			for _, route := range application.Router.Routes() {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", route.Method, route.Path, route.Handler)
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/passwords"
+	"sh-manage/tenant"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// 演示数据使用的用户和标签，已存在的用户会被跳过，重复执行不会产生重复数据
+var (
+	seedUsernames = []string{"alice", "bob", "carol"}
+	seedTags      = []string{"go", "gin", "gorm", "notes"}
+)
+
+type seedOptions struct {
+	tenant   string
+	password string
+	// passwords 生成密码哈希，为 nil 时使用默认配置
+	passwords       *passwords.Manager
+	postsPerUser    int
+	commentsPerPost int
+}
+
+type seedResult struct {
+	Users    int
+	Posts    int
+	Comments int
+}
+
+func newSeedCommand(opts *options) *cobra.Command {
+	seedOpts := seedOptions{}
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "写入演示用的用户、文章和评论",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, db, err := opts.openDB(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if seedOpts.passwords, err = passwords.New(cfg.Password); err != nil {
+				return err
+			}
+			t, err := resolveTenant(db, seedOpts.tenant)
+			if err != nil {
+				return err
+			}
+			result, err := seed(db.WithContext(tenant.WithID(cmd.Context(), t.ID)), seedOpts)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Seeded %d users, %d posts, %d comments\n", result.Users, result.Posts, result.Comments)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&seedOpts.tenant, "tenant", consts.DefaultTenant, "写入数据的租户")
+	cmd.Flags().StringVar(&seedOpts.password, "password", "sh-manage-demo", "演示用户的密码，不检查密码策略")
+	cmd.Flags().IntVar(&seedOpts.postsPerUser, "posts", 3, "每个用户的文章数")
+	cmd.Flags().IntVar(&seedOpts.commentsPerPost, "comments", 2, "每篇文章的评论数")
+	return cmd
+}
+
+// seed 在一个事务中写入演示数据，评论由其他演示用户发表。
+// 用户、文章和评论写入 db 上下文中的租户，标签为全局共享
+func seed(db *gorm.DB, opts seedOptions) (seedResult, error) {
+	var result seedResult
+	manager := opts.passwords
+	if manager == nil {
+		manager = passwords.Default()
+	}
+	hashedPassword, err := manager.Hash(opts.password)
+	if err != nil {
+		return result, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		tags := make([]models.Tag, len(seedTags))
+		for i, name := range seedTags {
+			if err := tx.Where(models.Tag{Name: name}).FirstOrCreate(&tags[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		var created []models.User
+		for _, username := range seedUsernames {
+			var count int64
+			if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			user := models.User{
+				Username: username,
+				Email:    username + "@example.com",
+				Password: hashedPassword,
+				Role:     consts.RoleUser,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			created = append(created, user)
+		}
+		result.Users = len(created)
+
+		now := time.Now()
+		for i, author := range created {
+			for n := 1; n <= opts.postsPerUser; n++ {
+				post := models.Post{
+					Title:       fmt.Sprintf("%s 的第 %d 篇文章", author.Username, n),
+					Content:     fmt.Sprintf("这是 %s 写的演示文章，用于本地开发和联调。", author.Username),
+					UserId:      author.ID,
+					Tags:        []models.Tag{tags[(i+n)%len(tags)]},
+					Status:      consts.PostStatusPublished,
+					PublishedAt: &now,
+				}
+				if err := tx.Create(&post).Error; err != nil {
+					return err
+				}
+				result.Posts++
+
+				for c := 0; c < opts.commentsPerPost && len(created) > 1; c++ {
+					commenter := created[(i+c+1)%len(created)]
+					if commenter.ID == author.ID {
+						continue
+					}
+					comment := models.Comment{
+						Content: fmt.Sprintf("%s 的评论", commenter.Username),
+						UserId:  commenter.ID,
+						PostId:  post.ID,
+					}
+					if err := tx.Create(&comment).Error; err != nil {
+						return err
+					}
+					result.Comments++
+				}
+			}
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"sh-manage/app"
+	"sh-manage/config"
+	"sh-manage/database"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+func newServeCommand(opts *options) *cobra.Command {
+	var migrate bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "启动 HTTP 服务，收到 SIGINT/SIGTERM 后优雅退出",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// serve 需要监听配置文件变化，使用 Load 而不是 Read
+			cfg := config.Load(opts.configPath)
+
+			// 收到 SIGINT/SIGTERM 时取消，启动阶段的数据库重试和后台任务都会随之退出
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			db, err := database.Open(ctx, cfg)
+			if err != nil {
+				return fmt.Errorf("connect database: %w", err)
+			}
+			defer func() {
+				if sqlDB, err := db.DB(); err == nil {
+					sqlDB.Close()
+				}
+			}()
+
+			if migrate {
+				if err := database.Migrate(db); err != nil {
+					return fmt.Errorf("migrate database: %w", err)
+				}
+			}
+
+			application, err := app.New(cfg, db)
+			if err != nil {
+				return err
+			}
+			return application.Serve(ctx)
+		},
+	}
+	cmd.Flags().BoolVar(&migrate, "migrate", true, "启动前自动迁移数据库")
+	return cmd
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"sh-manage/services"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func newTenantCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "租户管理",
+	}
+	cmd.AddCommand(newTenantCreateCommand(opts), newTenantListCommand(opts))
+	return cmd
+}
+
+func newTenantCreateCommand(opts *options) *cobra.Command {
+	var slug, name string
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "创建租户",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, db, err := opts.openDB(cmd.Context())
+			if err != nil {
+				return err
+			}
+			t, appErr := services.NewTenantService(db, nil).CreateTenant(slug, name)
+			if appErr != nil {
+				return appErr
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Created tenant %s (id=%d)\n", t.Slug, t.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&slug, "slug", "", "租户标识，用作子域名和 X-Tenant-ID 请求头的值")
+	cmd.Flags().StringVar(&name, "name", "", "租户名称，默认与标识相同")
+	cmd.MarkFlagRequired("slug")
+	return cmd
+}
+
+func newTenantListCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "列出所有租户",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, db, err := opts.openDB(cmd.Context())
+			if err != nil {
+				return err
+			}
+			tenants, appErr := services.NewTenantService(db, nil).ListTenants()
+			if appErr != nil {
+				return appErr
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSLUG\tNAME")
+			for _, t := range tenants {
+				fmt.Fprintf(w, "%d\t%s\t%s\n", t.ID, t.Slug, t.Name)
+			}
+			return w.Flush()
+		},
+	}
+}
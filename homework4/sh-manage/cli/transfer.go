@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sh-manage/cache"
+	"sh-manage/moderation"
+	"sh-manage/services"
+	"sh-manage/transfer"
+
+	"github.com/spf13/cobra"
+)
+
+// transferOptions 导入导出共用的参数，必须指定租户，避免跨租户读写文章
+type transferOptions struct {
+	tenant string
+	format string
+	file   string
+}
+
+func (t *transferOptions) addFlags(cmd *cobra.Command, fileUsage string) {
+	cmd.Flags().StringVar(&t.tenant, "tenant", "", "导入导出的租户")
+	cmd.Flags().StringVar(&t.format, "format", "", "文件格式: json、csv、markdown，默认按文件扩展名推断")
+	cmd.Flags().StringVar(&t.file, "file", "", fileUsage)
+	cmd.MarkFlagRequired("tenant")
+}
+
+// transferService 连接数据库并创建限定在租户内的导入导出服务
+// 使用与服务端相同的缓存，导入后正在运行的服务能看到新文章
+func (t *transferOptions) transferService(cmd *cobra.Command, opts *options) (*services.PostTransferService, error) {
+	cfg, db, err := opts.openDB(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolveTenant(db, t.tenant)
+	if err != nil {
+		return nil, err
+	}
+	cacheStore, err := cache.New(cfg.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("create cache: %w", err)
+	}
+	// 导入的内容与发布时一样经过审核，需要审核的内容进入审核队列
+	svc := services.NewPostTransferService(db, cacheStore)
+	svc.SetModerator(services.NewModerationService(db, moderation.New(cfg.Moderation), cacheStore))
+	return svc.WithTenant(target.ID), nil
+}
+
+// resolveFormat 未指定格式时按文件扩展名推断，都没有时使用 JSON
+func (t *transferOptions) resolveFormat() (transfer.Format, error) {
+	name := t.format
+	if name == "" {
+		name = filepath.Ext(t.file)
+	}
+	if name == "" {
+		return transfer.FormatJSON, nil
+	}
+	return transfer.ParseFormat(name)
+}
+
+func newExportCommand(opts *options) *cobra.Command {
+	transferOpts := &transferOptions{}
+	cmd := &cobra.Command{
+		Use:     "export",
+		Short:   "导出租户的全部文章及评论",
+		Example: "  sh-manage export --tenant default --format markdown --file posts.zip",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := transferOpts.resolveFormat()
+			if err != nil {
+				return err
+			}
+			service, err := transferOpts.transferService(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			if transferOpts.file != "" {
+				f, err := os.Create(transferOpts.file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			if appErr := service.Export(w, format); appErr != nil {
+				return appErr
+			}
+			return nil
+		},
+	}
+	transferOpts.addFlags(cmd, "导出的文件，为空时写到标准输出")
+	return cmd
+}
+
+func newImportCommand(opts *options) *cobra.Command {
+	transferOpts := &transferOptions{}
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:     "import",
+		Short:   "导入文章，作者和评论人按用户名匹配租户内已有的用户",
+		Example: "  sh-manage import --tenant default --file posts.csv --dry-run",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := transferOpts.resolveFormat()
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(transferOpts.file)
+			if err != nil {
+				return err
+			}
+			service, err := transferOpts.transferService(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			result, appErr := service.Import(data, format, dryRun)
+			if appErr != nil {
+				return appErr
+			}
+			if err := writeImportResult(cmd.OutOrStdout(), result); err != nil {
+				return err
+			}
+			if result.Failed > 0 {
+				return fmt.Errorf("%d of %d records failed to import", result.Failed, result.Total)
+			}
+			return nil
+		},
+	}
+	transferOpts.addFlags(cmd, "导入的文件")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "只校验不写入")
+	return cmd
+}
+
+// writeImportResult 输出 JSON 格式的导入报告，便于脚本处理
+func writeImportResult(w io.Writer, result *transfer.ImportResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
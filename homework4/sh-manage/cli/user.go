@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/services"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+func newUserCommand(opts *options) *cobra.Command {
+	var slug string
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "用户管理",
+	}
+	cmd.PersistentFlags().StringVar(&slug, "tenant", consts.DefaultTenant, "用户所属的租户")
+	cmd.AddCommand(newCreateAdminCommand(opts, &slug), newResetPasswordCommand(opts, &slug), newSetRoleCommand(opts, &slug))
+	return cmd
+}
+
+// userService 创建限定在指定租户内的用户服务
+func userService(db *gorm.DB, slug string) (*services.UserService, error) {
+	t, err := resolveTenant(db, slug)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewUserService(db, nil).WithTenant(t.ID), nil
+}
+
+func newCreateAdminCommand(opts *options, slug *string) *cobra.Command {
+	var req models.CreateUserRequest
+	cmd := &cobra.Command{
+		Use:   "create-admin",
+		Short: "创建管理员账号",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, err := passwordOrStdin(cmd, req.Password)
+			if err != nil {
+				return err
+			}
+			req.Password = password
+			if err := binding.Validator.ValidateStruct(&req); err != nil {
+				return err
+			}
+
+			_, db, err := opts.openDB(cmd.Context())
+			if err != nil {
+				return err
+			}
+			service, err := userService(db, *slug)
+			if err != nil {
+				return err
+			}
+			user, err := service.CreateAdmin(req)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Created admin %s (id=%d)\n", user.Username, user.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&req.Username, "username", "", "用户名")
+	cmd.Flags().StringVar(&req.Email, "email", "", "邮箱")
+	cmd.Flags().StringVar(&req.Password, "password", "", "密码，为空时从标准输入读取一行")
+	cmd.MarkFlagRequired("username")
+	cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+func newResetPasswordCommand(opts *options, slug *string) *cobra.Command {
+	var username, password string
+	cmd := &cobra.Command{
+		Use:   "reset-password",
+		Short: "重置用户密码",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, err := passwordOrStdin(cmd, password)
+			if err != nil {
+				return err
+			}
+			// 与注册接口的密码规则保持一致
+			if err := binding.Validator.ValidateStruct(&models.CreateUserRequest{Username: username, Email: "cli@example.com", Password: password}); err != nil {
+				return err
+			}
+
+			_, db, err := opts.openDB(cmd.Context())
+			if err != nil {
+				return err
+			}
+			service, err := userService(db, *slug)
+			if err != nil {
+				return err
+			}
+			user, err := service.ResetPassword(username, password)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Password reset for %s (id=%d)\n", user.Username, user.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&username, "username", "", "用户名")
+	cmd.Flags().StringVar(&password, "password", "", "新密码，为空时从标准输入读取一行")
+	cmd.MarkFlagRequired("username")
+	return cmd
+}
+
+func newSetRoleCommand(opts *options, slug *string) *cobra.Command {
+	var username, role string
+	cmd := &cobra.Command{
+		Use:   "set-role",
+		Short: "修改用户角色，例如把用户设为审核员",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, db, err := opts.openDB(cmd.Context())
+			if err != nil {
+				return err
+			}
+			service, err := userService(db, *slug)
+			if err != nil {
+				return err
+			}
+			user, err := service.SetRole(username, role)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Role of %s (id=%d) set to %s\n", user.Username, user.ID, user.Role)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&username, "username", "", "用户名")
+	cmd.Flags().StringVar(&role, "role", "", "user、moderator 或 admin")
+	cmd.MarkFlagRequired("username")
+	cmd.MarkFlagRequired("role")
+	return cmd
+}
+
+// passwordOrStdin 优先使用参数中的密码，否则从标准输入读取，避免密码出现在 shell 历史中
+func passwordOrStdin(cmd *cobra.Command, password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password = strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is required")
+	}
+	return password, nil
+}
@@ -10,15 +10,35 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	OIDC       OIDCConfig       `mapstructure:"oidc"`
+	Cache      CacheConfig      `mapstructure:"cache"`
+	Feed       FeedConfig       `mapstructure:"feed"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	Webhook    WebhookConfig    `mapstructure:"webhook"`
+	Log        LogConfig        `mapstructure:"log"`
+	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
+	CORS       CORSConfig       `mapstructure:"cors"`
+	Tenancy    TenancyConfig    `mapstructure:"tenancy"`
+	Moderation ModerationConfig `mapstructure:"moderation"`
+	Password   PasswordConfig   `mapstructure:"password"`
 }
 
 type ServerConfig struct {
 	Port string `mapstructure:"port"`
 	Host string `mapstructure:"host"`
 	Mode string `mapstructure:"mode"`
+	// GRPCPort gRPC 服务的端口，为空时不启动
+	GRPCPort string `mapstructure:"grpc_port"`
+	// ShutdownTimeout 收到退出信号后等待进行中请求完成的最长时间
+	ShutdownTimeout string `mapstructure:"shutdown_timeout"`
+	// ShutdownDelay 就绪检查失败后继续接收请求的时间，让负载均衡先摘除流量，为空时不等待
+	ShutdownDelay string `mapstructure:"shutdown_delay"`
+	// TrustedProxies 可信反向代理的 IP 或 CIDR，只有来自这些地址的 X-Forwarded-For 才用于识别客户端 IP
+	// 为空时不信任任何代理，限流和审计日志使用连接的对端地址
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
@@ -27,11 +47,164 @@ type DatabaseConfig struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	DBName   string `mapstructure:"dbname"`
+	// 启动时数据库不可用会按指数退避重试，ConnectBackoff 为第一次重试的等待时间
+	ConnectRetries int    `mapstructure:"connect_retries"`
+	ConnectBackoff string `mapstructure:"connect_backoff"`
 }
 
 type JWTConfig struct {
 	Secret string `mapstructure:"secret"`
 	Expire string `mapstructure:"expire"`
+	// RotationGrace 修改 secret 后旧密钥签发的令牌继续有效的时长，默认与 expire 相同
+	RotationGrace string `mapstructure:"rotation_grace"`
+}
+
+type LogConfig struct {
+	Level string `mapstructure:"level"` // debug, info, warn, error
+}
+
+type RateLimitConfig struct {
+	Enabled           bool    `mapstructure:"enabled"`
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"` // 每个客户端IP的平均速率
+	Burst             int     `mapstructure:"burst"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins 允许跨域的来源，包含 * 时允许所有来源
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+type TenancyConfig struct {
+	// BaseDomain 按子域名解析租户时的根域名，例如 example.com 时 acme.example.com 对应租户 acme，为空时不按子域名解析
+	BaseDomain string `mapstructure:"base_domain"`
+	// Header 指定租户的请求头，默认 X-Tenant-ID，优先于子域名
+	Header string `mapstructure:"header"`
+	// Default 既没有请求头也没有子域名时使用的租户，默认 default
+	Default string `mapstructure:"default"`
+	// Overrides key 为租户标识，覆盖该租户的限流和跨域配置
+	Overrides map[string]TenantOverride `mapstructure:"overrides"`
+}
+
+// TenantOverride 为 nil 的部分使用全局配置
+type TenantOverride struct {
+	RateLimit *RateLimitConfig `mapstructure:"rate_limit"`
+	CORS      *CORSConfig      `mapstructure:"cors"`
+}
+
+// ForTenant 返回合并了租户覆盖配置的副本，没有覆盖时返回 c 本身，不能修改返回值
+func (c *Config) ForTenant(slug string) *Config {
+	override, ok := c.Tenancy.Overrides[slug]
+	if !ok {
+		return c
+	}
+	merged := *c
+	if override.RateLimit != nil {
+		merged.RateLimit = *override.RateLimit
+	}
+	if override.CORS != nil {
+		merged.CORS = *override.CORS
+	}
+	return &merged
+}
+
+// ModerationConfig 文章和评论的内容审核，修改后立即生效
+type ModerationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// BlockedWords 包含这些词的内容直接拒绝，ReviewWords 包含这些词的内容进入审核队列
+	BlockedWords []string `mapstructure:"blocked_words"`
+	ReviewWords  []string `mapstructure:"review_words"`
+	// MaxLinks 链接数超过该值时进入审核队列，为 0 时使用默认值 3，小于 0 时不检查
+	MaxLinks int `mapstructure:"max_links"`
+	// DuplicateWindow 同一作者在该时间内重复提交相同内容时进入审核队列
+	DuplicateWindow string `mapstructure:"duplicate_window"`
+	// SpamThreshold 垃圾内容评分达到该值时进入审核队列，取值 0 到 1，为 0 时使用默认值 0.9
+	SpamThreshold float64 `mapstructure:"spam_threshold"`
+}
+
+// PasswordConfig 密码哈希和密码策略，修改后立即生效，已有用户的哈希在下次登录成功时按新配置重新生成
+type PasswordConfig struct {
+	// Algorithm 新密码使用的哈希算法：argon2id（默认）或 bcrypt，两种算法生成的已有哈希都可以校验
+	Algorithm string       `mapstructure:"algorithm"`
+	Argon2    Argon2Config `mapstructure:"argon2"`
+	// BcryptCost 为 0 时使用默认值 10
+	BcryptCost int `mapstructure:"bcrypt_cost"`
+	// MinLength、MaxLength 按字符计算，为 0 时使用默认值 8 和 128
+	MinLength int `mapstructure:"min_length"`
+	MaxLength int `mapstructure:"max_length"`
+	// MinClasses 至少包含的字符种类数（小写字母、大写字母、数字、其他字符），为 0 时不检查
+	MinClasses int `mapstructure:"min_classes"`
+	// BreachedList 泄露密码列表文件，每行一个，与内置的常见密码列表一起检查，忽略大小写
+	BreachedList string `mapstructure:"breached_list"`
+}
+
+// Argon2Config argon2id 的参数，为 0 的参数使用默认值
+type Argon2Config struct {
+	Memory      uint32 `mapstructure:"memory"`      // 内存，单位 KiB，默认 19456
+	Iterations  uint32 `mapstructure:"iterations"`  // 默认 2
+	Parallelism uint8  `mapstructure:"parallelism"` // 默认 1
+}
+
+type OIDCConfig struct {
+	// key为提供方名称，例如 google、keycloak，对应路由 /auth/oidc/:provider
+	Providers map[string]OIDCProviderConfig `mapstructure:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+type CacheConfig struct {
+	Driver     string      `mapstructure:"driver"` // memory, redis, none
+	TTL        string      `mapstructure:"ttl"`
+	MaxEntries int         `mapstructure:"max_entries"`
+	Redis      RedisConfig `mapstructure:"redis"`
+}
+
+type FeedConfig struct {
+	Title       string `mapstructure:"title"`
+	Description string `mapstructure:"description"`
+	BaseURL     string `mapstructure:"base_url"` // 生成文章链接使用的站点地址
+	Limit       int    `mapstructure:"limit"`    // 每个订阅源的文章数
+}
+
+type StorageConfig struct {
+	Driver       string   `mapstructure:"driver"`    // local, s3
+	LocalDir     string   `mapstructure:"local_dir"` // local 驱动的存储目录
+	BaseURL      string   `mapstructure:"base_url"`  // local 驱动对外访问的路径前缀
+	MaxSize      int64    `mapstructure:"max_size"`  // 单个文件的最大字节数
+	AllowedTypes []string `mapstructure:"allowed_types"`
+	// Thumbnails 缩略图名称到最大边长的映射
+	Thumbnails map[string]int `mapstructure:"thumbnails"`
+	S3         S3Config       `mapstructure:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"`
+	PublicURL string `mapstructure:"public_url"`
+}
+
+type WebhookConfig struct {
+	Timeout      string `mapstructure:"timeout"`      // 单次投递的超时时间
+	MaxAttempts  int    `mapstructure:"max_attempts"` // 达到次数后标记为失败
+	BaseBackoff  string `mapstructure:"base_backoff"` // 第一次重试的等待时间，之后每次翻倍
+	MaxBackoff   string `mapstructure:"max_backoff"`
+	PollInterval string `mapstructure:"poll_interval"` // 扫描待重试投递的间隔
+}
+
+type RedisConfig struct {
+	Addr     string `mapstructure:"addr"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	Prefix   string `mapstructure:"prefix"`
 }
 
 func LoadSimple() *Config {
@@ -41,6 +214,10 @@ func LoadSimple() *Config {
 			Port: "8080",
 			Host: "0.0.0.0",
 			Mode: "debug",
+
+			GRPCPort:        "9090",
+			ShutdownTimeout: "15s",
+			ShutdownDelay:   "5s",
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -48,54 +225,95 @@ func LoadSimple() *Config {
 			Username: "root",
 			Password: "password",
 			DBName:   "mydb",
+
+			ConnectRetries: 5,
+			ConnectBackoff: "1s",
 		},
 		JWT: JWTConfig{
 			Secret: "your-secret-key-change-in-production",
 			Expire: "24h",
 		},
+		Log: LogConfig{Level: "info"},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+		},
+		Cache: CacheConfig{
+			Driver:     "memory",
+			TTL:        "5m",
+			MaxEntries: 10000,
+		},
 	}
 }
 
 var GlobalConfig Config
 
+// Load 读取配置并监听文件变化，读取或校验失败时 panic
 func Load(configPath string) *Config {
+	cfg, err := read(viper.GetViper(), configPath)
+	if err != nil {
+		panic(err)
+	}
+	setCurrent(cfg)
+
+	// 监听配置文件变化，校验通过后才替换当前配置并通知订阅者
+	viper.WatchConfig()
+	viper.OnConfigChange(func(in fsnotify.Event) {
+		log.Println("配置文件已修改:", in.Name)
+		var next Config
+		if error := viper.Unmarshal(&next); error != nil {
+			log.Println("解析配置文件失败，继续使用原配置:", error)
+			return
+		}
+		if err := Apply(&next); err != nil {
+			log.Println("配置校验失败，继续使用原配置:", err)
+		}
+	})
+
+	log.Println("配置文件加载成功:", viper.ConfigFileUsed())
+	return Current()
+
+}
+
+// Read 只读取并校验配置，不修改当前配置也不监听文件，返回实际使用的配置文件路径
+func Read(configPath string) (*Config, string, error) {
+	v := viper.New()
+	cfg, err := read(v, configPath)
+	if err != nil {
+		return nil, v.ConfigFileUsed(), err
+	}
+	return cfg, v.ConfigFileUsed(), nil
+}
+
+func read(v *viper.Viper, configPath string) (*Config, error) {
 	if configPath != "" {
-		viper.SetConfigFile(configPath)
+		v.SetConfigFile(configPath)
 	} else {
-		viper.SetConfigName("config")
-		viper.AddConfigPath("./config")
-		viper.AddConfigPath("../config")
-		viper.AddConfigPath("../../config")
+		v.SetConfigName("config")
+		v.AddConfigPath("./config")
+		v.AddConfigPath("../config")
+		v.AddConfigPath("../../config")
 
 	}
 
-	viper.SetConfigType("yaml")
-	viper.AutomaticEnv()
-	viper.SetEnvPrefix("SH_MANAGE")
+	v.SetConfigType("yaml")
+	v.AutomaticEnv()
+	v.SetEnvPrefix("SH_MANAGE")
 
 	//环境标量使用下划线替换点
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	if err := viper.ReadInConfig(); err != nil {
-		panic(fmt.Errorf("读取配置文件失败: %w", err))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
-	if error := viper.Unmarshal(&GlobalConfig); error != nil {
-		panic(fmt.Errorf("解析配置文件失败: %w", error))
+	var cfg Config
+	if error := v.Unmarshal(&cfg); error != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", error)
 	}
-
-	// 监听配置文件变化
-	viper.WatchConfig()
-	viper.OnConfigChange(func(in fsnotify.Event) {
-		fmt.Println("配置文件已修改:", in.Name)
-		if error := viper.Unmarshal(&GlobalConfig); error != nil {
-			fmt.Println("解析配置文件失败:", error)
-		}
-	})
-
-	log.Println("配置文件加载成功:", viper.ConfigFileUsed())
-	return &GlobalConfig
-
+	if err := Validate(&cfg); err != nil {
+		return nil, fmt.Errorf("配置校验失败: %w", err)
+	}
+	return &cfg, nil
 }
 
 func GetMySQLDSN(config *Config) string {
@@ -105,6 +323,6 @@ func GetMySQLDSN(config *Config) string {
 	port := config.Database.Port
 	dbname := config.Database.DBName
 
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		user, pass, host, port, dbname)
 }
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// ChangeHandler 配置热更新的订阅者，old 和 next 都已通过校验，不能修改
+type ChangeHandler func(old, next *Config)
+
+var (
+	mu       sync.RWMutex
+	current  *Config
+	handlers []ChangeHandler
+)
+
+// Current 返回当前生效的配置，热更新时整体替换，不会被原地修改
+func Current() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// OnChange 注册配置变更的订阅者
+func OnChange(handler ChangeHandler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+// Apply 校验并替换当前配置，然后依次通知订阅者；校验失败时保持原配置
+func Apply(next *Config) error {
+	if err := Validate(next); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	old := current
+	current = next
+	GlobalConfig = *next
+	subscribers := append([]ChangeHandler(nil), handlers...)
+	mu.Unlock()
+
+	if old == nil {
+		return nil
+	}
+	for _, handler := range subscribers {
+		handler(old, next)
+	}
+	return nil
+}
+
+func setCurrent(cfg *Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = cfg
+	GlobalConfig = *cfg
+}
+
+// Validate 校验会在运行时使用的配置项
+func Validate(cfg *Config) error {
+	var errs []error
+	if len(cfg.JWT.Secret) < 16 {
+		errs = append(errs, errors.New("jwt.secret must be at least 16 characters"))
+	}
+	errs = appendDurationError(errs, "jwt.expire", cfg.JWT.Expire)
+	errs = appendDurationError(errs, "jwt.rotation_grace", cfg.JWT.RotationGrace)
+	errs = appendDurationError(errs, "cache.ttl", cfg.Cache.TTL)
+	errs = appendDurationError(errs, "server.shutdown_timeout", cfg.Server.ShutdownTimeout)
+	errs = appendDurationError(errs, "server.shutdown_delay", cfg.Server.ShutdownDelay)
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("server.trusted_proxies: invalid IP or CIDR %q", proxy))
+		}
+	}
+	errs = appendDurationError(errs, "database.connect_backoff", cfg.Database.ConnectBackoff)
+	errs = appendDurationError(errs, "moderation.duplicate_window", cfg.Moderation.DuplicateWindow)
+	if cfg.Moderation.SpamThreshold < 0 || cfg.Moderation.SpamThreshold > 1 {
+		errs = append(errs, errors.New("moderation.spam_threshold must be between 0 and 1"))
+	}
+	errs = appendPasswordErrors(errs, cfg.Password)
+	if cfg.Database.ConnectRetries < 0 {
+		errs = append(errs, errors.New("database.connect_retries must not be negative"))
+	}
+
+	switch cfg.Log.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q must be one of debug, info, warn, error", cfg.Log.Level))
+	}
+
+	errs = appendPolicyErrors(errs, "", cfg.RateLimit, cfg.CORS)
+	for slug, override := range cfg.Tenancy.Overrides {
+		merged := cfg.ForTenant(slug)
+		errs = appendPolicyErrors(errs, "tenancy.overrides."+slug+".", merged.RateLimit, merged.CORS)
+		if override.RateLimit == nil && override.CORS == nil {
+			errs = append(errs, fmt.Errorf("tenancy.overrides.%s: nothing to override", slug))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// appendPolicyErrors 校验限流和跨域配置，全局配置和租户覆盖配置共用，prefix 为配置项路径前缀
+func appendPolicyErrors(errs []error, prefix string, rateLimit RateLimitConfig, cors CORSConfig) []error {
+	if rateLimit.Enabled && (rateLimit.RequestsPerSecond <= 0 || rateLimit.Burst < 1) {
+		errs = append(errs, fmt.Errorf("%srate_limit.requests_per_second must be positive and %srate_limit.burst at least 1", prefix, prefix))
+	}
+
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("%scors.allowed_origins: invalid origin %q", prefix, origin))
+		}
+	}
+	return errs
+}
+
+func appendPasswordErrors(errs []error, cfg PasswordConfig) []error {
+	switch cfg.Algorithm {
+	case "", "argon2id", "bcrypt":
+	default:
+		errs = append(errs, fmt.Errorf("password.algorithm %q must be argon2id or bcrypt", cfg.Algorithm))
+	}
+	if cfg.BcryptCost != 0 && (cfg.BcryptCost < 4 || cfg.BcryptCost > 31) {
+		errs = append(errs, errors.New("password.bcrypt_cost must be between 4 and 31"))
+	}
+	if cfg.MinLength < 0 || cfg.MaxLength < 0 || (cfg.MaxLength != 0 && cfg.MaxLength < cfg.MinLength) {
+		errs = append(errs, errors.New("password.min_length and password.max_length must not be negative and max_length must not be less than min_length"))
+	}
+	if cfg.MinClasses < 0 || cfg.MinClasses > 4 {
+		errs = append(errs, errors.New("password.min_classes must be between 0 and 4"))
+	}
+	if cfg.BreachedList != "" {
+		if _, err := os.Stat(cfg.BreachedList); err != nil {
+			errs = append(errs, fmt.Errorf("password.breached_list: %w", err))
+		}
+	}
+	return errs
+}
+
+func appendDurationError(errs []error, name, value string) []error {
+	if value == "" {
+		return errs
+	}
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		return append(errs, fmt.Errorf("%s: invalid duration %q", name, value))
+	}
+	return errs
+}
+
+// Duration 解析已校验过的时长配置，为空时返回默认值
+func Duration(value string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
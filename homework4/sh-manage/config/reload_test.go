@@ -0,0 +1,119 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validConfig() *Config {
+	cfg := LoadSimple()
+	cfg.JWT.Secret = "0123456789abcdef0123"
+	cfg.Cache.TTL = "5m"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"valid", func(cfg *Config) {}, ""},
+		{"short secret", func(cfg *Config) { cfg.JWT.Secret = "short" }, "jwt.secret"},
+		{"bad expire", func(cfg *Config) { cfg.JWT.Expire = "tomorrow" }, "jwt.expire"},
+		{"negative grace", func(cfg *Config) { cfg.JWT.RotationGrace = "-1h" }, "jwt.rotation_grace"},
+		{"bad shutdown delay", func(cfg *Config) { cfg.Server.ShutdownDelay = "soon" }, "server.shutdown_delay"},
+		{"trusted proxies", func(cfg *Config) { cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "::1"} }, ""},
+		{"bad trusted proxy", func(cfg *Config) { cfg.Server.TrustedProxies = []string{"proxy.local"} }, "server.trusted_proxies"},
+		{"bad log level", func(cfg *Config) { cfg.Log.Level = "verbose" }, "log.level"},
+		{"rate limit without rate", func(cfg *Config) { cfg.RateLimit = RateLimitConfig{Enabled: true, Burst: 10} }, "rate_limit"},
+		{"disabled rate limit ignored", func(cfg *Config) { cfg.RateLimit = RateLimitConfig{Enabled: false} }, ""},
+		{"origin with path", func(cfg *Config) { cfg.CORS.AllowedOrigins = []string{"https://a.example.com/app"} }, "cors.allowed_origins"},
+		{"origin without scheme", func(cfg *Config) { cfg.CORS.AllowedOrigins = []string{"a.example.com"} }, "cors.allowed_origins"},
+		{"valid origins", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"https://a.example.com", "http://localhost:3000"}
+		}, ""},
+		{"tenant override origin", func(cfg *Config) {
+			cfg.Tenancy.Overrides = map[string]TenantOverride{"acme": {CORS: &CORSConfig{AllowedOrigins: []string{"acme.example.com"}}}}
+		}, "tenancy.overrides.acme.cors.allowed_origins"},
+		{"tenant override rate limit", func(cfg *Config) {
+			cfg.Tenancy.Overrides = map[string]TenantOverride{"acme": {RateLimit: &RateLimitConfig{Enabled: true}}}
+		}, "tenancy.overrides.acme.rate_limit"},
+		{"empty tenant override", func(cfg *Config) {
+			cfg.Tenancy.Overrides = map[string]TenantOverride{"acme": {}}
+		}, "tenancy.overrides.acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestForTenant(t *testing.T) {
+	cfg := validConfig()
+	cfg.RateLimit = RateLimitConfig{Enabled: true, RequestsPerSecond: 10, Burst: 20}
+	cfg.CORS.AllowedOrigins = []string{"https://www.example.com"}
+	cfg.Tenancy.Overrides = map[string]TenantOverride{
+		"acme": {RateLimit: &RateLimitConfig{Enabled: true, RequestsPerSecond: 1, Burst: 2}},
+	}
+
+	if got := cfg.ForTenant("default"); got != cfg {
+		t.Fatalf("tenant without override should share the global config")
+	}
+	acme := cfg.ForTenant("acme")
+	if acme.RateLimit.RequestsPerSecond != 1 || acme.RateLimit.Burst != 2 {
+		t.Fatalf("acme rate limit = %+v", acme.RateLimit)
+	}
+	if len(acme.CORS.AllowedOrigins) != 1 || acme.CORS.AllowedOrigins[0] != "https://www.example.com" {
+		t.Fatalf("acme cors should fall back to global: %+v", acme.CORS)
+	}
+	if cfg.RateLimit.RequestsPerSecond != 10 {
+		t.Fatalf("global rate limit modified: %+v", cfg.RateLimit)
+	}
+}
+
+func TestApplyNotifiesSubscribersAndRejectsInvalid(t *testing.T) {
+	initial := validConfig()
+	setCurrent(initial)
+
+	var calls int
+	var gotOld, gotNext *Config
+	OnChange(func(old, next *Config) {
+		calls++
+		gotOld, gotNext = old, next
+	})
+
+	next := validConfig()
+	next.Log.Level = "debug"
+	if err := Apply(next); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+	if calls != 1 || gotOld != initial || gotNext != next {
+		t.Fatalf("subscriber calls = %d, old = %p, next = %p", calls, gotOld, gotNext)
+	}
+	if Current() != next || GlobalConfig.Log.Level != "debug" {
+		t.Fatalf("current config not replaced")
+	}
+
+	invalid := validConfig()
+	invalid.JWT.Secret = "x"
+	if err := Apply(invalid); err == nil {
+		t.Fatalf("Apply() with invalid config should fail")
+	}
+	if calls != 1 || Current() != next {
+		t.Fatalf("invalid config must keep the previous one, calls = %d", calls)
+	}
+}
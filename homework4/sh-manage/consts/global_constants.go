@@ -1,7 +1,153 @@
 package consts
 
 const (
-	UserID      = "UserID"
-	UserName    = "UserName"
-	AuthTypePre = "Bearer"
+	UserID         = "UserID"
+	UserName       = "UserName"
+	AuthTypePre    = "Bearer"
+	AuthTypeApiKey = "ApiKey"
+	ApiKeyScopes   = "ApiKeyScopes"
+)
+
+// API Key 可授予的权限范围
+const (
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsRead  = "comments:read"
+	ScopeCommentsWrite = "comments:write"
+	ScopeReportsWrite  = "reports:write"
+	// 管理 API Key 本身的权限不允许授予 API Key，只能通过 JWT 登录访问
+	ScopeApiKeysManage = "api_keys:manage"
+)
+
+var GrantableScopes = []string{
+	ScopeUsersRead, ScopeUsersWrite,
+	ScopePostsRead, ScopePostsWrite,
+	ScopeCommentsRead, ScopeCommentsWrite,
+	ScopeReportsWrite,
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// RoleModerator 可以处理审核队列，管理员同样可以
+	RoleModerator = "moderator"
+	// 管理后台接口只能通过 JWT 访问
+	ScopeAdmin = "admin"
+)
+
+// 对外发布的事件类型，webhook 按 WebhookEvents 中的类型订阅，评论实时推送使用全部类型
+const (
+	EventPostCreated    = "post.created"
+	EventPostUpdated    = "post.updated"
+	EventPostDeleted    = "post.deleted"
+	EventCommentCreated = "comment.created"
+	EventCommentUpdated = "comment.updated"
+	EventCommentDeleted = "comment.deleted"
+	// EventPostUnavailable 文章改为草稿后推送给非作者的订阅者，随后结束对该文章的订阅
+	EventPostUnavailable = "post.unavailable"
+)
+
+// WebhookEvents 可以订阅的事件类型
+var WebhookEvents = []string{EventPostCreated, EventPostUpdated, EventPostDeleted, EventCommentCreated}
+
+// 文章状态，只有已发布的文章出现在列表和订阅源中
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+	// PostStatusPendingReview 内容审核命中规则，审核通过后发布；PostStatusRejected 审核未通过，都只有作者可见
+	PostStatusPendingReview = "pending_review"
+	PostStatusRejected      = "rejected"
+)
+
+// 评论状态，待审核和被拒绝的评论只有作者可见
+const (
+	CommentStatusPublished     = "published"
+	CommentStatusPendingReview = "pending_review"
+	CommentStatusRejected      = "rejected"
+)
+
+// 审核队列中条目的状态，作者修改或删除待审核内容后原条目变为 withdrawn
+const (
+	ModerationPending   = "pending"
+	ModerationApproved  = "approved"
+	ModerationRejected  = "rejected"
+	ModerationWithdrawn = "withdrawn"
+
+	// 进入审核的操作，审核通过的评论按操作发布 comment.created 或 comment.updated
+	ModerationActionCreate = "create"
+	ModerationActionUpdate = "update"
+)
+
+// 举报原因，other 需要填写说明
+var ReportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "other"}
+
+const ReportReasonOther = "other"
+
+// 举报状态，同一对象的未处理举报一起处理
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+// 举报的处理方式：忽略、删除内容、警告、限期停用、永久封禁
+const (
+	ReportActionDismiss       = "dismiss"
+	ReportActionRemoveContent = "remove_content"
+	ReportActionWarn          = "warn"
+	ReportActionSuspend       = "suspend"
+	ReportActionBan           = "ban"
+)
+
+const (
+	RequestID       = "RequestID"
+	RequestIDHeader = "X-Request-ID"
+)
+
+// 多租户：请求解析出的租户保存在 gin.Context 中，没有子域名和请求头时使用默认租户
+const (
+	TenantID      = "TenantID"
+	TenantSlug    = "TenantSlug"
+	TenantHeader  = "X-Tenant-ID"
+	DefaultTenant = "default"
+)
+
+// 审计日志的实体类型与动作
+const (
+	EntityUser       = "user"
+	EntityPost       = "post"
+	EntityComment    = "comment"
+	EntityAttachment = "attachment"
+	EntityWebhook    = "webhook"
+	EntityModeration = "moderation"
+	EntityReport     = "report"
+
+	AuditUserRegister      = "user.register"
+	AuditUserLogin         = "user.login"
+	AuditUserLoginFailed   = "user.login_failed"
+	AuditUserUpdate        = "user.update"
+	AuditUserDelete        = "user.delete"
+	AuditUserCreateAdmin   = "user.create_admin"
+	AuditUserResetPasswd   = "user.reset_password"
+	AuditUserSetRole       = "user.set_role"
+	AuditUserWarn          = "user.warn"
+	AuditUserSuspend       = "user.suspend"
+	AuditUserBan           = "user.ban"
+	AuditPostCreate        = "post.create"
+	AuditPostUpdate        = "post.update"
+	AuditPostDelete        = "post.delete"
+	AuditPostImport        = "post.import"
+	AuditCommentCreate     = "comment.create"
+	AuditCommentUpdate     = "comment.update"
+	AuditCommentDelete     = "comment.delete"
+	AuditAttachmentCreate  = "attachment.create"
+	AuditAttachmentDelete  = "attachment.delete"
+	AuditWebhookCreate     = "webhook.create"
+	AuditWebhookUpdate     = "webhook.update"
+	AuditWebhookDelete     = "webhook.delete"
+	AuditModerationApprove = "moderation.approve"
+	AuditModerationReject  = "moderation.reject"
+	AuditReportCreate      = "report.create"
+	AuditReportResolve     = "report.resolve"
 )
@@ -0,0 +1,59 @@
+// Package database 负责建立数据库连接
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sh-manage/config"
+	"sh-manage/tenant"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// 重试等待时间的上限
+const maxBackoff = 30 * time.Second
+
+// Open 连接 MySQL，数据库暂时不可用时按配置重试，ctx 取消后立即放弃
+func Open(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := Retry(ctx, cfg.Database.ConnectRetries, config.Duration(cfg.Database.ConnectBackoff, time.Second), func() error {
+		var err error
+		// gorm.Open 默认会 Ping 一次，连接失败时返回错误
+		db, err = gorm.Open(mysql.Open(config.GetMySQLDSN(cfg)), &gorm.Config{TranslateError: true})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 请求 context 中带有租户时，用户、文章、评论等模型自动按租户隔离
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Retry 执行 fn，失败后最多再重试 retries 次，等待时间从 backoff 开始每次翻倍
+func Retry(ctx context.Context, retries int, backoff time.Duration, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		log.Printf("Database not ready (attempt %d/%d): %v, retrying in %s", attempt+1, retries+1, err, backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
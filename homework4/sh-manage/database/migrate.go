@@ -0,0 +1,80 @@
+package database
+
+import (
+	"fmt"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/tenant"
+
+	"gorm.io/gorm"
+)
+
+// Migrate 迁移表结构，确保默认租户存在，并把多租户之前的数据归入默认租户
+func Migrate(db *gorm.DB) error {
+	all := models.GetModels()
+	if err := db.AutoMigrate(all...); err != nil {
+		return err
+	}
+	// 标签名改为租户内唯一，删除旧的全局唯一索引
+	if db.Migrator().HasIndex(&models.Tag{}, "idx_tags_name") {
+		if err := db.Migrator().DropIndex(&models.Tag{}, "idx_tags_name"); err != nil {
+			return fmt.Errorf("drop tag name index: %w", err)
+		}
+	}
+
+	defaultTenant := models.Tenant{Slug: consts.DefaultTenant, Name: "Default"}
+	if err := db.Where(models.Tenant{Slug: consts.DefaultTenant}).FirstOrCreate(&defaultTenant).Error; err != nil {
+		return fmt.Errorf("create default tenant: %w", err)
+	}
+	for _, model := range all {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		field := stmt.Schema.LookUpField(tenant.FieldName)
+		if field == nil {
+			continue
+		}
+		err := db.Unscoped().Model(model).Where(field.DBName+" = ?", 0).UpdateColumn(field.DBName, defaultTenant.ID).Error
+		if err != nil {
+			return fmt.Errorf("backfill %s: %w", stmt.Schema.Table, err)
+		}
+	}
+	if err := splitSharedTags(db); err != nil {
+		return fmt.Errorf("split shared tags: %w", err)
+	}
+	return nil
+}
+
+// splitSharedTags 多租户之前标签全局共享，回填后其他租户的文章仍关联默认租户的标签，
+// 在文章所属租户下创建同名标签并改为关联新标签
+func splitSharedTags(db *gorm.DB) error {
+	var links []struct {
+		PostID   uint
+		TagID    uint
+		TenantID uint
+		Name     string
+	}
+	err := db.Table("post_tags").
+		Select("post_tags.post_id, post_tags.tag_id, posts.tenant_id, tags.name").
+		Joins("JOIN posts ON posts.id = post_tags.post_id").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("posts.tenant_id <> tags.tenant_id").
+		Scan(&links).Error
+	if err != nil || len(links) == 0 {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, link := range links {
+			tag := models.Tag{TenantID: link.TenantID, Name: link.Name}
+			if err := tx.Where(&tag).FirstOrCreate(&tag).Error; err != nil {
+				return err
+			}
+			err := tx.Table("post_tags").Where("post_id = ? AND tag_id = ?", link.PostID, link.TagID).Update("tag_id", tag.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"sh-manage/models"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMigrateSplitsSharedTags 旧数据中其他租户的文章关联默认租户的标签，迁移后改为关联本租户的同名标签
+func TestMigrateSplitsSharedTags(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	acme := models.Tenant{Slug: "acme", Name: "Acme"}
+	db.Create(&acme)
+	shared := models.Tag{Name: "go"}
+	db.Create(&shared)
+	post := models.Post{TenantID: acme.ID, Title: "acme", Content: "post", Tags: []models.Tag{shared}}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate again: %v", err)
+	}
+	var reloaded models.Post
+	db.Preload("Tags").First(&reloaded, post.ID)
+	if len(reloaded.Tags) != 1 || reloaded.Tags[0].TenantID != acme.ID || reloaded.Tags[0].Name != "go" || reloaded.Tags[0].ID == shared.ID {
+		t.Fatalf("acme post tags = %+v", reloaded.Tags)
+	}
+	if db.First(&models.Tag{}, shared.ID).Error != nil {
+		t.Fatalf("default tenant tag removed")
+	}
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type CreateApiKeyDto struct {
+	Name      string     `json:"name" binding:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UpdateApiKeyDto struct {
+	Name      *string    `json:"name" binding:"omitempty,min=1,max=100"`
+	Scopes    []string   `json:"scopes" binding:"omitempty,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
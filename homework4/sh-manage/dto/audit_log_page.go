@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+type AuditLogPageDTO struct {
+	BasePageQuery
+	ActorID    *uint      `form:"actorId" json:"actorId" query:"actorId"`
+	Action     *string    `form:"action" json:"action" query:"action"`
+	EntityType *string    `form:"entityType" json:"entityType" query:"entityType"`
+	EntityID   *uint      `form:"entityId" json:"entityId" query:"entityId"`
+	From       *time.Time `form:"from" json:"from" query:"from"` // RFC3339
+	To         *time.Time `form:"to" json:"to" query:"to"`       // RFC3339
+}
@@ -27,10 +27,10 @@ func NewBasePageQuery() *BasePageQuery {
 // Validate 验证分页参数
 func (q *BasePageQuery) Validate() *utils.AppError {
 	if q.Page < 1 {
-		return utils.NewAppError(500, "页码不能小于1")
+		return utils.NewError(utils.ErrValidation, "页码不能小于1")
 	}
 	if q.PageSize < 1 || q.PageSize > 1000 {
-		return utils.NewAppError(500, "每页大小必须在1-1000之间")
+		return utils.NewError(utils.ErrValidation, "每页大小必须在1-1000之间")
 	}
 	return nil
 }
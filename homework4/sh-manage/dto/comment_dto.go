@@ -7,12 +7,14 @@ import (
 
 type CommentDto struct {
 	ID      *uint   `json:"id,omitempty"` // omitempty让nil不输出
+	PostID  *uint   `json:"post_id,omitempty"`
 	Content *string `json:"content" binding:"required"`
+	Version *uint   `json:"version,omitempty"` // 修改时必填，乐观锁版本号
 }
 
 func (d *CommentDto) Validate() *utils.AppError {
 	if d.Content == nil || strings.TrimSpace(*d.Content) == "" {
-		return utils.NewAppError(500, "内容不能为空")
+		return utils.NewError(utils.ErrValidation, "内容不能为空")
 	}
 	return nil
 }
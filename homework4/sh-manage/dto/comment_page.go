@@ -2,5 +2,6 @@ package dto
 
 type CommentPageDTO struct {
 	BasePageQuery
+	PostID  *uint   `form:"postId" json:"postId" query:"postId"`
 	Content *string `form:"content" json:"content" query:"content"`
 }
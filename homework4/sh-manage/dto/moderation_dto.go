@@ -0,0 +1,11 @@
+package dto
+
+type ModerationQueueDTO struct {
+	BasePageQuery
+	Status     *string `form:"status" json:"status" query:"status"` // 默认 pending
+	EntityType *string `form:"entityType" json:"entityType" query:"entityType"`
+}
+
+type ModerationDecisionDto struct {
+	Note string `json:"note" binding:"max=500"`
+}
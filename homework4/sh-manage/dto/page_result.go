@@ -10,3 +10,20 @@ type PageResult[T any] struct {
 	HasPrev    bool  `json:"hasPrev"`
 	Items      []T   `json:"items"`
 }
+
+// MapPage 转换分页结果中的元素类型，分页信息保持不变
+func MapPage[T any, R any](page *PageResult[T], fn func(*T) R) *PageResult[R] {
+	items := make([]R, 0, len(page.Items))
+	for i := range page.Items {
+		items = append(items, fn(&page.Items[i]))
+	}
+	return &PageResult[R]{
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		Total:      page.Total,
+		TotalPages: page.TotalPages,
+		HasNext:    page.HasNext,
+		HasPrev:    page.HasPrev,
+		Items:      items,
+	}
+}
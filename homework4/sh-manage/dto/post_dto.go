@@ -1,25 +1,43 @@
 package dto
 
 import (
+	"fmt"
+	"sh-manage/consts"
 	"sh-manage/utils"
 	"strings"
 )
 
+const MaxPostTags = 10
+
 type PostDto struct {
-	ID      *uint   `json:"id,omitempty"` // omitempty让nil不输出
-	Title   *string `json:"title" binding:"required"`
-	Content *string `json:"content" binding:"required"`
+	ID      *uint    `json:"id,omitempty"` // omitempty让nil不输出
+	Title   *string  `json:"title" binding:"required"`
+	Content *string  `json:"content" binding:"required"`
+	Tags    []string `json:"tags,omitempty"`    // 修改时为 nil 表示不变更标签
+	Status  *string  `json:"status,omitempty"`  // draft 或 published，创建时默认 published
+	Version *uint    `json:"version,omitempty"` // 修改时必填，乐观锁版本号
 }
 
 func (d *PostDto) Validate() *utils.AppError {
 	if d.Title == nil || strings.TrimSpace(*d.Title) == "" {
-		return utils.NewAppError(500, "标题不能为空")
+		return utils.NewError(utils.ErrValidation, "标题不能为空")
 	}
 	if len(*d.Title) > 100 {
-		return utils.NewAppError(500, "标题长度不能超过100字符")
+		return utils.NewError(utils.ErrValidation, "标题长度不能超过100字符")
 	}
 	if d.Content == nil || strings.TrimSpace(*d.Content) == "" {
-		return utils.NewAppError(500, "内容不能为空")
+		return utils.NewError(utils.ErrValidation, "内容不能为空")
+	}
+	if len(d.Tags) > MaxPostTags {
+		return utils.NewError(utils.ErrValidation, fmt.Sprintf("标签不能超过%d个", MaxPostTags))
+	}
+	for _, tag := range d.Tags {
+		if strings.TrimSpace(tag) == "" || len(tag) > 50 {
+			return utils.NewError(utils.ErrValidation, "标签不能为空且长度不能超过50字符")
+		}
+	}
+	if d.Status != nil && *d.Status != consts.PostStatusDraft && *d.Status != consts.PostStatusPublished {
+		return utils.NewError(utils.ErrValidation, "状态只能是 draft 或 published")
 	}
 	return nil
 }
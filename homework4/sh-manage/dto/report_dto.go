@@ -0,0 +1,24 @@
+package dto
+
+type CreateReportDto struct {
+	EntityType string `json:"entity_type" binding:"required,oneof=post comment user"`
+	EntityID   uint   `json:"entity_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+	Details    string `json:"details" binding:"max=1000"` // reason 为 other 时必填
+}
+
+type ReportPageDTO struct {
+	BasePageQuery
+	Status     *string `form:"status" json:"status" query:"status"` // 默认 open
+	EntityType *string `form:"entityType" json:"entityType" query:"entityType"`
+	Reason     *string `form:"reason" json:"reason" query:"reason"`
+}
+
+type ResolveReportDto struct {
+	Action string `json:"action" binding:"required,oneof=dismiss remove_content warn suspend ban"`
+	Note   string `json:"note" binding:"max=500"`
+	// Duration 停用时长，如 72h，action 为 suspend 时必填
+	Duration string `json:"duration"`
+	// RemoveContent 警告、停用或封禁的同时删除被举报的文章或评论
+	RemoveContent bool `json:"remove_content"`
+}
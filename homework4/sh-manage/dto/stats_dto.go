@@ -0,0 +1,67 @@
+package dto
+
+import "time"
+
+// StatsQueryDTO 统计查询条件，未指定时间范围时默认最近30天
+type StatsQueryDTO struct {
+	From     *time.Time `form:"from" json:"from" query:"from"`             // RFC3339
+	To       *time.Time `form:"to" json:"to" query:"to"`                   // RFC3339
+	Interval string     `form:"interval" json:"interval" query:"interval"` // day 或 week，由服务校验
+	Metric   string     `form:"metric" json:"metric" query:"metric" binding:"omitempty,oneof=users posts comments"`
+	Limit    int        `form:"limit" json:"limit" query:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type StatsOverview struct {
+	Users          int64 `json:"users"`
+	Admins         int64 `json:"admins"`
+	Posts          int64 `json:"posts"`
+	PublishedPosts int64 `json:"published_posts"`
+	DraftPosts     int64 `json:"draft_posts"`
+	Comments       int64 `json:"comments"`
+	Tags           int64 `json:"tags"`
+	Attachments    int64 `json:"attachments"`
+	// 最近7天新增
+	NewUsers    int64 `json:"new_users_7d"`
+	NewPosts    int64 `json:"new_posts_7d"`
+	NewComments int64 `json:"new_comments_7d"`
+}
+
+type TimeSeriesPoint struct {
+	Date  string `json:"date"` // 日或周一的日期 2006-01-02
+	Count int64  `json:"count"`
+}
+
+type TimeSeries struct {
+	Metric   string            `json:"metric"`
+	Interval string            `json:"interval"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Total    int64             `json:"total"`
+	Points   []TimeSeriesPoint `json:"points"`
+}
+
+type AuthorStat struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Posts    int64  `json:"posts"`
+	Comments int64  `json:"comments"`
+}
+
+type PostStat struct {
+	PostID   uint   `json:"post_id"`
+	Title    string `json:"title"`
+	UserID   uint   `json:"user_id"`
+	Comments int64  `json:"comments"`
+}
+
+type StorageStat struct {
+	ContentType string `json:"content_type"`
+	Files       int64  `json:"files"`
+	Bytes       int64  `json:"bytes"`
+}
+
+type StorageUsage struct {
+	Files  int64         `json:"files"`
+	Bytes  int64         `json:"bytes"` // 原文件大小之和，不含缩略图
+	ByType []StorageStat `json:"by_type"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+type CreateWebhookDto struct {
+	URL         string   `json:"url" binding:"required,url,max=500"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=100"` // 为空时自动生成
+	Description string   `json:"description" binding:"max=255"`
+	Active      *bool    `json:"active"`
+}
+
+type UpdateWebhookDto struct {
+	URL         *string  `json:"url" binding:"omitempty,url,max=500"`
+	Events      []string `json:"events" binding:"omitempty,min=1"`
+	Secret      *string  `json:"secret" binding:"omitempty,min=16,max=100"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Active      *bool    `json:"active"`
+}
+
+type WebhookDeliveryPageDTO struct {
+	BasePageQuery
+	Status    *string `form:"status" json:"status" query:"status"`
+	EventType *string `form:"eventType" json:"eventType" query:"eventType"`
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Event 领域事件，Data 为实体的响应结构
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"created_at"`
+	Data       interface{} `json:"data"`
+	// TenantID 事件所属的租户，实时推送按租户分发，不输出到 webhook 负载
+	TenantID uint `json:"-"`
+	// PreviousStatus 文章修改前的状态，webhook 据此判断文章是刚发布还是下线，不输出到 webhook 负载
+	PreviousStatus string `json:"-"`
+}
+
+func New(eventType string, data interface{}) Event {
+	return Event{ID: newID(), Type: eventType, OccurredAt: time.Now().UTC(), Data: data}
+}
+
+// Publisher 服务层通过它发布事件
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Nop 不发布任何事件
+type Nop struct{}
+
+func (Nop) Publish(Event) {}
+
+// Bus 进程内的事件总线，Publish 同步调用所有订阅者，订阅者不能阻塞
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]func(Event)
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[int]func(Event))}
+}
+
+// Subscribe 注册订阅者，返回取消订阅的函数
+func (b *Bus) Subscribe(handler func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func newID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,90 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func renderAtom(channel *Channel) ([]byte, error) {
+	// updated 为必填项，没有文章时使用生成时间
+	updated := channel.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	doc := atomFeed{
+		ID:      channel.FeedURL,
+		Title:   channel.Title,
+		Updated: atomTime(updated),
+		Links: []atomLink{
+			{Href: channel.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: channel.Link, Rel: "alternate"},
+		},
+		Subtitle:  channel.Description,
+		Generator: "sh-manage",
+		Entries:   make([]atomEntry, 0, len(channel.Items)),
+	}
+	for _, item := range channel.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Link:      atomLink{Href: item.Link, Rel: "alternate"},
+			Published: atomTime(item.Published),
+			Updated:   atomTime(item.Updated),
+			Content:   atomContent{Type: "text", Value: item.Content},
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return marshalXML(doc)
+}
+
+// atomTime Atom 使用 RFC 3339 格式
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,65 @@
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Format 订阅源格式
+type Format string
+
+const (
+	FormatRSS  Format = "rss"
+	FormatAtom Format = "atom"
+)
+
+// ContentType 订阅源的 MIME 类型
+func (f Format) ContentType() string {
+	if f == FormatAtom {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// Channel 与具体格式无关的订阅源内容
+type Channel struct {
+	Title       string
+	Link        string // 站点地址
+	FeedURL     string // 订阅源自身地址
+	Description string
+	Updated     time.Time // 最近一篇文章的更新时间，作为 lastBuildDate/updated
+	Items       []Item
+}
+
+type Item struct {
+	ID         string
+	Title      string
+	Link       string
+	Author     string
+	Categories []string
+	Content    string
+	Published  time.Time
+	Updated    time.Time
+}
+
+// Render 按格式生成 XML
+func Render(format Format, channel *Channel) ([]byte, error) {
+	switch format {
+	case FormatRSS:
+		return renderRSS(channel)
+	case FormatAtom:
+		return renderAtom(channel)
+	}
+	return nil, fmt.Errorf("unsupported feed format %q", format)
+}
+
+// summary 截取正文开头作为摘要，按字符截断避免破坏多字节字符
+func summary(content string, limit int) string {
+	content = strings.TrimSpace(content)
+	runes := []rune(content)
+	if len(runes) <= limit {
+		return content
+	}
+	return string(runes[:limit]) + "…"
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"errors"
+	"net/http"
+	"sh-manage/consts"
+	"sh-manage/logging"
+	"sh-manage/utils"
+	"slices"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Error 解析函数返回的错误，extensions.code 与 REST 接口的状态码一致，extensions.error_code 为稳定错误码
+type Error struct {
+	Code      int
+	ErrorCode utils.ErrorCode
+	Message   string
+	// Data 例如版本冲突时的服务端当前状态
+	Data interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	errorCode := e.ErrorCode
+	if errorCode == "" {
+		errorCode = utils.NewAppError(e.Code, e.Message).StableCode()
+	}
+	extensions := map[string]interface{}{"code": e.Code, "error_code": errorCode}
+	if e.Data != nil {
+		extensions["data"] = e.Data
+	}
+	return extensions
+}
+
+// newError 转换服务层返回的错误，err 不能为 nil；未知错误不暴露细节
+func newError(err error) error {
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) {
+		logging.Errorf("graphql request failed: %v", err)
+		return &Error{Code: http.StatusInternalServerError, ErrorCode: utils.ErrInternal, Message: "Internal server error"}
+	}
+	if appErr.Err != nil || appErr.Code >= http.StatusInternalServerError {
+		logging.Errorf("graphql request failed: code=%s err=%v", appErr.StableCode(), appErr)
+	}
+	return &Error{Code: appErr.Code, ErrorCode: appErr.StableCode(), Message: appErr.Message, Data: appErr.Data}
+}
+
+func isNotFound(err *utils.AppError) bool {
+	return err.Code == http.StatusNotFound
+}
+
+func parseID(id graphql.ID) (uint, error) {
+	value, err := strconv.ParseUint(string(id), 10, 64)
+	if err != nil || value == 0 {
+		return 0, &Error{Code: http.StatusUnprocessableEntity, Message: "Invalid id " + strconv.Quote(string(id))}
+	}
+	return uint(value), nil
+}
+
+func toID(id uint) graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(id), 10))
+}
+
+func (r *request) currentUserID() uint {
+	return utils.GetCurrentUserID(r.c)
+}
+
+// authorize 要求已登录，API Key 还需要拥有 scope，与 REST 接口的 middleware.RequireScope 一致
+func (r *request) authorize(scope string) error {
+	if r.currentUserID() == 0 {
+		return &Error{Code: http.StatusUnauthorized, Message: "Authentication required"}
+	}
+	if value, exists := r.c.Get(consts.ApiKeyScopes); exists {
+		scopes, _ := value.([]string)
+		if !slices.Contains(scopes, scope) {
+			return &Error{Code: http.StatusForbidden, Message: "Api key does not have scope " + scope}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Package graph 提供 /graphql 接口的 schema 和解析函数，查询和修改复用 services 中的业务逻辑
+//
+// 每个请求创建一组 Loader，同一层字段（例如一页文章的作者）合并为一次批量查询
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"sh-manage/models"
+	"sh-manage/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// 嵌套过深的查询会产生大量解析函数，限制深度和单个列表的并发数
+const (
+	maxDepth       = 10
+	maxParallelism = 100
+)
+
+type Schema struct {
+	schema   *graphql.Schema
+	users    *services.UserService
+	posts    *services.PostService
+	comments *services.CommentService
+}
+
+func NewSchema(users *services.UserService, posts *services.PostService, comments *services.CommentService) (*Schema, error) {
+	schema, err := graphql.ParseSchema(schemaSDL, &Resolver{}, graphql.MaxDepth(maxDepth), graphql.MaxParallelism(maxParallelism))
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: schema, users: users, posts: posts, comments: comments}, nil
+}
+
+// Exec 执行一次请求，c 中的租户和登录用户对所有解析函数生效
+func (s *Schema) Exec(c *gin.Context, query, operationName string, variables map[string]interface{}) *graphql.Response {
+	req := newRequest(c, s.users.WithContext(c), s.posts.WithContext(c), s.comments.WithContext(c))
+	ctx := context.WithValue(c.Request.Context(), requestKey{}, req)
+	return s.schema.Exec(ctx, query, operationName, variables)
+}
+
+type requestKey struct{}
+
+// request 一次 GraphQL 请求共享的服务和 Loader，服务已绑定当前请求
+type request struct {
+	c        *gin.Context
+	users    *services.UserService
+	posts    *services.PostService
+	comments *services.CommentService
+
+	userLoader    *Loader[uint, *models.User]
+	commentLoader *Loader[uint, []models.Comment]
+}
+
+func newRequest(c *gin.Context, users *services.UserService, posts *services.PostService, comments *services.CommentService) *request {
+	return &request{
+		c:        c,
+		users:    users,
+		posts:    posts,
+		comments: comments,
+		userLoader: NewLoader(func(ids []uint) (map[uint]*models.User, error) {
+			found, err := users.GetUsersByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint]*models.User, len(found))
+			for i := range found {
+				result[found[i].ID] = &found[i]
+			}
+			return result, nil
+		}),
+		commentLoader: NewLoader(func(postIDs []uint) (map[uint][]models.Comment, error) {
+			found, err := comments.ListByPosts(postIDs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint][]models.Comment, len(postIDs))
+			for _, comment := range found {
+				result[comment.PostId] = append(result[comment.PostId], comment)
+			}
+			return result, nil
+		}),
+	}
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
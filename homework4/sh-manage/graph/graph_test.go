@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/repository"
+	"sh-manage/services"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoaderBatchesConcurrentLoads(t *testing.T) {
+	var calls atomic.Int32
+	var batch []int
+	loader := NewLoader(func(keys []int) (map[int]string, error) {
+		calls.Add(1)
+		batch = keys
+		result := make(map[int]string)
+		for _, key := range keys {
+			if key != 0 {
+				result[key] = fmt.Sprint("v", key)
+			}
+		}
+		return result, nil
+	})
+
+	var wg sync.WaitGroup
+	values := make([]string, 6)
+	for i, key := range []int{1, 2, 3, 2, 1, 0} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], _ = loader.Load(key)
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 || len(batch) != 4 {
+		t.Fatalf("calls = %d, batch = %v; want one batch of distinct keys", calls.Load(), batch)
+	}
+	if fmt.Sprint(values) != "[v1 v2 v3 v2 v1 ]" {
+		t.Fatalf("values = %v", values)
+	}
+
+	// 已加载的键直接使用缓存
+	if value, _ := loader.Load(3); value != "v3" || calls.Load() != 1 {
+		t.Fatalf("cached load = %q, calls = %d", value, calls.Load())
+	}
+}
+
+func TestLoaderErrorsAndMaxBatch(t *testing.T) {
+	failing := NewLoader(func(keys []int) (map[int]int, error) {
+		return nil, errors.New("boom")
+	})
+	if _, err := failing.Load(1); err == nil {
+		t.Fatalf("error not propagated")
+	}
+
+	panicking := NewLoader(func(keys []int) (map[int]int, error) {
+		panic("boom")
+	})
+	if _, err := panicking.Load(1); err == nil {
+		t.Fatalf("panic not converted to error")
+	}
+
+	// 达到批次上限时立即查询，不等待时间窗口
+	var sizes []int
+	var mu sync.Mutex
+	limited := NewLoader(func(keys []int) (map[int]int, error) {
+		mu.Lock()
+		sizes = append(sizes, len(keys))
+		mu.Unlock()
+		return nil, nil
+	})
+	limited.maxBatch, limited.wait = 2, time.Hour
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limited.Load(i)
+		}()
+	}
+	wg.Wait()
+	if fmt.Sprint(sizes) != "[2 2]" {
+		t.Fatalf("batch sizes = %v", sizes)
+	}
+}
+
+// countingUsers 和 countingComments 记录批量查询的次数
+type countingUsers struct {
+	repository.UserRepository
+	calls atomic.Int32
+}
+
+func (r *countingUsers) FindByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	r.calls.Add(1)
+	return r.UserRepository.FindByIDs(ctx, ids)
+}
+
+type countingComments struct {
+	repository.CommentRepository
+	calls atomic.Int32
+}
+
+func (r *countingComments) FindByPosts(ctx context.Context, postIDs []uint) ([]models.Comment, error) {
+	r.calls.Add(1)
+	return r.CommentRepository.FindByPosts(ctx, postIDs)
+}
+
+type nopAudit struct{}
+
+func (nopAudit) Record(*gin.Context, services.AuditEntry) {}
+
+func TestSchemaAvoidsNPlusOneQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	repos := repository.NewMemory().Repositories()
+	users := &countingUsers{UserRepository: repos.Users}
+	comments := &countingComments{CommentRepository: repos.Comments}
+	repos.Users, repos.Comments = users, comments
+
+	var authors []uint
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user := &models.User{Username: name, Email: name + "@example.com", Role: consts.RoleUser}
+		repos.Users.Create(ctx, user)
+		authors = append(authors, user.ID)
+	}
+	const posts = 10
+	for i := range posts {
+		post := &models.Post{Title: fmt.Sprint("post ", i), Content: "content", UserId: authors[i%len(authors)], Status: consts.PostStatusPublished}
+		repos.Posts.Create(ctx, post, nil)
+		for j := range 3 {
+			repos.Comments.Create(ctx, &models.Comment{Content: "comment", UserId: authors[(i+j)%len(authors)], PostId: post.ID})
+		}
+	}
+
+	userService := services.NewUserServiceWithRepositories(repos, nil, nopAudit{})
+	schema, err := NewSchema(userService,
+		services.NewPostServiceWithRepositories(repos, userService, nil, nopAudit{}),
+		services.NewCommentServiceWithRepositories(repos, userService, nopAudit{}))
+	if err != nil {
+		t.Fatalf("NewSchema: %v", err)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	resp := schema.Exec(c, `{
+		posts(pageSize: 100) {
+			items { author { username } commentCount comments { author { username } } }
+		}
+	}`, "", nil)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("errors = %v", resp.Errors)
+	}
+	var data struct {
+		Posts struct {
+			Items []struct {
+				Author struct {
+					Username string `json:"username"`
+				} `json:"author"`
+				CommentCount int `json:"commentCount"`
+			} `json:"items"`
+		} `json:"posts"`
+	}
+	json.Unmarshal(resp.Data, &data)
+	if len(data.Posts.Items) != posts || data.Posts.Items[0].Author.Username == "" || data.Posts.Items[0].CommentCount != 3 {
+		t.Fatalf("data = %s", resp.Data)
+	}
+
+	// 文章作者和评论作者各一次批量查询，评论一次批量查询，与文章数量无关
+	if got := users.calls.Load(); got > 2 {
+		t.Fatalf("user queries = %d, want at most 2", got)
+	}
+	if got := comments.calls.Load(); got != 1 {
+		t.Fatalf("comment queries = %d, want 1", got)
+	}
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 同一层字段的解析函数并发执行，等待一个很短的窗口收集键后一次查询
+const (
+	defaultBatchWait = 2 * time.Millisecond
+	defaultMaxBatch  = 100
+)
+
+// Loader 把同一时间窗口内的 Load 调用合并为一次批量查询，结果在请求内缓存，避免 N+1 查询
+type Loader[K comparable, V any] struct {
+	fetch    func(keys []K) (map[K]V, error)
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[K]*loadResult[V]
+	pending *loadBatch[K, V]
+}
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type loadBatch[K comparable, V any] struct {
+	keys    []K
+	results []*loadResult[V]
+}
+
+// NewLoader fetch 返回的结果中没有的键解析为零值
+func NewLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     defaultBatchWait,
+		maxBatch: defaultMaxBatch,
+		cache:    make(map[K]*loadResult[V]),
+	}
+}
+
+// Load 等待键所在的批次查询完成，同一个键只查询一次
+func (l *Loader[K, V]) Load(key K) (V, error) {
+	l.mu.Lock()
+	result, ok := l.cache[key]
+	if !ok {
+		result = &loadResult[V]{done: make(chan struct{})}
+		l.cache[key] = result
+
+		b := l.pending
+		if b == nil {
+			b = &loadBatch[K, V]{}
+			l.pending = b
+			time.AfterFunc(l.wait, func() { l.dispatch(b) })
+		}
+		b.keys = append(b.keys, key)
+		b.results = append(b.results, result)
+		if len(b.keys) >= l.maxBatch {
+			go l.dispatch(b)
+		}
+	}
+	l.mu.Unlock()
+
+	<-result.done
+	return result.value, result.err
+}
+
+// dispatch 执行批次的查询，批次已经被执行过时直接返回
+func (l *Loader[K, V]) dispatch(b *loadBatch[K, V]) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+
+	values, err := l.safeFetch(b.keys)
+	for i, key := range b.keys {
+		b.results[i].value, b.results[i].err = values[key], err
+		close(b.results[i].done)
+	}
+}
+
+// safeFetch 查询时发生 panic 也要唤醒等待的解析函数
+func (l *Loader[K, V]) safeFetch(keys []K) (values map[K]V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			values, err = nil, fmt.Errorf("loader panic: %v", r)
+		}
+	}()
+	return l.fetch(keys)
+}
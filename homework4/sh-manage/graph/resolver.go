@@ -0,0 +1,317 @@
+package graph
+
+import (
+	"context"
+	"net/http"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Resolver Query 和 Mutation 的根解析函数，服务和当前用户从 ctx 中的 request 获取
+type Resolver struct{}
+
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	req := requestFrom(ctx)
+	if req.currentUserID() == 0 {
+		return nil, nil
+	}
+	if err := req.authorize(consts.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+	user, err := req.users.GetUserByID(req.currentUserID())
+	if err != nil {
+		return nil, newError(err)
+	}
+	return newUser(req, user), nil
+}
+
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	req := requestFrom(ctx)
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := req.userLoader.Load(id)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return newUser(req, user), nil
+}
+
+func (r *Resolver) Post(ctx context.Context, args struct{ ID graphql.ID }) (*postResolver, error) {
+	req := requestFrom(ctx)
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	post, appErr := req.posts.GetPostByID(id)
+	if appErr != nil {
+		if isNotFound(appErr) {
+			return nil, nil
+		}
+		return nil, newError(appErr)
+	}
+	return &postResolver{post: post, req: req}, nil
+}
+
+type postsArgs struct {
+	Page     int32
+	PageSize int32
+	Title    *string
+	Content  *string
+	OrderBy  string
+	Order    string
+}
+
+func (r *Resolver) Posts(ctx context.Context, args postsArgs) (*postPageResolver, error) {
+	req := requestFrom(ctx)
+	query, err := pageQuery(args.Page, args.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	query.OrderBy, query.Order = args.OrderBy, args.Order
+
+	page, appErr := req.posts.GetPostByPage(&dto.PostPageDTO{BasePageQuery: query, Title: args.Title, Content: args.Content})
+	if appErr != nil {
+		return nil, newError(appErr)
+	}
+	items := make([]*postResolver, len(page.Items))
+	for i := range page.Items {
+		items[i] = &postResolver{post: &page.Items[i], req: req}
+	}
+	return &postPageResolver{info: newPageInfo(page), items: items}, nil
+}
+
+func (r *Resolver) Comment(ctx context.Context, args struct{ ID graphql.ID }) (*commentResolver, error) {
+	req := requestFrom(ctx)
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	comment, appErr := req.comments.GetCommentByID(id)
+	if appErr != nil {
+		if isNotFound(appErr) {
+			return nil, nil
+		}
+		return nil, newError(appErr)
+	}
+	return &commentResolver{comment: comment, req: req}, nil
+}
+
+type commentsArgs struct {
+	PostID   graphql.ID
+	Page     int32
+	PageSize int32
+}
+
+// Comments 文章不可见（不存在或是其他人的草稿）时返回 404
+func (r *Resolver) Comments(ctx context.Context, args commentsArgs) (*commentPageResolver, error) {
+	req := requestFrom(ctx)
+	postID, err := parseID(args.PostID)
+	if err != nil {
+		return nil, err
+	}
+	query, err := pageQuery(args.Page, args.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	if _, appErr := req.posts.GetPostByID(postID); appErr != nil {
+		return nil, newError(appErr)
+	}
+
+	page, appErr := req.comments.GetCommentByPage(&dto.CommentPageDTO{BasePageQuery: query, PostID: &postID})
+	if appErr != nil {
+		return nil, newError(appErr)
+	}
+	items := make([]*commentResolver, len(page.Items))
+	for i := range page.Items {
+		items[i] = &commentResolver{comment: &page.Items[i], req: req}
+	}
+	return &commentPageResolver{info: newPageInfo(page), items: items}, nil
+}
+
+// pageQuery 分页参数的范围与 REST 接口一致
+func pageQuery(page, pageSize int32) (dto.BasePageQuery, error) {
+	query := *dto.NewBasePageQuery()
+	if page < 1 || pageSize < 1 || pageSize > 100 {
+		return query, &Error{Code: http.StatusUnprocessableEntity, Message: "page must be at least 1 and pageSize between 1 and 100"}
+	}
+	query.Page, query.PageSize = int(page), int(pageSize)
+	return query, nil
+}
+
+type createPostInput struct {
+	Title   string
+	Content string
+	Tags    *[]string
+	Status  *string
+}
+
+func (r *Resolver) CreatePost(ctx context.Context, args struct{ Input createPostInput }) (*postResolver, error) {
+	req := requestFrom(ctx)
+	if err := req.authorize(consts.ScopePostsWrite); err != nil {
+		return nil, err
+	}
+	input := args.Input
+	post, appErr := req.posts.CreatePost(&dto.PostDto{
+		Title:   &input.Title,
+		Content: &input.Content,
+		Tags:    derefTags(input.Tags),
+		Status:  postStatus(input.Status),
+	})
+	if appErr != nil {
+		return nil, newError(appErr)
+	}
+	return &postResolver{post: post, req: req}, nil
+}
+
+type updatePostInput struct {
+	Title   string
+	Content string
+	Tags    *[]string
+	Status  *string
+	Version int32
+}
+
+func (r *Resolver) UpdatePost(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updatePostInput
+}) (*postResolver, error) {
+	req := requestFrom(ctx)
+	if err := req.authorize(consts.ScopePostsWrite); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	input := args.Input
+	version := uint(input.Version)
+	post, appErr := req.posts.UpdatePost(&dto.PostDto{
+		ID:      &id,
+		Title:   &input.Title,
+		Content: &input.Content,
+		Tags:    derefTags(input.Tags),
+		Status:  postStatus(input.Status),
+		Version: &version,
+	})
+	if appErr != nil {
+		return nil, newError(appErr)
+	}
+	return &postResolver{post: post, req: req}, nil
+}
+
+func (r *Resolver) DeletePost(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	req := requestFrom(ctx)
+	if err := req.authorize(consts.ScopePostsWrite); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	if appErr := req.posts.DeleteByID(id); appErr != nil {
+		return false, newError(appErr)
+	}
+	return true, nil
+}
+
+func (r *Resolver) CreateComment(ctx context.Context, args struct {
+	PostID  graphql.ID
+	Content string
+}) (*commentResolver, error) {
+	req := requestFrom(ctx)
+	if err := req.authorize(consts.ScopeCommentsWrite); err != nil {
+		return nil, err
+	}
+	postID, err := parseID(args.PostID)
+	if err != nil {
+		return nil, err
+	}
+	comment, appErr := req.comments.CreateComment(&dto.CommentDto{PostID: &postID, Content: &args.Content})
+	if appErr != nil {
+		return nil, newError(appErr)
+	}
+	return &commentResolver{comment: comment, req: req}, nil
+}
+
+func (r *Resolver) UpdateComment(ctx context.Context, args struct {
+	ID      graphql.ID
+	Content string
+	Version int32
+}) (*commentResolver, error) {
+	req := requestFrom(ctx)
+	if err := req.authorize(consts.ScopeCommentsWrite); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	version := uint(args.Version)
+	comment, appErr := req.comments.UpdateComment(&dto.CommentDto{ID: &id, Content: &args.Content, Version: &version})
+	if appErr != nil {
+		return nil, newError(appErr)
+	}
+	return &commentResolver{comment: comment, req: req}, nil
+}
+
+func (r *Resolver) DeleteComment(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	req := requestFrom(ctx)
+	if err := req.authorize(consts.ScopeCommentsWrite); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	if appErr := req.comments.DeleteByID(id); appErr != nil {
+		return false, newError(appErr)
+	}
+	return true, nil
+}
+
+type updateProfileInput struct {
+	Email    *string
+	Password *string
+	Version  int32
+}
+
+func (r *Resolver) UpdateProfile(ctx context.Context, args struct{ Input updateProfileInput }) (*userResolver, error) {
+	req := requestFrom(ctx)
+	if err := req.authorize(consts.ScopeUsersWrite); err != nil {
+		return nil, err
+	}
+	version := uint(args.Input.Version)
+	update := models.UpdateUserRequest{Email: args.Input.Email, Password: args.Input.Password, Version: &version}
+	// 与 REST 接口的请求体使用相同的校验规则
+	if err := binding.Validator.ValidateStruct(&update); err != nil {
+		return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "validation failed", Data: err.Error()}
+	}
+	user, err := req.users.UpdateUser(req.currentUserID(), update)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return newUser(req, user), nil
+}
+
+func derefTags(tags *[]string) []string {
+	if tags == nil {
+		return nil
+	}
+	return *tags
+}
+
+// postStatus 把枚举值转换为数据库中的小写状态
+func postStatus(status *string) *string {
+	if status == nil {
+		return nil
+	}
+	value := strings.ToLower(*status)
+	return &value
+}
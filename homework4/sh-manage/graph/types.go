@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"sh-manage/dto"
+	"sh-manage/models"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+type userResolver struct {
+	user *models.User
+	req  *request
+}
+
+func newUser(req *request, user *models.User) *userResolver {
+	if user == nil {
+		return nil
+	}
+	return &userResolver{user: user, req: req}
+}
+
+func (u *userResolver) ID() graphql.ID {
+	return toID(u.user.ID)
+}
+
+func (u *userResolver) Username() string {
+	return u.user.Username
+}
+
+func (u *userResolver) Email() *string {
+	if u.user.ID != u.req.currentUserID() {
+		return nil
+	}
+	return &u.user.Email
+}
+
+func (u *userResolver) Version() int32 {
+	return int32(u.user.Version)
+}
+
+func (u *userResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: u.user.CreatedAt}
+}
+
+type postResolver struct {
+	post *models.Post
+	req  *request
+}
+
+func (p *postResolver) ID() graphql.ID {
+	return toID(p.post.ID)
+}
+
+func (p *postResolver) Title() string {
+	return p.post.Title
+}
+
+func (p *postResolver) Content() string {
+	return p.post.Content
+}
+
+// Status 枚举值为大写，数据库中为小写
+func (p *postResolver) Status() string {
+	return strings.ToUpper(p.post.Status)
+}
+
+func (p *postResolver) Tags() []string {
+	return p.post.TagNames()
+}
+
+func (p *postResolver) Version() int32 {
+	return int32(p.post.Version)
+}
+
+func (p *postResolver) PublishedAt() *graphql.Time {
+	if p.post.PublishedAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *p.post.PublishedAt}
+}
+
+func (p *postResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: p.post.CreatedAt}
+}
+
+func (p *postResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: p.post.UpdatedAt}
+}
+
+func (p *postResolver) Author() (*userResolver, error) {
+	user, err := p.req.userLoader.Load(p.post.UserId)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return newUser(p.req, user), nil
+}
+
+func (p *postResolver) Comments(args struct{ First int32 }) ([]*commentResolver, error) {
+	comments, err := p.req.commentLoader.Load(p.post.ID)
+	if err != nil {
+		return nil, newError(err)
+	}
+	if first := int(max(args.First, 0)); len(comments) > first {
+		comments = comments[:first]
+	}
+	result := make([]*commentResolver, len(comments))
+	for i := range comments {
+		result[i] = &commentResolver{comment: &comments[i], req: p.req}
+	}
+	return result, nil
+}
+
+func (p *postResolver) CommentCount() (int32, error) {
+	comments, err := p.req.commentLoader.Load(p.post.ID)
+	if err != nil {
+		return 0, newError(err)
+	}
+	return int32(len(comments)), nil
+}
+
+type commentResolver struct {
+	comment *models.Comment
+	req     *request
+}
+
+func (c *commentResolver) ID() graphql.ID {
+	return toID(c.comment.ID)
+}
+
+func (c *commentResolver) Content() string {
+	return c.comment.Content
+}
+
+func (c *commentResolver) Version() int32 {
+	return int32(c.comment.Version)
+}
+
+func (c *commentResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: c.comment.CreatedAt}
+}
+
+func (c *commentResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: c.comment.UpdatedAt}
+}
+
+func (c *commentResolver) Author() (*userResolver, error) {
+	user, err := c.req.userLoader.Load(c.comment.UserId)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return newUser(c.req, user), nil
+}
+
+// Post 文章读取经过缓存，草稿对其他人返回 null
+func (c *commentResolver) Post() (*postResolver, error) {
+	post, err := c.req.posts.GetPostByID(c.comment.PostId)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, newError(err)
+	}
+	return &postResolver{post: post, req: c.req}, nil
+}
+
+type pageInfoResolver struct {
+	page, pageSize, total, totalPages int32
+	hasNext, hasPrev                  bool
+}
+
+func newPageInfo[T any](page *dto.PageResult[T]) *pageInfoResolver {
+	return &pageInfoResolver{
+		page:       int32(page.Page),
+		pageSize:   int32(page.PageSize),
+		total:      int32(page.Total),
+		totalPages: int32(page.TotalPages),
+		hasNext:    page.HasNext,
+		hasPrev:    page.HasPrev,
+	}
+}
+
+func (p *pageInfoResolver) Page() int32       { return p.page }
+func (p *pageInfoResolver) PageSize() int32   { return p.pageSize }
+func (p *pageInfoResolver) Total() int32      { return p.total }
+func (p *pageInfoResolver) TotalPages() int32 { return p.totalPages }
+func (p *pageInfoResolver) HasNext() bool     { return p.hasNext }
+func (p *pageInfoResolver) HasPrev() bool     { return p.hasPrev }
+
+type postPageResolver struct {
+	info  *pageInfoResolver
+	items []*postResolver
+}
+
+func (p *postPageResolver) PageInfo() *pageInfoResolver { return p.info }
+func (p *postPageResolver) Items() []*postResolver      { return p.items }
+
+type commentPageResolver struct {
+	info  *pageInfoResolver
+	items []*commentResolver
+}
+
+func (p *commentPageResolver) PageInfo() *pageInfoResolver { return p.info }
+func (p *commentPageResolver) Items() []*commentResolver   { return p.items }
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiKeyHandler struct {
+	apiKeyService *services.ApiKeyService
+}
+
+func NewApiKeyHandler(apiKeyService *services.ApiKeyService) *ApiKeyHandler {
+	return &ApiKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+func (h *ApiKeyHandler) Create(c *gin.Context) {
+	var req dto.CreateApiKeyDto
+	if !bindBody(c, &req) {
+		return
+	}
+
+	apiKey, rawKey, err := h.apiKeyService.WithContext(c).CreateApiKey(utils.GetCurrentUserID(c), &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	// 明文 key 只在创建时返回一次
+	utils.Success(c, gin.H{
+		"key":     rawKey,
+		"api_key": apiKey.ToResponse(),
+	})
+}
+
+func (h *ApiKeyHandler) List(c *gin.Context) {
+	keys, err := h.apiKeyService.WithContext(c).ListApiKeys(utils.GetCurrentUserID(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	items := make([]models.ApiKeyResponse, 0, len(keys))
+	for i := range keys {
+		items = append(items, keys[i].ToResponse())
+	}
+	utils.Success(c, items)
+}
+
+func (h *ApiKeyHandler) Get(c *gin.Context) {
+	keyID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	apiKey, err := h.apiKeyService.WithContext(c).GetApiKey(utils.GetCurrentUserID(c), keyID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, apiKey.ToResponse())
+}
+
+func (h *ApiKeyHandler) Update(c *gin.Context) {
+	keyID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateApiKeyDto
+	if !bindBody(c, &req) {
+		return
+	}
+
+	apiKey, err := h.apiKeyService.WithContext(c).UpdateApiKey(utils.GetCurrentUserID(c), keyID, &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, apiKey.ToResponse())
+}
+
+func (h *ApiKeyHandler) Delete(c *gin.Context) {
+	keyID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.WithContext(c).DeleteApiKey(utils.GetCurrentUserID(c), keyID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// parseIDParam 解析路径参数 :id，失败时直接写入 400 响应
+func parseIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid id")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipart 表单中除文件外其他字段的预留字节数
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	attachmentService *services.AttachmentService
+}
+
+func NewAttachmentHandler(attachmentService *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+	}
+}
+
+// Upload 通过 multipart 的 file 字段上传附件
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.attachmentService.MaxSize()+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.HandleError(c, utils.NewError(utils.ErrPayloadTooLarge, "File is too large"))
+			return
+		}
+		utils.HandleError(c, utils.NewError(utils.ErrInvalidUpload, "File is required"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.HandleError(c, utils.NewError(utils.ErrInvalidUpload, "Failed to read upload"))
+		return
+	}
+	defer file.Close()
+
+	attachmentService := h.attachmentService.WithContext(c)
+	attachment, appErr := attachmentService.Upload(postID, fileHeader.Filename, file)
+	if appErr != nil {
+		utils.HandleError(c, appErr)
+		return
+	}
+	utils.Success(c, attachment.ToResponse(attachmentService.URL))
+}
+
+func (h *AttachmentHandler) ListByPost(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachmentService.WithContext(c).ListByPost(postID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	responses := make([]models.AttachmentResponse, 0, len(attachments))
+	for i := range attachments {
+		responses = append(responses, attachments[i].ToResponse(h.attachmentService.URL))
+	}
+	utils.Success(c, responses)
+}
+
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	attachmentID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentService.WithContext(c).DeleteByID(attachmentID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"sh-manage/dto"
+	"sh-manage/services"
+	"sh-manage/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// List 按操作人、实体和时间范围分页查询审计日志
+func (h *AuditHandler) List(c *gin.Context) {
+	query := dto.AuditLogPageDTO{BasePageQuery: *dto.NewBasePageQuery()}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	page, err := h.auditService.WithContext(c).GetAuditLogByPage(&query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, page)
+}
+
+// Export 按相同的过滤条件导出CSV
+func (h *AuditHandler) Export(c *gin.Context) {
+	query := dto.AuditLogPageDTO{BasePageQuery: *dto.NewBasePageQuery()}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if err := h.auditService.WithContext(c).ExportCSV(c.Writer, &query); err != nil {
+		// 已经开始写入时无法再返回JSON错误
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			utils.HandleError(c, err)
+		}
+		return
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAuditHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(models.GetModels()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	auditService := services.NewAuditService(db)
+	auditService.Record(nil, services.AuditEntry{ActorID: 1, ActorName: "alice", Action: consts.AuditPostCreate, EntityType: consts.EntityPost, EntityID: 10})
+	auditService.Record(nil, services.AuditEntry{ActorID: 2, ActorName: "bob", Action: consts.AuditUserLogin, EntityType: consts.EntityUser, EntityID: 2})
+
+	h := NewAuditHandler(auditService)
+	r := gin.New()
+	r.GET("/audit-logs", h.List)
+	r.GET("/audit-logs/export", h.Export)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	var page struct {
+		Total int64             `json:"total"`
+		Items []models.AuditLog `json:"items"`
+	}
+	decodeData(t, get("/audit-logs?actorId=2"), &page)
+	if page.Total != 1 || page.Items[0].ActorName != "bob" {
+		t.Fatalf("page = %+v", page)
+	}
+	if w := get("/audit-logs?from=yesterday"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid from = %d, want 422", w.Code)
+	}
+
+	w := get("/audit-logs/export?entityType=post")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") ||
+		!strings.Contains(w.Header().Get("Content-Disposition"), "attachment; filename=audit-logs-") {
+		t.Fatalf("export = %d %v", w.Code, w.Header())
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || len(rows) != 2 || rows[1][3] != "alice" {
+		t.Fatalf("rows = %v, %v", rows, err)
+	}
+}
@@ -1,6 +1,13 @@
 package handlers
 
-import "sh-manage/services"
+import (
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
 
 type CommentHandler struct {
 	CommentService *services.CommentService
@@ -11,3 +18,93 @@ func NewCommentHandler(commentService *services.CommentService) *CommentHandler
 		CommentService: commentService,
 	}
 }
+
+// Create 在 /posts/:id/comments 下创建评论
+func (h *CommentHandler) Create(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.CommentDto
+	if !bindBody(c, &req) {
+		return
+	}
+	req.PostID = &postID
+
+	comment, err := h.CommentService.WithContext(c).CreateComment(&req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, comment.ToResponse())
+}
+
+// ListByPost 分页查询 /posts/:id/comments
+func (h *CommentHandler) ListByPost(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	query := dto.CommentPageDTO{BasePageQuery: *dto.NewBasePageQuery()}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+	query.PostID = &postID
+
+	page, err := h.CommentService.WithContext(c).GetCommentByPage(&query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, dto.MapPage(page, (*models.Comment).ToResponse))
+}
+
+func (h *CommentHandler) Get(c *gin.Context) {
+	commentID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	comment, err := h.CommentService.WithContext(c).GetCommentByID(commentID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, comment.ToResponse())
+}
+
+func (h *CommentHandler) Update(c *gin.Context) {
+	commentID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.CommentDto
+	if !bindBody(c, &req) {
+		return
+	}
+	req.ID = &commentID
+
+	comment, err := h.CommentService.WithContext(c).UpdateComment(&req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, comment.ToResponse())
+}
+
+func (h *CommentHandler) Delete(c *gin.Context) {
+	commentID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.CommentService.WithContext(c).DeleteByID(commentID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCommentHandler(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+	_, bob := s.login(t, "bob")
+	postID := s.createPost(t, alice, gin.H{"title": "hello", "content": "world"})
+	draftID := s.createPost(t, alice, gin.H{"title": "draft", "content": "hidden", "status": "draft"})
+
+	var comment struct {
+		ID uint `json:"id"`
+	}
+	decodeData(t, s.do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), gin.H{"content": "first"}, bob, nil), &comment)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		token      string
+		wantStatus int
+	}{
+		{"create", http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), gin.H{"content": "second"}, alice, http.StatusOK},
+		{"create unauthenticated", http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), gin.H{"content": "x"}, "", http.StatusUnauthorized},
+		{"create missing post", http.MethodPost, "/api/v1/posts/999/comments", gin.H{"content": "x"}, alice, http.StatusNotFound},
+		{"create missing content", http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), gin.H{}, alice, http.StatusUnprocessableEntity},
+		// 草稿只对作者可见，其他人评论和查询评论与读取文章一样返回 404
+		{"create on other user's draft", http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", draftID), gin.H{"content": "x"}, bob, http.StatusNotFound},
+		{"list other user's draft", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d/comments", draftID), nil, bob, http.StatusNotFound},
+		{"list draft anonymously", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d/comments", draftID), nil, "", http.StatusNotFound},
+		{"create on own draft", http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", draftID), gin.H{"content": "note"}, alice, http.StatusOK},
+		{"create invalid post id", http.MethodPost, "/api/v1/posts/0/comments", gin.H{"content": "x"}, alice, http.StatusBadRequest},
+		{"list", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d/comments", postID), nil, "", http.StatusOK},
+		{"list invalid page", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d/comments?page=0", postID), nil, "", http.StatusUnprocessableEntity},
+		{"get", http.MethodGet, fmt.Sprintf("/api/v1/comments/%d", comment.ID), nil, "", http.StatusOK},
+		{"get missing", http.MethodGet, "/api/v1/comments/999", nil, "", http.StatusNotFound},
+		{"update by other user", http.MethodPut, fmt.Sprintf("/api/v1/comments/%d", comment.ID), gin.H{"content": "x", "version": 1}, alice, http.StatusForbidden},
+		{"update without version", http.MethodPut, fmt.Sprintf("/api/v1/comments/%d", comment.ID), gin.H{"content": "x"}, bob, http.StatusBadRequest},
+		{"update", http.MethodPut, fmt.Sprintf("/api/v1/comments/%d", comment.ID), gin.H{"content": "edited", "version": 1}, bob, http.StatusOK},
+		{"update stale version", http.MethodPut, fmt.Sprintf("/api/v1/comments/%d", comment.ID), gin.H{"content": "again", "version": 1}, bob, http.StatusConflict},
+		{"delete by other user", http.MethodDelete, fmt.Sprintf("/api/v1/comments/%d", comment.ID), nil, alice, http.StatusForbidden},
+		{"delete", http.MethodDelete, fmt.Sprintf("/api/v1/comments/%d", comment.ID), nil, bob, http.StatusOK},
+		{"delete missing", http.MethodDelete, fmt.Sprintf("/api/v1/comments/%d", comment.ID), nil, bob, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, tt.body, tt.token, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	var page struct {
+		Total int `json:"total"`
+		Items []struct {
+			Content string `json:"content"`
+		} `json:"items"`
+	}
+	decodeData(t, s.do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d/comments", postID), nil, "", nil), &page)
+	if page.Total != 1 || page.Items[0].Content != "second" {
+		t.Fatalf("page = %+v", page)
+	}
+}
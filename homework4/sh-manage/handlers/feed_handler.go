@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"sh-manage/feed"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedHandler struct {
+	feedService *services.FeedService
+}
+
+func NewFeedHandler(feedService *services.FeedService) *FeedHandler {
+	return &FeedHandler{
+		feedService: feedService,
+	}
+}
+
+func (h *FeedHandler) RSS(c *gin.Context) {
+	h.serve(c, feed.FormatRSS, services.FeedQuery{})
+}
+
+func (h *FeedHandler) Atom(c *gin.Context) {
+	h.serve(c, feed.FormatAtom, services.FeedQuery{})
+}
+
+func (h *FeedHandler) AuthorRSS(c *gin.Context) {
+	h.serve(c, feed.FormatRSS, services.FeedQuery{Author: c.Param("username")})
+}
+
+func (h *FeedHandler) AuthorAtom(c *gin.Context) {
+	h.serve(c, feed.FormatAtom, services.FeedQuery{Author: c.Param("username")})
+}
+
+func (h *FeedHandler) TagRSS(c *gin.Context) {
+	h.serve(c, feed.FormatRSS, services.FeedQuery{Tag: c.Param("tag")})
+}
+
+func (h *FeedHandler) TagAtom(c *gin.Context) {
+	h.serve(c, feed.FormatAtom, services.FeedQuery{Tag: c.Param("tag")})
+}
+
+// serve 输出订阅源，支持 If-None-Match/If-Modified-Since 条件请求
+func (h *FeedHandler) serve(c *gin.Context, format feed.Format, query services.FeedQuery) {
+	document, err := h.feedService.WithContext(c).GetFeed(format, query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	utils.Raw(c, format.ContentType(), document.Body, document.LastModified)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"sh-manage/graph"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLHandler struct {
+	schema *graph.Schema
+}
+
+func NewGraphQLHandler(schema *graph.Schema) *GraphQLHandler {
+	return &GraphQLHandler{schema: schema}
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query 处理 POST /graphql，按 GraphQL 约定返回 {data, errors}，业务错误的状态码在 errors[].extensions.code 中
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req graphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+	c.JSON(http.StatusOK, h.schema.Exec(c, req.Query, req.OperationName, req.Variables))
+}
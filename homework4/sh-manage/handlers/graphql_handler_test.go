@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sh-manage/consts"
+	"sh-manage/repository"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code int `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// graphql 执行请求并解析响应，HTTP 状态码必须为 200
+func (s *testServer) graphql(t *testing.T, token string, headers map[string]string, query string, variables gin.H) graphQLResponse {
+	t.Helper()
+	w := s.do(http.MethodPost, "/graphql", gin.H{"query": query, "variables": variables}, token, headers)
+	if w.Code != http.StatusOK {
+		t.Fatalf("graphql status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp graphQLResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return resp
+}
+
+// errorCode 返回第一个错误的 extensions.code，没有错误时为 0
+func (r graphQLResponse) errorCode() int {
+	if len(r.Errors) == 0 {
+		return 0
+	}
+	return r.Errors[0].Extensions.Code
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	s := newTestServer(t)
+	aliceID, alice := s.login(t, "alice")
+	_, bob := s.login(t, "bob")
+	postID := s.createPost(t, alice, gin.H{"title": "hello", "content": "graphql", "tags": []string{"go"}})
+	s.createPost(t, alice, gin.H{"title": "draft", "content": "hidden", "status": "draft"})
+	for _, content := range []string{"first", "second", "third"} {
+		if w := s.do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), gin.H{"content": content}, bob, nil); w.Code != http.StatusOK {
+			t.Fatalf("create comment: %d %s", w.Code, w.Body)
+		}
+	}
+
+	const postQuery = `query($id: ID!) {
+		post(id: $id) {
+			title status tags
+			author { id username email }
+			comments(first: 2) { content author { username email } }
+			commentCount
+		}
+	}`
+	resp := s.graphql(t, alice, nil, postQuery, gin.H{"id": fmt.Sprint(postID)})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("post query errors: %+v", resp.Errors)
+	}
+	var data struct {
+		Post struct {
+			Title  string   `json:"title"`
+			Status string   `json:"status"`
+			Tags   []string `json:"tags"`
+			Author struct {
+				ID       string  `json:"id"`
+				Username string  `json:"username"`
+				Email    *string `json:"email"`
+			} `json:"author"`
+			Comments []struct {
+				Content string `json:"content"`
+				Author  struct {
+					Username string  `json:"username"`
+					Email    *string `json:"email"`
+				} `json:"author"`
+			} `json:"comments"`
+			CommentCount int `json:"commentCount"`
+		} `json:"post"`
+	}
+	json.Unmarshal(resp.Data, &data)
+	post := data.Post
+	if post.Title != "hello" || post.Status != "PUBLISHED" || len(post.Tags) != 1 || post.Author.ID != fmt.Sprint(aliceID) {
+		t.Fatalf("post = %+v", post)
+	}
+	// 只有本人能看到自己的邮箱
+	if post.Author.Email == nil || *post.Author.Email != "alice@example.com" {
+		t.Fatalf("own email hidden: %+v", post.Author)
+	}
+	if post.CommentCount != 3 || len(post.Comments) != 2 || post.Comments[0].Content != "first" ||
+		post.Comments[0].Author.Username != "bob" || post.Comments[0].Author.Email != nil {
+		t.Fatalf("comments = %+v, count = %d", post.Comments, post.CommentCount)
+	}
+
+	resp = s.graphql(t, "", nil, `{ posts(pageSize: 1) { pageInfo { total hasNext } items { title } } me { id } }`, nil)
+	if len(resp.Errors) != 0 || string(resp.Data) != `{"posts":{"pageInfo":{"total":1,"hasNext":false},"items":[{"title":"hello"}]},"me":null}` {
+		t.Fatalf("anonymous posts = %s, errors = %+v", resp.Data, resp.Errors)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantData string
+		wantCode int
+	}{
+		{"missing post is null", `{ post(id: "999") { title } }`, `{"post":null}`, 0},
+		{"invalid id", `{ post(id: "abc") { title } }`, `{"post":null}`, http.StatusUnprocessableEntity},
+		{"page size too large", `{ posts(pageSize: 1000) { items { title } } }`, ``, http.StatusUnprocessableEntity},
+		{"comments of missing post", `{ comments(postId: "999") { items { content } } }`, ``, http.StatusNotFound},
+		{"mutation requires login", `mutation { createPost(input: {title: "t", content: "c"}) { id } }`, ``, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.graphql(t, "", nil, tt.query, nil)
+			if resp.errorCode() != tt.wantCode {
+				t.Fatalf("error code = %d, want %d (%+v)", resp.errorCode(), tt.wantCode, resp.Errors)
+			}
+			if tt.wantData != "" && string(resp.Data) != tt.wantData {
+				t.Fatalf("data = %s, want %s", resp.Data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+	_, bob := s.login(t, "bob")
+
+	resp := s.graphql(t, alice, nil, `mutation($input: CreatePostInput!) { createPost(input: $input) { id version status } }`,
+		gin.H{"input": gin.H{"title": "graphql", "content": "created", "tags": []string{"api"}, "status": "DRAFT"}})
+	var created struct {
+		CreatePost struct {
+			ID      string `json:"id"`
+			Version int    `json:"version"`
+			Status  string `json:"status"`
+		} `json:"createPost"`
+	}
+	json.Unmarshal(resp.Data, &created)
+	if len(resp.Errors) != 0 || created.CreatePost.Status != "DRAFT" || created.CreatePost.Version != 1 {
+		t.Fatalf("createPost = %s, %+v", resp.Data, resp.Errors)
+	}
+	postID := created.CreatePost.ID
+
+	// 草稿对其他人不可见
+	resp = s.graphql(t, bob, nil, `query($id: ID!) { post(id: $id) { title } }`, gin.H{"id": postID})
+	if string(resp.Data) != `{"post":null}` {
+		t.Fatalf("bob sees draft: %s", resp.Data)
+	}
+
+	const updatePost = `mutation($id: ID!, $version: Int!) {
+		updatePost(id: $id, input: {title: "updated", content: "c", status: PUBLISHED, version: $version}) { title version }
+	}`
+	if resp := s.graphql(t, bob, nil, updatePost, gin.H{"id": postID, "version": 1}); resp.errorCode() != http.StatusForbidden {
+		t.Fatalf("bob update error = %+v", resp.Errors)
+	}
+	resp = s.graphql(t, alice, nil, updatePost, gin.H{"id": postID, "version": 1})
+	if len(resp.Errors) != 0 || string(resp.Data) != `{"updatePost":{"title":"updated","version":2}}` {
+		t.Fatalf("updatePost = %s, %+v", resp.Data, resp.Errors)
+	}
+	if resp := s.graphql(t, alice, nil, updatePost, gin.H{"id": postID, "version": 1}); resp.errorCode() != http.StatusConflict {
+		t.Fatalf("stale update error = %+v", resp.Errors)
+	}
+
+	resp = s.graphql(t, bob, nil, `mutation($id: ID!) { createComment(postId: $id, content: "nice") { id content author { username } post { title } } }`, gin.H{"id": postID})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("createComment errors = %+v", resp.Errors)
+	}
+	var comment struct {
+		CreateComment struct {
+			ID string `json:"id"`
+		} `json:"createComment"`
+	}
+	json.Unmarshal(resp.Data, &comment)
+
+	if resp := s.graphql(t, alice, nil, `mutation($id: ID!) { deleteComment(id: $id) }`, gin.H{"id": comment.CreateComment.ID}); resp.errorCode() != http.StatusForbidden {
+		t.Fatalf("alice delete bob's comment = %+v", resp.Errors)
+	}
+	resp = s.graphql(t, bob, nil, `mutation($id: ID!) { updateComment(id: $id, content: "edited", version: 1) { content version } }`, gin.H{"id": comment.CreateComment.ID})
+	if string(resp.Data) != `{"updateComment":{"content":"edited","version":2}}` {
+		t.Fatalf("updateComment = %s, %+v", resp.Data, resp.Errors)
+	}
+
+	if resp := s.graphql(t, alice, nil, `mutation { updateProfile(input: {email: "not-an-email", version: 1}) { email } }`, nil); resp.errorCode() != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid email error = %+v", resp.Errors)
+	}
+	resp = s.graphql(t, alice, nil, `mutation { updateProfile(input: {email: "alice@new.example.com", version: 1}) { email version } }`, nil)
+	if string(resp.Data) != `{"updateProfile":{"email":"alice@new.example.com","version":2}}` {
+		t.Fatalf("updateProfile = %s, %+v", resp.Data, resp.Errors)
+	}
+
+	resp = s.graphql(t, alice, nil, `mutation($id: ID!) { deletePost(id: $id) }`, gin.H{"id": postID})
+	if string(resp.Data) != `{"deletePost":true}` {
+		t.Fatalf("deletePost = %s, %+v", resp.Data, resp.Errors)
+	}
+}
+
+// TestGraphQLModerationStatuses 审核中和被拒绝的文章对作者可见，状态需要能序列化为枚举值
+func TestGraphQLModerationStatuses(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+	postID := s.createPost(t, alice, gin.H{"title": "reviewed", "content": "c"})
+
+	for _, status := range []string{consts.PostStatusPendingReview, consts.PostStatusRejected} {
+		if _, err := s.repo.Repositories().Posts.Update(context.Background(), postID, 0, repository.PostChanges{Status: &status}); err != nil {
+			t.Fatalf("set status %s: %v", status, err)
+		}
+		resp := s.graphql(t, alice, nil, `query($id: ID!) { post(id: $id) { status } }`, gin.H{"id": strconv.FormatUint(uint64(postID), 10)})
+		if want := fmt.Sprintf(`{"post":{"status":"%s"}}`, strings.ToUpper(status)); len(resp.Errors) != 0 || string(resp.Data) != want {
+			t.Fatalf("status %s = %s, %+v", status, resp.Data, resp.Errors)
+		}
+	}
+
+	// 作者不能直接把文章设为审核状态
+	resp := s.graphql(t, alice, nil, `mutation { createPost(input: {title: "t", content: "c", status: PENDING_REVIEW}) { id } }`, nil)
+	if resp.errorCode() != http.StatusUnprocessableEntity {
+		t.Fatalf("create pending post = %s, %+v", resp.Data, resp.Errors)
+	}
+}
+
+func TestGraphQLAuthentication(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+
+	// 携带无效令牌时与 REST 接口一样直接返回 401
+	if w := s.do(http.MethodPost, "/graphql", gin.H{"query": "{ me { id } }"}, "invalid", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("invalid token = %d", w.Code)
+	}
+	if w := s.do(http.MethodPost, "/graphql", gin.H{"query": "{ me { id } }"}, alice, map[string]string{"X-Tenant-ID": "acme"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("token from other tenant = %d", w.Code)
+	}
+	if w := s.do(http.MethodPost, "/graphql", gin.H{}, "", nil); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("missing query = %d", w.Code)
+	}
+
+	resp := s.graphql(t, alice, nil, `{ me { username email } }`, nil)
+	if string(resp.Data) != `{"me":{"username":"alice","email":"alice@example.com"}}` {
+		t.Fatalf("me = %s, %+v", resp.Data, resp.Errors)
+	}
+}
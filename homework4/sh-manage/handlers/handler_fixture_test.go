@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sh-manage/config"
+	"sh-manage/events"
+	"sh-manage/graph"
+	"sh-manage/live"
+	"sh-manage/middleware"
+	"sh-manage/models"
+	"sh-manage/repository"
+	"sh-manage/services"
+	"sh-manage/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type nopAudit struct{}
+
+func (nopAudit) Record(*gin.Context, services.AuditEntry) {}
+
+// stubTenants 按标识返回固定 ID 的租户，未登记的标识返回 404
+type stubTenants map[string]uint
+
+func (s stubTenants) Resolve(slug string) (*models.Tenant, *utils.AppError) {
+	id, ok := s[slug]
+	if !ok {
+		return nil, utils.NewError(utils.ErrTenantNotFound, "Tenant not found")
+	}
+	return &models.Tenant{Model: gorm.Model{ID: id}, Slug: slug}, nil
+}
+
+// testServer 使用内存存储和真实的 JWT 认证中间件，路由与 app 包中的注册方式一致
+type testServer struct {
+	router *gin.Engine
+	repo   *repository.Memory
+	hub    *live.Hub
+	users  *services.UserService
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemory()
+	jwtKeys := utils.NewJWTKeys([]byte("handler-test-secret-0123"), time.Hour)
+	repos := repo.Repositories()
+	userService := services.NewUserServiceWithRepositories(repos, nil, nopAudit{})
+	postService := services.NewPostServiceWithRepositories(repos, userService, nil, nopAudit{})
+	commentService := services.NewCommentServiceWithRepositories(repos, userService, nopAudit{})
+	bus := events.NewBus()
+	postService.SetPublisher(bus)
+	commentService.SetPublisher(bus)
+	hub := live.NewHub(live.DefaultBuffer)
+	bus.Subscribe(hub.Publish)
+
+	userHandler := NewUserHandler(userService, jwtKeys)
+	postHandler := NewPostHandler(postService)
+	commentHandler := NewCommentHandler(commentService)
+	schema, err := graph.NewSchema(userService, postService, commentService)
+	if err != nil {
+		t.Fatalf("parse graphql schema: %v", err)
+	}
+	graphQLHandler := NewGraphQLHandler(schema)
+	streamHandler := NewStreamHandler(hub, postService)
+	streamHandler.heartbeat = 50 * time.Millisecond
+
+	// 不带 X-Tenant-ID 的请求属于 default 租户
+	tenants := middleware.NewTenants(stubTenants{"default": 1, "acme": 2}, config.LoadSimple())
+	r := gin.New()
+	r.Use(middleware.Tenant(tenants))
+	r.POST("/graphql", middleware.OptionalAuth(jwtKeys, nil, userService), graphQLHandler.Query)
+	public := r.Group("/api/v1", middleware.Negotiate())
+	{
+		public.POST("/users/register", userHandler.Register)
+		public.POST("/users/login", userHandler.Login)
+		public.GET("/posts", postHandler.List)
+		public.GET("/posts/:id", postHandler.Get)
+		public.GET("/posts/:id/comments", commentHandler.ListByPost)
+		public.GET("/comments/:id", commentHandler.Get)
+	}
+	protected := r.Group("/api/v1")
+	protected.Use(middleware.Negotiate(), middleware.Auth(jwtKeys, nil, userService))
+	{
+		protected.GET("/users/me", userHandler.GetProfile)
+		protected.PUT("/users/me", userHandler.UpdateProfile)
+		protected.POST("/posts", postHandler.Create)
+		protected.PUT("/posts/:id", postHandler.Update)
+		protected.DELETE("/posts/:id", postHandler.Delete)
+		protected.POST("/posts/:id/comments", commentHandler.Create)
+		protected.PUT("/comments/:id", commentHandler.Update)
+		protected.DELETE("/comments/:id", commentHandler.Delete)
+	}
+	streams := r.Group("/api/v1")
+	streams.Use(middleware.QueryToken(), middleware.Auth(jwtKeys, nil, userService))
+	{
+		streams.GET("/posts/:id/comments/stream", streamHandler.CommentStream)
+		streams.GET("/ws/comments", streamHandler.WebSocket)
+	}
+	return &testServer{router: r, repo: repo, hub: hub, users: userService}
+}
+
+// do 发送请求，body 不是 string 时编码为 JSON
+func (s *testServer) do(method, path string, body interface{}, token string, headers map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		data, _ := json.Marshal(b)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// login 在默认租户注册并登录用户，返回用户ID和令牌
+func (s *testServer) login(t *testing.T, username string) (uint, string) {
+	t.Helper()
+	return s.loginTenant(t, "", username)
+}
+
+// loginTenant 在 X-Tenant-ID 指定的租户注册并登录用户
+func (s *testServer) loginTenant(t *testing.T, slug, username string) (uint, string) {
+	t.Helper()
+	headers := map[string]string{"X-Tenant-ID": slug}
+	body := gin.H{"username": username, "email": username + "@example.com", "password": "Correct-Horse-42"}
+	if w := s.do(http.MethodPost, "/api/v1/users/register", body, "", headers); w.Code != http.StatusOK {
+		t.Fatalf("register %s: %d %s", username, w.Code, w.Body)
+	}
+	w := s.do(http.MethodPost, "/api/v1/users/login", body, "", headers)
+	var data struct {
+		Token string `json:"token"`
+		User  struct {
+			ID uint `json:"id"`
+		} `json:"user"`
+	}
+	decodeData(t, w, &data)
+	return data.User.ID, data.Token
+}
+
+// createPost 通过接口创建文章并返回ID
+func (s *testServer) createPost(t *testing.T, token string, body gin.H) uint {
+	t.Helper()
+	w := s.do(http.MethodPost, "/api/v1/posts", body, token, nil)
+	var post struct {
+		ID uint `json:"id"`
+	}
+	decodeData(t, w, &post)
+	return post.ID
+}
+
+// decodeData 校验响应为 200 并解析 data 字段
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, data interface{}) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	resp := utils.Response{Data: data}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+}
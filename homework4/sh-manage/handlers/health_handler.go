@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"sh-manage/logging"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService *services.HealthService
+}
+
+func NewHealthHandler(healthService *services.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+// Livez 进程存活即返回成功，不检查外部依赖，避免依赖故障导致进程被反复重启
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, utils.Response{Code: 200, Message: "success", Data: gin.H{"status": "ok"}})
+}
+
+// Readyz 检查数据库等依赖，任一不可用或正在停机时返回 503
+// 接口不需要认证，响应只包含各项检查的状态，失败原因记录在服务端日志中
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	report, ready := h.healthService.Ready(c.Request.Context())
+	for name, result := range report.Checks {
+		if result.Error != "" {
+			logging.Warnf("readiness check %s failed: %s", name, result.Error)
+		}
+	}
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, utils.Response{Code: 503, Message: "service not ready", Data: report})
+		return
+	}
+	c.JSON(http.StatusOK, utils.Response{Code: 200, Message: "success", Data: report})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sh-manage/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestReadyzHidesCheckErrors 就绪检查失败时只返回状态，不把依赖的错误信息返回给未认证的调用方
+func TestReadyzHidesCheckErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	healthService := services.NewHealthService()
+	healthService.AddCheck("database", func(ctx context.Context) error {
+		return errors.New("dial tcp 10.0.0.5:3306: access denied for user 'app'")
+	})
+	r := gin.New()
+	r.GET("/readyz", NewHealthHandler(healthService).Readyz)
+	s := &testServer{router: r}
+
+	w := s.do(http.MethodGet, "/readyz", nil, "", nil)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"status":"fail"`) {
+		t.Fatalf("readyz = %d %s", w.Code, w.Body)
+	}
+	if body := w.Body.String(); strings.Contains(body, "10.0.0.5") || strings.Contains(body, "error") {
+		t.Fatalf("readyz leaks check error: %s", body)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationHandler struct {
+	moderationService *services.ModerationService
+}
+
+func NewModerationHandler(moderationService *services.ModerationService) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: moderationService,
+	}
+}
+
+// Queue 分页查询审核队列，status 默认为 pending
+func (h *ModerationHandler) Queue(c *gin.Context) {
+	query := dto.ModerationQueueDTO{BasePageQuery: *dto.NewBasePageQuery()}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	page, err := h.moderationService.WithContext(c).GetQueue(&query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, dto.MapPage(page, (*models.ModerationItem).ToResponse))
+}
+
+// Approve 审核通过，发布文章或评论
+func (h *ModerationHandler) Approve(c *gin.Context) {
+	h.decide(c, (*services.ModerationService).Approve)
+}
+
+// Reject 审核拒绝，内容仍然只有作者可见
+func (h *ModerationHandler) Reject(c *gin.Context) {
+	h.decide(c, (*services.ModerationService).Reject)
+}
+
+func (h *ModerationHandler) decide(c *gin.Context, decide func(*services.ModerationService, uint, string) (*models.ModerationItem, *utils.AppError)) {
+	itemID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	// 备注可以省略，此时请求体为空
+	var req dto.ModerationDecisionDto
+	if c.Request.ContentLength != 0 {
+		if !bindBody(c, &req) {
+			return
+		}
+	}
+
+	item, err := decide(h.moderationService.WithContext(c), itemID, req.Note)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, item.ToResponse())
+}
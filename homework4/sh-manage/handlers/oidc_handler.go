@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	oidcService *services.OIDCService
+	jwtKeys     *utils.JWTKeys
+}
+
+func NewOIDCHandler(oidcService *services.OIDCService, jwtKeys *utils.JWTKeys) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		jwtKeys:     jwtKeys,
+	}
+}
+
+// Login 重定向到身份提供方的授权页面
+func (h *OIDCHandler) Login(c *gin.Context) {
+	url, err := h.oidcService.AuthCodeURL(c.Param("provider"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback 身份提供方回调，换取令牌后签发本系统的 JWT
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		utils.Error(c, http.StatusUnauthorized, "OIDC login failed: "+errCode)
+		return
+	}
+
+	user, err := h.oidcService.Exchange(c.Request.Context(), c.Param("provider"), c.Query("state"), c.Query("code"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	token, e := h.jwtKeys.Sign(user.ID, user.TenantID, user.Username)
+	if e != nil {
+		utils.HandleError(c, e)
+		return
+	}
+
+	utils.Success(c, gin.H{"token": token,
+		"user": user.ToResponse(),
+	})
+}
@@ -1,6 +1,13 @@
 package handlers
 
-import "sh-manage/services"
+import (
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
 
 type PostHandler struct {
 	PostService *services.PostService
@@ -11,3 +18,80 @@ func NewPostHandler(postService *services.PostService) *PostHandler {
 		PostService: postService,
 	}
 }
+
+func (h *PostHandler) Create(c *gin.Context) {
+	var req dto.PostDto
+	if !bindBody(c, &req) {
+		return
+	}
+
+	post, err := h.PostService.WithContext(c).CreatePost(&req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, post.ToResponse())
+}
+
+func (h *PostHandler) Get(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.PostService.WithContext(c).GetPostByID(postID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, post.ToResponse())
+}
+
+func (h *PostHandler) List(c *gin.Context) {
+	query := dto.PostPageDTO{BasePageQuery: *dto.NewBasePageQuery()}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	page, err := h.PostService.WithContext(c).GetPostByPage(&query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, dto.MapPage(page, (*models.Post).ToResponse))
+}
+
+func (h *PostHandler) Update(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.PostDto
+	if !bindBody(c, &req) {
+		return
+	}
+	req.ID = &postID
+
+	// 携带 If-Match 时，只有与当前版本的 ETag 一致才允许修改，请求体可以不带版本号
+	post, err := h.PostService.WithContext(c).UpdatePostIfMatch(&req, c.GetHeader("If-Match"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, post.ToResponse())
+}
+
+func (h *PostHandler) Delete(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.PostService.WithContext(c).DeleteByID(postID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
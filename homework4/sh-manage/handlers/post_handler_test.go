@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostHandler(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+	_, bob := s.login(t, "bob")
+
+	postID := s.createPost(t, alice, gin.H{"title": "hello", "content": "world", "tags": []string{"go"}})
+	draftID := s.createPost(t, alice, gin.H{"title": "draft", "content": "wip", "status": "draft"})
+	deleteID := s.createPost(t, alice, gin.H{"title": "to delete", "content": "bye"})
+
+	var current struct {
+		Version uint `json:"version"`
+	}
+	w := s.do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", postID), nil, "", nil)
+	decodeData(t, w, &current)
+	etag := w.Header().Get("ETag")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		token      string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"create", http.MethodPost, "/api/v1/posts", gin.H{"title": "t", "content": "c"}, alice, nil, http.StatusOK},
+		{"create unauthenticated", http.MethodPost, "/api/v1/posts", gin.H{"title": "t", "content": "c"}, "", nil, http.StatusUnauthorized},
+		{"create missing content", http.MethodPost, "/api/v1/posts", gin.H{"title": "t"}, alice, nil, http.StatusUnprocessableEntity},
+		{"create bad status", http.MethodPost, "/api/v1/posts", gin.H{"title": "t", "content": "c", "status": "archived"}, alice, nil, http.StatusUnprocessableEntity},
+		{"get", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", postID), nil, "", nil, http.StatusOK},
+		{"get not modified", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", postID), nil, "", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"get invalid id", http.MethodGet, "/api/v1/posts/abc", nil, "", nil, http.StatusBadRequest},
+		{"get missing", http.MethodGet, "/api/v1/posts/999", nil, "", nil, http.StatusNotFound},
+		{"get draft hidden", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", draftID), nil, "", nil, http.StatusNotFound},
+		{"list", http.MethodGet, "/api/v1/posts?page=1&pageSize=10", nil, "", nil, http.StatusOK},
+		{"list invalid page size", http.MethodGet, "/api/v1/posts?pageSize=0", nil, "", nil, http.StatusUnprocessableEntity},
+		{"update by other user", http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", postID), gin.H{"title": "x", "content": "y", "version": current.Version}, bob, nil, http.StatusForbidden},
+		{"update if-match mismatch", http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", postID), gin.H{"title": "x", "content": "y", "version": current.Version}, alice, map[string]string{"If-Match": `"stale"`}, http.StatusPreconditionFailed},
+		{"update stale version", http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", postID), gin.H{"title": "x", "content": "y", "version": 99}, alice, nil, http.StatusConflict},
+		{"update if-match without version", http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", postID), gin.H{"title": "x", "content": "y"}, alice, map[string]string{"If-Match": etag}, http.StatusOK},
+		{"update reusing etag", http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", postID), gin.H{"title": "x", "content": "z"}, alice, map[string]string{"If-Match": etag}, http.StatusPreconditionFailed},
+		{"delete by other user", http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", deleteID), nil, bob, nil, http.StatusForbidden},
+		{"delete", http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", deleteID), nil, alice, nil, http.StatusOK},
+		{"delete missing", http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", deleteID), nil, alice, nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, tt.body, tt.token, tt.headers)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	var page struct {
+		Total int `json:"total"`
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+	}
+	decodeData(t, s.do(http.MethodGet, "/api/v1/posts?title=x", nil, "", nil), &page)
+	if page.Total != 1 || page.Items[0].Title != "x" {
+		t.Fatalf("page = %+v", page)
+	}
+}
+
+func TestPostHandlerContentNegotiation(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+
+	yamlBody := "title: hello\ncontent: from yaml\ntags: [go]\n"
+	w := s.do(http.MethodPost, "/api/v1/posts", yamlBody, alice, map[string]string{"Content-Type": "application/yaml", "Accept": "application/xml"})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<title>hello</title>") || !strings.Contains(w.Body.String(), "<content>from yaml</content>") {
+		t.Fatalf("create with yaml = %d %s", w.Code, w.Body)
+	}
+
+	// 不支持的 Accept 在修改数据前返回 406，不支持的请求体格式返回 415
+	if w := s.do(http.MethodPost, "/api/v1/posts", gin.H{"title": "t", "content": "c"}, alice, map[string]string{"Accept": "text/html"}); w.Code != http.StatusNotAcceptable {
+		t.Fatalf("unsupported accept = %d", w.Code)
+	}
+	if w := s.do(http.MethodPost, "/api/v1/posts", "title=t", alice, map[string]string{"Content-Type": "application/x-www-form-urlencoded"}); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("unsupported content type = %d", w.Code)
+	}
+	var page struct {
+		Total int64 `json:"total"`
+	}
+	decodeData(t, s.do(http.MethodGet, "/api/v1/posts", nil, "", nil), &page)
+	if page.Total != 1 {
+		t.Fatalf("total = %d", page.Total)
+	}
+
+	// XML 表示的 ETag 带格式后缀，条件更新时与 JSON 的 ETag 一样有效
+	postID := s.createPost(t, alice, gin.H{"title": "conditional", "content": "xml"})
+	path := fmt.Sprintf("/api/v1/posts/%d", postID)
+	w = s.do(http.MethodGet, path, nil, "", map[string]string{"Accept": "application/xml"})
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasSuffix(etag, `-xml"`) {
+		t.Fatalf("get xml = %d, etag %q", w.Code, etag)
+	}
+	if w := s.do(http.MethodPut, path, gin.H{"title": "conditional", "content": "updated"}, alice, map[string]string{"If-Match": etag}); w.Code != http.StatusOK {
+		t.Fatalf("update with xml etag = %d %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPut, path, gin.H{"title": "conditional", "content": "again"}, alice, map[string]string{"If-Match": etag}); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("update with stale xml etag = %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sh-manage/services"
+	"sh-manage/transfer"
+	"sh-manage/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导入文件的最大字节数
+const maxImportFileSize = 20 << 20
+
+type PostTransferHandler struct {
+	transferService *services.PostTransferService
+}
+
+func NewPostTransferHandler(transferService *services.PostTransferService) *PostTransferHandler {
+	return &PostTransferHandler{
+		transferService: transferService,
+	}
+}
+
+// Export 导出文章，format 可选 json、csv、markdown，默认 json
+func (h *PostTransferHandler) Export(c *gin.Context) {
+	format, err := transfer.ParseFormat(c.DefaultQuery("format", string(transfer.FormatJSON)))
+	if err != nil {
+		utils.HandleError(c, utils.NewError(utils.ErrUnsupportedFormat, err.Error()))
+		return
+	}
+
+	filename := fmt.Sprintf("posts-%s.%s", time.Now().Format("20060102150405"), format.Extension())
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if err := h.transferService.WithContext(c).Export(c.Writer, format); err != nil {
+		// 已经开始写入时无法再返回JSON错误
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			utils.HandleError(c, err)
+		}
+		return
+	}
+}
+
+// Import 导入文章，文件通过 multipart 的 file 字段上传
+// format 未指定时按文件扩展名推断，dryRun=true 时只校验不写入
+func (h *PostTransferHandler) Import(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.HandleError(c, utils.NewError(utils.ErrInvalidImportFile, "Import file is required"))
+		return
+	}
+
+	formatName := c.Query("format")
+	if formatName == "" {
+		formatName = filepath.Ext(fileHeader.Filename)
+	}
+	format, err := transfer.ParseFormat(formatName)
+	if err != nil {
+		utils.HandleError(c, utils.NewError(utils.ErrUnsupportedFormat, err.Error()))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.HandleError(c, utils.NewError(utils.ErrInvalidImportFile, "Failed to read import file"))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.HandleError(c, utils.NewError(utils.ErrInvalidImportFile, "Failed to read import file"))
+		return
+	}
+
+	result, appErr := h.transferService.WithContext(c).Import(data, format, dryRun)
+	if appErr != nil {
+		utils.HandleError(c, appErr)
+		return
+	}
+	utils.Success(c, result)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// Create 举报文章、评论或用户
+func (h *ReportHandler) Create(c *gin.Context) {
+	var req dto.CreateReportDto
+	if !bindBody(c, &req) {
+		return
+	}
+
+	report, err := h.reportService.WithContext(c).CreateReport(&req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, report.ToResponse())
+}
+
+// List 分页查询举报，status 默认为 open
+func (h *ReportHandler) List(c *gin.Context) {
+	query := dto.ReportPageDTO{BasePageQuery: *dto.NewBasePageQuery()}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	page, err := h.reportService.WithContext(c).GetReports(&query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, dto.MapPage(page, (*models.Report).ToResponse))
+}
+
+func (h *ReportHandler) Get(c *gin.Context) {
+	reportID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.WithContext(c).GetReportByID(reportID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, report.ToResponse())
+}
+
+// Resolve 处理举报：忽略、删除内容、警告、停用或封禁
+func (h *ReportHandler) Resolve(c *gin.Context) {
+	reportID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	var req dto.ResolveReportDto
+	if !bindBody(c, &req) {
+		return
+	}
+
+	report, err := h.reportService.WithContext(c).Resolve(reportID, &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, report.ToResponse())
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"sh-manage/dto"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatsHandler struct {
+	statsService *services.StatsService
+}
+
+func NewStatsHandler(statsService *services.StatsService) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
+
+func (h *StatsHandler) Overview(c *gin.Context) {
+	overview, err := h.statsService.WithContext(c).Overview()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, overview)
+}
+
+// TimeSeries metric 可选 users、posts、comments，interval 可选 day、week
+func (h *StatsHandler) TimeSeries(c *gin.Context) {
+	query, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.statsService.WithContext(c).TimeSeries(query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, series)
+}
+
+func (h *StatsHandler) TopAuthors(c *gin.Context) {
+	query, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	authors, err := h.statsService.WithContext(c).TopAuthors(query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, *authors)
+}
+
+func (h *StatsHandler) TopPosts(c *gin.Context) {
+	query, ok := bindStatsQuery(c)
+	if !ok {
+		return
+	}
+
+	posts, err := h.statsService.WithContext(c).TopCommentedPosts(query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, *posts)
+}
+
+func (h *StatsHandler) Storage(c *gin.Context) {
+	usage, err := h.statsService.WithContext(c).StorageUsage()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, usage)
+}
+
+func bindStatsQuery(c *gin.Context) (*dto.StatsQueryDTO, bool) {
+	var query dto.StatsQueryDTO
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return nil, false
+	}
+	return &query, true
+}
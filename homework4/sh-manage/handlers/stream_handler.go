@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sh-manage/consts"
+	"sh-manage/events"
+	"sh-manage/live"
+	"sh-manage/services"
+	"sh-manage/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxSubscriptions 一个 WebSocket 连接最多同时订阅的文章数
+	maxSubscriptions = 20
+	// writeWait 写入一条 WebSocket 消息的最长时间
+	writeWait = 10 * time.Second
+)
+
+// StreamHandler 通过 SSE 和 WebSocket 推送评论，事件来自 live.Hub
+type StreamHandler struct {
+	hub         *live.Hub
+	postService *services.PostService
+	// heartbeat SSE 注释行和 WebSocket ping 的间隔，超过两个间隔没有收到 pong 时断开 WebSocket
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(hub *live.Hub, postService *services.PostService) *StreamHandler {
+	return &StreamHandler{hub: hub, postService: postService, heartbeat: 15 * time.Second}
+}
+
+// CommentStream 以 SSE 推送 /posts/:id/comments/stream 的评论事件，文章删除或不可见后结束
+func (h *StreamHandler) CommentStream(c *gin.Context) {
+	postID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	if _, err := h.postService.WithContext(c).GetPostByID(postID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	sub := h.hub.NewSubscriber(utils.GetCurrentUserID(c))
+	defer h.hub.Close(sub)
+	h.hub.Subscribe(sub, live.Topic{TenantID: utils.GetCurrentTenantID(c), PostID: postID})
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// 断开后浏览器的 EventSource 3 秒后自动重连
+	if _, err := fmt.Fprint(c.Writer, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-sub.Done():
+			// 缓冲区满或服务关闭，结束响应让客户端重连
+			return
+		case <-ticker.C:
+			_, err = fmt.Fprint(c.Writer, ": ping\n\n")
+		case event := <-sub.Events():
+			data, _ := json.Marshal(event)
+			_, err = fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			if err == nil && endsSubscription(event) {
+				c.Writer.Flush()
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// endsSubscription 文章被删除或对订阅者不可见后不会再有事件
+func endsSubscription(event events.Event) bool {
+	return event.Type == consts.EventPostDeleted || event.Type == consts.EventPostUnavailable
+}
+
+// streamMessage 客户端发送的消息，action 为 subscribe 或 unsubscribe
+type streamMessage struct {
+	Action string `json:"action"`
+	PostID uint   `json:"post_id"`
+}
+
+// streamReply 对客户端消息的回复，type 为 subscribed、unsubscribed 或 error；事件以 events.Event 的格式发送
+type streamReply struct {
+	Type    string `json:"type"`
+	PostID  uint   `json:"post_id,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// WebSocket 在一个连接上订阅多篇文章的评论事件
+func (h *StreamHandler) WebSocket(c *gin.Context) {
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin(c)}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade 已经返回了 HTTP 错误
+		return
+	}
+	defer conn.Close()
+
+	sub := h.hub.NewSubscriber(utils.GetCurrentUserID(c))
+	defer h.hub.Close(sub)
+
+	replies := make(chan streamReply, 8)
+	quit := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		h.writeLoop(conn, sub, replies, quit)
+	}()
+	defer func() {
+		close(quit)
+		<-writerDone
+	}()
+
+	pongWait := 2 * h.heartbeat
+	conn.SetReadLimit(4096)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	// 服务层只在读取消息的这个 goroutine 中使用 c
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var reply streamReply
+		var msg streamMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			reply = streamReply{Type: "error", Message: "Invalid message"}
+		} else {
+			reply = h.handleMessage(c, sub, msg)
+		}
+		select {
+		case replies <- reply:
+		case <-writerDone:
+			return
+		}
+	}
+}
+
+func (h *StreamHandler) handleMessage(c *gin.Context, sub *live.Subscriber, msg streamMessage) streamReply {
+	topic := live.Topic{TenantID: utils.GetCurrentTenantID(c), PostID: msg.PostID}
+	switch msg.Action {
+	case "subscribe":
+		if h.hub.Count(sub) >= maxSubscriptions {
+			return streamReply{Type: "error", PostID: msg.PostID, Message: fmt.Sprintf("At most %d posts can be subscribed", maxSubscriptions)}
+		}
+		if _, err := h.postService.WithContext(c).GetPostByID(msg.PostID); err != nil {
+			return streamReply{Type: "error", PostID: msg.PostID, Message: err.Message}
+		}
+		h.hub.Subscribe(sub, topic)
+		return streamReply{Type: "subscribed", PostID: msg.PostID}
+	case "unsubscribe":
+		h.hub.Unsubscribe(sub, topic)
+		return streamReply{Type: "unsubscribed", PostID: msg.PostID}
+	default:
+		return streamReply{Type: "error", Message: "Action must be subscribe or unsubscribe"}
+	}
+}
+
+// writeLoop 是唯一写入连接的 goroutine，退出时关闭连接让读取结束
+func (h *StreamHandler) writeLoop(conn *websocket.Conn, sub *live.Subscriber, replies <-chan streamReply, quit <-chan struct{}) {
+	defer conn.Close()
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-quit:
+			return
+		case <-sub.Done():
+			code := websocket.CloseGoingAway
+			if errors.Is(sub.Err(), live.ErrOverflow) {
+				code = websocket.CloseTryAgainLater
+			}
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, sub.Err().Error()), time.Now().Add(writeWait))
+			return
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+		case reply := <-replies:
+			err = writeJSON(conn, reply)
+		case event := <-sub.Events():
+			err = writeJSON(conn, event)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeJSON(conn *websocket.Conn, v interface{}) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(v)
+}
+
+// checkOrigin 允许同源请求；跨域请求的来源需要在租户的跨域白名单中，此时 TenantCORS 已经写入 Access-Control-Allow-Origin
+func checkOrigin(c *gin.Context) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		return c.Writer.Header().Get("Access-Control-Allow-Origin") == origin
+	}
+}
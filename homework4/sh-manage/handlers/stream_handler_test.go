@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// sseEvent 一条 SSE 消息，注释行（心跳）的 event 为 ping
+type sseEvent struct {
+	event string
+	data  string
+}
+
+// readSSE 按空行切分 SSE 消息，连接结束时关闭通道
+func readSSE(resp *http.Response) <-chan sseEvent {
+	ch := make(chan sseEvent, 16)
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(resp.Body)
+		var current sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if current != (sseEvent{}) {
+					ch <- current
+				}
+				current = sseEvent{}
+			case strings.HasPrefix(line, ": "):
+				current.event = "ping"
+			case strings.HasPrefix(line, "event: "):
+				current.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				current.data = strings.TrimPrefix(line, "data: ")
+			case strings.HasPrefix(line, "retry: "):
+				current.event = "retry"
+			}
+		}
+	}()
+	return ch
+}
+
+// nextSSE 跳过心跳，返回下一条事件；连接结束时 ok 为 false
+func nextSSE(t *testing.T, events <-chan sseEvent) (sseEvent, bool) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok || event.event != "ping" {
+				return event, ok
+			}
+		case <-timeout:
+			t.Fatalf("no SSE event within 2s")
+		}
+	}
+}
+
+func TestCommentStreamSSE(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+	_, bob := s.login(t, "bob")
+	_, carol := s.loginTenant(t, "acme", "carol")
+	postID := s.createPost(t, alice, gin.H{"title": "live", "content": "stream"})
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	streamURL := fmt.Sprintf("%s/api/v1/posts/%d/comments/stream", server.URL, postID)
+	tests := []struct {
+		name string
+		url  string
+		slug string
+		want int
+	}{
+		{"missing token", streamURL, "", http.StatusUnauthorized},
+		{"missing post", fmt.Sprintf("%s/api/v1/posts/999/comments/stream?access_token=%s", server.URL, bob), "", http.StatusNotFound},
+		{"post from other tenant", streamURL + "?access_token=" + carol, "acme", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set("X-Tenant-ID", tt.slug)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	resp, err := http.Get(streamURL + "?access_token=" + bob)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := readSSE(resp)
+	// retry 在订阅之后写入，收到它说明已经订阅
+	if event, _ := nextSSE(t, events); event.event != "retry" {
+		t.Fatalf("first event = %+v", event)
+	}
+
+	// 心跳间隔为 50ms
+	select {
+	case event := <-events:
+		if event.event != "ping" {
+			t.Fatalf("heartbeat = %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no heartbeat")
+	}
+
+	if w := s.do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), gin.H{"content": "hello live"}, alice, nil); w.Code != http.StatusOK {
+		t.Fatalf("create comment: %d %s", w.Code, w.Body)
+	}
+	event, _ := nextSSE(t, events)
+	if event.event != "comment.created" || !strings.Contains(event.data, `"content":"hello live"`) {
+		t.Fatalf("event = %+v", event)
+	}
+
+	// 文章删除后推送删除事件并结束响应
+	if w := s.do(http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", postID), nil, alice, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete post: %d %s", w.Code, w.Body)
+	}
+	if event, _ := nextSSE(t, events); event.event != "post.deleted" {
+		t.Fatalf("event = %+v", event)
+	}
+	if event, ok := nextSSE(t, events); ok {
+		t.Fatalf("stream not closed, got %+v", event)
+	}
+}
+
+type wsFrame struct {
+	Type    string `json:"type"`
+	PostID  uint   `json:"post_id"`
+	Message string `json:"message"`
+	Data    struct {
+		Content string `json:"content"`
+		PostID  uint   `json:"post_id"`
+	} `json:"data"`
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) wsFrame {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var frame wsFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	return frame
+}
+
+func TestCommentStreamWebSocket(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+	_, bob := s.login(t, "bob")
+	first := s.createPost(t, alice, gin.H{"title": "first", "content": "stream"})
+	second := s.createPost(t, alice, gin.H{"title": "second", "content": "stream"})
+	draft := s.createPost(t, alice, gin.H{"title": "draft", "content": "hidden", "status": "draft"})
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/comments"
+
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("dial without token: %v", err)
+	}
+	header := http.Header{"Origin": {"http://evil.example.com"}}
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"?access_token="+bob, header); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("dial from other origin: %v", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + bob}})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	var pings atomic.Int32
+	conn.SetPingHandler(func(data string) error {
+		pings.Add(1)
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	send := func(msg string) wsFrame {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return readFrame(t, conn)
+	}
+	for _, id := range []uint{first, second} {
+		if frame := send(fmt.Sprintf(`{"action":"subscribe","post_id":%d}`, id)); frame.Type != "subscribed" || frame.PostID != id {
+			t.Fatalf("subscribe %d = %+v", id, frame)
+		}
+	}
+	// 其他人的草稿、不存在的文章和无效消息都返回错误，连接保持
+	for _, msg := range []string{fmt.Sprintf(`{"action":"subscribe","post_id":%d}`, draft), `{"action":"subscribe","post_id":999}`, `not json`, `{"action":"publish"}`} {
+		if frame := send(msg); frame.Type != "error" || frame.Message == "" {
+			t.Fatalf("%s = %+v", msg, frame)
+		}
+	}
+
+	comment := func(postID uint, content string) {
+		t.Helper()
+		if w := s.do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", postID), gin.H{"content": content}, alice, nil); w.Code != http.StatusOK {
+			t.Fatalf("create comment: %d %s", w.Code, w.Body)
+		}
+	}
+	comment(first, "on first")
+	comment(second, "on second")
+	for _, want := range []uint{first, second} {
+		if frame := readFrame(t, conn); frame.Type != "comment.created" || frame.Data.PostID != want {
+			t.Fatalf("event = %+v, want comment on %d", frame, want)
+		}
+	}
+
+	if frame := send(fmt.Sprintf(`{"action":"unsubscribe","post_id":%d}`, first)); frame.Type != "unsubscribed" {
+		t.Fatalf("unsubscribe = %+v", frame)
+	}
+	comment(first, "ignored")
+	comment(second, "still streamed")
+	if frame := readFrame(t, conn); frame.Data.Content != "still streamed" {
+		t.Fatalf("event = %+v", frame)
+	}
+
+	// 读取期间会处理服务端的 ping
+	_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, _ = conn.ReadMessage()
+	if pings.Load() == 0 {
+		t.Fatalf("no heartbeat ping")
+	}
+}
+
+func TestCommentStreamShutdown(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.login(t, "alice")
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/v1/ws/comments?access_token="+alice, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	// 服务关闭时以 1001 关闭连接
+	s.hub.Shutdown()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("close error = %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/events"
+	"sh-manage/middleware"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/tenant"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestTenantIsolation 两个租户使用同一套存储，数据和令牌都不能跨租户使用
+func TestTenantIsolation(t *testing.T) {
+	s := newTestServer(t)
+	acme := map[string]string{"X-Tenant-ID": "acme"}
+
+	// 同名用户可以在两个租户分别注册
+	_, defaultToken := s.loginTenant(t, "", "alice")
+	_, acmeToken := s.loginTenant(t, "acme", "alice")
+
+	defaultPost := s.createPost(t, defaultToken, gin.H{"title": "default post", "content": "only in default"})
+	w := s.do(http.MethodPost, "/api/v1/posts", gin.H{"title": "acme post", "content": "only in acme"}, acmeToken, acme)
+	var acmePost struct {
+		ID uint `json:"id"`
+	}
+	decodeData(t, w, &acmePost)
+
+	var list struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+		Total int64 `json:"total"`
+	}
+	decodeData(t, s.do(http.MethodGet, "/api/v1/posts", nil, "", acme), &list)
+	if list.Total != 1 || len(list.Items) != 1 || list.Items[0].Title != "acme post" {
+		t.Fatalf("acme post list = %+v", list)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    interface{}
+		token   string
+		headers map[string]string
+		want    int
+	}{
+		{"read other tenant post", http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", defaultPost), nil, "", acme, http.StatusNotFound},
+		{"token used in other tenant", http.MethodGet, "/api/v1/users/me", nil, defaultToken, acme, http.StatusUnauthorized},
+		{"update other tenant post", http.MethodPut, fmt.Sprintf("/api/v1/posts/%d", defaultPost), gin.H{"title": "hijacked", "content": "hijacked", "version": 1}, acmeToken, acme, http.StatusNotFound},
+		{"delete other tenant post", http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", defaultPost), nil, acmeToken, acme, http.StatusNotFound},
+		{"comment on other tenant post", http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", defaultPost), gin.H{"content": "hi"}, acmeToken, acme, http.StatusNotFound},
+		{"unknown tenant", http.MethodGet, "/api/v1/posts", nil, "", map[string]string{"X-Tenant-ID": "globex"}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.do(tt.method, tt.path, tt.body, tt.token, tt.headers); w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	// 给 acme 的文章发表评论，默认租户看不到
+	if w := s.do(http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/comments", acmePost.ID), gin.H{"content": "acme comment"}, acmeToken, acme); w.Code != http.StatusOK {
+		t.Fatalf("create acme comment: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d/comments", acmePost.ID), nil, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("default tenant lists acme comments: %d %s", w.Code, w.Body)
+	}
+
+	// 越权请求之后默认租户的文章保持不变
+	var post struct {
+		Title string `json:"title"`
+	}
+	decodeData(t, s.do(http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", defaultPost), nil, "", nil), &post)
+	if post.Title != "default post" {
+		t.Fatalf("default post title = %q", post.Title)
+	}
+}
+
+// TestTenantIsolationAdmin webhook、审计日志和标签按租户隔离，事件只投递给所属租户的订阅
+func TestTenantIsolationAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatalf("use tenant plugin: %v", err)
+	}
+	if err := db.AutoMigrate(models.GetModels()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	webhookService := services.NewWebhookService(db, config.WebhookConfig{})
+	webhookHandler := NewWebhookHandler(webhookService)
+	auditHandler := NewAuditHandler(services.NewAuditService(db))
+	r := gin.New()
+	r.Use(middleware.Tenant(middleware.NewTenants(stubTenants{"default": 1, "acme": 2}, config.LoadSimple())))
+	r.POST("/webhooks", webhookHandler.Create)
+	r.GET("/webhooks", webhookHandler.List)
+	r.GET("/webhooks/:id", webhookHandler.Get)
+	r.DELETE("/webhooks/:id", webhookHandler.Delete)
+	r.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+	r.GET("/audit-logs", auditHandler.List)
+	r.GET("/audit-logs/export", auditHandler.Export)
+	// 每个租户的 1 号和 2 号用户分别作为文章作者
+	postHandler := NewPostHandler(services.NewPostService(db, services.NewUserService(db, nil), nil, nil))
+	r.POST("/posts", func(c *gin.Context) {
+		tenantID, _ := tenant.FromContext(c.Request.Context())
+		c.Set(consts.UserID, tenantID)
+		c.Next()
+	}, postHandler.Create)
+	r.GET("/stats/overview", NewStatsHandler(services.NewStatsService(db, nil)).Overview)
+	s := &testServer{router: r}
+	acme := map[string]string{"X-Tenant-ID": "acme"}
+
+	var created struct {
+		Webhook models.WebhookResponse `json:"webhook"`
+	}
+	decodeData(t, s.do(http.MethodPost, "/webhooks", gin.H{"url": "https://acme.example.com/hook", "events": []string{consts.EventPostCreated}}, "", acme), &created)
+	webhook := created.Webhook
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"get other tenant webhook", fmt.Sprintf("/webhooks/%d", webhook.ID), http.StatusNotFound},
+		{"other tenant deliveries", fmt.Sprintf("/webhooks/%d/deliveries", webhook.ID), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.do(http.MethodGet, tt.path, nil, "", nil); w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+	if w := s.do(http.MethodDelete, fmt.Sprintf("/webhooks/%d", webhook.ID), nil, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("delete other tenant webhook = %d", w.Code)
+	}
+
+	var webhooks []models.WebhookResponse
+	decodeData(t, s.do(http.MethodGet, "/webhooks", nil, "", nil), &webhooks)
+	if len(webhooks) != 0 {
+		t.Fatalf("default tenant sees acme webhooks: %+v", webhooks)
+	}
+	decodeData(t, s.do(http.MethodGet, "/webhooks", nil, "", acme), &webhooks)
+	if len(webhooks) != 1 || webhooks[0].ID != webhook.ID {
+		t.Fatalf("acme webhooks = %+v", webhooks)
+	}
+
+	// 创建 webhook 的审计日志属于 acme
+	var page struct {
+		Total int64 `json:"total"`
+	}
+	decodeData(t, s.do(http.MethodGet, "/audit-logs", nil, "", nil), &page)
+	if page.Total != 0 {
+		t.Fatalf("default tenant audit logs = %d, want 0", page.Total)
+	}
+	decodeData(t, s.do(http.MethodGet, "/audit-logs?action="+consts.AuditWebhookCreate, nil, "", acme), &page)
+	if page.Total != 1 {
+		t.Fatalf("acme audit logs = %d, want 1", page.Total)
+	}
+	if w := s.do(http.MethodGet, "/audit-logs/export", nil, "", nil); w.Code != http.StatusOK || w.Body.String() != "id,created_at,actor_id,actor_name,action,entity_type,entity_id,before,after,ip,request_id\n" {
+		t.Fatalf("default tenant export = %d %q", w.Code, w.Body)
+	}
+
+	// 标签名在租户内唯一，两个租户可以使用同名标签，统计只计算本租户的标签
+	for tenantID, slug := range []string{"default", "acme"} {
+		author := models.User{TenantID: uint(tenantID + 1), Username: "author", Email: "author@example.com", Password: "x"}
+		author.ID = uint(tenantID + 1)
+		if err := db.Create(&author).Error; err != nil {
+			t.Fatalf("create %s author: %v", slug, err)
+		}
+	}
+	if w := s.do(http.MethodPost, "/posts", gin.H{"title": "default", "content": "tagged", "tags": []string{"go", "rust"}}, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("create default post: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPost, "/posts", gin.H{"title": "acme", "content": "tagged", "tags": []string{"go"}}, "", acme); w.Code != http.StatusOK {
+		t.Fatalf("create acme post with existing tag name: %d %s", w.Code, w.Body)
+	}
+	var tags []models.Tag
+	db.Order("tenant_id").Find(&tags)
+	if len(tags) != 3 || tags[2].TenantID != 2 || tags[2].Name != "go" {
+		t.Fatalf("tags = %+v", tags)
+	}
+	var overview struct {
+		Tags int64 `json:"tags"`
+	}
+	decodeData(t, s.do(http.MethodGet, "/stats/overview", nil, "", nil), &overview)
+	if overview.Tags != 2 {
+		t.Fatalf("default tenant tag count = %d, want 2", overview.Tags)
+	}
+	decodeData(t, s.do(http.MethodGet, "/stats/overview", nil, "", acme), &overview)
+	if overview.Tags != 1 {
+		t.Fatalf("acme tag count = %d, want 1", overview.Tags)
+	}
+
+	// 默认租户的事件不会投递给 acme 的订阅
+	countDeliveries := func() int64 {
+		var count int64
+		db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID).Count(&count)
+		return count
+	}
+	webhookService.HandleEvent(events.Event{ID: "evt_default", Type: consts.EventPostCreated, TenantID: 1})
+	if count := countDeliveries(); count != 0 {
+		t.Fatalf("default tenant event created %d acme deliveries", count)
+	}
+	webhookService.HandleEvent(events.Event{ID: "evt_acme", Type: consts.EventPostCreated, TenantID: 2})
+	var delivery models.WebhookDelivery
+	if err := db.Where("webhook_id = ?", webhook.ID).First(&delivery).Error; err != nil || delivery.TenantID != 2 {
+		t.Fatalf("acme delivery = %+v, %v", delivery, err)
+	}
+}
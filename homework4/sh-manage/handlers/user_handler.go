@@ -1,49 +1,46 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sh-manage/consts"
 	"sh-manage/models"
 	"sh-manage/services"
 	"sh-manage/utils"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
 	userService *services.UserService
-	jwtSecret   []byte
+	jwtKeys     *utils.JWTKeys
 }
 
-func NewUserHandler(userService *services.UserService, jwtSecret []byte) *UserHandler {
+func NewUserHandler(userService *services.UserService, jwtKeys *utils.JWTKeys) *UserHandler {
 	return &UserHandler{
 		userService: userService,
-		jwtSecret:   jwtSecret,
+		jwtKeys:     jwtKeys,
 	}
 }
 
 func (h *UserHandler) Register(c *gin.Context) {
 	var req models.CreateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ValidationError(c, parseValidationErrors(err))
+	if !bindBody(c, &req) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(req)
+	user, err := h.userService.WithContext(c).CreateUser(req)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	utils.Success(c, user.ToResponse())
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
-	userIDInterface, exists := c.Get("userID")
+	userIDInterface, exists := c.Get(consts.UserID)
 	if !exists {
 		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -55,74 +52,71 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.WithContext(c).GetUserByID(userID)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	utils.Success(c, user.ToResponse())
 }
 
 func (h *UserHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ValidationError(c, parseValidationErrors(err))
+	if !bindBody(c, &req) {
 		return
 	}
 
-	user, err := h.userService.Authenticate(req.Username, req.Password)
+	user, err := h.userService.WithContext(c).Authenticate(req.Username, req.Password)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	token, err := utils.GenerateToken(h.jwtSecret, user.ID, user.Username)
+	token, err := h.jwtKeys.Sign(user.ID, user.TenantID, user.Username)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
 	utils.Success(c, gin.H{"token": token,
-		"user": models.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-		},
+		"user": user.ToResponse(),
 	})
 }
 
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get(consts.UserID)
 	if !exists {
 		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ValidationError(c, parseValidationErrors(err))
+	if !bindBody(c, &req) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(userID.(uint), req)
+	user, err := h.userService.WithContext(c).UpdateUser(userID.(uint), req)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	utils.Success(c, user.ToResponse())
+}
+
+// Delete 管理员删除用户，用户的文章、评论和附件一并删除
+func (h *UserHandler) Delete(c *gin.Context) {
+	userID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.WithContext(c).DeleteUser(userID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
 }
 
 func parseValidationErrors(err error) map[string]string {
@@ -132,3 +126,17 @@ func parseValidationErrors(err error) map[string]string {
 	errors["general"] = err.Error()
 	return errors
 }
+
+// bindBody 按 Content-Type 解析请求体，格式不支持时返回 415，解析或校验失败时返回 422
+func bindBody(c *gin.Context, obj interface{}) bool {
+	err := utils.BindBody(c, obj)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, utils.ErrUnsupportedMediaType) {
+		utils.Error(c, http.StatusUnsupportedMediaType, "Content-Type must be one of "+strings.Join(utils.SupportedFormats, ", "))
+		return false
+	}
+	utils.ValidationError(c, parseValidationErrors(err))
+	return false
+}
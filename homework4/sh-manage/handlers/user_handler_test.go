@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sh-manage/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserHandler(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.login(t, "alice")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		token      string
+		wantStatus int
+	}{
+		{"register", http.MethodPost, "/api/v1/users/register", gin.H{"username": "bob", "email": "bob@example.com", "password": "Correct-Horse-42"}, "", http.StatusOK},
+		{"register duplicate", http.MethodPost, "/api/v1/users/register", gin.H{"username": "bob", "email": "bob2@example.com", "password": "Correct-Horse-42"}, "", http.StatusConflict},
+		{"register invalid email", http.MethodPost, "/api/v1/users/register", gin.H{"username": "carol", "email": "nope", "password": "Correct-Horse-42"}, "", http.StatusUnprocessableEntity},
+		{"register malformed json", http.MethodPost, "/api/v1/users/register", "{", "", http.StatusUnprocessableEntity},
+		{"login", http.MethodPost, "/api/v1/users/login", gin.H{"username": "alice", "email": "alice@example.com", "password": "Correct-Horse-42"}, "", http.StatusOK},
+		{"login wrong password", http.MethodPost, "/api/v1/users/login", gin.H{"username": "alice", "email": "alice@example.com", "password": "wrong-pass"}, "", http.StatusUnauthorized},
+		{"login missing fields", http.MethodPost, "/api/v1/users/login", gin.H{"username": "alice"}, "", http.StatusUnprocessableEntity},
+		{"profile", http.MethodGet, "/api/v1/users/me", nil, token, http.StatusOK},
+		{"profile without token", http.MethodGet, "/api/v1/users/me", nil, "", http.StatusUnauthorized},
+		{"profile invalid token", http.MethodGet, "/api/v1/users/me", nil, "not-a-jwt", http.StatusUnauthorized},
+		{"update without version", http.MethodPut, "/api/v1/users/me", gin.H{"email": "a@example.com"}, token, http.StatusUnprocessableEntity},
+		{"update stale version", http.MethodPut, "/api/v1/users/me", gin.H{"email": "a@example.com", "version": 9}, token, http.StatusConflict},
+		{"update", http.MethodPut, "/api/v1/users/me", gin.H{"email": "a@example.com", "version": 1}, token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, tt.body, tt.token, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	var profile struct {
+		Email   string `json:"email"`
+		Version uint   `json:"version"`
+	}
+	decodeData(t, s.do(http.MethodGet, "/api/v1/users/me", nil, token, nil), &profile)
+	if profile.Email != "a@example.com" || profile.Version != 2 {
+		t.Fatalf("profile = %+v", profile)
+	}
+}
+
+func TestSuspendedAndBannedUsers(t *testing.T) {
+	s := newTestServer(t)
+	aliceID, aliceToken := s.login(t, "alice")
+	bobID, bobToken := s.login(t, "bob")
+	users := s.users.WithTenant(1)
+	login := func(username string) int {
+		body := gin.H{"username": username, "email": username + "@example.com", "password": "Correct-Horse-42"}
+		return s.do(http.MethodPost, "/api/v1/users/login", body, "", nil).Code
+	}
+
+	if _, err := users.Suspend(aliceID, time.Now().Add(time.Hour), "spam"); err != nil {
+		t.Fatalf("Suspend: %v", err)
+	}
+	// 停用前签发的令牌也不能使用
+	if w := s.do(http.MethodGet, "/api/v1/users/me", nil, aliceToken, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("suspended profile = %d %s", w.Code, w.Body)
+	}
+	if code := login("alice"); code != http.StatusForbidden {
+		t.Fatalf("suspended login = %d", code)
+	}
+
+	if _, err := users.Ban(bobID, "abuse"); err != nil {
+		t.Fatalf("Ban: %v", err)
+	}
+	if w := s.do(http.MethodPost, "/api/v1/posts", gin.H{"title": "t", "content": "c"}, bobToken, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("banned create post = %d %s", w.Code, w.Body)
+	}
+	if code := login("bob"); code != http.StatusForbidden {
+		t.Fatalf("banned login = %d", code)
+	}
+	// 密码错误时不提示账号状态
+	if w := s.do(http.MethodPost, "/api/v1/users/login", gin.H{"username": "bob", "email": "bob@example.com", "password": "wrong-pass"}, "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("banned wrong password = %d", w.Code)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.login(t, "alice")
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     interface{}
+		token    string
+		wantCode utils.ErrorCode
+	}{
+		{"register duplicate", http.MethodPost, "/api/v1/users/register", gin.H{"username": "alice", "email": "other@example.com", "password": "Correct-Horse-42"}, "", utils.ErrUserExists},
+		{"login wrong password", http.MethodPost, "/api/v1/users/login", gin.H{"username": "alice", "email": "alice@example.com", "password": "wrong-pass"}, "", utils.ErrInvalidCredentials},
+		{"missing token", http.MethodGet, "/api/v1/users/me", nil, "", utils.ErrAuthMissing},
+		{"unknown post", http.MethodGet, "/api/v1/posts/999", nil, token, utils.ErrPostNotFound},
+		{"invalid body", http.MethodPost, "/api/v1/posts", gin.H{"title": "t", "content": "c", "status": "archived"}, token, utils.ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, tt.body, tt.token, nil)
+			var problem utils.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+			if problem.Code != tt.wantCode || problem.Status != w.Code || !strings.HasPrefix(w.Header().Get("Content-Type"), utils.MIMEProblemJSON) {
+				t.Fatalf("status = %d, problem = %+v", w.Code, problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req dto.CreateWebhookDto
+	if !bindBody(c, &req) {
+		return
+	}
+
+	webhook, err := h.webhookService.WithContext(c).CreateWebhook(&req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	// 签名密钥只在创建时返回一次
+	utils.Success(c, gin.H{
+		"secret":  webhook.Secret,
+		"webhook": webhook.ToResponse(),
+	})
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	webhooks, err := h.webhookService.WithContext(c).ListWebhooks()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	items := make([]models.WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		items = append(items, webhooks[i].ToResponse())
+	}
+	utils.Success(c, items)
+}
+
+func (h *WebhookHandler) Get(c *gin.Context) {
+	webhookID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.WithContext(c).GetWebhook(webhookID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, webhook.ToResponse())
+}
+
+func (h *WebhookHandler) Update(c *gin.Context) {
+	webhookID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateWebhookDto
+	if !bindBody(c, &req) {
+		return
+	}
+
+	webhook, err := h.webhookService.WithContext(c).UpdateWebhook(webhookID, &req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, webhook.ToResponse())
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	webhookID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.WithContext(c).DeleteWebhook(webhookID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ListDeliveries 分页查询投递日志，可按状态和事件类型过滤
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	webhookID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	query := dto.WebhookDeliveryPageDTO{BasePageQuery: *dto.NewBasePageQuery()}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	page, err := h.webhookService.WithContext(c).GetDeliveryByPage(webhookID, &query)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, dto.MapPage(page, (*models.WebhookDelivery).ToResponse))
+}
+
+// Redeliver 手动重新投递
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	deliveryID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.WithContext(c).Redeliver(deliveryID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, delivery.ToResponse())
+}
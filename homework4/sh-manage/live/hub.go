@@ -0,0 +1,197 @@
+// Package live 把事件总线上的文章和评论事件实时推送给订阅了对应文章的客户端
+package live
+
+import (
+	"errors"
+	"sh-manage/consts"
+	"sh-manage/events"
+	"sh-manage/models"
+	"sync"
+)
+
+// DefaultBuffer 每个订阅者最多缓存的未发送事件数
+const DefaultBuffer = 64
+
+var (
+	// ErrOverflow 客户端消费太慢，缓冲区已满
+	ErrOverflow = errors.New("subscriber is too slow")
+	// ErrShutdown 服务正在关闭
+	ErrShutdown = errors.New("server is shutting down")
+)
+
+// Topic 订阅的文章，不同租户的同一文章ID互不影响
+type Topic struct {
+	TenantID uint
+	PostID   uint
+}
+
+// Hub 按文章分发事件，Publish 不会阻塞事件总线
+type Hub struct {
+	mu          sync.Mutex
+	topics      map[Topic]map[*Subscriber]struct{}
+	subscribers map[*Subscriber]struct{}
+	buffer      int
+	closed      bool
+}
+
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Hub{
+		topics:      make(map[Topic]map[*Subscriber]struct{}),
+		subscribers: make(map[*Subscriber]struct{}),
+		buffer:      buffer,
+	}
+}
+
+// Subscriber 一个客户端连接，可以同时订阅多篇文章
+type Subscriber struct {
+	userID uint
+	events chan events.Event
+	done   chan struct{}
+	// err 在关闭 done 之前写入，topics 由 Hub.mu 保护
+	err    error
+	topics map[Topic]struct{}
+}
+
+// NewSubscriber 创建订阅者，userID 用于判断文章改为草稿后能否继续接收事件；连接断开时需要调用 Close
+func (h *Hub) NewSubscriber(userID uint) *Subscriber {
+	s := &Subscriber{
+		userID: userID,
+		events: make(chan events.Event, h.buffer),
+		done:   make(chan struct{}),
+		topics: make(map[Topic]struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		h.drop(s, ErrShutdown)
+	}
+	h.subscribers[s] = struct{}{}
+	return s
+}
+
+// Events 待发送的事件
+func (s *Subscriber) Events() <-chan events.Event {
+	return s.events
+}
+
+// Done 订阅者被 Hub 丢弃时关闭，原因由 Err 返回，连接应当断开，客户端重连后重新订阅
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err 返回 ErrOverflow 或 ErrShutdown，只能在 Done 关闭后调用
+func (s *Subscriber) Err() error {
+	return s.err
+}
+
+// drop 丢弃订阅者，调用时需要持有 h.mu
+func (h *Hub) drop(s *Subscriber, err error) {
+	if s.err == nil {
+		s.err = err
+		close(s.done)
+	}
+}
+
+// Count 订阅者当前订阅的文章数
+func (h *Hub) Count(s *Subscriber) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(s.topics)
+}
+
+// Subscribe 订阅文章，重复订阅不会重复推送
+func (h *Hub) Subscribe(s *Subscriber, topic Topic) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subscribers, ok := h.topics[topic]
+	if !ok {
+		subscribers = make(map[*Subscriber]struct{})
+		h.topics[topic] = subscribers
+	}
+	subscribers[s] = struct{}{}
+	s.topics[topic] = struct{}{}
+}
+
+// Unsubscribe 取消订阅文章
+func (h *Hub) Unsubscribe(s *Subscriber, topic Topic) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribe(s, topic)
+}
+
+func (h *Hub) unsubscribe(s *Subscriber, topic Topic) {
+	delete(s.topics, topic)
+	if subscribers, ok := h.topics[topic]; ok {
+		delete(subscribers, s)
+		if len(subscribers) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// Close 连接断开时取消订阅者的全部订阅
+func (h *Hub) Close(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for topic := range s.topics {
+		h.unsubscribe(s, topic)
+	}
+	delete(h.subscribers, s)
+}
+
+// Shutdown 丢弃所有订阅者，之后创建的订阅者立即结束；注册到 http.Server.RegisterOnShutdown，避免长连接拖慢退出
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subscribers {
+		h.drop(s, ErrShutdown)
+	}
+}
+
+// Publish 作为事件总线的订阅者注册，只处理文章和评论事件
+func (h *Hub) Publish(event events.Event) {
+	var postID, authorID uint
+	visible := true
+	switch data := event.Data.(type) {
+	case models.CommentResponse:
+		postID = data.PostId
+	case models.PostResponse:
+		postID, authorID = data.ID, data.UserId
+		visible = data.Status == consts.PostStatusPublished || event.Type == consts.EventPostDeleted
+	default:
+		return
+	}
+	topic := Topic{TenantID: event.TenantID, PostID: postID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.topics[topic] {
+		switch {
+		case visible || s.userID == authorID:
+			h.send(s, event)
+		default:
+			// 文章改为草稿后只有作者可见，其他订阅者收到通知后不再接收该文章的事件
+			h.send(s, events.Event{ID: event.ID, Type: consts.EventPostUnavailable, OccurredAt: event.OccurredAt, Data: map[string]uint{"post_id": postID}})
+			h.unsubscribe(s, topic)
+		}
+		if event.Type == consts.EventPostDeleted {
+			h.unsubscribe(s, topic)
+		}
+	}
+}
+
+// send 缓冲区满时不等待，丢弃订阅者，由连接断开后调用 Close 释放
+func (h *Hub) send(s *Subscriber, event events.Event) {
+	if s.err != nil {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		h.drop(s, ErrOverflow)
+	}
+}
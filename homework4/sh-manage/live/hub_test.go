@@ -0,0 +1,123 @@
+package live
+
+import (
+	"sh-manage/consts"
+	"sh-manage/events"
+	"sh-manage/models"
+	"testing"
+)
+
+func commentEvent(tenantID, postID uint) events.Event {
+	event := events.New(consts.EventCommentCreated, models.CommentResponse{PostId: postID})
+	event.TenantID = tenantID
+	return event
+}
+
+func postEvent(eventType string, postID, authorID uint, status string) events.Event {
+	event := events.New(eventType, models.PostResponse{ID: postID, UserId: authorID, Status: status})
+	event.TenantID = 1
+	return event
+}
+
+// received 取出订阅者缓冲区中的全部事件类型
+func received(s *Subscriber) []string {
+	var types []string
+	for {
+		select {
+		case event := <-s.Events():
+			types = append(types, event.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestHubRoutesByTenantAndPost(t *testing.T) {
+	hub := NewHub(0)
+	sub := hub.NewSubscriber(1)
+	hub.Subscribe(sub, Topic{TenantID: 1, PostID: 10})
+	hub.Subscribe(sub, Topic{TenantID: 1, PostID: 11})
+	if hub.Count(sub) != 2 {
+		t.Fatalf("count = %d", hub.Count(sub))
+	}
+
+	hub.Publish(commentEvent(1, 10))
+	hub.Publish(commentEvent(1, 11))
+	hub.Publish(commentEvent(2, 10)) // 其他租户的同一文章ID
+	hub.Publish(commentEvent(1, 12))
+	hub.Publish(events.New("user.created", nil))
+	if got := received(sub); len(got) != 2 {
+		t.Fatalf("received %v, want 2 events", got)
+	}
+
+	hub.Unsubscribe(sub, Topic{TenantID: 1, PostID: 10})
+	hub.Publish(commentEvent(1, 10))
+	if got := received(sub); len(got) != 0 {
+		t.Fatalf("received %v after unsubscribe", got)
+	}
+
+	hub.Close(sub)
+	hub.Publish(commentEvent(1, 11))
+	if got := received(sub); len(got) != 0 || len(hub.topics) != 0 || len(hub.subscribers) != 0 {
+		t.Fatalf("received %v after close, topics = %v", got, hub.topics)
+	}
+}
+
+func TestHubPostVisibility(t *testing.T) {
+	hub := NewHub(0)
+	topic := Topic{TenantID: 1, PostID: 10}
+	author, reader := hub.NewSubscriber(1), hub.NewSubscriber(2)
+	hub.Subscribe(author, topic)
+	hub.Subscribe(reader, topic)
+
+	// 改为草稿后其他订阅者只收到不可见通知，之后不再收到该文章的事件
+	hub.Publish(postEvent(consts.EventPostUpdated, 10, 1, consts.PostStatusDraft))
+	hub.Publish(commentEvent(1, 10))
+	if got := received(author); len(got) != 2 || got[0] != consts.EventPostUpdated {
+		t.Fatalf("author received %v", got)
+	}
+	if got := received(reader); len(got) != 1 || got[0] != consts.EventPostUnavailable {
+		t.Fatalf("reader received %v", got)
+	}
+
+	// 删除后所有订阅结束
+	hub.Publish(postEvent(consts.EventPostDeleted, 10, 1, consts.PostStatusDraft))
+	hub.Publish(commentEvent(1, 10))
+	if got := received(author); len(got) != 1 || got[0] != consts.EventPostDeleted || hub.Count(author) != 0 {
+		t.Fatalf("author received %v after delete", got)
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewHub(2)
+	topic := Topic{TenantID: 1, PostID: 10}
+	slow, fast := hub.NewSubscriber(1), hub.NewSubscriber(2)
+	hub.Subscribe(slow, topic)
+	hub.Subscribe(fast, topic)
+
+	for range 3 {
+		hub.Publish(commentEvent(1, 10))
+		received(fast)
+	}
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatalf("slow subscriber not dropped")
+	}
+	if slow.Err() != ErrOverflow {
+		t.Fatalf("err = %v", slow.Err())
+	}
+	select {
+	case <-fast.Done():
+		t.Fatalf("fast subscriber dropped")
+	default:
+	}
+
+	hub.Shutdown()
+	<-fast.Done()
+	if fast.Err() != ErrShutdown || slow.Err() != ErrOverflow {
+		t.Fatalf("errors after shutdown: %v, %v", fast.Err(), slow.Err())
+	}
+	late := hub.NewSubscriber(3)
+	<-late.Done()
+}
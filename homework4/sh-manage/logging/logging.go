@@ -0,0 +1,76 @@
+// Package logging 按级别输出日志，级别可在运行时修改
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+}
+
+var current atomic.Int32
+
+func init() {
+	current.Store(int32(LevelInfo))
+}
+
+// ParseLevel 解析配置中的级别名称，为空时返回 info
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// SetLevel 修改全局日志级别，低于该级别的日志不再输出
+func SetLevel(level Level) {
+	current.Store(int32(level))
+}
+
+func GetLevel() Level {
+	return Level(current.Load())
+}
+
+func Enabled(level Level) bool {
+	return level >= GetLevel()
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Logf 按指定级别输出日志
+func Logf(level Level, format string, args ...interface{}) {
+	if !Enabled(level) {
+		return
+	}
+	log.Printf("["+level.String()+"] "+format, args...)
+}
+
+func Debugf(format string, args ...interface{}) { Logf(LevelDebug, format, args...) }
+func Infof(format string, args ...interface{})  { Logf(LevelInfo, format, args...) }
+func Warnf(format string, args ...interface{})  { Logf(LevelWarn, format, args...) }
+func Errorf(format string, args ...interface{}) { Logf(LevelError, format, args...) }
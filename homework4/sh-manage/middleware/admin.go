@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin 需要放在 Auth 之后，只允许管理员角色访问，角色不经过缓存读取，降级后立即失效
+func RequireAdmin(userService *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userService.WithContext(c).GetUserByIDUncached(utils.GetCurrentUserID(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		if !user.IsAdmin() {
+			utils.Fail(c, utils.NewError(utils.ErrRoleRequired, "Admin role required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireModerator 需要放在 Auth 之后，只允许审核员和管理员访问，与 RequireAdmin 一样不经过缓存
+func RequireModerator(userService *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := userService.WithContext(c).GetUserByIDUncached(utils.GetCurrentUserID(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		if !user.IsModerator() {
+			utils.Fail(c, utils.NewError(utils.ErrRoleRequired, "Moderator role required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,9 +1,10 @@
 package middleware
 
 import (
-	"net/http"
 	"sh-manage/consts"
+	"sh-manage/services"
 	"sh-manage/utils"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -11,39 +12,133 @@ import (
 
 // 这里实现JWT认证逻辑
 // 示例：检查Authorization头部，验证JWT令牌等
+// 支持两种方式: "Bearer {jwt}" 和 "ApiKey {key}"
 // 如果认证失败，返回401错误
 // 如果认证成功，调用c.Next()继续处理请求
-func Auth(jwtSecret []byte) gin.HandlerFunc {
+// 认证成功后检查账号是否被停用或封禁，userService 为 nil 时不检查
+func Auth(jwtKeys *utils.JWTKeys, apiKeyService *services.ApiKeyService, userService *services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			utils.Error(c, http.StatusUnauthorized, "Authorization header is missing")
+			utils.Fail(c, utils.NewError(utils.ErrAuthMissing, "Authorization header is missing"))
 			c.Abort()
 			//c.AbortWithStatusJSON(401, gin.H{"error": "Authorization header is missing"})
 			return
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != consts.AuthTypePre {
-			utils.Error(c, http.StatusUnauthorized, "Authorization header format must be Bearer {token}")
+		if len(parts) != 2 || (parts[0] != consts.AuthTypePre && parts[0] != consts.AuthTypeApiKey) {
+			utils.Fail(c, utils.NewError(utils.ErrAuthMissing, "Authorization header format must be Bearer {token} or ApiKey {key}"))
 			c.Abort()
 			//c.AbortWithStatusJSON(401, gin.H{"error": "Authorization header format must be Bearer {token}"})
 			return
 		}
 
+		if parts[0] == consts.AuthTypeApiKey {
+			// 只查找当前租户的 API Key，其他租户的 key 视为无效
+			apiKey, err := apiKeyService.WithContext(c).Authenticate(parts[1])
+			if err != nil {
+				utils.HandleError(c, err)
+				c.Abort()
+				return
+			}
+
+			c.Set(consts.UserID, apiKey.UserId)
+			c.Set(consts.UserName, apiKey.User.Username)
+			c.Set(consts.ApiKeyScopes, apiKey.ScopeList())
+			if !ensureActive(c, userService, apiKey.UserId) {
+				return
+			}
+
+			c.Next()
+			return
+		}
+
 		tokenString := parts[1]
 
-		claims, error := utils.ParseToken(tokenString, jwtSecret)
+		claims, error := jwtKeys.Parse(tokenString)
 		if error != nil {
-			utils.Error(c, http.StatusUnauthorized, "Invalid token: "+error.Error())
+			utils.Fail(c, utils.NewError(utils.ErrTokenInvalid, "Invalid token: "+error.Error()))
 			c.Abort()
 			//c.AbortWithStatusJSON(401, gin.H{"error": "Invalid token: " + error.Error()})
 			return
 		}
 
+		// 令牌只能在签发它的租户下使用
+		if claims.TenantId != utils.GetCurrentTenantID(c) {
+			utils.Fail(c, utils.NewError(utils.ErrTokenInvalid, "Token was issued for another tenant"))
+			c.Abort()
+			return
+		}
+
 		c.Set(consts.UserID, claims.UserId)
 		c.Set(consts.UserName, claims.Username)
+		if !ensureActive(c, userService, claims.UserId) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ensureActive 账号被停用或封禁时中止请求，已签发的令牌在停用期间不能使用
+func ensureActive(c *gin.Context, userService *services.UserService, userID uint) bool {
+	if userService == nil {
+		return true
+	}
+	if err := userService.WithContext(c).EnsureActive(userID); err != nil {
+		utils.HandleError(c, err)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// OptionalAuth 没有 Authorization 请求头时按匿名用户继续处理，携带时与 Auth 相同，认证失败返回 401
+func OptionalAuth(jwtKeys *utils.JWTKeys, apiKeyService *services.ApiKeyService, userService *services.UserService) gin.HandlerFunc {
+	auth := Auth(jwtKeys, apiKeyService, userService)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// QueryToken 浏览器的 EventSource 和 WebSocket 不能设置请求头，允许用 access_token 查询参数传递 JWT，需要在 Auth 之前注册
+// 令牌复制到请求头后从 URL 中删除，之后的日志和处理函数看不到令牌
+func QueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		if token := query.Get("access_token"); token != "" {
+			if c.GetHeader("Authorization") == "" {
+				c.Request.Header.Set("Authorization", consts.AuthTypePre+" "+token)
+			}
+			query.Del("access_token")
+			c.Request.URL.RawQuery = query.Encode()
+			c.Request.RequestURI = c.Request.URL.RequestURI()
+		}
+		c.Next()
+	}
+}
+
+// RequireScope 限制 API Key 访问的权限范围，JWT 登录的用户不受限制
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get(consts.ApiKeyScopes)
+		if !exists {
+			c.Next()
+			return
+		}
+
+		scopes, _ := value.([]string)
+		if !slices.Contains(scopes, scope) {
+			utils.Fail(c, utils.NewError(utils.ErrScopeMissing, "Api key does not have scope "+scope))
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sh-manage/cache"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newAuthRouter(t *testing.T) (*gin.Engine, *gorm.DB, *services.ApiKeyService, *utils.JWTKeys) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(models.GetModels()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	apiKeys := services.NewApiKeyService(db)
+	jwtKeys := utils.NewJWTKeys([]byte("test-secret"), time.Hour)
+	r := gin.New()
+	r.Use(Auth(jwtKeys, apiKeys, nil))
+	r.GET("/posts", RequireScope(consts.ScopePostsRead), func(c *gin.Context) { c.String(http.StatusOK, "%d", utils.GetCurrentUserID(c)) })
+	r.POST("/posts", RequireScope(consts.ScopePostsWrite), func(c *gin.Context) { c.String(http.StatusOK, "%d", utils.GetCurrentUserID(c)) })
+	return r, db, apiKeys, jwtKeys
+}
+
+func TestApiKeyAuth(t *testing.T) {
+	r, db, apiKeys, jwtKeys := newAuthRouter(t)
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	db.Create(alice)
+
+	readOnly, readKey, appErr := apiKeys.CreateApiKey(alice.ID, &dto.CreateApiKeyDto{Name: "reader", Scopes: []string{consts.ScopePostsRead}})
+	if appErr != nil {
+		t.Fatalf("CreateApiKey: %v", appErr)
+	}
+	_, expiredKey, _ := apiKeys.CreateApiKey(alice.ID, &dto.CreateApiKeyDto{Name: "old", Scopes: []string{consts.ScopePostsRead}})
+	db.Model(&models.ApiKey{}).Where("name = ?", "old").UpdateColumn("expires_at", time.Now().Add(-time.Minute))
+	revoked, revokedKey, _ := apiKeys.CreateApiKey(alice.ID, &dto.CreateApiKeyDto{Name: "revoked", Scopes: []string{consts.ScopePostsRead}})
+	if err := apiKeys.DeleteApiKey(alice.ID, revoked.ID); err != nil {
+		t.Fatalf("DeleteApiKey: %v", err)
+	}
+	token, _ := jwtKeys.Sign(alice.ID, 0, alice.Username)
+
+	tests := []struct {
+		name     string
+		method   string
+		auth     string
+		wantCode int
+		wantErr  utils.ErrorCode
+	}{
+		{"missing header", http.MethodGet, "", http.StatusUnauthorized, utils.ErrAuthMissing},
+		{"unknown scheme", http.MethodGet, "Basic abc", http.StatusUnauthorized, utils.ErrAuthMissing},
+		{"key with scope", http.MethodGet, "ApiKey " + readKey, http.StatusOK, ""},
+		{"key without scope", http.MethodPost, "ApiKey " + readKey, http.StatusForbidden, utils.ErrScopeMissing},
+		{"expired key", http.MethodGet, "ApiKey " + expiredKey, http.StatusUnauthorized, utils.ErrApiKeyInvalid},
+		{"revoked key", http.MethodGet, "ApiKey " + revokedKey, http.StatusUnauthorized, utils.ErrApiKeyInvalid},
+		{"tampered key", http.MethodGet, "ApiKey " + readOnly.Prefix + "_tampered", http.StatusUnauthorized, utils.ErrApiKeyInvalid},
+		// JWT 登录的用户不受 scope 限制
+		{"jwt", http.MethodPost, "Bearer " + token, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/posts", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantErr != "" && !containsCode(w.Body.String(), tt.wantErr) {
+				t.Fatalf("body = %s, want code %s", w.Body, tt.wantErr)
+			}
+			if tt.wantCode == http.StatusOK && w.Body.String() != "1" {
+				t.Fatalf("user id = %s, want 1", w.Body)
+			}
+		})
+	}
+}
+
+func containsCode(body string, code utils.ErrorCode) bool {
+	return strings.Contains(body, `"code":"`+string(code)+`"`)
+}
+
+func TestRequireAdminReadsRoleUncached(t *testing.T) {
+	_, db, apiKeys, jwtKeys := newAuthRouter(t)
+	root := &models.User{Username: "root", Email: "root@example.com", Password: "x", Role: consts.RoleAdmin}
+	db.Create(root)
+	userService := services.NewUserService(db, cache.NewLRU(100, time.Hour))
+
+	r := gin.New()
+	r.GET("/admin", Auth(jwtKeys, apiKeys, userService), RequireAdmin(userService), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/moderation", Auth(jwtKeys, apiKeys, userService), RequireModerator(userService), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	token, _ := jwtKeys.Sign(root.ID, 0, root.Username)
+	get := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("/admin"); code != http.StatusNoContent {
+		t.Fatalf("admin = %d, want 204", code)
+	}
+	// 缓存中仍是管理员，模拟其他实例直接修改数据库中的角色
+	if _, err := userService.GetUserByID(root.ID); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	db.Model(&models.User{}).Where("id = ?", root.ID).UpdateColumn("role", consts.RoleUser)
+	if code := get("/admin"); code != http.StatusForbidden {
+		t.Fatalf("demoted admin = %d, want 403", code)
+	}
+	if code := get("/moderation"); code != http.StatusForbidden {
+		t.Fatalf("demoted admin on moderation = %d, want 403", code)
+	}
+}
+
+func TestQueryTokenStripsAccessToken(t *testing.T) {
+	_, db, _, jwtKeys := newAuthRouter(t)
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	db.Create(user)
+	token, _ := jwtKeys.Sign(user.ID, 0, user.Username)
+
+	var seenQuery, seenURI string
+	r := gin.New()
+	r.GET("/stream", QueryToken(), Auth(jwtKeys, nil, nil), func(c *gin.Context) {
+		seenQuery, seenURI = c.Request.URL.RawQuery, c.Request.RequestURI
+		c.String(http.StatusOK, "%d", utils.GetCurrentUserID(c))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?access_token="+token+"&since=5", nil))
+	if w.Code != http.StatusOK || w.Body.String() != strconv.FormatUint(uint64(user.ID), 10) {
+		t.Fatalf("stream = %d %s", w.Code, w.Body)
+	}
+	if seenQuery != "since=5" || seenURI != "/stream?since=5" {
+		t.Fatalf("query = %q, uri = %q; access_token not stripped", seenQuery, seenURI)
+	}
+}
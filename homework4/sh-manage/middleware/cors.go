@@ -1,27 +1,61 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"slices"
+	"sync/atomic"
 
+	"github.com/gin-gonic/gin"
+)
+
+// CORSPolicy 跨域来源白名单，可在配置热更新时替换
+type CORSPolicy struct {
+	origins atomic.Pointer[[]string]
+}
+
+func NewCORSPolicy(origins []string) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.SetAllowedOrigins(origins)
+	return p
+}
+
+// SetAllowedOrigins 替换允许的来源，包含 * 时允许所有来源
+func (p *CORSPolicy) SetAllowedOrigins(origins []string) {
+	copied := slices.Clone(origins)
+	p.origins.Store(&copied)
+}
+
+func (p *CORSPolicy) allowed(origin string) bool {
+	origins := *p.origins.Load()
+	return slices.Contains(origins, "*") || slices.Contains(origins, origin)
+}
+
+// CORS 使用固定的白名单，允许所有来源
 func CORS() gin.HandlerFunc {
+	return CORSWithPolicy(NewCORSPolicy([]string{"*"}))
+}
+
+// CORSWithPolicy 每次请求读取 policy 的当前白名单，不在白名单中的来源不返回跨域头
+func CORSWithPolicy(policy *CORSPolicy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
-		if origin != "" {
-			//c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-			//c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-			//c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Authorization, Accept, X-Requested-With")
-			//c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-
-			c.Header("Access-Control-Allow-Origin", origin)
-			c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-			c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Authorization, Accept, X-Requested-With")
-			c.Header("Access-Control-Allow-Credentials", "true")
-		}
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
+		cors(c, policy)
+	}
+}
+
+func cors(c *gin.Context, policy *CORSPolicy) {
+	origin := c.Request.Header.Get("Origin")
+	if origin != "" && policy.allowed(origin) {
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Authorization, Accept, X-Requested-With, X-Request-ID, X-Tenant-ID, If-Match, If-None-Match, If-Modified-Since")
+		c.Header("Access-Control-Expose-Headers", "ETag, Last-Modified, X-Request-ID")
+		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Vary", "Origin")
+	}
+
+	if c.Request.Method == "OPTIONS" {
+		c.AbortWithStatus(204)
+		return
 	}
+
+	c.Next()
 }
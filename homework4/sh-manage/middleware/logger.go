@@ -1,12 +1,13 @@
 package middleware
 
 import (
-	"log"
+	"sh-manage/logging"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Logger 记录访问日志，5xx 记为 error，4xx 记为 warn，其余为 info，输出受全局日志级别控制
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 记录请求开始时间
@@ -25,8 +26,16 @@ func Logger() gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		clientIP := c.ClientIP()
 
+		level := logging.LevelInfo
+		switch {
+		case statusCode >= 500:
+			level = logging.LevelError
+		case statusCode >= 400:
+			level = logging.LevelWarn
+		}
+
 		// 记录日志
-		log.Printf("%s - [%s] \"%s %s\" %d %s\n",
+		logging.Logf(level, "%s - [%s] \"%s %s\" %d %s\n",
 			clientIP,
 			endTime.Format(time.RFC1123),
 			method,
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Negotiate 处理请求前检查 Accept 头，客户端不接受任何支持的格式时直接返回 406，避免修改数据后才发现无法输出
+// 只用于通过 utils 输出响应的路由，订阅源、导出和实时推送使用各自的格式
+func Negotiate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := utils.ResponseFormat(c); !ok {
+			utils.NotAcceptable(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
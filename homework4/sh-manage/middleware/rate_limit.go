@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sh-manage/config"
+	"sh-manage/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 超过该时长没有请求的客户端会被清理
+const rateLimitIdleTTL = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter 按客户端IP限流的令牌桶，配置可在运行时修改
+type RateLimiter struct {
+	mu        sync.Mutex
+	enabled   bool
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{buckets: make(map[string]*tokenBucket), now: time.Now}
+	l.Update(cfg)
+	return l
+}
+
+// Update 修改限流参数，已有客户端的令牌数不超过新的 burst
+func (l *RateLimiter) Update(cfg config.RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enabled = cfg.Enabled
+	l.rate = cfg.RequestsPerSecond
+	l.burst = float64(cfg.Burst)
+	for _, b := range l.buckets {
+		b.tokens = math.Min(b.tokens, l.burst)
+	}
+}
+
+// Allow 消耗一个令牌，返回是否放行以及被拒绝时建议的重试等待时间
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.enabled {
+		return true, 0
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+		b.lastSeen = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep 清理长时间没有请求的客户端，调用方需持有锁
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitIdleTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > rateLimitIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit 超出限流时返回 429 和 Retry-After
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rateLimit(c, limiter)
+	}
+}
+
+func rateLimit(c *gin.Context, limiter *RateLimiter) {
+	ok, retryAfter := limiter.Allow(c.ClientIP())
+	if !ok {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(max(seconds, 1)))
+		utils.Error(c, http.StatusTooManyRequests, "Too many requests")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
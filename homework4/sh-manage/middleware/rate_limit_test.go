@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sh-manage/config"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(config.RateLimitConfig{Enabled: true, RequestsPerSecond: 2, Burst: 3})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("request %d within burst rejected", i+1)
+		}
+	}
+	ok, retryAfter := limiter.Allow("a")
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("Allow() = %v, %v; want rejected with 500ms", ok, retryAfter)
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Fatalf("other client should have its own bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatalf("token should be refilled after 500ms")
+	}
+
+	// 热更新关闭限流后立即放行
+	limiter.Update(config.RateLimitConfig{Enabled: false})
+	for i := 0; i < 10; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("disabled limiter rejected a request")
+		}
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(trustedProxies []string) *gin.Engine {
+		r := gin.New()
+		if err := r.SetTrustedProxies(trustedProxies); err != nil {
+			t.Fatal(err)
+		}
+		r.Use(RateLimit(NewRateLimiter(config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.001, Burst: 1})))
+		r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+	get := func(r *gin.Engine, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 未配置可信代理时按连接地址计数，伪造 X-Forwarded-For 不能换一个新的令牌桶
+	r := newRouter(nil)
+	if code := get(r, "203.0.113.7:1234", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("first request = %d", code)
+	}
+	if code := get(r, "203.0.113.7:1234", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For = %d, want 429", code)
+	}
+
+	// 来自可信代理的请求按代理传来的客户端 IP 计数
+	r = newRouter([]string{"10.0.0.0/8"})
+	if code := get(r, "10.0.0.1:1234", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("first client via proxy = %d", code)
+	}
+	if code := get(r, "10.0.0.1:1234", "198.51.100.2"); code != http.StatusOK {
+		t.Fatalf("second client via proxy = %d", code)
+	}
+	if code := get(r, "10.0.0.1:1234", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("first client again = %d, want 429", code)
+	}
+}
+
+func TestCORSPolicyReload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := NewCORSPolicy([]string{"https://a.example.com"})
+	r := gin.New()
+	r.Use(CORSWithPolicy(policy))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	allowOrigin := func(origin string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		r.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if got := allowOrigin("https://a.example.com"); got != "https://a.example.com" {
+		t.Fatalf("allowed origin header = %q", got)
+	}
+	if got := allowOrigin("https://b.example.com"); got != "" {
+		t.Fatalf("unlisted origin got header %q", got)
+	}
+
+	policy.SetAllowedOrigins([]string{"https://b.example.com"})
+	if got := allowOrigin("https://a.example.com"); got != "" {
+		t.Fatalf("removed origin still allowed: %q", got)
+	}
+	if got := allowOrigin("https://b.example.com"); got != "https://b.example.com" {
+		t.Fatalf("new origin not allowed: %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sh-manage/consts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID 透传或生成请求ID，写入上下文和响应头
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(consts.RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			buf := make([]byte, 16)
+			_, _ = rand.Read(buf)
+			requestID = hex.EncodeToString(buf)
+		}
+
+		c.Set(consts.RequestID, requestID)
+		c.Header(consts.RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
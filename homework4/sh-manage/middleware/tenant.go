@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/tenant"
+	"sh-manage/utils"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantResolver 按标识查找租户，由 services.TenantService 实现
+type TenantResolver interface {
+	Resolve(slug string) (*models.Tenant, *utils.AppError)
+}
+
+// Tenants 租户解析规则以及每个租户的限流器和跨域白名单，配置热更新时调用 Update
+type Tenants struct {
+	resolver TenantResolver
+
+	mu       sync.Mutex
+	cfg      *config.Config
+	limiters map[string]*RateLimiter
+	policies map[string]*CORSPolicy
+}
+
+func NewTenants(resolver TenantResolver, cfg *config.Config) *Tenants {
+	return &Tenants{
+		resolver: resolver,
+		cfg:      cfg,
+		limiters: make(map[string]*RateLimiter),
+		policies: make(map[string]*CORSPolicy),
+	}
+}
+
+// Update 替换配置，已经创建的限流器和跨域白名单按合并后的租户配置更新
+func (t *Tenants) Update(cfg *config.Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cfg = cfg
+	for slug, limiter := range t.limiters {
+		limiter.Update(cfg.ForTenant(slug).RateLimit)
+	}
+	for slug, policy := range t.policies {
+		policy.SetAllowedOrigins(cfg.ForTenant(slug).CORS.AllowedOrigins)
+	}
+}
+
+func (t *Tenants) rateLimiter(slug string) *RateLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limiter, ok := t.limiters[slug]
+	if !ok {
+		limiter = NewRateLimiter(t.cfg.ForTenant(slug).RateLimit)
+		t.limiters[slug] = limiter
+	}
+	return limiter
+}
+
+func (t *Tenants) corsPolicy(slug string) *CORSPolicy {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	policy, ok := t.policies[slug]
+	if !ok {
+		policy = NewCORSPolicy(t.cfg.ForTenant(slug).CORS.AllowedOrigins)
+		t.policies[slug] = policy
+	}
+	return policy
+}
+
+// slugFromRequest 请求头优先，其次是根域名下的一级子域名，都没有时使用默认租户
+func (t *Tenants) slugFromRequest(r *http.Request) string {
+	t.mu.Lock()
+	cfg := t.cfg.Tenancy
+	t.mu.Unlock()
+
+	header := cfg.Header
+	if header == "" {
+		header = consts.TenantHeader
+	}
+	if slug := strings.ToLower(strings.TrimSpace(r.Header.Get(header))); slug != "" {
+		return slug
+	}
+
+	if cfg.BaseDomain != "" {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		sub, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(cfg.BaseDomain))
+		if ok && sub != "" && !strings.Contains(sub, ".") {
+			return sub
+		}
+	}
+
+	if cfg.Default != "" {
+		return cfg.Default
+	}
+	return consts.DefaultTenant
+}
+
+// Resolve 按请求头和域名查找请求所属的租户，gRPC 服务使用元数据构造的请求调用
+func (t *Tenants) Resolve(r *http.Request) (*models.Tenant, *utils.AppError) {
+	return t.resolver.Resolve(t.slugFromRequest(r))
+}
+
+// Tenant 解析请求所属的租户，写入 gin.Context 和请求的 context，之后的查询自动限定在该租户；租户不存在时返回 404
+func Tenant(tenants *Tenants) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current, err := tenants.Resolve(c.Request)
+		if err != nil {
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Set(consts.TenantID, current.ID)
+		c.Set(consts.TenantSlug, current.Slug)
+		c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), current.ID))
+		c.Next()
+	}
+}
+
+// TenantRateLimit 使用租户的限流配置，同一个客户端IP在不同租户分别计数，需要在 Tenant 之后注册
+func TenantRateLimit(tenants *Tenants) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rateLimit(c, tenants.rateLimiter(utils.GetCurrentTenantSlug(c)))
+	}
+}
+
+// TenantCORS 使用租户的跨域白名单，需要在 Tenant 之后注册
+func TenantCORS(tenants *Tenants) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cors(c, tenants.corsPolicy(utils.GetCurrentTenantSlug(c)))
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sh-manage/config"
+	"sh-manage/models"
+	"sh-manage/tenant"
+	"sh-manage/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type stubTenants map[string]uint
+
+func (s stubTenants) Resolve(slug string) (*models.Tenant, *utils.AppError) {
+	id, ok := s[slug]
+	if !ok {
+		return nil, utils.NewError(utils.ErrTenantNotFound, "Tenant not found")
+	}
+	return &models.Tenant{Model: gorm.Model{ID: id}, Slug: slug}, nil
+}
+
+func newTenantRouter(cfg *config.Config, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	tenants := NewTenants(stubTenants{"default": 1, "acme": 2}, cfg)
+	r := gin.New()
+	r.Use(Tenant(tenants), TenantRateLimit(tenants))
+	r.GET("/", append(handlers, func(c *gin.Context) {
+		id, _ := tenant.FromContext(c.Request.Context())
+		c.String(http.StatusOK, "%s:%d", utils.GetCurrentTenantSlug(c), id)
+	})...)
+	return r
+}
+
+func TestTenantResolution(t *testing.T) {
+	cfg := config.LoadSimple()
+	cfg.Tenancy = config.TenancyConfig{BaseDomain: "example.com"}
+	r := newTenantRouter(cfg)
+
+	tests := []struct {
+		name     string
+		host     string
+		header   string
+		wantCode int
+		wantBody string
+	}{
+		{"default", "localhost:8080", "", http.StatusOK, "default:1"},
+		{"header", "localhost:8080", "ACME", http.StatusOK, "acme:2"},
+		{"subdomain", "acme.example.com:8080", "", http.StatusOK, "acme:2"},
+		{"header before subdomain", "acme.example.com", "default", http.StatusOK, "default:1"},
+		{"nested subdomain ignored", "www.acme.example.com", "", http.StatusOK, "default:1"},
+		{"unknown header", "localhost", "globex", http.StatusNotFound, ""},
+		{"unknown subdomain", "globex.example.com", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Fatalf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTenantRateLimitOverride(t *testing.T) {
+	cfg := config.LoadSimple()
+	cfg.RateLimit = config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.001, Burst: 5}
+	cfg.Tenancy.Overrides = map[string]config.TenantOverride{
+		"acme": {RateLimit: &config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.001, Burst: 1}},
+	}
+	r := newTenantRouter(cfg)
+
+	get := func(slug string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant-ID", slug)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("acme"); code != http.StatusOK {
+		t.Fatalf("first acme request = %d", code)
+	}
+	if code := get("acme"); code != http.StatusTooManyRequests {
+		t.Fatalf("second acme request = %d, want 429", code)
+	}
+	// 同一个客户端在默认租户使用全局配置，单独计数
+	for i := 0; i < 5; i++ {
+		if code := get("default"); code != http.StatusOK {
+			t.Fatalf("default request %d = %d", i+1, code)
+		}
+	}
+}
+
+func TestAuthRejectsTokenFromOtherTenant(t *testing.T) {
+	keys := utils.NewJWTKeys([]byte("tenant-test-secret-0123"), time.Hour)
+	r := newTenantRouter(config.LoadSimple(), Auth(keys, nil, nil))
+
+	token, err := keys.Sign(7, 2, "alice")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	get := func(slug string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant-ID", slug)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("acme"); w.Code != http.StatusOK {
+		t.Fatalf("same tenant = %d %s", w.Code, w.Body)
+	}
+	if w := get("default"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("other tenant = %d, want 401", w.Code)
+	}
+}
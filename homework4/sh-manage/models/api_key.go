@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ApiKey 用户创建的服务间调用凭证，数据库中只保存哈希
+type ApiKey struct {
+	gorm.Model
+	TenantID   uint   `gorm:"not null;default:0;index"`
+	Name       string `gorm:"not null;size:100"`
+	Prefix     string `gorm:"uniqueIndex;not null;size:16"`
+	KeyHash    string `gorm:"not null;size:64"`
+	Scopes     string `gorm:"size:255"` // 逗号分隔
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	UserId     uint `gorm:"index"`
+	User       User
+}
+
+func (k *ApiKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+func (k *ApiKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+type ApiKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (k *ApiKey) ToResponse() ApiKeyResponse {
+	return ApiKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.ScopeList(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
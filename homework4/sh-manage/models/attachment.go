@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Attachment 文章的附件，文件本身保存在存储后端，这里只记录 key
+type Attachment struct {
+	gorm.Model
+	TenantID    uint   `gorm:"not null;default:0;index"`
+	PostId      uint   `gorm:"index;not null"`
+	Post        Post   `json:"-"`
+	UserId      uint   `gorm:"index"`
+	FileName    string `gorm:"not null;size:255"`
+	StorageKey  string `gorm:"not null;size:255"`
+	ContentType string `gorm:"size:100"`
+	Size        int64
+	// 图片的原始尺寸，非图片为 0
+	Width      int
+	Height     int
+	Thumbnails []AttachmentThumbnail `gorm:"serializer:json;type:text"`
+}
+
+type AttachmentThumbnail struct {
+	Name       string `json:"name"`
+	StorageKey string `json:"storage_key"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+}
+
+// StorageKeys 附件及其所有缩略图的 key
+func (a *Attachment) StorageKeys() []string {
+	keys := []string{a.StorageKey}
+	for _, thumbnail := range a.Thumbnails {
+		keys = append(keys, thumbnail.StorageKey)
+	}
+	return keys
+}
+
+type AttachmentResponse struct {
+	ID          uint                         `json:"id"`
+	PostId      uint                         `json:"post_id"`
+	FileName    string                       `json:"file_name"`
+	ContentType string                       `json:"content_type"`
+	Size        int64                        `json:"size"`
+	URL         string                       `json:"url"`
+	Width       int                          `json:"width,omitempty"`
+	Height      int                          `json:"height,omitempty"`
+	Thumbnails  map[string]ThumbnailResponse `json:"thumbnails,omitempty"`
+	CreatedAt   time.Time                    `json:"created_at"`
+}
+
+type ThumbnailResponse struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ToResponse urlFor 把存储 key 转换为访问地址
+func (a *Attachment) ToResponse(urlFor func(key string) string) AttachmentResponse {
+	response := AttachmentResponse{
+		ID:          a.ID,
+		PostId:      a.PostId,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		URL:         urlFor(a.StorageKey),
+		Width:       a.Width,
+		Height:      a.Height,
+		CreatedAt:   a.CreatedAt,
+	}
+	if len(a.Thumbnails) > 0 {
+		response.Thumbnails = make(map[string]ThumbnailResponse, len(a.Thumbnails))
+		for _, thumbnail := range a.Thumbnails {
+			response.Thumbnails[thumbnail.Name] = ThumbnailResponse{
+				URL:    urlFor(thumbnail.StorageKey),
+				Width:  thumbnail.Width,
+				Height: thumbnail.Height,
+			}
+		}
+	}
+	return response
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAuditLogAppendOnly = errors.New("audit log is append-only")
+
+// AuditLog 审计日志，只允许追加，不允许修改和删除
+// 没有请求上下文时（如后台任务）TenantID 为 0，不属于任何租户
+type AuditLog struct {
+	ID         uint            `gorm:"primarykey" json:"id"`
+	TenantID   uint            `gorm:"not null;default:0;index" json:"-"`
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+	ActorId    uint            `gorm:"index" json:"actor_id"`
+	ActorName  string          `gorm:"size:50" json:"actor_name"`
+	Action     string          `gorm:"not null;size:50;index" json:"action"`
+	EntityType string          `gorm:"not null;size:50;index:idx_audit_entity" json:"entity_type"`
+	EntityId   uint            `gorm:"index:idx_audit_entity" json:"entity_id"`
+	Before     json.RawMessage `gorm:"type:text" json:"before,omitempty"`
+	After      json.RawMessage `gorm:"type:text" json:"after,omitempty"`
+	IP         string          `gorm:"size:64" json:"ip"`
+	RequestID  string          `gorm:"size:64;index" json:"request_id"`
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogAppendOnly
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogAppendOnly
+}
@@ -1,12 +1,54 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"sh-manage/consts"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Comment struct {
 	gorm.Model
-	Content string `gorm:"not null"`
-	UserId  uint
-	User    User
-	PostId  uint
-	Post    Post
+	TenantID uint   `gorm:"not null;default:0;index"`
+	Content  string `gorm:"not null"`
+	UserId   uint
+	User     User
+	PostId   uint
+	Post     Post
+	// Status 待审核和被拒绝的评论只有作者可见
+	Status string `gorm:"size:20;not null;default:published;index"`
+	// Version 乐观锁版本号，每次修改加一
+	Version uint `gorm:"not null;default:1"`
+}
+
+type CommentResponse struct {
+	ID        uint      `json:"id"`
+	Content   string    `json:"content"`
+	UserId    uint      `json:"user_id"`
+	PostId    uint      `json:"post_id"`
+	Status    string    `json:"status"`
+	Version   uint      `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m *Comment) ToResponse() CommentResponse {
+	return CommentResponse{
+		ID:        m.ID,
+		Content:   m.Content,
+		UserId:    m.UserId,
+		PostId:    m.PostId,
+		Status:    m.Status,
+		Version:   m.Version,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (m *Comment) IsPublished() bool {
+	return m.Status == consts.CommentStatusPublished
+}
+
+func (r CommentResponse) LastModified() time.Time {
+	return r.UpdatedAt
 }
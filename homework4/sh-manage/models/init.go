@@ -7,8 +7,18 @@ func Register(model interface{}) {
 }
 
 func GetModels() []interface{} {
+	Register(&Tenant{})
 	Register(&User{})
 	Register(&Comment{})
 	Register(&Post{})
+	Register(&UserIdentity{})
+	Register(&ApiKey{})
+	Register(&AuditLog{})
+	Register(&Tag{})
+	Register(&Attachment{})
+	Register(&Webhook{})
+	Register(&WebhookDelivery{})
+	Register(&ModerationItem{})
+	Register(&Report{})
 	return allModels
 }
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ModerationItem 审核队列中的一条待审核内容，审核员的决定用于训练垃圾内容分类器
+type ModerationItem struct {
+	gorm.Model
+	TenantID   uint   `gorm:"not null;default:0;index"`
+	EntityType string `gorm:"not null;size:20;index:idx_moderation_entity"` // post 或 comment
+	EntityID   uint   `gorm:"not null;index:idx_moderation_entity"`
+	AuthorID   uint   `gorm:"not null;index"`
+	Action     string `gorm:"not null;size:20"` // create 或 update，审核通过后据此发布评论事件
+	// Content 提交审核时的标题和正文快照
+	Content   string `gorm:"not null"`
+	Reasons   string `gorm:"size:255"` // 逗号分隔
+	SpamScore float64
+	Status    string `gorm:"not null;size:20;default:pending;index"`
+	// ModeratorID、DecidedAt 和 Note 在审核后填写
+	ModeratorID uint
+	DecidedAt   *time.Time
+	Note        string `gorm:"size:500"`
+}
+
+func (m *ModerationItem) ReasonList() []string {
+	if m.Reasons == "" {
+		return []string{}
+	}
+	return strings.Split(m.Reasons, ",")
+}
+
+type ModerationItemResponse struct {
+	ID          uint       `json:"id"`
+	EntityType  string     `json:"entity_type"`
+	EntityID    uint       `json:"entity_id"`
+	AuthorID    uint       `json:"author_id"`
+	Action      string     `json:"action"`
+	Content     string     `json:"content"`
+	Reasons     []string   `json:"reasons"`
+	SpamScore   float64    `json:"spam_score"`
+	Status      string     `json:"status"`
+	ModeratorID uint       `json:"moderator_id,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	Note        string     `json:"note,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (m *ModerationItem) ToResponse() ModerationItemResponse {
+	return ModerationItemResponse{
+		ID:          m.ID,
+		EntityType:  m.EntityType,
+		EntityID:    m.EntityID,
+		AuthorID:    m.AuthorID,
+		Action:      m.Action,
+		Content:     m.Content,
+		Reasons:     m.ReasonList(),
+		SpamScore:   m.SpamScore,
+		Status:      m.Status,
+		ModeratorID: m.ModeratorID,
+		DecidedAt:   m.DecidedAt,
+		Note:        m.Note,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
@@ -1,11 +1,67 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"sh-manage/consts"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Post struct {
 	gorm.Model
-	Title   string `gorm:"not null"`
-	Content string `gorm:"not null"`
-	UserId  uint
-	User    User
+	TenantID    uint   `gorm:"not null;default:0;index"`
+	Title       string `gorm:"not null"`
+	Content     string `gorm:"not null"`
+	UserId      uint
+	User        User
+	Tags        []Tag `gorm:"many2many:post_tags;"`
+	Comments    []Comment
+	Status      string     `gorm:"size:20;not null;default:published;index"`
+	PublishedAt *time.Time `gorm:"index"`
+	// Version 乐观锁版本号，每次修改加一
+	Version uint `gorm:"not null;default:1"`
+}
+
+type PostResponse struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	UserId      uint       `json:"user_id"`
+	Tags        []string   `json:"tags"`
+	Status      string     `json:"status"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Version     uint       `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (p *Post) TagNames() []string {
+	names := make([]string, 0, len(p.Tags))
+	for _, tag := range p.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func (p *Post) ToResponse() PostResponse {
+	return PostResponse{
+		ID:          p.ID,
+		Title:       p.Title,
+		Content:     p.Content,
+		UserId:      p.UserId,
+		Tags:        p.TagNames(),
+		Status:      p.Status,
+		PublishedAt: p.PublishedAt,
+		Version:     p.Version,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+func (p *Post) IsPublished() bool {
+	return p.Status == consts.PostStatusPublished
+}
+
+func (r PostResponse) LastModified() time.Time {
+	return r.UpdatedAt
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Report 用户对文章、评论或其他用户的举报，同一对象的未处理举报由审核员一起处理
+type Report struct {
+	gorm.Model
+	TenantID   uint   `gorm:"not null;default:0;index"`
+	ReporterID uint   `gorm:"not null;index"`
+	EntityType string `gorm:"not null;size:20;index:idx_report_entity"` // post、comment 或 user
+	EntityID   uint   `gorm:"not null;index:idx_report_entity"`
+	// TargetUserID 被举报内容的作者，举报用户时与 EntityID 相同
+	TargetUserID uint   `gorm:"not null;index"`
+	Reason       string `gorm:"not null;size:20"`
+	Details      string `gorm:"size:1000"`
+	Status       string `gorm:"not null;size:20;default:open;index"`
+	// Resolution、ResolverID、ResolvedAt 和 Note 在处理后填写
+	Resolution string `gorm:"size:20"`
+	ResolverID uint
+	ResolvedAt *time.Time
+	Note       string `gorm:"size:500"`
+}
+
+type ReportResponse struct {
+	ID           uint       `json:"id"`
+	ReporterID   uint       `json:"reporter_id"`
+	EntityType   string     `json:"entity_type"`
+	EntityID     uint       `json:"entity_id"`
+	TargetUserID uint       `json:"target_user_id"`
+	Reason       string     `json:"reason"`
+	Details      string     `json:"details,omitempty"`
+	Status       string     `json:"status"`
+	Resolution   string     `json:"resolution,omitempty"`
+	ResolverID   uint       `json:"resolver_id,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	Note         string     `json:"note,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (r *Report) ToResponse() ReportResponse {
+	return ReportResponse{
+		ID:           r.ID,
+		ReporterID:   r.ReporterID,
+		EntityType:   r.EntityType,
+		EntityID:     r.EntityID,
+		TargetUserID: r.TargetUserID,
+		Reason:       r.Reason,
+		Details:      r.Details,
+		Status:       r.Status,
+		Resolution:   r.Resolution,
+		ResolverID:   r.ResolverID,
+		ResolvedAt:   r.ResolvedAt,
+		Note:         r.Note,
+		CreatedAt:    r.CreatedAt,
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+type Tag struct {
+	gorm.Model
+	// 标签名在租户内唯一
+	TenantID uint   `gorm:"not null;default:0;uniqueIndex:idx_tags_tenant_name"`
+	Name     string `gorm:"not null;size:50;uniqueIndex:idx_tags_tenant_name"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tenant 同一部署中的一个社区（工作区），用户、文章、评论都属于某个租户
+type Tenant struct {
+	gorm.Model
+	// Slug 用于子域名和 X-Tenant-ID 请求头，例如 acme 对应 acme.example.com
+	Slug string `gorm:"uniqueIndex;not null;size:50" json:"slug"`
+	Name string `gorm:"not null;size:100" json:"name"`
+}
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,48}[a-z0-9])?$`)
+
+// ValidTenantSlug 租户标识需要能作为子域名使用
+func ValidTenantSlug(slug string) bool {
+	return tenantSlugPattern.MatchString(slug)
+}
+
+type TenantResponse struct {
+	ID        uint      `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (t *Tenant) ToResponse() TenantResponse {
+	return TenantResponse{ID: t.ID, Slug: t.Slug, Name: t.Name, CreatedAt: t.CreatedAt}
+}
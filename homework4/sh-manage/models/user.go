@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sh-manage/consts"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,20 +9,58 @@ import (
 
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique;not null;size:50" json:"username"`
-	Email    string `gorm:"unique;not null;size:100" json:"email"`
+	// 用户名和邮箱在租户内唯一
+	TenantID uint   `gorm:"not null;default:0;uniqueIndex:idx_users_tenant_username;uniqueIndex:idx_users_tenant_email" json:"-"`
+	Username string `gorm:"not null;size:50;uniqueIndex:idx_users_tenant_username" json:"username"`
+	Email    string `gorm:"not null;size:100;uniqueIndex:idx_users_tenant_email" json:"email"`
 	Password string `gorm:"not null" json:"-"`
+	Role     string `gorm:"not null;size:20;default:user" json:"role"`
+	// Version 乐观锁版本号，每次修改加一
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// 举报处理的结果：警告次数、限期停用的截止时间和永久封禁时间
+	Warnings       uint       `gorm:"not null;default:0" json:"warnings"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	BannedAt       *time.Time `json:"banned_at,omitempty"`
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == consts.RoleAdmin
+}
+
+// IsModerator 审核员和管理员都可以处理审核队列
+func (u *User) IsModerator() bool {
+	return u.Role == consts.RoleModerator || u.IsAdmin()
+}
+
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
+}
+
+// IsSuspended 停用到期后自动恢复
+func (u *User) IsSuspended(now time.Time) bool {
+	return u.SuspendedUntil != nil && now.Before(*u.SuspendedUntil)
+}
+
+func (u *User) ToResponse() UserResponse {
+	return UserResponse{
+		ID:        u.ID,
+		Username:  u.Username,
+		Email:     u.Email,
+		Version:   u.Version,
+		CreatedAt: u.CreatedAt,
+	}
 }
 
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email,max=100"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 type UpdateUserRequest struct {
 	Email    *string `json:"email" binding:"omitempty,email,max=100"`
-	Password *string `json:"password" binding:"omitempty,min=6"`
+	Password *string `json:"password" binding:"omitempty"`
+	Version  *uint   `json:"version" binding:"required"`
 }
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email,max=100"`
@@ -33,5 +72,6 @@ type UserResponse struct {
 	ID        uint      `json:"id"`
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
+	Version   uint      `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserIdentity 外部身份提供方(OIDC)账号与本地用户的绑定关系
+type UserIdentity struct {
+	gorm.Model
+	// 同一个外部账号可以分别绑定不同租户的用户
+	TenantID uint   `gorm:"not null;default:0;uniqueIndex:idx_identity_tenant_provider_subject" json:"-"`
+	Provider string `gorm:"not null;size:50;uniqueIndex:idx_identity_tenant_provider_subject" json:"provider"`
+	Subject  string `gorm:"not null;size:255;uniqueIndex:idx_identity_tenant_provider_subject" json:"subject"`
+	Email    string `gorm:"size:100" json:"email"`
+	UserId   uint   `gorm:"index" json:"user_id"`
+	User     User   `json:"-"`
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook 出站 webhook 订阅，Secret 用于对投递内容做 HMAC 签名
+type Webhook struct {
+	gorm.Model
+	TenantID    uint   `gorm:"not null;default:0;index"`
+	URL         string `gorm:"not null;size:500"`
+	Secret      string `gorm:"not null;size:100"`
+	Events      string `gorm:"not null;size:255"` // 逗号分隔
+	Description string `gorm:"size:255"`
+	Active      bool   `gorm:"not null"`
+	UserId      uint   `gorm:"index"` // 创建人
+}
+
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, event := range w.EventList() {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (w *Webhook) ToResponse() WebhookResponse {
+	return WebhookResponse{
+		ID:          w.ID,
+		URL:         w.URL,
+		Events:      w.EventList(),
+		Description: w.Description,
+		Active:      w.Active,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+// 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery 一次事件投递及其重试记录
+type WebhookDelivery struct {
+	gorm.Model
+	// TenantID 与所属 webhook 相同，后台投递没有租户上下文，创建时显式写入
+	TenantID      uint       `gorm:"not null;default:0;index"`
+	WebhookId     uint       `gorm:"index;not null"`
+	EventID       string     `gorm:"size:64;index"`
+	EventType     string     `gorm:"size:50"`
+	Payload       string     `gorm:"type:text"`
+	Status        string     `gorm:"size:20;not null;index"`
+	Attempts      int        `gorm:"not null;default:0"`
+	NextAttemptAt *time.Time `gorm:"index"`
+	// 最近一次尝试的结果
+	ResponseStatus int    `gorm:"default:0"`
+	ResponseBody   string `gorm:"type:text"`
+	Error          string `gorm:"size:500"`
+	DurationMs     int64
+	DeliveredAt    *time.Time
+	// RedeliveryOf 手动重新投递时指向原投递
+	RedeliveryOf *uint
+}
+
+type WebhookDeliveryResponse struct {
+	ID             uint       `json:"id"`
+	WebhookId      uint       `json:"webhook_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body"`
+	Error          string     `json:"error"`
+	DurationMs     int64      `json:"duration_ms"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	RedeliveryOf   *uint      `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (d *WebhookDelivery) ToResponse() WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             d.ID,
+		WebhookId:      d.WebhookId,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		Error:          d.Error,
+		DurationMs:     d.DurationMs,
+		DeliveredAt:    d.DeliveredAt,
+		RedeliveryOf:   d.RedeliveryOf,
+		CreatedAt:      d.CreatedAt,
+	}
+}
@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// minTrainingDocs 两类样本都达到该数量前分类器不给出评分
+const minTrainingDocs = 5
+
+// classifier 朴素贝叶斯分类器，使用拉普拉斯平滑，在对数空间计算避免下溢
+type classifier struct {
+	tokens [2]map[string]int // 0 为正常内容，1 为垃圾内容
+	totals [2]int            // 各类的词数
+	docs   [2]int            // 各类的样本数
+	vocab  map[string]struct{}
+}
+
+func newClassifier() *classifier {
+	return &classifier{
+		tokens: [2]map[string]int{make(map[string]int), make(map[string]int)},
+		vocab:  make(map[string]struct{}),
+	}
+}
+
+func classIndex(spam bool) int {
+	if spam {
+		return 1
+	}
+	return 0
+}
+
+func (c *classifier) train(tokens []string, spam bool) {
+	class := classIndex(spam)
+	c.docs[class]++
+	for _, token := range tokens {
+		c.tokens[class][token]++
+		c.totals[class]++
+		c.vocab[token] = struct{}{}
+	}
+}
+
+// score 返回内容是垃圾内容的概率，训练样本不足时返回 0
+func (c *classifier) score(tokens []string) float64 {
+	if c.docs[0] < minTrainingDocs || c.docs[1] < minTrainingDocs {
+		return 0
+	}
+	var logProb [2]float64
+	vocab := float64(len(c.vocab))
+	for class := range logProb {
+		logProb[class] = math.Log(float64(c.docs[class]) / float64(c.docs[0]+c.docs[1]))
+		for _, token := range tokens {
+			logProb[class] += math.Log((float64(c.tokens[class][token]) + 1) / (float64(c.totals[class]) + vocab))
+		}
+	}
+	// P(spam|tokens) = 1 / (1 + exp(log P(ham) - log P(spam)))
+	return 1 / (1 + math.Exp(logProb[0]-logProb[1]))
+}
+
+// tokenize 按字母和数字切分并转为小写，中日韩文字每个字作为一个词；链接统一为 __link__
+func tokenize(text string) []string {
+	text = linkPattern.ReplaceAllString(text, " __link__ ")
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
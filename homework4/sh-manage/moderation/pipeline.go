@@ -0,0 +1,252 @@
+// Package moderation 在文章和评论发布前检查内容：屏蔽词、需审核词、链接数量、重复内容和垃圾内容评分
+//
+// Pipeline 只给出判定结果，不访问数据库；待审核内容的入队和审核由 services.ModerationService 完成
+package moderation
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"sh-manage/config"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Verdict 审核结果
+type Verdict int
+
+const (
+	// Allow 直接发布
+	Allow Verdict = iota
+	// Review 进入审核队列，审核通过前只有作者可见
+	Review
+	// Reject 拒绝提交
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Review:
+		return "review"
+	case Reject:
+		return "reject"
+	default:
+		return "allow"
+	}
+}
+
+// 触发审核或拒绝的原因
+const (
+	ReasonBlockedWord  = "blocked_word"
+	ReasonReviewWord   = "review_word"
+	ReasonTooManyLinks = "too_many_links"
+	ReasonDuplicate    = "duplicate"
+	ReasonSpam         = "spam"
+)
+
+// 未配置时的默认值
+const (
+	DefaultMaxLinks        = 3
+	DefaultDuplicateWindow = 10 * time.Minute
+	DefaultSpamThreshold   = 0.9
+)
+
+// maxFingerprints 重复检测最多保留的内容指纹数，超过时清理过期的指纹
+const maxFingerprints = 10000
+
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s]+|\bwww\.[^\s]+`)
+
+// Content 待检查的内容
+type Content struct {
+	TenantID uint
+	AuthorID uint
+	// Key 内容所属的实体，例如 post:12；同一实体修改时不算重复，新建时为空
+	Key  string
+	Text string
+	// Preview 为 true 时只检查不记录指纹，用于导入的试运行
+	Preview bool
+}
+
+// Result 检查结果，Reasons 按检查顺序排列
+type Result struct {
+	Verdict   Verdict
+	Reasons   []string
+	SpamScore float64
+}
+
+func (r *Result) raise(verdict Verdict, reason string) {
+	if verdict > r.Verdict {
+		r.Verdict = verdict
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
+
+type rules struct {
+	enabled         bool
+	blocked         []*regexp.Regexp
+	review          []*regexp.Regexp
+	maxLinks        int
+	duplicateWindow time.Duration
+	spamThreshold   float64
+}
+
+type fingerprint struct {
+	key string
+	at  time.Time
+}
+
+// Pipeline 并发安全，配置可以在运行时通过 Update 替换
+type Pipeline struct {
+	mu           sync.RWMutex
+	rules        rules
+	fingerprints map[string]fingerprint
+	classifiers  map[uint]*classifier
+	now          func() time.Time
+}
+
+func New(cfg config.ModerationConfig) *Pipeline {
+	p := &Pipeline{
+		fingerprints: make(map[string]fingerprint),
+		classifiers:  make(map[uint]*classifier),
+		now:          time.Now,
+	}
+	p.Update(cfg)
+	return p
+}
+
+// Update 替换词表和阈值，已训练的分类器保留
+func (p *Pipeline) Update(cfg config.ModerationConfig) {
+	next := rules{
+		enabled:         cfg.Enabled,
+		blocked:         compileWords(cfg.BlockedWords),
+		review:          compileWords(cfg.ReviewWords),
+		maxLinks:        cfg.MaxLinks,
+		duplicateWindow: config.Duration(cfg.DuplicateWindow, DefaultDuplicateWindow),
+		spamThreshold:   cfg.SpamThreshold,
+	}
+	if next.maxLinks == 0 {
+		next.maxLinks = DefaultMaxLinks
+	}
+	if next.spamThreshold == 0 {
+		next.spamThreshold = DefaultSpamThreshold
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules = next
+}
+
+// compileWords 英文和数字组成的词按整词匹配，其他词（例如中文）按子串匹配，都忽略大小写
+func compileWords(words []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		expr := regexp.QuoteMeta(word)
+		if isASCIIWord(word) {
+			expr = `\b` + expr + `\b`
+		}
+		patterns = append(patterns, regexp.MustCompile("(?i)"+expr))
+	}
+	return patterns
+}
+
+func isASCIIWord(word string) bool {
+	for _, r := range word {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// Check 依次执行各项检查；未启用时总是返回 Allow
+// 没有被拒绝且不是预览的内容会记录指纹，同一作者在时间窗口内再次提交相同内容时进入审核
+func (p *Pipeline) Check(content Content) Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var result Result
+	if !p.rules.enabled {
+		return result
+	}
+
+	for _, pattern := range p.rules.blocked {
+		if pattern.MatchString(content.Text) {
+			result.raise(Reject, ReasonBlockedWord)
+			break
+		}
+	}
+	for _, pattern := range p.rules.review {
+		if pattern.MatchString(content.Text) {
+			result.raise(Review, ReasonReviewWord)
+			break
+		}
+	}
+	if p.rules.maxLinks > 0 && len(linkPattern.FindAllStringIndex(content.Text, -1)) > p.rules.maxLinks {
+		result.raise(Review, ReasonTooManyLinks)
+	}
+	if p.duplicate(content) {
+		result.raise(Review, ReasonDuplicate)
+	}
+	if c, ok := p.classifiers[content.TenantID]; ok {
+		result.SpamScore = c.score(tokenize(content.Text))
+		if result.SpamScore >= p.rules.spamThreshold {
+			result.raise(Review, ReasonSpam)
+		}
+	}
+
+	if result.Verdict != Reject && !content.Preview {
+		p.remember(content)
+	}
+	return result
+}
+
+func (p *Pipeline) fingerprintKey(content Content) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(content.Text)), " ")
+	return fmt.Sprintf("%d:%d:%x", content.TenantID, content.AuthorID, sha1.Sum([]byte(normalized)))
+}
+
+// duplicate 调用时需要持有 p.mu
+func (p *Pipeline) duplicate(content Content) bool {
+	if p.rules.duplicateWindow <= 0 || strings.TrimSpace(content.Text) == "" {
+		return false
+	}
+	previous, ok := p.fingerprints[p.fingerprintKey(content)]
+	if !ok || p.now().Sub(previous.at) > p.rules.duplicateWindow {
+		return false
+	}
+	return content.Key == "" || previous.key != content.Key
+}
+
+// remember 调用时需要持有 p.mu
+func (p *Pipeline) remember(content Content) {
+	if p.rules.duplicateWindow <= 0 || strings.TrimSpace(content.Text) == "" {
+		return
+	}
+	now := p.now()
+	if len(p.fingerprints) >= maxFingerprints {
+		for key, previous := range p.fingerprints {
+			if now.Sub(previous.at) > p.rules.duplicateWindow {
+				delete(p.fingerprints, key)
+			}
+		}
+	}
+	if len(p.fingerprints) < maxFingerprints {
+		p.fingerprints[p.fingerprintKey(content)] = fingerprint{key: content.Key, at: now}
+	}
+}
+
+// Train 用审核员的决定训练租户的垃圾内容分类器，spam 为 true 表示内容被拒绝
+func (p *Pipeline) Train(tenantID uint, text string, spam bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.classifiers[tenantID]
+	if !ok {
+		c = newClassifier()
+		p.classifiers[tenantID] = c
+	}
+	c.train(tokenize(text), spam)
+}
@@ -0,0 +1,118 @@
+package moderation
+
+import (
+	"reflect"
+	"sh-manage/config"
+	"testing"
+	"time"
+)
+
+func TestPipelineRules(t *testing.T) {
+	p := New(config.ModerationConfig{
+		Enabled:      true,
+		BlockedWords: []string{"casino", "赌博"},
+		ReviewWords:  []string{"crypto"},
+		MaxLinks:     2,
+	})
+	tests := []struct {
+		name    string
+		text    string
+		verdict Verdict
+		reasons []string
+	}{
+		{"clean", "a normal post about go", Allow, nil},
+		{"blocked word", "Visit our CASINO today", Reject, []string{ReasonBlockedWord}},
+		{"blocked word inside another word", "occasionally", Allow, nil},
+		{"blocked chinese word", "网上赌博平台", Reject, []string{ReasonBlockedWord}},
+		{"review word", "thoughts on crypto", Review, []string{ReasonReviewWord}},
+		{"links within limit", "see https://a.example and www.b.example", Allow, nil},
+		{"too many links", "http://a.example http://b.example http://c.example", Review, []string{ReasonTooManyLinks}},
+		{"blocked wins over review", "crypto casino", Reject, []string{ReasonBlockedWord, ReasonReviewWord}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 每个用例使用不同的作者，避免触发重复内容检查
+			result := p.Check(Content{TenantID: 1, AuthorID: uint(i + 1), Text: tt.text})
+			if result.Verdict != tt.verdict || !reflect.DeepEqual(result.Reasons, tt.reasons) {
+				t.Fatalf("Check(%q) = %v %v, want %v %v", tt.text, result.Verdict, result.Reasons, tt.verdict, tt.reasons)
+			}
+		})
+	}
+
+	p.Update(config.ModerationConfig{Enabled: false, BlockedWords: []string{"casino"}})
+	if result := p.Check(Content{TenantID: 1, AuthorID: 1, Text: "casino"}); result.Verdict != Allow {
+		t.Fatalf("disabled pipeline returned %v", result.Verdict)
+	}
+}
+
+func TestPipelineDuplicates(t *testing.T) {
+	p := New(config.ModerationConfig{Enabled: true, DuplicateWindow: "1m"})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	check := func(authorID uint, key, text string) Verdict {
+		return p.Check(Content{TenantID: 1, AuthorID: authorID, Key: key, Text: text}).Verdict
+	}
+	if v := check(1, "", "Buy now"); v != Allow {
+		t.Fatalf("first submission = %v", v)
+	}
+	// 大小写和空白不同也算重复
+	if v := check(1, "", "buy   NOW"); v != Review {
+		t.Fatalf("duplicate submission = %v", v)
+	}
+	if v := check(2, "", "buy now"); v != Allow {
+		t.Fatalf("same text by another author = %v", v)
+	}
+	// 修改同一篇文章不算重复
+	if v := check(3, "post:1", "hello"); v != Allow {
+		t.Fatalf("first edit = %v", v)
+	}
+	if v := check(3, "post:1", "hello"); v != Allow {
+		t.Fatalf("repeated edit of the same post = %v", v)
+	}
+	if v := check(3, "post:2", "hello"); v != Review {
+		t.Fatalf("same text on another post = %v", v)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if v := check(1, "", "buy now"); v != Allow {
+		t.Fatalf("submission after window = %v", v)
+	}
+}
+
+func TestPipelineSpamScore(t *testing.T) {
+	p := New(config.ModerationConfig{Enabled: true, DuplicateWindow: "1ns", SpamThreshold: 0.8})
+	spam := []string{"cheap pills buy now", "buy cheap watches now", "win money now click", "cheap loans click now", "free money buy now"}
+	ham := []string{"how to write go tests", "notes on database indexes", "reading list for this week", "refactoring the post service", "今天学习了 go 的接口"}
+
+	// 训练样本不足时不评分
+	p.Train(1, spam[0], true)
+	if result := p.Check(Content{TenantID: 1, AuthorID: 1, Text: "cheap pills"}); result.SpamScore != 0 {
+		t.Fatalf("score before training = %v", result.SpamScore)
+	}
+	for i := range spam {
+		p.Train(1, spam[i], true)
+		p.Train(1, ham[i], false)
+	}
+
+	result := p.Check(Content{TenantID: 1, AuthorID: 1, Text: "buy cheap pills now"})
+	if result.Verdict != Review || result.SpamScore < 0.8 || !reflect.DeepEqual(result.Reasons, []string{ReasonSpam}) {
+		t.Fatalf("spam = %+v", result)
+	}
+	result = p.Check(Content{TenantID: 1, AuthorID: 1, Text: "notes on writing go tests"})
+	if result.Verdict != Allow || result.SpamScore > 0.5 {
+		t.Fatalf("ham = %+v", result)
+	}
+	// 每个租户的分类器独立训练
+	if result := p.Check(Content{TenantID: 2, AuthorID: 1, Text: "buy cheap pills now"}); result.SpamScore != 0 {
+		t.Fatalf("other tenant score = %v", result.SpamScore)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Hello, 世界! see https://example.com/x?y=1 v2")
+	want := []string{"hello", "世", "界", "see", "__link__", "v2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tokenize = %v, want %v", got, want)
+	}
+}
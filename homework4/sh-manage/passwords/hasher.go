@@ -0,0 +1,165 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownFormat 哈希不是任何已知算法生成的
+var ErrUnknownFormat = errors.New("passwords: unknown hash format")
+
+// ErrMalformedHash 哈希的格式或参数无法解析
+var ErrMalformedHash = errors.New("passwords: malformed hash")
+
+// Hasher 一种密码哈希算法，哈希中保存算法和参数，修改参数后旧哈希仍可校验
+type Hasher interface {
+	// Name 算法名称，与配置 password.algorithm 一致
+	Name() string
+	// Recognizes 哈希是否由该算法生成
+	Recognizes(encoded string) bool
+	Hash(password string) (string, error)
+	// Verify 密码不匹配时返回 false 和 nil，哈希无法解析时返回错误
+	Verify(password, encoded string) (bool, error)
+	// Outdated 哈希的参数与当前参数不同，需要重新生成
+	Outdated(encoded string) bool
+}
+
+// Argon2id 默认参数，取自 OWASP 密码存储建议的最低配置
+const (
+	DefaultArgon2Memory      uint32 = 19 * 1024
+	DefaultArgon2Iterations  uint32 = 2
+	DefaultArgon2Parallelism uint8  = 1
+	argon2SaltLength                = 16
+	argon2KeyLength                 = 32
+)
+
+// Argon2id 生成 PHC 格式的哈希：$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+type argon2Hash struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+const argon2Prefix = "$argon2id$"
+
+func (a Argon2id) Name() string {
+	return "argon2id"
+}
+
+func (a Argon2id) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2Prefix)
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(password, encoded string) (bool, error) {
+	h, err := parseArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.iterations, h.memory, h.parallelism, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+func (a Argon2id) Outdated(encoded string) bool {
+	h, err := parseArgon2(encoded)
+	if err != nil {
+		return true
+	}
+	return h.version != argon2.Version || h.memory != a.Memory || h.iterations != a.Iterations || h.parallelism != a.Parallelism ||
+		len(h.salt) != argon2SaltLength || len(h.key) != argon2KeyLength
+}
+
+func parseArgon2(encoded string) (*argon2Hash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrMalformedHash
+	}
+	var h argon2Hash
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &h.version); err != nil {
+		return nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return nil, ErrMalformedHash
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrMalformedHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, ErrMalformedHash
+	}
+	if h.iterations == 0 || h.parallelism == 0 {
+		return nil, ErrMalformedHash
+	}
+	return &h, nil
+}
+
+// DefaultBcryptCost 与 bcrypt.DefaultCost 相同，升级前的用户密码都使用这个成本
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+// bcryptMaxBytes bcrypt 只使用密码的前 72 个字节，超过时拒绝而不是截断
+const bcryptMaxBytes = 72
+
+// Bcrypt 兼容升级前生成的哈希
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Name() string {
+	return "bcrypt"
+}
+
+func (b Bcrypt) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (b Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %v", ErrMalformedHash, err)
+	}
+}
+
+func (b Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
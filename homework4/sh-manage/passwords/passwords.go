@@ -0,0 +1,110 @@
+// Package passwords 生成和校验密码哈希，检查密码策略
+//
+// 新密码使用配置的算法生成哈希，已有哈希按其中保存的算法和参数校验；
+// 校验成功但算法或参数与当前配置不同时提示重新生成，由 services.UserService 在登录时完成
+package passwords
+
+import (
+	"sh-manage/config"
+	"sync"
+)
+
+// Manager 并发安全，配置可以在运行时通过 Update 替换
+type Manager struct {
+	mu      sync.RWMutex
+	current Hasher
+	hashers []Hasher
+	policy  Policy
+}
+
+// New 按配置创建，泄露密码列表文件无法读取时返回错误
+func New(cfg config.PasswordConfig) (*Manager, error) {
+	m := &Manager{}
+	if err := m.Update(cfg); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Default 使用默认配置，只使用内置的泄露密码列表
+func Default() *Manager {
+	m, _ := New(config.PasswordConfig{})
+	return m
+}
+
+// Update 替换算法参数和密码策略，失败时保持原配置
+func (m *Manager) Update(cfg config.PasswordConfig) error {
+	breached, err := loadBreached(cfg.BreachedList)
+	if err != nil {
+		return err
+	}
+
+	argon := Argon2id{Memory: cfg.Argon2.Memory, Iterations: cfg.Argon2.Iterations, Parallelism: cfg.Argon2.Parallelism}
+	if argon.Memory == 0 {
+		argon.Memory = DefaultArgon2Memory
+	}
+	if argon.Iterations == 0 {
+		argon.Iterations = DefaultArgon2Iterations
+	}
+	if argon.Parallelism == 0 {
+		argon.Parallelism = DefaultArgon2Parallelism
+	}
+	bcryptHasher := Bcrypt{Cost: cfg.BcryptCost}
+	if bcryptHasher.Cost == 0 {
+		bcryptHasher.Cost = DefaultBcryptCost
+	}
+
+	policy := Policy{MinLength: cfg.MinLength, MaxLength: cfg.MaxLength, MinClasses: cfg.MinClasses, breached: breached}
+	if policy.MinLength == 0 {
+		policy.MinLength = DefaultMinLength
+	}
+	if policy.MaxLength == 0 {
+		policy.MaxLength = DefaultMaxLength
+	}
+	var current Hasher = argon
+	if cfg.Algorithm == bcryptHasher.Name() {
+		current = bcryptHasher
+		policy.MaxBytes = bcryptMaxBytes
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current = current
+	m.hashers = []Hasher{argon, bcryptHasher}
+	m.policy = policy
+	return nil
+}
+
+// Hash 使用当前算法生成哈希，调用前应先通过 Check
+func (m *Manager) Hash(password string) (string, error) {
+	m.mu.RLock()
+	current := m.current
+	m.mu.RUnlock()
+	return current.Hash(password)
+}
+
+// Verify 校验密码，rehash 为 true 表示密码正确但哈希的算法或参数已过时
+func (m *Manager) Verify(password, encoded string) (ok bool, rehash bool, err error) {
+	m.mu.RLock()
+	current, hashers := m.current, m.hashers
+	m.mu.RUnlock()
+
+	for _, hasher := range hashers {
+		if !hasher.Recognizes(encoded) {
+			continue
+		}
+		if ok, err = hasher.Verify(password, encoded); !ok || err != nil {
+			return false, false, err
+		}
+		return true, hasher.Name() != current.Name() || hasher.Outdated(encoded), nil
+	}
+	return false, false, ErrUnknownFormat
+}
+
+// Check 按当前策略检查密码，返回违反的规则
+func (m *Manager) Check(password, username, email string) []string {
+	m.mu.RLock()
+	policy := m.policy
+	m.mu.RUnlock()
+	return policy.Check(password, username, email)
+}
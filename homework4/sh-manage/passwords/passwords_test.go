@@ -0,0 +1,121 @@
+package passwords
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sh-manage/config"
+	"strings"
+	"testing"
+)
+
+// 测试使用较小的参数，避免拖慢测试
+var fastArgon2 = config.Argon2Config{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHash(t *testing.T) {
+	m, err := New(config.PasswordConfig{Argon2: fastArgon2})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	encoded, err := m.Hash("Correct-Horse-42")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("encoded = %q", encoded)
+	}
+	if other, _ := m.Hash("Correct-Horse-42"); other == encoded {
+		t.Fatal("hashes of the same password share a salt")
+	}
+
+	if ok, rehash, err := m.Verify("Correct-Horse-42", encoded); !ok || rehash || err != nil {
+		t.Fatalf("Verify = %v %v %v, want true false nil", ok, rehash, err)
+	}
+	if ok, _, err := m.Verify("wrong-pass", encoded); ok || err != nil {
+		t.Fatalf("Verify wrong password = %v %v", ok, err)
+	}
+
+	// 修改参数后旧哈希仍可校验，但需要重新生成
+	if err := m.Update(config.PasswordConfig{Argon2: config.Argon2Config{Memory: 128, Iterations: 1, Parallelism: 1}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if ok, rehash, err := m.Verify("Correct-Horse-42", encoded); !ok || !rehash || err != nil {
+		t.Fatalf("Verify after update = %v %v %v, want true true nil", ok, rehash, err)
+	}
+	// 密码错误时不提示重新生成
+	if ok, rehash, _ := m.Verify("wrong-pass", encoded); ok || rehash {
+		t.Fatalf("Verify wrong password after update = %v %v", ok, rehash)
+	}
+}
+
+func TestVerifyLegacyBcrypt(t *testing.T) {
+	legacy, err := Bcrypt{Cost: 4}.Hash("Correct-Horse-42")
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	m, _ := New(config.PasswordConfig{Argon2: fastArgon2})
+	if ok, rehash, err := m.Verify("Correct-Horse-42", legacy); !ok || !rehash || err != nil {
+		t.Fatalf("Verify bcrypt = %v %v %v, want true true nil", ok, rehash, err)
+	}
+
+	// 继续使用 bcrypt 且成本相同时不需要重新生成
+	m, _ = New(config.PasswordConfig{Algorithm: "bcrypt", BcryptCost: 4})
+	if ok, rehash, err := m.Verify("Correct-Horse-42", legacy); !ok || rehash || err != nil {
+		t.Fatalf("Verify bcrypt with bcrypt = %v %v %v, want true false nil", ok, rehash, err)
+	}
+
+	for _, encoded := range []string{"plain", "$argon2id$v=19$m=64$bad", "$2a$broken"} {
+		if ok, _, err := m.Verify("Correct-Horse-42", encoded); ok || err == nil {
+			t.Fatalf("Verify(%q) = %v %v, want error", encoded, ok, err)
+		}
+	}
+	if _, _, err := m.Verify("x", "plain"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Verify plain = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(list, []byte("# leaked\nHunter2-Hunter2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(config.PasswordConfig{MinLength: 10, MaxLength: 20, MinClasses: 3, BreachedList: list})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		reasons  []string
+	}{
+		{"valid", "Correct-Horse-42", nil},
+		{"too short", "Ab-1", []string{ReasonTooShort}},
+		{"too long", strings.Repeat("Ab-1", 6), []string{ReasonTooLong}},
+		{"too few classes", "correcthorsebattery", []string{ReasonTooFewClasses}},
+		{"length counts characters", "密码-Abc-1234", nil},
+		{"breached from list", "hunter2-HUNTER2", []string{ReasonBreached}},
+		{"breached builtin", "password123", []string{ReasonTooFewClasses, ReasonBreached}},
+		{"matches username", "Alice-Smith-1", []string{ReasonMatchesIdentity}},
+		{"matches email", "ALICE@example.COM", []string{ReasonMatchesIdentity}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reasons := m.Check(tt.password, "alice-smith-1", "alice@example.com"); !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Fatalf("Check(%q) = %v, want %v", tt.password, reasons, tt.reasons)
+			}
+		})
+	}
+
+	// bcrypt 只使用前 72 个字节
+	m, _ = New(config.PasswordConfig{Algorithm: "bcrypt"})
+	if reasons := m.Check(strings.Repeat("密", 30), "", ""); !reflect.DeepEqual(reasons, []string{ReasonTooLong}) {
+		t.Fatalf("Check long bcrypt password = %v", reasons)
+	}
+
+	if _, err := New(config.PasswordConfig{BreachedList: filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Fatal("New with missing breached list succeeded")
+	}
+}
@@ -0,0 +1,115 @@
+package passwords
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 密码不符合策略的原因
+const (
+	ReasonTooShort        = "too_short"
+	ReasonTooLong         = "too_long"
+	ReasonTooFewClasses   = "too_few_character_classes"
+	ReasonBreached        = "breached"
+	ReasonMatchesIdentity = "matches_username_or_email"
+)
+
+// 未配置时的默认值
+const (
+	DefaultMinLength = 8
+	DefaultMaxLength = 128
+)
+
+//go:embed common.txt
+var commonPasswords string
+
+// Policy 密码策略，breached 中的密码已转为小写
+type Policy struct {
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+	// MaxBytes bcrypt 只使用前 72 个字节，为 0 时不限制
+	MaxBytes int
+	breached map[string]struct{}
+}
+
+// Check 返回密码违反的规则，符合策略时返回 nil；username、email 为空时不参与比较
+func (p *Policy) Check(password, username, email string) []string {
+	var reasons []string
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		reasons = append(reasons, ReasonTooShort)
+	}
+	if length > p.MaxLength || (p.MaxBytes > 0 && len(password) > p.MaxBytes) {
+		reasons = append(reasons, ReasonTooLong)
+	}
+	if p.MinClasses > 0 && characterClasses(password) < p.MinClasses {
+		reasons = append(reasons, ReasonTooFewClasses)
+	}
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		reasons = append(reasons, ReasonBreached)
+	}
+	if (username != "" && strings.EqualFold(password, username)) || (email != "" && strings.EqualFold(password, email)) {
+		reasons = append(reasons, ReasonMatchesIdentity)
+	}
+	return reasons
+}
+
+// characterClasses 统计包含的字符种类：小写字母、大写字母、数字、其他字符
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	count := 0
+	for _, has := range []bool{lower, upper, digit, other} {
+		if has {
+			count++
+		}
+	}
+	return count
+}
+
+// loadBreached 读取内置列表和 path 指定的列表，path 为空时只使用内置列表
+func loadBreached(path string) (map[string]struct{}, error) {
+	breached := make(map[string]struct{})
+	readList(strings.NewReader(commonPasswords), breached)
+	if path == "" {
+		return breached, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := readList(file, breached); err != nil {
+		return nil, err
+	}
+	return breached, nil
+}
+
+func readList(r io.Reader, into map[string]struct{}) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		into[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
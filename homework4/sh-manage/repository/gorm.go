@@ -0,0 +1,352 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/tools"
+
+	"gorm.io/gorm"
+)
+
+// updateWithVersion 仅当数据库中的版本号与 version 一致时更新，并将版本号加一
+// 返回 false 表示记录已被其他请求修改（或已删除）；version 为 0 时不检查版本号
+func updateWithVersion(db *gorm.DB, model interface{}, id uint, version uint, values map[string]interface{}) (bool, error) {
+	values["version"] = gorm.Expr("version + 1")
+	query := db.Model(model).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(values)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// first 查询单条记录，不存在时返回 ErrNotFound
+func first[T any](db *gorm.DB, dest *T) (*T, error) {
+	if err := db.First(dest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return dest, nil
+}
+
+// page 分页查询，参数错误时返回 *utils.AppError
+func page[T any](db *gorm.DB, query dto.BasePageQuery) (*dto.PageResult[T], error) {
+	var items []T
+	result, appErr := tools.Paginate(db, query, &items)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return result, nil
+}
+
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicated
+	}
+	return err
+}
+
+// NewGorm 返回基于同一个数据库连接的全部存储
+func NewGorm(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:       NewGormUserRepository(db),
+		Posts:       NewGormPostRepository(db),
+		Comments:    NewGormCommentRepository(db),
+		Attachments: NewGormAttachmentRepository(db),
+		Tx:          NewGormUnitOfWork(db),
+	}
+}
+
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	return first(conn(ctx, r.db).Where("id = ?", id), &models.User{})
+}
+
+func (r *GormUserRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := conn(ctx, r.db).Where("id IN ?", ids).Order("id asc").Find(&users).Error
+	return users, err
+}
+
+func (r *GormUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return first(conn(ctx, r.db).Where("username = ?", username), &models.User{})
+}
+
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return first(conn(ctx, r.db).Where("email = ?", email), &models.User{})
+}
+
+func (r *GormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return translate(conn(ctx, r.db).Create(user).Error)
+}
+
+func (r *GormUserRepository) Update(ctx context.Context, id uint, version uint, changes UserChanges) (bool, error) {
+	values := map[string]interface{}{}
+	if changes.Email != nil {
+		values["email"] = *changes.Email
+	}
+	if changes.Password != nil {
+		values["password"] = *changes.Password
+	}
+	if changes.Role != nil {
+		values["role"] = *changes.Role
+	}
+	if changes.Warnings != nil {
+		values["warnings"] = *changes.Warnings
+	}
+	if changes.SuspendedUntil != nil {
+		values["suspended_until"] = *changes.SuspendedUntil
+	}
+	if changes.BannedAt != nil {
+		values["banned_at"] = *changes.BannedAt
+	}
+	updated, err := updateWithVersion(conn(ctx, r.db), &models.User{}, id, version, values)
+	return updated, translate(err)
+}
+
+func (r *GormUserRepository) ReplacePassword(ctx context.Context, id uint, oldHash, newHash string) (bool, error) {
+	result := conn(ctx, r.db).Model(&models.User{}).Where("id = ? AND password = ?", id, oldHash).Update("password", newHash)
+	return result.RowsAffected > 0, translate(result.Error)
+}
+
+func (r *GormUserRepository) Delete(ctx context.Context, id uint) error {
+	return transaction(ctx, r.db, func(_ context.Context, tx *gorm.DB) error {
+		// 身份绑定有唯一索引，需要物理删除，否则同一外部账号无法再次登录
+		if err := tx.Unscoped().Where("user_id = ?", id).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.ApiKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
+}
+
+type GormPostRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPostRepository(db *gorm.DB) *GormPostRepository {
+	return &GormPostRepository{db: db}
+}
+
+func (r *GormPostRepository) FindByID(ctx context.Context, id uint) (*models.Post, error) {
+	return first(conn(ctx, r.db).Preload("Tags").Where("id = ?", id), &models.Post{})
+}
+
+func (r *GormPostRepository) Exists(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	if err := conn(ctx, r.db).Model(&models.Post{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *GormPostRepository) Page(ctx context.Context, filter PostFilter, query dto.BasePageQuery) (*dto.PageResult[models.Post], error) {
+	db := conn(ctx, r.db).Model(&models.Post{}).Preload("Tags")
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if filter.Title != "" {
+		db = db.Where("title LIKE ?", "%"+filter.Title+"%")
+	}
+	if filter.Content != "" {
+		db = db.Where("content LIKE ?", "%"+filter.Content+"%")
+	}
+	return page[models.Post](db, query)
+}
+
+func (r *GormPostRepository) Create(ctx context.Context, post *models.Post, tags []string) error {
+	return transaction(ctx, r.db, func(_ context.Context, tx *gorm.DB) error {
+		tagModels, err := findOrCreateTags(tx, tags)
+		if err != nil {
+			return err
+		}
+		post.Tags = tagModels
+		return tx.Create(post).Error
+	})
+}
+
+func (r *GormPostRepository) Update(ctx context.Context, id uint, version uint, changes PostChanges) (updated bool, err error) {
+	err = transaction(ctx, r.db, func(_ context.Context, tx *gorm.DB) error {
+		updated, err = r.update(tx, id, version, changes)
+		return err
+	})
+	return updated, err
+}
+
+func (r *GormPostRepository) update(db *gorm.DB, id uint, version uint, changes PostChanges) (bool, error) {
+	values := map[string]interface{}{}
+	if changes.Title != nil {
+		values["title"] = *changes.Title
+	}
+	if changes.Content != nil {
+		values["content"] = *changes.Content
+	}
+	if changes.Status != nil {
+		values["status"] = *changes.Status
+	}
+	if changes.PublishedAt != nil {
+		values["published_at"] = *changes.PublishedAt
+	}
+	updated, err := updateWithVersion(db, &models.Post{}, id, version, values)
+	if err != nil || !updated || changes.Tags == nil {
+		return updated, err
+	}
+
+	tags, err := findOrCreateTags(db, changes.Tags)
+	if err != nil {
+		return false, err
+	}
+	post := &models.Post{Model: gorm.Model{ID: id}}
+	if err := db.Model(post).Association("Tags").Replace(tags); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *GormPostRepository) Delete(ctx context.Context, id uint) error {
+	return conn(ctx, r.db).Delete(&models.Post{}, id).Error
+}
+
+func (r *GormPostRepository) DeleteByUser(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := transaction(ctx, r.db, func(_ context.Context, tx *gorm.DB) error {
+		if err := tx.Model(&models.Post{}).Where("user_id = ?", userID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		// 文章是软删除，标签关联需要单独删除，否则标签的文章数仍会计入
+		if err := tx.Exec("DELETE FROM post_tags WHERE post_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Post{}, ids).Error
+	})
+	return ids, err
+}
+
+// findOrCreateTags 按名称查找标签，不存在的自动创建，names 需要已经规范化
+func findOrCreateTags(db *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		var tag models.Tag
+		if err := db.Where(models.Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+type GormCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewGormCommentRepository(db *gorm.DB) *GormCommentRepository {
+	return &GormCommentRepository{db: db}
+}
+
+func (r *GormCommentRepository) FindByID(ctx context.Context, id uint) (*models.Comment, error) {
+	return first(conn(ctx, r.db).Where("id = ?", id), &models.Comment{})
+}
+
+func (r *GormCommentRepository) FindByPosts(ctx context.Context, postIDs []uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	if len(postIDs) == 0 {
+		return comments, nil
+	}
+	err := conn(ctx, r.db).Where("post_id IN ?", postIDs).Order("id asc").Find(&comments).Error
+	return comments, err
+}
+
+func (r *GormCommentRepository) Page(ctx context.Context, filter CommentFilter, query dto.BasePageQuery) (*dto.PageResult[models.Comment], error) {
+	db := conn(ctx, r.db).Model(&models.Comment{})
+	if filter.PostID != 0 {
+		db = db.Where("post_id = ?", filter.PostID)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if filter.Content != "" {
+		db = db.Where("content LIKE ?", "%"+filter.Content+"%")
+	}
+	return page[models.Comment](db, query)
+}
+
+func (r *GormCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	return conn(ctx, r.db).Create(comment).Error
+}
+
+func (r *GormCommentRepository) Update(ctx context.Context, id uint, version uint, changes CommentChanges) (bool, error) {
+	values := map[string]interface{}{}
+	if changes.Content != nil {
+		values["content"] = *changes.Content
+	}
+	if changes.Status != nil {
+		values["status"] = *changes.Status
+	}
+	return updateWithVersion(conn(ctx, r.db), &models.Comment{}, id, version, values)
+}
+
+func (r *GormCommentRepository) Delete(ctx context.Context, id uint) error {
+	return conn(ctx, r.db).Delete(&models.Comment{}, id).Error
+}
+
+func (r *GormCommentRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&models.Comment{}).Error
+}
+
+func (r *GormCommentRepository) DeleteByPosts(ctx context.Context, postIDs []uint) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	return conn(ctx, r.db).Where("post_id IN ?", postIDs).Delete(&models.Comment{}).Error
+}
+
+type GormAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAttachmentRepository(db *gorm.DB) *GormAttachmentRepository {
+	return &GormAttachmentRepository{db: db}
+}
+
+func (r *GormAttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	return conn(ctx, r.db).Create(attachment).Error
+}
+
+func (r *GormAttachmentRepository) DeleteByPosts(ctx context.Context, postIDs []uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+	err := transaction(ctx, r.db, func(_ context.Context, tx *gorm.DB) error {
+		if err := tx.Where("post_id IN ?", postIDs).Order("id asc").Find(&attachments).Error; err != nil {
+			return err
+		}
+		if len(attachments) == 0 {
+			return nil
+		}
+		return tx.Where("post_id IN ?", postIDs).Delete(&models.Attachment{}).Error
+	})
+	return attachments, err
+}
@@ -0,0 +1,559 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"math"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/tenant"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory 内存中的存储，用户、文章、评论共享同一份数据，用于测试
+// 行为与 GORM 实现保持一致：版本号从 1 开始，删除后查询不到，标签按名称去重，按 ctx 中的租户隔离
+type Memory struct {
+	mu          sync.Mutex
+	nextID      uint
+	users       map[uint]models.User
+	posts       map[uint]models.Post
+	comments    map[uint]models.Comment
+	attachments map[uint]models.Attachment
+	tags        map[memoryTagKey]models.Tag
+	now         func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		users:       make(map[uint]models.User),
+		posts:       make(map[uint]models.Post),
+		comments:    make(map[uint]models.Comment),
+		attachments: make(map[uint]models.Attachment),
+		tags:        make(map[memoryTagKey]models.Tag),
+		now:         time.Now,
+	}
+}
+
+// Repositories 返回共享这份数据的全部存储，Memory 自身作为事务实现
+func (m *Memory) Repositories() Repositories {
+	return Repositories{Users: m.Users(), Posts: m.Posts(), Comments: m.Comments(), Attachments: m.Attachments(), Tx: m}
+}
+
+func (m *Memory) Users() *MemoryUserRepository             { return &MemoryUserRepository{m} }
+func (m *Memory) Posts() *MemoryPostRepository             { return &MemoryPostRepository{m} }
+func (m *Memory) Comments() *MemoryCommentRepository       { return &MemoryCommentRepository{m} }
+func (m *Memory) Attachments() *MemoryAttachmentRepository { return &MemoryAttachmentRepository{m} }
+
+// memorySnapshot 事务开始时的数据副本，回滚时整体恢复
+type memorySnapshot struct {
+	nextID      uint
+	users       map[uint]models.User
+	posts       map[uint]models.Post
+	comments    map[uint]models.Comment
+	attachments map[uint]models.Attachment
+	tags        map[memoryTagKey]models.Tag
+}
+
+// Do 实现 UnitOfWork：执行前保存副本，fn 返回错误或 panic 时恢复
+// 嵌套调用各自保存副本，效果与保存点一致；不隔离并发的事务，只适合测试
+func (m *Memory) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	m.mu.Lock()
+	snapshot := memorySnapshot{
+		nextID:      m.nextID,
+		users:       maps.Clone(m.users),
+		posts:       maps.Clone(m.posts),
+		comments:    maps.Clone(m.comments),
+		attachments: maps.Clone(m.attachments),
+		tags:        maps.Clone(m.tags),
+	}
+	m.mu.Unlock()
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		m.mu.Lock()
+		m.nextID, m.users, m.posts, m.comments, m.attachments, m.tags = snapshot.nextID, snapshot.users, snapshot.posts, snapshot.comments, snapshot.attachments, snapshot.tags
+		m.mu.Unlock()
+	}()
+
+	if err = fn(ctx); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// stamp 分配新的 ID 并返回当前时间，调用方需持有锁
+func (m *Memory) stamp() (uint, time.Time) {
+	m.nextID++
+	return m.nextID, m.now()
+}
+
+// memoryTagKey 标签名在租户内唯一
+type memoryTagKey struct {
+	tenantID uint
+	name     string
+}
+
+// tagsByName 在文章所属租户内按名称查找或创建标签，调用方需持有锁
+func (m *Memory) tagsByName(tenantID uint, names []string) []models.Tag {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		key := memoryTagKey{tenantID: tenantID, name: name}
+		tag, ok := m.tags[key]
+		if !ok {
+			tag = models.Tag{TenantID: tenantID, Name: name}
+			tag.ID, tag.CreatedAt = m.stamp()
+			tag.UpdatedAt = tag.CreatedAt
+			m.tags[key] = tag
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+type MemoryUserRepository struct{ m *Memory }
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	user, ok := r.m.users[id]
+	if !ok || !tenant.Visible(ctx, user.TenantID) {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var users []models.User
+	for _, id := range ids {
+		if user, ok := r.m.users[id]; ok && tenant.Visible(ctx, user.TenantID) {
+			users = append(users, user)
+		}
+	}
+	slices.SortFunc(users, func(a, b models.User) int { return cmp.Compare(a.ID, b.ID) })
+	return slices.CompactFunc(users, func(a, b models.User) bool { return a.ID == b.ID }), nil
+}
+
+func (r *MemoryUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findBy(ctx, func(u *models.User) bool { return u.Username == username })
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findBy(ctx, func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *MemoryUserRepository) findBy(ctx context.Context, match func(*models.User) bool) (*models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for _, user := range r.m.users {
+		if tenant.Visible(ctx, user.TenantID) && match(&user) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	assignTenant(ctx, &user.TenantID)
+	// 用户名和邮箱在租户内唯一
+	for _, existing := range r.m.users {
+		if existing.TenantID == user.TenantID && (existing.Username == user.Username || existing.Email == user.Email) {
+			return ErrDuplicated
+		}
+	}
+	user.ID, user.CreatedAt = r.m.stamp()
+	user.UpdatedAt = user.CreatedAt
+	if user.Role == "" {
+		user.Role = consts.RoleUser
+	}
+	if user.Version == 0 {
+		user.Version = 1
+	}
+	r.m.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, id uint, version uint, changes UserChanges) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	user, ok := r.m.users[id]
+	if !ok || !tenant.Visible(ctx, user.TenantID) || (version != 0 && user.Version != version) {
+		return false, nil
+	}
+	if changes.Email != nil {
+		for _, existing := range r.m.users {
+			if existing.ID != id && existing.TenantID == user.TenantID && existing.Email == *changes.Email {
+				return false, ErrDuplicated
+			}
+		}
+		user.Email = *changes.Email
+	}
+	if changes.Password != nil {
+		user.Password = *changes.Password
+	}
+	if changes.Role != nil {
+		user.Role = *changes.Role
+	}
+	if changes.Warnings != nil {
+		user.Warnings = *changes.Warnings
+	}
+	if changes.SuspendedUntil != nil {
+		until := *changes.SuspendedUntil
+		user.SuspendedUntil = &until
+	}
+	if changes.BannedAt != nil {
+		bannedAt := *changes.BannedAt
+		user.BannedAt = &bannedAt
+	}
+	user.Version++
+	user.UpdatedAt = r.m.now()
+	r.m.users[id] = user
+	return true, nil
+}
+
+func (r *MemoryUserRepository) ReplacePassword(ctx context.Context, id uint, oldHash, newHash string) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	user, ok := r.m.users[id]
+	if !ok || !tenant.Visible(ctx, user.TenantID) || user.Password != oldHash {
+		return false, nil
+	}
+	user.Password = newHash
+	r.m.users[id] = user
+	return true, nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if user, ok := r.m.users[id]; ok && tenant.Visible(ctx, user.TenantID) {
+		delete(r.m.users, id)
+	}
+	return nil
+}
+
+type MemoryPostRepository struct{ m *Memory }
+
+func (r *MemoryPostRepository) FindByID(ctx context.Context, id uint) (*models.Post, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	post, ok := r.m.posts[id]
+	if !ok || !tenant.Visible(ctx, post.TenantID) {
+		return nil, ErrNotFound
+	}
+	return clonePost(post), nil
+}
+
+func (r *MemoryPostRepository) Exists(ctx context.Context, id uint) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	post, ok := r.m.posts[id]
+	return ok && tenant.Visible(ctx, post.TenantID), nil
+}
+
+func (r *MemoryPostRepository) Page(ctx context.Context, filter PostFilter, query dto.BasePageQuery) (*dto.PageResult[models.Post], error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var items []models.Post
+	for _, post := range r.m.posts {
+		if !tenant.Visible(ctx, post.TenantID) {
+			continue
+		}
+		if filter.Status != "" && post.Status != filter.Status {
+			continue
+		}
+		if !containsFold(post.Title, filter.Title) || !containsFold(post.Content, filter.Content) {
+			continue
+		}
+		items = append(items, *clonePost(post))
+	}
+	return memoryPage(items, query, func(a, b *models.Post, field string) int {
+		if field == "title" {
+			return strings.Compare(a.Title, b.Title)
+		}
+		return compareModel(a.ID, b.ID, a.CreatedAt, b.CreatedAt, a.UpdatedAt, b.UpdatedAt, field)
+	})
+}
+
+func (r *MemoryPostRepository) Create(ctx context.Context, post *models.Post, tags []string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	assignTenant(ctx, &post.TenantID)
+	post.ID, post.CreatedAt = r.m.stamp()
+	post.UpdatedAt = post.CreatedAt
+	post.Tags = r.m.tagsByName(post.TenantID, tags)
+	if post.Status == "" {
+		post.Status = consts.PostStatusPublished
+	}
+	if post.Version == 0 {
+		post.Version = 1
+	}
+	r.m.posts[post.ID] = *clonePost(*post)
+	return nil
+}
+
+func (r *MemoryPostRepository) Update(ctx context.Context, id uint, version uint, changes PostChanges) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	post, ok := r.m.posts[id]
+	if !ok || !tenant.Visible(ctx, post.TenantID) || (version != 0 && post.Version != version) {
+		return false, nil
+	}
+	if changes.Title != nil {
+		post.Title = *changes.Title
+	}
+	if changes.Content != nil {
+		post.Content = *changes.Content
+	}
+	if changes.Status != nil {
+		post.Status = *changes.Status
+	}
+	if changes.PublishedAt != nil {
+		publishedAt := *changes.PublishedAt
+		post.PublishedAt = &publishedAt
+	}
+	if changes.Tags != nil {
+		post.Tags = r.m.tagsByName(post.TenantID, changes.Tags)
+	}
+	post.Version++
+	post.UpdatedAt = r.m.now()
+	r.m.posts[id] = post
+	return true, nil
+}
+
+func (r *MemoryPostRepository) Delete(ctx context.Context, id uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if post, ok := r.m.posts[id]; ok && tenant.Visible(ctx, post.TenantID) {
+		delete(r.m.posts, id)
+	}
+	return nil
+}
+
+func (r *MemoryPostRepository) DeleteByUser(ctx context.Context, userID uint) ([]uint, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var ids []uint
+	for id, post := range r.m.posts {
+		if post.UserId == userID && tenant.Visible(ctx, post.TenantID) {
+			ids = append(ids, id)
+			delete(r.m.posts, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+type MemoryCommentRepository struct{ m *Memory }
+
+func (r *MemoryCommentRepository) FindByID(ctx context.Context, id uint) (*models.Comment, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	comment, ok := r.m.comments[id]
+	if !ok || !tenant.Visible(ctx, comment.TenantID) {
+		return nil, ErrNotFound
+	}
+	return &comment, nil
+}
+
+func (r *MemoryCommentRepository) FindByPosts(ctx context.Context, postIDs []uint) ([]models.Comment, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var comments []models.Comment
+	for _, comment := range r.m.comments {
+		if slices.Contains(postIDs, comment.PostId) && tenant.Visible(ctx, comment.TenantID) {
+			comments = append(comments, comment)
+		}
+	}
+	slices.SortFunc(comments, func(a, b models.Comment) int { return cmp.Compare(a.ID, b.ID) })
+	return comments, nil
+}
+
+func (r *MemoryCommentRepository) Page(ctx context.Context, filter CommentFilter, query dto.BasePageQuery) (*dto.PageResult[models.Comment], error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var items []models.Comment
+	for _, comment := range r.m.comments {
+		if !tenant.Visible(ctx, comment.TenantID) {
+			continue
+		}
+		if filter.PostID != 0 && comment.PostId != filter.PostID {
+			continue
+		}
+		if filter.Status != "" && comment.Status != filter.Status {
+			continue
+		}
+		if !containsFold(comment.Content, filter.Content) {
+			continue
+		}
+		items = append(items, comment)
+	}
+	return memoryPage(items, query, func(a, b *models.Comment, field string) int {
+		return compareModel(a.ID, b.ID, a.CreatedAt, b.CreatedAt, a.UpdatedAt, b.UpdatedAt, field)
+	})
+}
+
+func (r *MemoryCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	assignTenant(ctx, &comment.TenantID)
+	comment.ID, comment.CreatedAt = r.m.stamp()
+	comment.UpdatedAt = comment.CreatedAt
+	if comment.Version == 0 {
+		comment.Version = 1
+	}
+	if comment.Status == "" {
+		comment.Status = consts.CommentStatusPublished
+	}
+	r.m.comments[comment.ID] = *comment
+	return nil
+}
+
+func (r *MemoryCommentRepository) Update(ctx context.Context, id uint, version uint, changes CommentChanges) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	comment, ok := r.m.comments[id]
+	if !ok || !tenant.Visible(ctx, comment.TenantID) || (version != 0 && comment.Version != version) {
+		return false, nil
+	}
+	if changes.Content != nil {
+		comment.Content = *changes.Content
+	}
+	if changes.Status != nil {
+		comment.Status = *changes.Status
+	}
+	comment.Version++
+	comment.UpdatedAt = r.m.now()
+	r.m.comments[id] = comment
+	return true, nil
+}
+
+func (r *MemoryCommentRepository) Delete(ctx context.Context, id uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if comment, ok := r.m.comments[id]; ok && tenant.Visible(ctx, comment.TenantID) {
+		delete(r.m.comments, id)
+	}
+	return nil
+}
+
+func (r *MemoryCommentRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	maps.DeleteFunc(r.m.comments, func(_ uint, comment models.Comment) bool {
+		return comment.UserId == userID && tenant.Visible(ctx, comment.TenantID)
+	})
+	return nil
+}
+
+func (r *MemoryCommentRepository) DeleteByPosts(ctx context.Context, postIDs []uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	maps.DeleteFunc(r.m.comments, func(_ uint, comment models.Comment) bool {
+		return slices.Contains(postIDs, comment.PostId) && tenant.Visible(ctx, comment.TenantID)
+	})
+	return nil
+}
+
+type MemoryAttachmentRepository struct{ m *Memory }
+
+func (r *MemoryAttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	assignTenant(ctx, &attachment.TenantID)
+	attachment.ID, attachment.CreatedAt = r.m.stamp()
+	attachment.UpdatedAt = attachment.CreatedAt
+	r.m.attachments[attachment.ID] = *attachment
+	return nil
+}
+
+func (r *MemoryAttachmentRepository) DeleteByPosts(ctx context.Context, postIDs []uint) ([]models.Attachment, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	var deleted []models.Attachment
+	for id, attachment := range r.m.attachments {
+		if slices.Contains(postIDs, attachment.PostId) && tenant.Visible(ctx, attachment.TenantID) {
+			deleted = append(deleted, attachment)
+			delete(r.m.attachments, id)
+		}
+	}
+	slices.SortFunc(deleted, func(a, b models.Attachment) int { return cmp.Compare(a.ID, b.ID) })
+	return deleted, nil
+}
+
+// assignTenant 新记录没有指定租户时使用 ctx 中的租户，与 tenant.Plugin 的行为一致
+func assignTenant(ctx context.Context, tenantID *uint) {
+	if current, ok := tenant.FromContext(ctx); ok && *tenantID == 0 {
+		*tenantID = current
+	}
+}
+
+func clonePost(post models.Post) *models.Post {
+	post.Tags = slices.Clone(post.Tags)
+	return &post
+}
+
+// containsFold 与 MySQL 默认排序规则下的 LIKE 一致，不区分大小写
+func containsFold(s, substr string) bool {
+	return substr == "" || strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func compareModel(aID, bID uint, aCreated, bCreated, aUpdated, bUpdated time.Time, field string) int {
+	switch field {
+	case "created_at":
+		return aCreated.Compare(bCreated)
+	case "updated_at":
+		return aUpdated.Compare(bUpdated)
+	}
+	return cmp.Compare(aID, bID)
+}
+
+// memoryPage 按 BasePageQuery 排序分页，分页信息的计算与 tools.Paginate 相同
+func memoryPage[T any](items []T, query dto.BasePageQuery, compare func(a, b *T, field string) int) (*dto.PageResult[T], error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	field := ""
+	if clause := query.GetOrderClause(); clause != "" {
+		field = strings.Fields(clause)[0]
+	}
+	desc := strings.EqualFold(query.Order, "desc") && field != ""
+	slices.SortStableFunc(items, func(a, b T) int {
+		result := compare(&a, &b, field)
+		if desc {
+			return -result
+		}
+		return result
+	})
+
+	total := len(items)
+	start := min(query.GetOffset(), total)
+	end := min(start+query.GetLimit(), total)
+	totalPages := int(math.Ceil(float64(total) / float64(query.PageSize)))
+	pageItems := slices.Clone(items[start:end])
+	if pageItems == nil {
+		pageItems = []T{}
+	}
+	return &dto.PageResult[T]{
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		Total:      int64(total),
+		TotalPages: totalPages,
+		HasNext:    query.Page < totalPages,
+		HasPrev:    query.Page > 1,
+		Items:      pageItems,
+	}, nil
+}
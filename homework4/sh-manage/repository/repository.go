@@ -0,0 +1,112 @@
+// Package repository 定义用户、文章、评论聚合的存储接口，提供 GORM 和内存两种实现
+// 服务层只依赖接口，测试可以使用内存实现而不需要数据库
+package repository
+
+import (
+	"context"
+	"errors"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"time"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("repository: record not found")
+
+// ErrDuplicated 违反唯一约束，例如用户名或邮箱重复
+var ErrDuplicated = errors.New("repository: duplicated key")
+
+// Repositories 共享同一份数据的一组存储，Tx 用于跨聚合的事务
+type Repositories struct {
+	Users       UserRepository
+	Posts       PostRepository
+	Comments    CommentRepository
+	Attachments AttachmentRepository
+	Tx          UnitOfWork
+}
+
+type UserRepository interface {
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	// FindByIDs 批量查询，不存在的 ID 被忽略，结果按 ID 升序
+	FindByIDs(ctx context.Context, ids []uint) ([]models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	// Update 版本号一致时修改并将版本号加一，返回 false 表示已被其他请求修改；version 为 0 时不检查版本号
+	Update(ctx context.Context, id uint, version uint, changes UserChanges) (bool, error)
+	// ReplacePassword 密码哈希仍为 oldHash 时替换为 newHash，不修改版本号，用于登录时按新参数重新生成哈希
+	// 返回 false 表示密码已被其他请求修改
+	ReplacePassword(ctx context.Context, id uint, oldHash, newHash string) (bool, error)
+	// Delete 同时删除用户的第三方身份绑定和 API Key
+	Delete(ctx context.Context, id uint) error
+}
+
+// UserChanges 为 nil 的字段保持不变
+type UserChanges struct {
+	Email    *string
+	Password *string // 密码哈希
+	Role     *string
+	// 举报处理的警告和停用、封禁
+	Warnings       *uint
+	SuspendedUntil *time.Time
+	BannedAt       *time.Time
+}
+
+type PostRepository interface {
+	// FindByID 返回的文章包含标签
+	FindByID(ctx context.Context, id uint) (*models.Post, error)
+	Exists(ctx context.Context, id uint) (bool, error)
+	Page(ctx context.Context, filter PostFilter, query dto.BasePageQuery) (*dto.PageResult[models.Post], error)
+	// Create 按名称关联标签，不存在的标签自动创建
+	Create(ctx context.Context, post *models.Post, tags []string) error
+	Update(ctx context.Context, id uint, version uint, changes PostChanges) (bool, error)
+	Delete(ctx context.Context, id uint) error
+	// DeleteByUser 删除用户的全部文章及其标签关联，返回被删除文章的 ID
+	DeleteByUser(ctx context.Context, userID uint) ([]uint, error)
+}
+
+// PostFilter 为空的条件不参与过滤，Title 和 Content 为模糊匹配
+type PostFilter struct {
+	Status  string
+	Title   string
+	Content string
+}
+
+type PostChanges struct {
+	Title       *string
+	Content     *string
+	Status      *string
+	PublishedAt *time.Time
+	// Tags 不为 nil 时替换文章的全部标签
+	Tags []string
+}
+
+type CommentRepository interface {
+	FindByID(ctx context.Context, id uint) (*models.Comment, error)
+	// FindByPosts 返回多篇文章的全部评论，按 ID 升序
+	FindByPosts(ctx context.Context, postIDs []uint) ([]models.Comment, error)
+	Page(ctx context.Context, filter CommentFilter, query dto.BasePageQuery) (*dto.PageResult[models.Comment], error)
+	Create(ctx context.Context, comment *models.Comment) error
+	Update(ctx context.Context, id uint, version uint, changes CommentChanges) (bool, error)
+	Delete(ctx context.Context, id uint) error
+	DeleteByUser(ctx context.Context, userID uint) error
+	DeleteByPosts(ctx context.Context, postIDs []uint) error
+}
+
+type CommentFilter struct {
+	PostID  uint // 为 0 时不过滤
+	Status  string
+	Content string
+}
+
+type CommentChanges struct {
+	Content *string
+	Status  *string
+}
+
+// AttachmentRepository 附件记录的存储，文件本身由 storage 包管理
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	// DeleteByPosts 删除多篇文章的附件记录并返回这些记录，调用方在事务提交后删除存储中的文件
+	DeleteByPosts(ctx context.Context, postIDs []uint) ([]models.Attachment, error)
+}
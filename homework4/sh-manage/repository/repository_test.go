@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/tenant"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// forEachBackend 对 GORM 和内存实现执行同一组用例，保证两者行为一致
+func forEachBackend(t *testing.T, fn func(t *testing.T, b Repositories)) {
+	t.Run("gorm", func(t *testing.T) {
+		dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+		db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), TranslateError: true})
+		if err != nil {
+			t.Fatalf("open db: %v", err)
+		}
+		if err := db.Use(tenant.Plugin{}); err != nil {
+			t.Fatalf("register tenant plugin: %v", err)
+		}
+		if err := db.AutoMigrate(models.GetModels()...); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		sqlDB, _ := db.DB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		fn(t, NewGorm(db))
+	})
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemory().Repositories())
+	})
+}
+
+func TestUserRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Repositories) {
+		ctx := context.Background()
+		user := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash", Role: consts.RoleUser}
+		if err := b.Users.Create(ctx, user); err != nil || user.ID == 0 || user.Version != 1 {
+			t.Fatalf("Create = %v, user = %+v", err, user)
+		}
+
+		for name, find := range map[string]func() (*models.User, error){
+			"id":       func() (*models.User, error) { return b.Users.FindByID(ctx, user.ID) },
+			"username": func() (*models.User, error) { return b.Users.FindByUsername(ctx, "alice") },
+			"email":    func() (*models.User, error) { return b.Users.FindByEmail(ctx, "alice@example.com") },
+		} {
+			if found, err := find(); err != nil || found.ID != user.ID {
+				t.Fatalf("find by %s = %+v, %v", name, found, err)
+			}
+		}
+		if _, err := b.Users.FindByUsername(ctx, "nobody"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("missing user err = %v", err)
+		}
+		if users, err := b.Users.FindByIDs(ctx, []uint{user.ID + 100, user.ID, user.ID}); err != nil || len(users) != 1 || users[0].ID != user.ID {
+			t.Fatalf("FindByIDs = %+v, %v", users, err)
+		}
+
+		email := "new@example.com"
+		if updated, err := b.Users.Update(ctx, user.ID, 1, UserChanges{Email: &email}); err != nil || !updated {
+			t.Fatalf("Update = %v, %v", updated, err)
+		}
+		// 第二个请求仍然基于版本1修改，必须失败
+		if updated, err := b.Users.Update(ctx, user.ID, 1, UserChanges{Email: &email}); err != nil || updated {
+			t.Fatalf("stale Update = %v, %v", updated, err)
+		}
+		password := "other-hash"
+		if updated, err := b.Users.Update(ctx, user.ID, 0, UserChanges{Password: &password}); err != nil || !updated {
+			t.Fatalf("Update without version = %v, %v", updated, err)
+		}
+		found, _ := b.Users.FindByID(ctx, user.ID)
+		if found.Email != email || found.Password != password || found.Version != 3 {
+			t.Fatalf("after updates = %+v", found)
+		}
+		// 重新生成哈希不修改版本号，哈希已被修改时不替换
+		if replaced, err := b.Users.ReplacePassword(ctx, user.ID, "hash", "rehashed"); err != nil || replaced {
+			t.Fatalf("ReplacePassword with stale hash = %v, %v", replaced, err)
+		}
+		if replaced, err := b.Users.ReplacePassword(ctx, user.ID, password, "rehashed"); err != nil || !replaced {
+			t.Fatalf("ReplacePassword = %v, %v", replaced, err)
+		}
+		if found, _ := b.Users.FindByID(ctx, user.ID); found.Password != "rehashed" || found.Version != 3 {
+			t.Fatalf("after ReplacePassword = %+v", found)
+		}
+
+		if err := b.Users.Delete(ctx, user.ID); err != nil {
+			t.Fatalf("Delete = %v", err)
+		}
+		if _, err := b.Users.FindByID(ctx, user.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleted user err = %v", err)
+		}
+	})
+}
+
+func TestPostRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Repositories) {
+		ctx := context.Background()
+		first := &models.Post{Title: "Hello Go", Content: "gin and gorm", UserId: 1}
+		if err := b.Posts.Create(ctx, first, []string{"go", "gin"}); err != nil {
+			t.Fatalf("Create = %v", err)
+		}
+		if first.Status != consts.PostStatusPublished || first.Version != 1 || len(first.Tags) != 2 {
+			t.Fatalf("created post = %+v", first)
+		}
+		draft := &models.Post{Title: "draft", Content: "wip", UserId: 1, Status: consts.PostStatusDraft}
+		second := &models.Post{Title: "second go post", Content: "more", UserId: 2}
+		b.Posts.Create(ctx, draft, nil)
+		b.Posts.Create(ctx, second, []string{"go"})
+
+		found, err := b.Posts.FindByID(ctx, first.ID)
+		if err != nil || found.Title != "Hello Go" || len(found.TagNames()) != 2 {
+			t.Fatalf("FindByID = %+v, %v", found, err)
+		}
+		if exists, _ := b.Posts.Exists(ctx, draft.ID); !exists {
+			t.Fatalf("Exists(draft) = false")
+		}
+		if exists, _ := b.Posts.Exists(ctx, 999); exists {
+			t.Fatalf("Exists(999) = true")
+		}
+
+		query := *dto.NewBasePageQuery()
+		tests := []struct {
+			name   string
+			filter PostFilter
+			query  dto.BasePageQuery
+			want   []uint
+		}{
+			{"all newest first", PostFilter{}, query, []uint{second.ID, draft.ID, first.ID}},
+			{"published", PostFilter{Status: consts.PostStatusPublished}, query, []uint{second.ID, first.ID}},
+			{"title case insensitive", PostFilter{Title: "GO"}, query, []uint{second.ID, first.ID}},
+			{"content", PostFilter{Content: "gorm"}, query, []uint{first.ID}},
+			{"order by title asc", PostFilter{}, dto.BasePageQuery{Page: 1, PageSize: 10, OrderBy: "title", Order: "asc"}, []uint{first.ID, draft.ID, second.ID}},
+			{"second page", PostFilter{}, dto.BasePageQuery{Page: 2, PageSize: 2, OrderBy: "id", Order: "desc"}, []uint{first.ID}},
+		}
+		for _, tt := range tests {
+			page, err := b.Posts.Page(ctx, tt.filter, tt.query)
+			if err != nil {
+				t.Fatalf("%s: Page = %v", tt.name, err)
+			}
+			var got []uint
+			for _, post := range page.Items {
+				got = append(got, post.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("%s: ids = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+		if page, _ := b.Posts.Page(ctx, PostFilter{}, dto.BasePageQuery{Page: 2, PageSize: 2, OrderBy: "id"}); page.Total != 3 || page.TotalPages != 2 || page.HasNext || !page.HasPrev {
+			t.Fatalf("page info = %+v", page)
+		}
+		if _, err := b.Posts.Page(ctx, PostFilter{}, dto.BasePageQuery{Page: 0, PageSize: 10}); err == nil {
+			t.Fatalf("invalid page query accepted")
+		}
+
+		title := "renamed"
+		if updated, err := b.Posts.Update(ctx, first.ID, 1, PostChanges{Title: &title, Tags: []string{"gorm"}}); err != nil || !updated {
+			t.Fatalf("Update = %v, %v", updated, err)
+		}
+		if updated, _ := b.Posts.Update(ctx, first.ID, 1, PostChanges{Title: &title}); updated {
+			t.Fatalf("stale Update succeeded")
+		}
+		found, _ = b.Posts.FindByID(ctx, first.ID)
+		if found.Title != title || found.Version != 2 || fmt.Sprint(found.TagNames()) != "[gorm]" {
+			t.Fatalf("after update = %+v tags %v", found, found.TagNames())
+		}
+		// Tags 为 nil 时保留原标签
+		content := "changed"
+		b.Posts.Update(ctx, first.ID, 2, PostChanges{Content: &content})
+		found, _ = b.Posts.FindByID(ctx, first.ID)
+		if fmt.Sprint(found.TagNames()) != "[gorm]" || found.Content != content {
+			t.Fatalf("tags should be kept, got %+v", found)
+		}
+
+		b.Posts.Delete(ctx, first.ID)
+		if _, err := b.Posts.FindByID(ctx, first.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleted post err = %v", err)
+		}
+
+		if ids, err := b.Posts.DeleteByUser(ctx, 1); err != nil || fmt.Sprint(ids) != fmt.Sprint([]uint{draft.ID}) {
+			t.Fatalf("DeleteByUser = %v, %v", ids, err)
+		}
+		if exists, _ := b.Posts.Exists(ctx, second.ID); !exists {
+			t.Fatalf("other user's post deleted")
+		}
+	})
+}
+
+func TestCommentRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Repositories) {
+		ctx := context.Background()
+		comments := []*models.Comment{
+			{Content: "first", UserId: 1, PostId: 1},
+			{Content: "second", UserId: 2, PostId: 1},
+			{Content: "other post", UserId: 1, PostId: 2},
+		}
+		for _, comment := range comments {
+			if err := b.Comments.Create(ctx, comment); err != nil || comment.Version != 1 {
+				t.Fatalf("Create = %v, %+v", err, comment)
+			}
+		}
+
+		page, err := b.Comments.Page(ctx, CommentFilter{PostID: 1}, *dto.NewBasePageQuery())
+		if err != nil || page.Total != 2 || page.Items[0].ID != comments[1].ID {
+			t.Fatalf("Page(post 1) = %+v, %v", page, err)
+		}
+		page, _ = b.Comments.Page(ctx, CommentFilter{Content: "OTHER"}, *dto.NewBasePageQuery())
+		if page.Total != 1 || page.Items[0].ID != comments[2].ID {
+			t.Fatalf("Page(content) = %+v", page)
+		}
+		byPosts, err := b.Comments.FindByPosts(ctx, []uint{1, 2})
+		if err != nil || len(byPosts) != 3 || byPosts[0].ID != comments[0].ID || byPosts[2].ID != comments[2].ID {
+			t.Fatalf("FindByPosts = %+v, %v", byPosts, err)
+		}
+
+		content := "edited"
+		if updated, err := b.Comments.Update(ctx, comments[0].ID, 1, CommentChanges{Content: &content}); err != nil || !updated {
+			t.Fatalf("Update = %v, %v", updated, err)
+		}
+		if updated, _ := b.Comments.Update(ctx, comments[0].ID, 1, CommentChanges{Content: &content}); updated {
+			t.Fatalf("stale Update succeeded")
+		}
+		found, err := b.Comments.FindByID(ctx, comments[0].ID)
+		if err != nil || found.Content != content || found.Version != 2 {
+			t.Fatalf("FindByID = %+v, %v", found, err)
+		}
+
+		b.Comments.Delete(ctx, comments[0].ID)
+		if _, err := b.Comments.FindByID(ctx, comments[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleted comment err = %v", err)
+		}
+
+		b.Comments.Create(ctx, &models.Comment{Content: "third", UserId: 3, PostId: 3})
+		if err := b.Comments.DeleteByUser(ctx, 1); err != nil {
+			t.Fatalf("DeleteByUser = %v", err)
+		}
+		if err := b.Comments.DeleteByPosts(ctx, []uint{1}); err != nil {
+			t.Fatalf("DeleteByPosts = %v", err)
+		}
+		page, _ = b.Comments.Page(ctx, CommentFilter{}, *dto.NewBasePageQuery())
+		if page.Total != 1 || page.Items[0].Content != "third" {
+			t.Fatalf("after cascading deletes = %+v", page)
+		}
+	})
+}
+
+func TestAttachmentRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Repositories) {
+		ctx := context.Background()
+		for _, postID := range []uint{1, 1, 2} {
+			attachment := &models.Attachment{PostId: postID, UserId: 1, FileName: "a.png", StorageKey: fmt.Sprintf("attachments/%d.png", postID)}
+			if err := b.Attachments.Create(ctx, attachment); err != nil || attachment.ID == 0 {
+				t.Fatalf("Create = %v, attachment = %+v", err, attachment)
+			}
+		}
+
+		deleted, err := b.Attachments.DeleteByPosts(ctx, []uint{1, 3})
+		if err != nil || len(deleted) != 2 || deleted[0].PostId != 1 || deleted[0].ID > deleted[1].ID {
+			t.Fatalf("DeleteByPosts = %+v, %v", deleted, err)
+		}
+		if deleted, _ := b.Attachments.DeleteByPosts(ctx, []uint{1}); len(deleted) != 0 {
+			t.Fatalf("second DeleteByPosts = %+v", deleted)
+		}
+		if deleted, _ := b.Attachments.DeleteByPosts(ctx, []uint{2}); len(deleted) != 1 {
+			t.Fatalf("DeleteByPosts(2) = %+v", deleted)
+		}
+	})
+}
+
+func TestUnitOfWork(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Repositories) {
+		ctx := context.Background()
+		create := func(ctx context.Context, name string) error {
+			return b.Users.Create(ctx, &models.User{Username: name, Email: name + "@example.com", Password: "hash"})
+		}
+		exists := func(name string) bool {
+			_, err := b.Users.FindByUsername(ctx, name)
+			return err == nil
+		}
+		errFailed := errors.New("failed")
+
+		if err := b.Tx.Do(ctx, func(ctx context.Context) error { return create(ctx, "committed") }); err != nil || !exists("committed") {
+			t.Fatalf("commit = %v", err)
+		}
+
+		err := b.Tx.Do(ctx, func(ctx context.Context) error {
+			if err := create(ctx, "rolled-back"); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) || exists("rolled-back") {
+			t.Fatalf("rollback err = %v, exists = %v", err, exists("rolled-back"))
+		}
+
+		// 内层失败只回滚到保存点，外层继续提交
+		err = b.Tx.Do(ctx, func(ctx context.Context) error {
+			if err := create(ctx, "outer"); err != nil {
+				return err
+			}
+			if err := b.Tx.Do(ctx, func(ctx context.Context) error {
+				if err := create(ctx, "inner"); err != nil {
+					return err
+				}
+				return errFailed
+			}); !errors.Is(err, errFailed) {
+				t.Errorf("inner err = %v", err)
+			}
+			return nil
+		})
+		if err != nil || !exists("outer") || exists("inner") {
+			t.Fatalf("savepoint err = %v, outer = %v, inner = %v", err, exists("outer"), exists("inner"))
+		}
+
+		// 外层失败时已提交的内层一并回滚
+		b.Tx.Do(ctx, func(ctx context.Context) error {
+			b.Tx.Do(ctx, func(ctx context.Context) error { return create(ctx, "nested") })
+			return errFailed
+		})
+		if exists("nested") {
+			t.Fatalf("nested change survived outer rollback")
+		}
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("panic not propagated")
+				}
+			}()
+			b.Tx.Do(ctx, func(ctx context.Context) error {
+				create(ctx, "panicked")
+				panic("boom")
+			})
+		}()
+		if exists("panicked") {
+			t.Fatalf("change survived panic")
+		}
+	})
+}
+
+func TestTenantIsolation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Repositories) {
+		acme, globex := tenant.WithID(context.Background(), 1), tenant.WithID(context.Background(), 2)
+
+		// 不同租户可以使用相同的用户名和邮箱
+		alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+		if err := b.Users.Create(acme, alice); err != nil || alice.TenantID != 1 {
+			t.Fatalf("Create(acme) = %v, tenant = %d", err, alice.TenantID)
+		}
+		other := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+		if err := b.Users.Create(globex, other); err != nil || other.TenantID != 2 {
+			t.Fatalf("Create(globex) = %v, tenant = %d", err, other.TenantID)
+		}
+		if err := b.Users.Create(acme, &models.User{Username: "alice", Email: "a2@example.com", Password: "hash"}); !errors.Is(err, ErrDuplicated) {
+			t.Fatalf("duplicate in same tenant err = %v", err)
+		}
+		if found, err := b.Users.FindByUsername(globex, "alice"); err != nil || found.ID != other.ID {
+			t.Fatalf("FindByUsername(globex) = %+v, %v", found, err)
+		}
+		if _, err := b.Users.FindByID(globex, alice.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("cross-tenant FindByID err = %v", err)
+		}
+
+		post := &models.Post{Title: "acme news", Content: "secret", UserId: alice.ID}
+		if err := b.Posts.Create(acme, post, []string{"go"}); err != nil || post.TenantID != 1 {
+			t.Fatalf("Create post = %v, tenant = %d", err, post.TenantID)
+		}
+		comment := &models.Comment{Content: "hi", UserId: alice.ID, PostId: post.ID}
+		if err := b.Comments.Create(acme, comment); err != nil || comment.TenantID != 1 {
+			t.Fatalf("Create comment = %v, tenant = %d", err, comment.TenantID)
+		}
+
+		if _, err := b.Posts.FindByID(globex, post.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("cross-tenant post err = %v", err)
+		}
+		if exists, _ := b.Posts.Exists(globex, post.ID); exists {
+			t.Fatalf("cross-tenant Exists = true")
+		}
+		if page, _ := b.Posts.Page(globex, PostFilter{}, *dto.NewBasePageQuery()); page.Total != 0 {
+			t.Fatalf("cross-tenant post page = %+v", page)
+		}
+		if page, _ := b.Comments.Page(globex, CommentFilter{PostID: post.ID}, *dto.NewBasePageQuery()); page.Total != 0 {
+			t.Fatalf("cross-tenant comment page = %+v", page)
+		}
+		if _, err := b.Comments.FindByID(globex, comment.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("cross-tenant comment err = %v", err)
+		}
+
+		// 其他租户的写操作不能影响记录
+		title := "hijacked"
+		if updated, _ := b.Posts.Update(globex, post.ID, 0, PostChanges{Title: &title}); updated {
+			t.Fatalf("cross-tenant post update succeeded")
+		}
+		content := "hijacked"
+		if updated, _ := b.Comments.Update(globex, comment.ID, 0, CommentChanges{Content: &content}); updated {
+			t.Fatalf("cross-tenant comment update succeeded")
+		}
+		b.Posts.Delete(globex, post.ID)
+		b.Comments.Delete(globex, comment.ID)
+		b.Users.Delete(globex, alice.ID)
+		if ids, _ := b.Posts.DeleteByUser(globex, alice.ID); len(ids) != 0 {
+			t.Fatalf("cross-tenant DeleteByUser = %v", ids)
+		}
+
+		found, err := b.Posts.FindByID(acme, post.ID)
+		if err != nil || found.Title != "acme news" {
+			t.Fatalf("acme post after cross-tenant writes = %+v, %v", found, err)
+		}
+		if found, err := b.Comments.FindByID(acme, comment.ID); err != nil || found.Content != "hi" {
+			t.Fatalf("acme comment after cross-tenant writes = %+v, %v", found, err)
+		}
+		if _, err := b.Users.FindByID(acme, alice.ID); err != nil {
+			t.Fatalf("acme user after cross-tenant delete = %v", err)
+		}
+
+		// 没有租户的 context（命令行、后台任务）可以看到所有租户
+		if page, _ := b.Posts.Page(context.Background(), PostFilter{}, *dto.NewBasePageQuery()); page.Total != 1 {
+			t.Fatalf("unscoped page total = %d", page.Total)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork 把跨聚合的多个存储操作放在同一个事务中
+// 事务通过 context 传递，fn 中使用传入的 ctx 调用存储即可加入事务
+type UnitOfWork interface {
+	// Do 在事务中执行 fn，fn 返回错误或 panic 时回滚
+	// ctx 中已有事务时创建保存点，内层失败只回滚到保存点，由外层决定是否整体回滚
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// conn 返回 ctx 中的事务连接，不在事务中时返回 db
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// Conn 返回 ctx 中的事务连接，供服务中直接使用 gorm 的查询在 UnitOfWork.Do 中加入同一个事务
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return conn(ctx, db)
+}
+
+// transaction 在 ctx 的事务中执行 fn，不在事务中时开启新事务，已在事务中时使用保存点
+func transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	return conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), tx)
+	})
+}
+
+type GormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewGormUnitOfWork(db *gorm.DB) *GormUnitOfWork {
+	return &GormUnitOfWork{db: db}
+}
+
+func (u *GormUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return transaction(ctx, u.db, func(ctx context.Context, _ *gorm.DB) error {
+		return fn(ctx)
+	})
+}
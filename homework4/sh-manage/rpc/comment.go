@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"context"
+	shmanagev1 "sh-manage/api/shmanage/v1"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type commentServer struct {
+	shmanagev1.UnimplementedCommentServiceServer
+	posts    *services.PostService
+	comments *services.CommentService
+}
+
+func (s *commentServer) CreateComment(ctx context.Context, req *shmanagev1.CreateCommentRequest) (*shmanagev1.Comment, error) {
+	postID := uint(req.PostId)
+	comment := &dto.CommentDto{PostID: &postID, Content: &req.Content}
+	if appErr := comment.Validate(); appErr != nil {
+		return nil, status.Error(codes.InvalidArgument, appErr.Message)
+	}
+	created, appErr := s.comments.WithContext(ginContext(ctx)).CreateComment(comment)
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return toComment(created), nil
+}
+
+func (s *commentServer) GetComment(ctx context.Context, req *shmanagev1.GetCommentRequest) (*shmanagev1.Comment, error) {
+	comment, appErr := s.comments.WithContext(ginContext(ctx)).GetCommentByID(uint(req.Id))
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return toComment(comment), nil
+}
+
+// ListComments 文章不可见（不存在或是其他人的草稿）时返回 NotFound
+func (s *commentServer) ListComments(ctx context.Context, req *shmanagev1.ListCommentsRequest) (*shmanagev1.ListCommentsResponse, error) {
+	query, err := pageQuery(req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	c := ginContext(ctx)
+	postID := uint(req.PostId)
+	if _, appErr := s.posts.WithContext(c).GetPostByID(postID); appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	page, appErr := s.comments.WithContext(c).GetCommentByPage(&dto.CommentPageDTO{BasePageQuery: query, PostID: &postID})
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	items := make([]*shmanagev1.Comment, len(page.Items))
+	for i := range page.Items {
+		items[i] = toComment(&page.Items[i])
+	}
+	return &shmanagev1.ListCommentsResponse{PageInfo: toPageInfo(page), Items: items}, nil
+}
+
+func (s *commentServer) UpdateComment(ctx context.Context, req *shmanagev1.UpdateCommentRequest) (*shmanagev1.Comment, error) {
+	id, version := uint(req.Id), uint(req.Version)
+	comment := &dto.CommentDto{ID: &id, Content: &req.Content, Version: &version}
+	if appErr := comment.Validate(); appErr != nil {
+		return nil, status.Error(codes.InvalidArgument, appErr.Message)
+	}
+	updated, appErr := s.comments.WithContext(ginContext(ctx)).UpdateComment(comment)
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return toComment(updated), nil
+}
+
+func (s *commentServer) DeleteComment(ctx context.Context, req *shmanagev1.DeleteCommentRequest) (*emptypb.Empty, error) {
+	if appErr := s.comments.WithContext(ginContext(ctx)).DeleteByID(uint(req.Id)); appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func toComment(comment *models.Comment) *shmanagev1.Comment {
+	return &shmanagev1.Comment{
+		Id:        uint64(comment.ID),
+		Content:   comment.Content,
+		UserId:    uint64(comment.UserId),
+		PostId:    uint64(comment.PostId),
+		Version:   uint64(comment.Version),
+		CreatedAt: timestamppb.New(comment.CreatedAt),
+		UpdatedAt: timestamppb.New(comment.UpdatedAt),
+	}
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"errors"
+	"net/http"
+	"sh-manage/logging"
+	"sh-manage/utils"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code 把 AppError 的 HTTP 状态码映射为 gRPC 状态码
+func Code(appErr *utils.AppError) codes.Code {
+	switch appErr.Code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		// 版本冲突会附带服务端当前状态，客户端应重新读取后重试；其余冲突是唯一性约束
+		if appErr.ErrorCode == utils.ErrVersionConflict || appErr.Data != nil {
+			return codes.Aborted
+		}
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// Status 把服务层返回的错误转换为 gRPC 状态，与 utils.HandleError 一样不暴露未知错误的细节
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) {
+		logging.Errorf("grpc request failed: %v", err)
+		return status.Error(codes.Internal, "Internal server error")
+	}
+	if appErr.Err != nil || appErr.Code >= http.StatusInternalServerError {
+		logging.Errorf("grpc request failed: code=%s err=%v", appErr.StableCode(), appErr)
+	}
+	// 稳定错误码放在 ErrorInfo.Reason 中，与 REST 接口 problem 的 code 一致
+	st := status.New(Code(appErr), appErr.Message)
+	if detailed, e := st.WithDetails(&errdetails.ErrorInfo{Reason: string(appErr.StableCode()), Domain: errorDomain}); e == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// errorDomain ErrorInfo 的 Domain
+const errorDomain = "sh-manage"
+
+// appStatus 服务层返回 *utils.AppError 时使用，避免 nil 指针被转换为非 nil 的 error
+func appStatus(appErr *utils.AppError) error {
+	if appErr == nil {
+		return nil
+	}
+	return Status(appErr)
+}
+
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
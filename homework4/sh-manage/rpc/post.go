@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	shmanagev1 "sh-manage/api/shmanage/v1"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/services"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type postServer struct {
+	shmanagev1.UnimplementedPostServiceServer
+	posts *services.PostService
+}
+
+func (s *postServer) CreatePost(ctx context.Context, req *shmanagev1.CreatePostRequest) (*shmanagev1.Post, error) {
+	post := &dto.PostDto{Title: &req.Title, Content: &req.Content, Tags: req.Tags, Status: optionalString(req.Status)}
+	// 参数错误在服务层是 500，这里提前校验并返回 InvalidArgument
+	if appErr := post.Validate(); appErr != nil {
+		return nil, status.Error(codes.InvalidArgument, appErr.Message)
+	}
+	created, appErr := s.posts.WithContext(ginContext(ctx)).CreatePost(post)
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return toPost(created), nil
+}
+
+func (s *postServer) GetPost(ctx context.Context, req *shmanagev1.GetPostRequest) (*shmanagev1.Post, error) {
+	post, appErr := s.posts.WithContext(ginContext(ctx)).GetPostByID(uint(req.Id))
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return toPost(post), nil
+}
+
+func (s *postServer) ListPosts(ctx context.Context, req *shmanagev1.ListPostsRequest) (*shmanagev1.ListPostsResponse, error) {
+	query, err := pageQuery(req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	if req.OrderBy != "" {
+		query.OrderBy = req.OrderBy
+	}
+	if req.Order != "" {
+		query.Order = req.Order
+	}
+	page, appErr := s.posts.WithContext(ginContext(ctx)).GetPostByPage(&dto.PostPageDTO{BasePageQuery: query, Title: req.Title, Content: req.Content})
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	items := make([]*shmanagev1.Post, len(page.Items))
+	for i := range page.Items {
+		items[i] = toPost(&page.Items[i])
+	}
+	return &shmanagev1.ListPostsResponse{PageInfo: toPageInfo(page), Items: items}, nil
+}
+
+func (s *postServer) UpdatePost(ctx context.Context, req *shmanagev1.UpdatePostRequest) (*shmanagev1.Post, error) {
+	id, version := uint(req.Id), uint(req.Version)
+	post := &dto.PostDto{ID: &id, Title: &req.Title, Content: &req.Content, Status: optionalString(req.Status), Version: &version}
+	if req.Tags != nil {
+		// 空列表表示清空标签，与 nil（不修改）区分
+		post.Tags = append([]string{}, req.Tags.Names...)
+	}
+	if appErr := post.Validate(); appErr != nil {
+		return nil, status.Error(codes.InvalidArgument, appErr.Message)
+	}
+	updated, appErr := s.posts.WithContext(ginContext(ctx)).UpdatePost(post)
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return toPost(updated), nil
+}
+
+func (s *postServer) DeletePost(ctx context.Context, req *shmanagev1.DeletePostRequest) (*emptypb.Empty, error) {
+	if appErr := s.posts.WithContext(ginContext(ctx)).DeleteByID(uint(req.Id)); appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// pageQuery 分页参数为 0 时使用默认值，范围与 REST 接口一致
+func pageQuery(page, pageSize int32) (dto.BasePageQuery, error) {
+	query := *dto.NewBasePageQuery()
+	if page != 0 {
+		query.Page = int(page)
+	}
+	if pageSize != 0 {
+		query.PageSize = int(pageSize)
+	}
+	if query.Page < 1 || query.PageSize < 1 || query.PageSize > 100 {
+		return query, status.Error(codes.InvalidArgument, "page must be at least 1 and page_size between 1 and 100")
+	}
+	return query, nil
+}
+
+func toPageInfo[T any](page *dto.PageResult[T]) *shmanagev1.PageInfo {
+	return &shmanagev1.PageInfo{
+		Page:       int32(page.Page),
+		PageSize:   int32(page.PageSize),
+		Total:      page.Total,
+		TotalPages: int32(page.TotalPages),
+		HasNext:    page.HasNext,
+		HasPrev:    page.HasPrev,
+	}
+}
+
+func toPost(post *models.Post) *shmanagev1.Post {
+	result := &shmanagev1.Post{
+		Id:        uint64(post.ID),
+		Title:     post.Title,
+		Content:   post.Content,
+		UserId:    uint64(post.UserId),
+		Tags:      post.TagNames(),
+		Status:    post.Status,
+		Version:   uint64(post.Version),
+		CreatedAt: timestamppb.New(post.CreatedAt),
+		UpdatedAt: timestamppb.New(post.UpdatedAt),
+	}
+	if post.PublishedAt != nil {
+		result.PublishedAt = timestamppb.New(*post.PublishedAt)
+	}
+	return result
+}
+
+// optionalString proto3 的空字符串表示未设置
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,167 @@
+// Package rpc 与 REST 接口并行的 gRPC 服务，业务逻辑全部委托给 services 包
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime/debug"
+	shmanagev1 "sh-manage/api/shmanage/v1"
+	"sh-manage/consts"
+	"sh-manage/logging"
+	"sh-manage/middleware"
+	"sh-manage/services"
+	"sh-manage/tenant"
+	"sh-manage/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods 不需要认证的方法，携带令牌时仍会校验
+var publicMethods = map[string]bool{
+	shmanagev1.UserService_Register_FullMethodName:        true,
+	shmanagev1.UserService_Login_FullMethodName:           true,
+	shmanagev1.PostService_GetPost_FullMethodName:         true,
+	shmanagev1.PostService_ListPosts_FullMethodName:       true,
+	shmanagev1.CommentService_GetComment_FullMethodName:   true,
+	shmanagev1.CommentService_ListComments_FullMethodName: true,
+}
+
+// Services gRPC 服务委托的服务层实例
+type Services struct {
+	Users    *services.UserService
+	Posts    *services.PostService
+	Comments *services.CommentService
+}
+
+// interceptor 解析租户和令牌，并为每次调用构造服务层需要的 gin.Context
+type interceptor struct {
+	jwtKeys *utils.JWTKeys
+	tenants *middleware.Tenants
+	engine  *gin.Engine
+	users   *services.UserService
+}
+
+// NewServer 创建注册了用户、文章和评论服务的 gRPC 服务器
+func NewServer(svc Services, jwtKeys *utils.JWTKeys, tenants *middleware.Tenants, opts ...grpc.ServerOption) *grpc.Server {
+	engine := gin.New()
+	// ClientIP 只使用连接的对端地址，不信任元数据中的 X-Forwarded-For
+	_ = engine.SetTrustedProxies(nil)
+	i := &interceptor{jwtKeys: jwtKeys, tenants: tenants, engine: engine, users: svc.Users}
+
+	opts = append(opts, grpc.ChainUnaryInterceptor(recovery, i.unary))
+	server := grpc.NewServer(opts...)
+	shmanagev1.RegisterUserServiceServer(server, &userServer{users: svc.Users, jwtKeys: jwtKeys})
+	shmanagev1.RegisterPostServiceServer(server, &postServer{posts: svc.Posts})
+	shmanagev1.RegisterCommentServiceServer(server, &commentServer{posts: svc.Posts, comments: svc.Comments})
+	return server
+}
+
+// recovery 处理函数 panic 时返回 Internal，不中断连接上的其他调用
+func recovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Errorf("grpc %s panic: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "Internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func (i *interceptor) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	c, err := i.context(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.authenticate(c, publicMethods[info.FullMethod]); err != nil {
+		return nil, err
+	}
+	resp, err := handler(context.WithValue(c.Request.Context(), ginContextKey{}, c), req)
+	return resp, Status(err)
+}
+
+type ginContextKey struct{}
+
+// context 服务层通过 gin.Context 读取当前用户、租户、请求ID和客户端IP，这里用元数据构造一个等价的请求上下文
+func (i *interceptor) context(ctx context.Context) (*gin.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	if authority := md.Get(":authority"); len(authority) > 0 {
+		r.Host = authority[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+
+	c := gin.CreateTestContextOnly(nil, i.engine)
+	c.Request = r
+
+	requestID := r.Header.Get(consts.RequestIDHeader)
+	if requestID == "" || len(requestID) > 64 {
+		buf := make([]byte, 16)
+		_, _ = rand.Read(buf)
+		requestID = hex.EncodeToString(buf)
+	}
+	c.Set(consts.RequestID, requestID)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(consts.RequestIDHeader), requestID))
+
+	current, appErr := i.tenants.Resolve(r)
+	if appErr != nil {
+		return nil, appStatus(appErr)
+	}
+	c.Set(consts.TenantID, current.ID)
+	c.Set(consts.TenantSlug, current.Slug)
+	c.Request = r.WithContext(tenant.WithID(ctx, current.ID))
+	return c, nil
+}
+
+// authenticate 与 middleware.Auth 的 JWT 校验一致，public 为 true 时允许匿名调用
+func (i *interceptor) authenticate(c *gin.Context, public bool) error {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		if public {
+			return nil
+		}
+		return status.Error(codes.Unauthenticated, "Authorization metadata is missing")
+	}
+
+	scheme, token, ok := strings.Cut(authHeader, " ")
+	if !ok || scheme != consts.AuthTypePre {
+		return status.Error(codes.Unauthenticated, "Authorization metadata format must be Bearer {token}")
+	}
+	claims, err := i.jwtKeys.Parse(token)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, "Invalid token: "+err.Error())
+	}
+	if claims.TenantId != utils.GetCurrentTenantID(c) {
+		return status.Error(codes.Unauthenticated, "Token was issued for another tenant")
+	}
+
+	c.Set(consts.UserID, claims.UserId)
+	c.Set(consts.UserName, claims.Username)
+	// 停用或封禁的账号已签发的令牌也不能使用
+	if i.users != nil {
+		return appStatus(i.users.WithContext(c).EnsureActive(claims.UserId))
+	}
+	return nil
+}
+
+// ginContext 返回拦截器为本次调用构造的 gin.Context
+func ginContext(ctx context.Context) *gin.Context {
+	return ctx.Value(ginContextKey{}).(*gin.Context)
+}
@@ -0,0 +1,298 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	shmanagev1 "sh-manage/api/shmanage/v1"
+	"sh-manage/config"
+	"sh-manage/middleware"
+	"sh-manage/models"
+	"sh-manage/repository"
+	"sh-manage/services"
+	"sh-manage/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+)
+
+type nopAudit struct{}
+
+func (nopAudit) Record(*gin.Context, services.AuditEntry) {}
+
+type stubTenants map[string]uint
+
+func (s stubTenants) Resolve(slug string) (*models.Tenant, *utils.AppError) {
+	id, ok := s[slug]
+	if !ok {
+		return nil, utils.NewError(utils.ErrTenantNotFound, "Tenant not found")
+	}
+	return &models.Tenant{Model: gorm.Model{ID: id}, Slug: slug}, nil
+}
+
+type testClient struct {
+	users    shmanagev1.UserServiceClient
+	posts    shmanagev1.PostServiceClient
+	comments shmanagev1.CommentServiceClient
+}
+
+// newTestClient 使用内存存储启动 gRPC 服务，客户端通过 bufconn 连接，不占用端口
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	repos := repository.NewMemory().Repositories()
+	userService := services.NewUserServiceWithRepositories(repos, nil, nopAudit{})
+	svc := Services{
+		Users:    userService,
+		Posts:    services.NewPostServiceWithRepositories(repos, userService, nil, nopAudit{}),
+		Comments: services.NewCommentServiceWithRepositories(repos, userService, nopAudit{}),
+	}
+	jwtKeys := utils.NewJWTKeys([]byte("rpc-test-secret-0123456"), time.Hour)
+	tenants := middleware.NewTenants(stubTenants{"default": 1, "acme": 2}, config.LoadSimple())
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(svc, jwtKeys, tenants)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{
+		users:    shmanagev1.NewUserServiceClient(conn),
+		posts:    shmanagev1.NewPostServiceClient(conn),
+		comments: shmanagev1.NewCommentServiceClient(conn),
+	}
+}
+
+// withAuth 返回携带令牌和租户的 ctx，slug 为空时使用默认租户
+func withAuth(token, slug string) context.Context {
+	md := metadata.MD{}
+	if token != "" {
+		md.Set("authorization", "Bearer "+token)
+	}
+	if slug != "" {
+		md.Set("x-tenant-id", slug)
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+// login 注册并登录用户，返回用户ID和令牌
+func (tc *testClient) login(t *testing.T, slug, username string) (uint64, string) {
+	t.Helper()
+	ctx := withAuth("", slug)
+	if _, err := tc.users.Register(ctx, &shmanagev1.RegisterRequest{Username: username, Email: username + "@example.com", Password: "Correct-Horse-42"}); err != nil {
+		t.Fatalf("register %s: %v", username, err)
+	}
+	resp, err := tc.users.Login(ctx, &shmanagev1.LoginRequest{Username: username, Email: username + "@example.com", Password: "Correct-Horse-42"})
+	if err != nil {
+		t.Fatalf("login %s: %v", username, err)
+	}
+	return resp.User.Id, resp.Token
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("code = %v, want %v (%v)", got, want, err)
+	}
+}
+
+func TestUserService(t *testing.T) {
+	tc := newTestClient(t)
+	aliceID, alice := tc.login(t, "", "alice")
+
+	_, err := tc.users.Register(withAuth("", ""), &shmanagev1.RegisterRequest{Username: "alice", Email: "other@example.com", Password: "Correct-Horse-42"})
+	assertCode(t, err, codes.AlreadyExists)
+	_, err = tc.users.Register(withAuth("", ""), &shmanagev1.RegisterRequest{Username: "bo", Email: "bob@example.com", Password: "Correct-Horse-42"})
+	assertCode(t, err, codes.InvalidArgument)
+	_, err = tc.users.Login(withAuth("", ""), &shmanagev1.LoginRequest{Username: "alice", Email: "alice@example.com", Password: "wrong-password"})
+	assertCode(t, err, codes.Unauthenticated)
+
+	profile, err := tc.users.GetProfile(withAuth(alice, ""), &shmanagev1.GetProfileRequest{})
+	if err != nil || profile.Id != aliceID || profile.Email != "alice@example.com" {
+		t.Fatalf("profile = %v, err = %v", profile, err)
+	}
+
+	email := "alice@new.example.com"
+	updated, err := tc.users.UpdateProfile(withAuth(alice, ""), &shmanagev1.UpdateProfileRequest{Email: &email, Version: profile.Version})
+	if err != nil || updated.Email != email || updated.Version != profile.Version+1 {
+		t.Fatalf("update = %v, err = %v", updated, err)
+	}
+	_, err = tc.users.UpdateProfile(withAuth(alice, ""), &shmanagev1.UpdateProfileRequest{Email: &email, Version: profile.Version})
+	assertCode(t, err, codes.Aborted)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	tc := newTestClient(t)
+	_, alice := tc.login(t, "", "alice")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"missing token", withAuth("", ""), codes.Unauthenticated},
+		{"invalid token", withAuth("invalid", ""), codes.Unauthenticated},
+		{"token from other tenant", withAuth(alice, "acme"), codes.Unauthenticated},
+		{"unknown tenant", withAuth(alice, "missing"), codes.NotFound},
+		{"valid token", withAuth(alice, ""), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tc.users.GetProfile(tt.ctx, &shmanagev1.GetProfileRequest{})
+			assertCode(t, err, tt.want)
+		})
+	}
+
+	// 公开方法允许匿名调用，但携带的令牌仍然需要有效
+	if _, err := tc.posts.ListPosts(withAuth("", ""), &shmanagev1.ListPostsRequest{}); err != nil {
+		t.Fatalf("anonymous list: %v", err)
+	}
+	_, err := tc.posts.ListPosts(withAuth("invalid", ""), &shmanagev1.ListPostsRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+
+	// 响应头返回请求ID
+	var header metadata.MD
+	if _, err := tc.posts.ListPosts(withAuth("", ""), &shmanagev1.ListPostsRequest{}, grpc.Header(&header)); err != nil || len(header.Get("x-request-id")) != 1 {
+		t.Fatalf("request id header = %v, err = %v", header, err)
+	}
+}
+
+func TestPostAndCommentServices(t *testing.T) {
+	tc := newTestClient(t)
+	aliceID, alice := tc.login(t, "", "alice")
+	_, bob := tc.login(t, "", "bob")
+	_, carol := tc.login(t, "acme", "carol")
+
+	_, err := tc.posts.CreatePost(withAuth(alice, ""), &shmanagev1.CreatePostRequest{Title: "", Content: "c"})
+	assertCode(t, err, codes.InvalidArgument)
+
+	post, err := tc.posts.CreatePost(withAuth(alice, ""), &shmanagev1.CreatePostRequest{Title: "hello", Content: "grpc", Tags: []string{"go", "rpc"}})
+	if err != nil || post.UserId != aliceID || post.Status != "published" || len(post.Tags) != 2 || post.PublishedAt == nil {
+		t.Fatalf("create = %v, err = %v", post, err)
+	}
+	draft, err := tc.posts.CreatePost(withAuth(alice, ""), &shmanagev1.CreatePostRequest{Title: "draft", Content: "hidden", Status: "draft"})
+	if err != nil {
+		t.Fatalf("create draft: %v", err)
+	}
+
+	// 草稿对其他人不可见，其他租户看不到文章
+	_, err = tc.posts.GetPost(withAuth(bob, ""), &shmanagev1.GetPostRequest{Id: draft.Id})
+	assertCode(t, err, codes.NotFound)
+	_, err = tc.posts.GetPost(withAuth(carol, "acme"), &shmanagev1.GetPostRequest{Id: post.Id})
+	assertCode(t, err, codes.NotFound)
+
+	page, err := tc.posts.ListPosts(withAuth("", ""), &shmanagev1.ListPostsRequest{PageSize: 1})
+	if err != nil || page.PageInfo.Total != 1 || len(page.Items) != 1 || page.Items[0].Id != post.Id {
+		t.Fatalf("list = %v, err = %v", page, err)
+	}
+	_, err = tc.posts.ListPosts(withAuth("", ""), &shmanagev1.ListPostsRequest{PageSize: 1000})
+	assertCode(t, err, codes.InvalidArgument)
+
+	update := &shmanagev1.UpdatePostRequest{Id: post.Id, Title: "updated", Content: "grpc", Version: post.Version}
+	_, err = tc.posts.UpdatePost(withAuth(bob, ""), update)
+	assertCode(t, err, codes.PermissionDenied)
+	updated, err := tc.posts.UpdatePost(withAuth(alice, ""), update)
+	if err != nil || updated.Title != "updated" || len(updated.Tags) != 2 || updated.Version != post.Version+1 {
+		t.Fatalf("update without tags = %v, err = %v", updated, err)
+	}
+	_, err = tc.posts.UpdatePost(withAuth(alice, ""), update)
+	assertCode(t, err, codes.Aborted)
+	update.Version, update.Tags = updated.Version, &shmanagev1.TagList{}
+	if updated, err = tc.posts.UpdatePost(withAuth(alice, ""), update); err != nil || len(updated.Tags) != 0 {
+		t.Fatalf("clear tags = %v, err = %v", updated, err)
+	}
+
+	_, err = tc.comments.CreateComment(withAuth("", ""), &shmanagev1.CreateCommentRequest{PostId: post.Id, Content: "anonymous"})
+	assertCode(t, err, codes.Unauthenticated)
+	comment, err := tc.comments.CreateComment(withAuth(bob, ""), &shmanagev1.CreateCommentRequest{PostId: post.Id, Content: "nice"})
+	if err != nil || comment.PostId != post.Id {
+		t.Fatalf("create comment = %v, err = %v", comment, err)
+	}
+	comments, err := tc.comments.ListComments(withAuth("", ""), &shmanagev1.ListCommentsRequest{PostId: post.Id})
+	if err != nil || comments.PageInfo.Total != 1 || comments.Items[0].Content != "nice" {
+		t.Fatalf("list comments = %v, err = %v", comments, err)
+	}
+	_, err = tc.comments.ListComments(withAuth("", ""), &shmanagev1.ListCommentsRequest{PostId: draft.Id})
+	assertCode(t, err, codes.NotFound)
+
+	_, err = tc.comments.UpdateComment(withAuth(alice, ""), &shmanagev1.UpdateCommentRequest{Id: comment.Id, Content: "edited", Version: comment.Version})
+	assertCode(t, err, codes.PermissionDenied)
+	edited, err := tc.comments.UpdateComment(withAuth(bob, ""), &shmanagev1.UpdateCommentRequest{Id: comment.Id, Content: "edited", Version: comment.Version})
+	if err != nil || edited.Content != "edited" {
+		t.Fatalf("update comment = %v, err = %v", edited, err)
+	}
+	if _, err := tc.comments.DeleteComment(withAuth(bob, ""), &shmanagev1.DeleteCommentRequest{Id: comment.Id}); err != nil {
+		t.Fatalf("delete comment: %v", err)
+	}
+	_, err = tc.comments.GetComment(withAuth("", ""), &shmanagev1.GetCommentRequest{Id: comment.Id})
+	assertCode(t, err, codes.NotFound)
+
+	if _, err := tc.posts.DeletePost(withAuth(alice, ""), &shmanagev1.DeletePostRequest{Id: post.Id}); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	_, err = tc.posts.GetPost(withAuth(alice, ""), &shmanagev1.GetPostRequest{Id: post.Id})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{utils.NewAppError(http.StatusBadRequest, "bad"), codes.InvalidArgument},
+		{utils.NewAppError(http.StatusUnprocessableEntity, "invalid"), codes.InvalidArgument},
+		{utils.NewAppError(http.StatusUnauthorized, "login"), codes.Unauthenticated},
+		{utils.NewAppError(http.StatusForbidden, "forbidden"), codes.PermissionDenied},
+		{utils.NewAppError(http.StatusNotFound, "missing"), codes.NotFound},
+		{utils.NewAppError(http.StatusConflict, "exists"), codes.AlreadyExists},
+		{utils.NewAppErrorWithData(http.StatusConflict, "stale", "current"), codes.Aborted},
+		{utils.NewError(utils.ErrVersionConflict, ""), codes.Aborted},
+		{utils.NewAppError(http.StatusPreconditionFailed, "etag"), codes.FailedPrecondition},
+		{utils.NewAppError(http.StatusTooManyRequests, "slow down"), codes.ResourceExhausted},
+		{utils.NewAppError(http.StatusServiceUnavailable, "down"), codes.Unavailable},
+		{utils.NewAppError(http.StatusInternalServerError, "boom"), codes.Internal},
+		{utils.NewAppError(http.StatusTeapot, "teapot"), codes.Unknown},
+		{errors.New("database is gone"), codes.Internal},
+		{status.Error(codes.Canceled, "canceled"), codes.Canceled},
+	}
+	for _, tt := range tests {
+		if got := status.Code(Status(tt.err)); got != tt.want {
+			t.Errorf("Status(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+
+	// 未知错误不暴露细节
+	if msg := status.Convert(Status(errors.New("database is gone"))).Message(); msg != "Internal server error" {
+		t.Fatalf("message = %q", msg)
+	}
+	// 稳定错误码放在 ErrorInfo 中
+	details := status.Convert(Status(utils.NewError(utils.ErrPostNotFound, ""))).Details()
+	if len(details) != 1 {
+		t.Fatalf("details = %v", details)
+	}
+	if info, ok := details[0].(*errdetails.ErrorInfo); !ok || info.Reason != string(utils.ErrPostNotFound) {
+		t.Fatalf("details = %v", details)
+	}
+	if Status(nil) != nil || appStatus(nil) != nil {
+		t.Fatalf("nil error converted to status")
+	}
+}
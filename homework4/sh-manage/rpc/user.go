@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	shmanagev1 "sh-manage/api/shmanage/v1"
+	"sh-manage/models"
+	"sh-manage/services"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userServer struct {
+	shmanagev1.UnimplementedUserServiceServer
+	users   *services.UserService
+	jwtKeys *utils.JWTKeys
+}
+
+func (s *userServer) Register(ctx context.Context, req *shmanagev1.RegisterRequest) (*shmanagev1.User, error) {
+	create := models.CreateUserRequest{Username: req.Username, Email: req.Email, Password: req.Password}
+	// 与 REST 接口的请求体使用相同的校验规则
+	if err := binding.Validator.ValidateStruct(&create); err != nil {
+		return nil, invalidArgument(err)
+	}
+	user, err := s.users.WithContext(ginContext(ctx)).CreateUser(create)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *userServer) Login(ctx context.Context, req *shmanagev1.LoginRequest) (*shmanagev1.LoginResponse, error) {
+	login := models.LoginRequest{Username: req.Username, Email: req.Email, Password: req.Password}
+	if err := binding.Validator.ValidateStruct(&login); err != nil {
+		return nil, invalidArgument(err)
+	}
+	user, err := s.users.WithContext(ginContext(ctx)).Authenticate(login.Username, login.Password)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.jwtKeys.Sign(user.ID, user.TenantID, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	return &shmanagev1.LoginResponse{Token: token, User: toUser(user)}, nil
+}
+
+func (s *userServer) GetProfile(ctx context.Context, _ *shmanagev1.GetProfileRequest) (*shmanagev1.User, error) {
+	c := ginContext(ctx)
+	user, err := s.users.WithContext(c).GetUserByID(utils.GetCurrentUserID(c))
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *userServer) UpdateProfile(ctx context.Context, req *shmanagev1.UpdateProfileRequest) (*shmanagev1.User, error) {
+	c := ginContext(ctx)
+	version := uint(req.Version)
+	update := models.UpdateUserRequest{Email: req.Email, Password: req.Password, Version: &version}
+	if err := binding.Validator.ValidateStruct(&update); err != nil {
+		return nil, invalidArgument(err)
+	}
+	user, err := s.users.WithContext(c).UpdateUser(utils.GetCurrentUserID(c), update)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func toUser(user *models.User) *shmanagev1.User {
+	return &shmanagev1.User{
+		Id:        uint64(user.ID),
+		Username:  user.Username,
+		Email:     user.Email,
+		Version:   uint64(user.Version),
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}
+}
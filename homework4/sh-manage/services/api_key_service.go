@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// API Key 格式: shm_<8位十六进制前缀>_<随机密钥>，前缀明文保存用于查找和展示
+const apiKeyPrefix = "shm_"
+
+type ApiKeyService struct {
+	db *gorm.DB
+}
+
+func NewApiKeyService(db *gorm.DB) *ApiKeyService {
+	return &ApiKeyService{db: db}
+}
+
+// WithContext 返回绑定当前请求的副本，只能查找和管理请求所属租户的 API Key
+func (s *ApiKeyService) WithContext(c *gin.Context) *ApiKeyService {
+	return &ApiKeyService{db: s.db.WithContext(requestContext(c))}
+}
+
+// CreateApiKey 创建 API Key，返回的明文 key 只在创建时出现一次
+func (s *ApiKeyService) CreateApiKey(userID uint, req *dto.CreateApiKeyDto) (*models.ApiKey, string, *utils.AppError) {
+	if req == nil {
+		return nil, "", utils.NewError(utils.ErrBadRequest, "参数不能为空")
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, "", utils.NewError(utils.ErrInvalidExpiry, "过期时间不能早于当前时间")
+	}
+
+	prefix, rawKey, e := generateApiKey()
+	if e != nil {
+		return nil, "", utils.NewError(utils.ErrInternal, "Failed to generate api key").Wrap(e)
+	}
+
+	apiKey := &models.ApiKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashApiKey(rawKey),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+		UserId:    userID,
+	}
+	if err := s.db.Create(apiKey).Error; err != nil {
+		return nil, "", utils.NewError(utils.ErrInternal, "Failed to create api key").Wrap(err)
+	}
+	return apiKey, rawKey, nil
+}
+
+func (s *ApiKeyService) ListApiKeys(userID uint) ([]models.ApiKey, *utils.AppError) {
+	var keys []models.ApiKey
+	if err := s.db.Where("user_id = ?", userID).Order("id desc").Find(&keys).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve api keys").Wrap(err)
+	}
+	return keys, nil
+}
+
+func (s *ApiKeyService) GetApiKey(userID, keyID uint) (*models.ApiKey, *utils.AppError) {
+	var apiKey models.ApiKey
+	if err := s.db.Where("user_id = ?", userID).First(&apiKey, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewError(utils.ErrApiKeyNotFound, "Api key not found")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve api key").Wrap(err)
+	}
+	return &apiKey, nil
+}
+
+func (s *ApiKeyService) UpdateApiKey(userID, keyID uint, req *dto.UpdateApiKeyDto) (*models.ApiKey, *utils.AppError) {
+	if req == nil {
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
+	}
+	apiKey, err := s.GetApiKey(userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		apiKey.Name = *req.Name
+	}
+	if req.Scopes != nil {
+		scopes, err := normalizeScopes(req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		apiKey.Scopes = strings.Join(scopes, ",")
+	}
+	if req.ExpiresAt != nil {
+		if req.ExpiresAt.Before(time.Now()) {
+			return nil, utils.NewError(utils.ErrInvalidExpiry, "过期时间不能早于当前时间")
+		}
+		apiKey.ExpiresAt = req.ExpiresAt
+	}
+
+	if err := s.db.Save(apiKey).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update api key").Wrap(err)
+	}
+	return apiKey, nil
+}
+
+func (s *ApiKeyService) DeleteApiKey(userID, keyID uint) *utils.AppError {
+	result := s.db.Where("user_id = ?", userID).Delete(&models.ApiKey{}, keyID)
+	if result.Error != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to delete api key").Wrap(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewError(utils.ErrApiKeyNotFound, "Api key not found")
+	}
+	return nil
+}
+
+// Authenticate 校验明文 API Key，成功后记录最后使用时间
+func (s *ApiKeyService) Authenticate(rawKey string) (*models.ApiKey, *utils.AppError) {
+	prefix, ok := parseApiKeyPrefix(rawKey)
+	if !ok {
+		return nil, utils.NewError(utils.ErrApiKeyInvalid, "Invalid api key")
+	}
+
+	var apiKey models.ApiKey
+	if err := s.db.Preload("User").Where("prefix = ?", prefix).First(&apiKey).Error; err != nil {
+		return nil, utils.NewError(utils.ErrApiKeyInvalid, "Invalid api key")
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashApiKey(rawKey))) != 1 {
+		return nil, utils.NewError(utils.ErrApiKeyInvalid, "Invalid api key")
+	}
+
+	now := time.Now()
+	if apiKey.IsExpired(now) {
+		return nil, utils.NewError(utils.ErrApiKeyInvalid, "Api key expired")
+	}
+
+	// 只更新 last_used_at，不影响 updated_at
+	if err := s.db.Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update api key").Wrap(err)
+	}
+	apiKey.LastUsedAt = &now
+	return &apiKey, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, *utils.AppError) {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(consts.GrantableScopes, scope) {
+			return nil, utils.NewError(utils.ErrInvalidScope, "Invalid scope: "+scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, utils.NewError(utils.ErrInvalidScope, "Scopes不能为空")
+	}
+	return result, nil
+}
+
+func generateApiKey() (string, string, error) {
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix := apiKeyPrefix + hex.EncodeToString(prefixBytes)
+	return prefix, prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func parseApiKeyPrefix(rawKey string) (string, bool) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return "", false
+	}
+	idx := strings.Index(rawKey[len(apiKeyPrefix):], "_")
+	if idx <= 0 {
+		return "", false
+	}
+	return rawKey[:len(apiKeyPrefix)+idx], true
+}
+
+// API Key 本身是高熵随机串，使用 SHA-256 保存即可，无需慢哈希
+func hashApiKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiKeyCreateAndAuthenticate(t *testing.T) {
+	db := newTestDB(t)
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	db.Create(alice)
+	svc := NewApiKeyService(db)
+
+	tests := []struct {
+		name     string
+		req      *dto.CreateApiKeyDto
+		wantCode int
+	}{
+		{"nil request", nil, 400},
+		{"unknown scope", &dto.CreateApiKeyDto{Name: "ci", Scopes: []string{"posts:write", "admin"}}, 400},
+		{"no scopes", &dto.CreateApiKeyDto{Name: "ci", Scopes: []string{" "}}, 400},
+		{"expired", &dto.CreateApiKeyDto{Name: "ci", Scopes: []string{consts.ScopePostsRead}, ExpiresAt: ptr(time.Now().Add(-time.Minute))}, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := svc.CreateApiKey(alice.ID, tt.req); appErrorCode(err) != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+		})
+	}
+
+	apiKey, rawKey, err := svc.CreateApiKey(alice.ID, &dto.CreateApiKeyDto{
+		Name:   "ci",
+		Scopes: []string{consts.ScopePostsRead, consts.ScopePostsWrite, consts.ScopePostsRead},
+	})
+	if err != nil {
+		t.Fatalf("CreateApiKey: %v", err)
+	}
+	if !strings.HasPrefix(rawKey, apiKey.Prefix+"_") || apiKey.Scopes != "posts:read,posts:write" {
+		t.Fatalf("key = %q, model = %+v", rawKey, apiKey)
+	}
+
+	// 数据库中只保存前缀和哈希，不保存明文
+	var stored models.ApiKey
+	db.First(&stored, apiKey.ID)
+	if stored.KeyHash != hashApiKey(rawKey) || strings.Contains(stored.KeyHash, rawKey[len(apiKey.Prefix)+1:]) {
+		t.Fatalf("stored hash = %q", stored.KeyHash)
+	}
+
+	authenticated, err := svc.Authenticate(rawKey)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if authenticated.UserId != alice.ID || authenticated.User.Username != "alice" || authenticated.LastUsedAt == nil {
+		t.Fatalf("authenticated = %+v", authenticated)
+	}
+
+	for _, key := range []string{"", "shm_", "Bearer " + rawKey, apiKey.Prefix + "_wrong", "shm_00000000_" + rawKey[len(apiKey.Prefix)+1:]} {
+		if _, err := svc.Authenticate(key); err == nil || err.ErrorCode != utils.ErrApiKeyInvalid {
+			t.Fatalf("Authenticate(%q) = %v, want %s", key, err, utils.ErrApiKeyInvalid)
+		}
+	}
+}
+
+func TestApiKeyExpiryAndRevocation(t *testing.T) {
+	db := newTestDB(t)
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	db.Create(alice)
+	db.Create(bob)
+	svc := NewApiKeyService(db)
+
+	apiKey, rawKey, err := svc.CreateApiKey(alice.ID, &dto.CreateApiKeyDto{
+		Name:      "ci",
+		Scopes:    []string{consts.ScopePostsRead},
+		ExpiresAt: ptr(time.Now().Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("CreateApiKey: %v", err)
+	}
+	if _, err := svc.Authenticate(rawKey); err != nil {
+		t.Fatalf("Authenticate before expiry: %v", err)
+	}
+
+	db.Model(&models.ApiKey{}).Where("id = ?", apiKey.ID).UpdateColumn("expires_at", time.Now().Add(-time.Second))
+	if _, err := svc.Authenticate(rawKey); err == nil || err.ErrorCode != utils.ErrApiKeyInvalid {
+		t.Fatalf("expired key = %v", err)
+	}
+
+	// 续期后可以继续使用
+	if _, err := svc.UpdateApiKey(alice.ID, apiKey.ID, &dto.UpdateApiKeyDto{ExpiresAt: ptr(time.Now().Add(time.Hour))}); err != nil {
+		t.Fatalf("UpdateApiKey: %v", err)
+	}
+	if _, err := svc.Authenticate(rawKey); err != nil {
+		t.Fatalf("Authenticate after renewal: %v", err)
+	}
+
+	// 只有所有者可以查看和删除
+	if _, err := svc.GetApiKey(bob.ID, apiKey.ID); err == nil || err.ErrorCode != utils.ErrApiKeyNotFound {
+		t.Fatalf("other user GetApiKey = %v", err)
+	}
+	if err := svc.DeleteApiKey(bob.ID, apiKey.ID); err == nil || err.ErrorCode != utils.ErrApiKeyNotFound {
+		t.Fatalf("other user DeleteApiKey = %v", err)
+	}
+	if err := svc.DeleteApiKey(alice.ID, apiKey.ID); err != nil {
+		t.Fatalf("DeleteApiKey: %v", err)
+	}
+	if _, err := svc.Authenticate(rawKey); err == nil || err.ErrorCode != utils.ErrApiKeyInvalid {
+		t.Fatalf("revoked key = %v", err)
+	}
+	if keys, _ := svc.ListApiKeys(alice.ID); len(keys) != 0 {
+		t.Fatalf("keys after revocation = %v", keys)
+	}
+}
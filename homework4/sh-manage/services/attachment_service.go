@@ -0,0 +1,304 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/storage"
+	"sh-manage/thumbnail"
+	"sh-manage/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 未配置时单个附件的最大字节数
+const defaultMaxAttachmentSize = 10 << 20
+
+var defaultAllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf", "text/plain"}
+
+var defaultThumbnails = map[string]int{"small": 150, "medium": 480, "large": 1024}
+
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// 可以生成缩略图的类型
+var thumbnailTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true}
+
+type AttachmentService struct {
+	db           *gorm.DB
+	storage      storage.Storage
+	postService  *PostService
+	maxSize      int64
+	allowedTypes map[string]bool
+	thumbnails   []thumbnail.Size
+	context      *gin.Context
+	auditService *AuditService
+}
+
+// postService 用于按当前用户检查文章是否可见
+func NewAttachmentService(db *gorm.DB, store storage.Storage, postService *PostService, cfg config.StorageConfig) *AttachmentService {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultMaxAttachmentSize
+	}
+	if len(cfg.AllowedTypes) == 0 {
+		cfg.AllowedTypes = defaultAllowedTypes
+	}
+	if cfg.Thumbnails == nil {
+		cfg.Thumbnails = defaultThumbnails
+	}
+
+	allowedTypes := make(map[string]bool, len(cfg.AllowedTypes))
+	for _, contentType := range cfg.AllowedTypes {
+		allowedTypes[strings.ToLower(contentType)] = true
+	}
+	return &AttachmentService{
+		db:           db,
+		storage:      store,
+		postService:  postService,
+		maxSize:      cfg.MaxSize,
+		allowedTypes: allowedTypes,
+		thumbnails:   thumbnail.SizesFromMap(cfg.Thumbnails),
+		auditService: NewAuditService(db),
+	}
+}
+
+// WithContext 返回绑定当前请求的副本，用于获取当前用户、记录审计日志和按租户限定查询
+func (s *AttachmentService) WithContext(c *gin.Context) *AttachmentService {
+	clone := *s
+	clone.context = c
+	clone.db = s.db.WithContext(requestContext(c))
+	return &clone
+}
+
+func (s *AttachmentService) currentUserID() uint {
+	if s.context == nil {
+		return 0
+	}
+	return utils.GetCurrentUserID(s.context)
+}
+
+func (s *AttachmentService) requestContext() context.Context {
+	if s.context == nil || s.context.Request == nil {
+		return context.Background()
+	}
+	return s.context.Request.Context()
+}
+
+// MaxSize 单个附件的最大字节数
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// URL 存储 key 对应的访问地址
+func (s *AttachmentService) URL(key string) string {
+	return s.storage.URL(key)
+}
+
+// Upload 上传附件，只有文章作者可以上传；文件类型按内容识别，不信任客户端声明的类型
+func (s *AttachmentService) Upload(postID uint, fileName string, r io.Reader) (*models.Attachment, *utils.AppError) {
+	var post models.Post
+	if err := s.db.First(&post, postID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewError(utils.ErrPostNotFound, "Post not found")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve Post").Wrap(err)
+	}
+	if post.UserId != s.currentUserID() {
+		return nil, utils.NewError(utils.ErrNotAuthor, "Only the author can upload attachments")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInvalidUpload, "Failed to read upload")
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, utils.NewError(utils.ErrPayloadTooLarge, fmt.Sprintf("File is larger than %d bytes", s.maxSize))
+	}
+	if len(data) == 0 {
+		return nil, utils.NewError(utils.ErrInvalidUpload, "File is empty")
+	}
+
+	contentType := detectContentType(data)
+	if !s.allowedTypes[contentType] {
+		return nil, utils.NewError(utils.ErrFileTypeNotAllowed, fmt.Sprintf("File type %s is not allowed", contentType))
+	}
+
+	fileName = sanitizeFileName(fileName)
+	base, e := randomHex(16)
+	if e != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to generate file name").Wrap(e)
+	}
+	prefix := fmt.Sprintf("attachments/%d/%s", postID, base)
+
+	attachment := &models.Attachment{
+		PostId:      postID,
+		UserId:      s.currentUserID(),
+		FileName:    fileName,
+		StorageKey:  prefix + extensionFor(contentType),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	objects := map[string][]byte{attachment.StorageKey: data}
+	objectTypes := map[string]string{attachment.StorageKey: contentType}
+	if thumbnailTypes[contentType] && len(s.thumbnails) > 0 {
+		width, height, thumbnails, err := thumbnail.Generate(data, s.thumbnails)
+		if err != nil {
+			return nil, utils.NewError(utils.ErrFileTypeNotAllowed, "Invalid image file")
+		}
+		attachment.Width, attachment.Height = width, height
+		for _, t := range thumbnails {
+			key := fmt.Sprintf("%s_%s%s", prefix, t.Name, extensionFor(t.ContentType))
+			objects[key] = t.Data
+			objectTypes[key] = t.ContentType
+			attachment.Thumbnails = append(attachment.Thumbnails, models.AttachmentThumbnail{
+				Name:       t.Name,
+				StorageKey: key,
+				Width:      t.Width,
+				Height:     t.Height,
+			})
+		}
+	}
+
+	ctx := s.requestContext()
+	stored := make([]string, 0, len(objects))
+	for key, body := range objects {
+		if err := s.storage.Put(ctx, key, bytes.NewReader(body), int64(len(body)), objectTypes[key]); err != nil {
+			log.Printf("Failed to store attachment %s: %v", key, err)
+			s.deleteObjects(stored)
+			return nil, utils.NewError(utils.ErrInternal, "Failed to store attachment").Wrap(err)
+		}
+		stored = append(stored, key)
+	}
+
+	if err := s.db.Create(attachment).Error; err != nil {
+		s.deleteObjects(stored)
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create attachment").Wrap(err)
+	}
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditAttachmentCreate,
+		EntityType: consts.EntityAttachment,
+		EntityID:   attachment.ID,
+		After:      attachment.ToResponse(s.URL),
+	})
+	return attachment, nil
+}
+
+// ListByPost 列出文章的附件，文章对当前用户不可见（如他人的草稿）时返回 404
+func (s *AttachmentService) ListByPost(postID uint) ([]models.Attachment, *utils.AppError) {
+	if _, err := s.postService.WithContext(s.context).GetPostByID(postID); err != nil {
+		return nil, err
+	}
+
+	var attachments []models.Attachment
+	if err := s.db.Where("post_id = ?", postID).Order("id asc").Find(&attachments).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve attachments").Wrap(err)
+	}
+	return attachments, nil
+}
+
+func (s *AttachmentService) GetAttachmentByID(attachmentID uint) (*models.Attachment, *utils.AppError) {
+	var attachment models.Attachment
+	if err := s.db.First(&attachment, attachmentID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewError(utils.ErrAttachmentNotFound, "Attachment not found")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve attachment").Wrap(err)
+	}
+	return &attachment, nil
+}
+
+// DeleteByID 删除附件记录及存储中的原文件和缩略图
+func (s *AttachmentService) DeleteByID(attachmentID uint) *utils.AppError {
+	attachment, err := s.GetAttachmentByID(attachmentID)
+	if err != nil {
+		return err
+	}
+	if attachment.UserId != s.currentUserID() {
+		return utils.NewError(utils.ErrNotAuthor, "Only the author can delete this attachment")
+	}
+
+	if err := s.db.Delete(&models.Attachment{}, attachmentID).Error; err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to delete attachment").Wrap(err)
+	}
+	s.deleteObjects(attachment.StorageKeys())
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditAttachmentDelete,
+		EntityType: consts.EntityAttachment,
+		EntityID:   attachmentID,
+		Before:     attachment.ToResponse(s.URL),
+	})
+	return nil
+}
+
+// deleteObjects 清理存储中的文件，失败只记录日志
+func (s *AttachmentService) deleteObjects(keys []string) {
+	ctx := s.requestContext()
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete attachment object %s: %v", key, err)
+		}
+	}
+}
+
+// detectContentType 根据文件内容识别 MIME 类型，去掉 charset 等参数
+func detectContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
+
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+// extensionFor 存储 key 的扩展名由识别出的类型决定，避免上传 .html 等文件被静态服务按网页输出
+func extensionFor(contentType string) string {
+	if ext, ok := attachmentExtensions[contentType]; ok {
+		return ext
+	}
+	return ".bin"
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
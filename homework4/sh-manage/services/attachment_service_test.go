@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/storage"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x*height/width, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAttachmentUpload(t *testing.T) {
+	db := newTestDB(t)
+	post := &models.Post{Title: "t", Content: "c", UserId: 1, Status: consts.PostStatusPublished}
+	db.Create(post)
+
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir, "/static/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewAttachmentService(db, store, NewPostService(db, NewUserService(db, nil), nil, nil), config.StorageConfig{
+		MaxSize:    64 << 10,
+		Thumbnails: map[string]int{"small": 50, "large": 400},
+	})
+	asUser := func(userID uint) *AttachmentService {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/", nil)
+		c.Set(consts.UserID, userID)
+		return svc.WithContext(c)
+	}
+
+	attachment, appErr := asUser(1).Upload(post.ID, "../photo.png", bytes.NewReader(testPNG(t, 200, 100)))
+	if appErr != nil {
+		t.Fatalf("upload: %v", appErr)
+	}
+	if attachment.FileName != "photo.png" || attachment.ContentType != "image/png" || attachment.Width != 200 || attachment.Height != 100 {
+		t.Fatalf("attachment = %+v", attachment)
+	}
+	sizes := map[string][2]int{}
+	for _, thumbnail := range attachment.Thumbnails {
+		sizes[thumbnail.Name] = [2]int{thumbnail.Width, thumbnail.Height}
+	}
+	// 小于规格的图片不放大
+	if sizes["small"] != [2]int{50, 25} || sizes["large"] != [2]int{200, 100} {
+		t.Fatalf("thumbnail sizes = %v", sizes)
+	}
+	for _, key := range attachment.StorageKeys() {
+		if _, err := os.Stat(filepath.Join(dir, key)); err != nil {
+			t.Fatalf("stored file %s: %v", key, err)
+		}
+	}
+	response := attachment.ToResponse(svc.URL)
+	if !strings.HasPrefix(response.Thumbnails["small"].URL, "/static/uploads/attachments/") {
+		t.Fatalf("thumbnail url = %q", response.Thumbnails["small"].URL)
+	}
+
+	// 按内容识别类型，扩展名不可信
+	if _, appErr := asUser(1).Upload(post.ID, "page.png", strings.NewReader("<html><script>alert(1)</script></html>")); appErr == nil || appErr.Code != 415 {
+		t.Fatalf("html upload = %v, want 415", appErr)
+	}
+	if _, appErr := asUser(1).Upload(post.ID, "big.txt", strings.NewReader(strings.Repeat("a", 65<<10))); appErr == nil || appErr.Code != 413 {
+		t.Fatalf("large upload = %v, want 413", appErr)
+	}
+	if _, appErr := asUser(2).Upload(post.ID, "a.txt", strings.NewReader("hello")); appErr == nil || appErr.Code != 403 {
+		t.Fatalf("upload by other user = %v, want 403", appErr)
+	}
+
+	text, appErr := asUser(1).Upload(post.ID, "notes.html", strings.NewReader("plain notes"))
+	if appErr != nil {
+		t.Fatalf("text upload: %v", appErr)
+	}
+	if !strings.HasSuffix(text.StorageKey, ".txt") || len(text.Thumbnails) != 0 {
+		t.Fatalf("text attachment = %+v", text)
+	}
+
+	if appErr := asUser(1).DeleteByID(attachment.ID); appErr != nil {
+		t.Fatalf("delete: %v", appErr)
+	}
+	for _, key := range attachment.StorageKeys() {
+		if _, err := os.Stat(filepath.Join(dir, key)); !os.IsNotExist(err) {
+			t.Fatalf("file %s still exists after delete", key)
+		}
+	}
+	list, _ := asUser(2).ListByPost(post.ID)
+	if len(list) != 1 || list[0].ID != text.ID {
+		t.Fatalf("attachments after delete = %+v", list)
+	}
+
+	// 草稿的附件只有作者可以列出
+	db.Model(post).Update("status", consts.PostStatusDraft)
+	if _, appErr := asUser(1).ListByPost(post.ID); appErr != nil {
+		t.Fatalf("list draft attachments by author: %v", appErr)
+	}
+	if _, appErr := asUser(2).ListByPost(post.ID); appErr == nil || appErr.Code != 404 {
+		t.Fatalf("list draft attachments by other user = %v, want 404", appErr)
+	}
+	if _, appErr := asUser(1).ListByPost(999); appErr == nil || appErr.Code != 404 {
+		t.Fatalf("list attachments of missing post = %v, want 404", appErr)
+	}
+}
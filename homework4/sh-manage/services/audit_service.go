@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/tools"
+	"sh-manage/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 单次导出的最大行数
+const auditExportLimit = 100000
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// WithContext 返回限定在当前请求租户内的副本，查询和导出只能看到本租户的审计日志
+func (s *AuditService) WithContext(c *gin.Context) *AuditService {
+	return &AuditService{db: s.db.WithContext(requestContext(c))}
+}
+
+// AuditRecorder 写入审计日志，服务通过该接口记录操作，测试中可以替换为内存实现
+type AuditRecorder interface {
+	Record(c *gin.Context, entry AuditEntry)
+}
+
+// AuditEntry 一条待写入的审计记录，before/after 为变更前后的实体快照
+type AuditEntry struct {
+	ActorID    uint
+	ActorName  string
+	Action     string
+	EntityType string
+	EntityID   uint
+	Before     interface{}
+	After      interface{}
+}
+
+// Record 写入审计日志；c 不为空时从请求中补充操作人、IP、请求ID 和所属租户
+// 审计失败只记录日志，不影响业务操作
+func (s *AuditService) Record(c *gin.Context, entry AuditEntry) {
+	db := s.db
+	auditLog := &models.AuditLog{
+		ActorId:    entry.ActorID,
+		ActorName:  entry.ActorName,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityId:   entry.EntityID,
+		Before:     marshalSnapshot(entry.Before),
+		After:      marshalSnapshot(entry.After),
+	}
+	if c != nil {
+		if auditLog.ActorId == 0 {
+			auditLog.ActorId = utils.GetCurrentUserID(c)
+			if name := utils.GetCurrentUserName(c); name != "" {
+				auditLog.ActorName = name
+			}
+		}
+		auditLog.IP = c.ClientIP()
+		auditLog.RequestID = utils.GetRequestID(c)
+		db = s.db.WithContext(requestContext(c))
+	}
+
+	if err := db.Create(auditLog).Error; err != nil {
+		log.Printf("Failed to write audit log %s %s#%d: %v", entry.Action, entry.EntityType, entry.EntityID, err)
+	}
+}
+
+func (s *AuditService) GetAuditLogByPage(query *dto.AuditLogPageDTO) (*dto.PageResult[models.AuditLog], *utils.AppError) {
+	var logs []models.AuditLog
+	return tools.Paginate(s.filter(query), query.BasePageQuery, &logs)
+}
+
+// ExportCSV 按查询条件导出审计日志为CSV，分批读取避免一次加载全部数据
+func (s *AuditService) ExportCSV(w io.Writer, query *dto.AuditLogPageDTO) *utils.AppError {
+	writer := csv.NewWriter(w)
+	header := []string{"id", "created_at", "actor_id", "actor_name", "action", "entity_type", "entity_id", "before", "after", "ip", "request_id"}
+	if err := writer.Write(header); err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to export audit logs").Wrap(err)
+	}
+
+	var batch []models.AuditLog
+	result := s.filter(query).Order("id asc").Limit(auditExportLimit).FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, item := range batch {
+			row := []string{
+				strconv.FormatUint(uint64(item.ID), 10),
+				item.CreatedAt.Format(time.RFC3339),
+				strconv.FormatUint(uint64(item.ActorId), 10),
+				item.ActorName,
+				item.Action,
+				item.EntityType,
+				strconv.FormatUint(uint64(item.EntityId), 10),
+				string(item.Before),
+				string(item.After),
+				item.IP,
+				item.RequestID,
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if result.Error != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to export audit logs").Wrap(result.Error)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to export audit logs").Wrap(err)
+	}
+	return nil
+}
+
+func (s *AuditService) filter(query *dto.AuditLogPageDTO) *gorm.DB {
+	db := s.db.Model(&models.AuditLog{})
+	if query.ActorID != nil {
+		db = db.Where("actor_id = ?", *query.ActorID)
+	}
+	if query.Action != nil && *query.Action != "" {
+		db = db.Where("action = ?", *query.Action)
+	}
+	if query.EntityType != nil && *query.EntityType != "" {
+		db = db.Where("entity_type = ?", *query.EntityType)
+	}
+	if query.EntityID != nil {
+		db = db.Where("entity_id = ?", *query.EntityID)
+	}
+	if query.From != nil {
+		db = db.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("created_at < ?", *query.To)
+	}
+	return db
+}
+
+func marshalSnapshot(snapshot interface{}) json.RawMessage {
+	if snapshot == nil {
+		return nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"net/http/httptest"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditRecord(t *testing.T) {
+	db := newTestDB(t)
+	svc := NewAuditService(db)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PUT", "/api/v1/posts/7", nil)
+	c.Request.RemoteAddr = "203.0.113.9:1234"
+	c.Set(consts.UserID, uint(3))
+	c.Set(consts.UserName, "alice")
+	c.Set(consts.RequestID, "req-1")
+
+	svc.Record(c, AuditEntry{
+		Action:     consts.AuditPostUpdate,
+		EntityType: consts.EntityPost,
+		EntityID:   7,
+		Before:     gin.H{"title": "old"},
+		After:      gin.H{"title": "new"},
+	})
+	// 没有请求上下文时使用传入的操作人
+	svc.Record(nil, AuditEntry{ActorID: 5, ActorName: "bob", Action: consts.AuditUserLogin, EntityType: consts.EntityUser, EntityID: 5})
+
+	var logs []models.AuditLog
+	db.Order("id").Find(&logs)
+	if len(logs) != 2 {
+		t.Fatalf("logs = %d, want 2", len(logs))
+	}
+	got := logs[0]
+	if got.ActorId != 3 || got.ActorName != "alice" || got.IP != "203.0.113.9" || got.RequestID != "req-1" ||
+		string(got.Before) != `{"title":"old"}` || string(got.After) != `{"title":"new"}` {
+		t.Fatalf("log = %+v", got)
+	}
+	if logs[1].ActorId != 5 || logs[1].ActorName != "bob" || logs[1].Before != nil {
+		t.Fatalf("log without context = %+v", logs[1])
+	}
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	db := newTestDB(t)
+	NewAuditService(db).Record(nil, AuditEntry{ActorID: 1, Action: consts.AuditUserLogin, EntityType: consts.EntityUser, EntityID: 1})
+
+	var entry models.AuditLog
+	db.First(&entry)
+	entry.Action = "tampered"
+	if err := db.Save(&entry).Error; !errors.Is(err, models.ErrAuditLogAppendOnly) {
+		t.Fatalf("Save = %v, want append-only error", err)
+	}
+	if err := db.Model(&entry).Update("action", "tampered").Error; !errors.Is(err, models.ErrAuditLogAppendOnly) {
+		t.Fatalf("Update = %v, want append-only error", err)
+	}
+	if err := db.Delete(&entry).Error; !errors.Is(err, models.ErrAuditLogAppendOnly) {
+		t.Fatalf("Delete = %v, want append-only error", err)
+	}
+
+	var stored models.AuditLog
+	db.First(&stored, entry.ID)
+	if stored.Action != consts.AuditUserLogin {
+		t.Fatalf("stored action = %q", stored.Action)
+	}
+}
+
+// seedAuditLogs 写入三条记录，创建时间分别为 base、base+1h、base+2h
+func seedAuditLogs(t *testing.T, svc *AuditService, base time.Time) {
+	t.Helper()
+	entries := []AuditEntry{
+		{ActorID: 1, ActorName: "alice", Action: consts.AuditPostCreate, EntityType: consts.EntityPost, EntityID: 10, After: gin.H{"title": "a, \"quoted\""}},
+		{ActorID: 1, ActorName: "alice", Action: consts.AuditPostUpdate, EntityType: consts.EntityPost, EntityID: 10},
+		{ActorID: 2, ActorName: "bob", Action: consts.AuditCommentCreate, EntityType: consts.EntityComment, EntityID: 20},
+	}
+	for i, entry := range entries {
+		svc.Record(nil, entry)
+		// 审计日志不允许更新，直接修改测试数据的创建时间
+		svc.db.Exec("UPDATE audit_logs SET created_at = ? WHERE id = ?", base.Add(time.Duration(i)*time.Hour), i+1)
+	}
+}
+
+func TestAuditQueryAndExport(t *testing.T) {
+	db := newTestDB(t)
+	svc := NewAuditService(db)
+	base := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	seedAuditLogs(t, svc, base)
+
+	tests := []struct {
+		name    string
+		query   dto.AuditLogPageDTO
+		wantIDs []uint
+	}{
+		{"all", dto.AuditLogPageDTO{}, []uint{1, 2, 3}},
+		{"by actor", dto.AuditLogPageDTO{ActorID: ptr(uint(2))}, []uint{3}},
+		{"by action", dto.AuditLogPageDTO{Action: ptr(consts.AuditPostUpdate)}, []uint{2}},
+		{"by entity", dto.AuditLogPageDTO{EntityType: ptr(consts.EntityPost), EntityID: ptr(uint(10))}, []uint{1, 2}},
+		{"from inclusive", dto.AuditLogPageDTO{From: ptr(base.Add(time.Hour))}, []uint{2, 3}},
+		{"to exclusive", dto.AuditLogPageDTO{To: ptr(base.Add(time.Hour))}, []uint{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			query.BasePageQuery = *dto.NewBasePageQuery()
+			query.Order = "asc"
+			page, err := svc.GetAuditLogByPage(&query)
+			if err != nil {
+				t.Fatalf("GetAuditLogByPage: %v", err)
+			}
+			var ids []uint
+			for _, item := range page.Items {
+				ids = append(ids, item.ID)
+			}
+			if len(ids) != len(tt.wantIDs) || page.Total != int64(len(tt.wantIDs)) {
+				t.Fatalf("ids = %v (total %d), want %v", ids, page.Total, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("ids = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := svc.ExportCSV(&buf, &dto.AuditLogPageDTO{ActorID: ptr(uint(1))}); err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "id" || rows[0][10] != "request_id" {
+		t.Fatalf("rows = %v", rows)
+	}
+	if rows[1][0] != "1" || rows[1][1] != "2024-03-01T08:00:00Z" || rows[1][4] != consts.AuditPostCreate || rows[1][8] != `{"title":"a, \"quoted\""}` {
+		t.Fatalf("first row = %v", rows[1])
+	}
+	if rows[2][0] != "2" {
+		t.Fatalf("second row = %v", rows[2])
+	}
+}
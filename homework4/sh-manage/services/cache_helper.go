@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sh-manage/cache"
+	"sh-manage/tenant"
+	"sh-manage/utils"
+	"strconv"
+)
+
+// 文章列表缓存的版本号，文章变更时自增使所有列表页失效
+const postListGenerationKey = "posts:gen"
+
+// tenantCache 请求属于某个租户时为缓存 key 加上租户前缀，列表、订阅源和统计缓存不会在租户之间共用
+func tenantCache(c cache.Cache, ctx context.Context) cache.Cache {
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		return cache.WithPrefix(c, fmt.Sprintf("tenant:%d:", tenantID))
+	}
+	return c
+}
+
+func tenantSlugCacheKey(slug string) string {
+	return "tenants:slug:" + slug
+}
+
+func userCacheKey(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func postCacheKey(postID uint) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// postPageCacheKey 列表页缓存key由版本号和查询条件的哈希组成
+func postPageCacheKey(c cache.Cache, query interface{}) string {
+	data, _ := json.Marshal(query)
+	sum := sha1.Sum(data)
+	return fmt.Sprintf("posts:page:%s:%s", postListGeneration(c), hex.EncodeToString(sum[:]))
+}
+
+// feedCacheKey 订阅源与列表页共用版本号，文章变更后一起失效
+func feedCacheKey(c cache.Cache, format, author, tag string) string {
+	return fmt.Sprintf("feeds:%s:%s:%s:%s", postListGeneration(c), format, url.QueryEscape(author), url.QueryEscape(tag))
+}
+
+func postListGeneration(c cache.Cache) string {
+	if data, err := c.Get(postListGenerationKey); err == nil {
+		return string(data)
+	}
+	// 版本号丢失时生成新的版本，不能复用可能残留的旧列表缓存
+	n, _ := c.Incr(postListGenerationKey)
+	return strconv.FormatInt(n, 10)
+}
+
+// readThrough 先读缓存，未命中时调用 load 并回写缓存；缓存故障按未命中处理
+func readThrough[T any](c cache.Cache, key string, load func() (*T, *utils.AppError)) (*T, *utils.AppError) {
+	if data, err := c.Get(key); err == nil {
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			return &value, nil
+		}
+	} else if err != cache.ErrMiss {
+		log.Printf("Cache get %s failed: %v", key, err)
+	}
+
+	value, appErr := load()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		if err := c.Set(key, data, 0); err != nil {
+			log.Printf("Cache set %s failed: %v", key, err)
+		}
+	}
+	return value, nil
+}
+
+func invalidate(c cache.Cache, keys ...string) {
+	if err := c.Delete(keys...); err != nil {
+		log.Printf("Cache delete %v failed: %v", keys, err)
+	}
+}
+
+func invalidatePostList(c cache.Cache) {
+	if _, err := c.Incr(postListGenerationKey); err != nil {
+		log.Printf("Cache incr %s failed: %v", postListGenerationKey, err)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"net/http/httptest"
+	"sh-manage/cache"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostReadThroughAndInvalidation(t *testing.T) {
+	db := newTestDB(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PUT", "/", nil)
+	c.Set(consts.UserID, uint(1))
+	svc := NewPostService(db, NewUserService(db, nil), cache.NewLRU(100, time.Minute), c)
+
+	post := &models.Post{Title: "title", Content: "content", UserId: 1}
+	db.Create(post)
+
+	if _, err := svc.GetPostByID(post.ID); err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	page, err := svc.GetPostByPage(&dto.PostPageDTO{BasePageQuery: *dto.NewBasePageQuery()})
+	if err != nil || page.Total != 1 {
+		t.Fatalf("GetPostByPage = %+v, %v", page, err)
+	}
+
+	// 绕过服务直接修改数据库，读到的仍是缓存
+	db.Model(&models.Post{}).Where("id = ?", post.ID).Update("title", "changed in db")
+	db.Create(&models.Post{Title: "other", Content: "content", UserId: 1})
+	cached, _ := svc.GetPostByID(post.ID)
+	if cached.Title != "title" {
+		t.Fatalf("expected cached title, got %q", cached.Title)
+	}
+	page, _ = svc.GetPostByPage(&dto.PostPageDTO{BasePageQuery: *dto.NewBasePageQuery()})
+	if page.Total != 1 {
+		t.Fatalf("expected cached page, got total %d", page.Total)
+	}
+
+	// 通过服务修改后单条和列表缓存都失效
+	title, content, version := "updated", "content", uint(1)
+	if _, err := svc.UpdatePost(&dto.PostDto{ID: &post.ID, Title: &title, Content: &content, Version: &version}); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	fresh, _ := svc.GetPostByID(post.ID)
+	if fresh.Title != "updated" {
+		t.Fatalf("expected updated title, got %q", fresh.Title)
+	}
+	page, _ = svc.GetPostByPage(&dto.PostPageDTO{BasePageQuery: *dto.NewBasePageQuery()})
+	if page.Total != 2 {
+		t.Fatalf("expected fresh page, got total %d", page.Total)
+	}
+
+	if err := svc.DeleteByID(post.ID); err != nil {
+		t.Fatalf("DeleteByID: %v", err)
+	}
+	if _, err := svc.GetPostByID(post.ID); err == nil || err.Code != 404 {
+		t.Fatalf("expected 404 after delete, got %v", err)
+	}
+}
+
+func TestUserReadThroughAndInvalidation(t *testing.T) {
+	db := newTestDB(t)
+	svc := NewUserService(db, cache.NewLRU(100, time.Minute))
+	user, err := svc.CreateUser(models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "Correct-Horse-42"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if _, err := svc.GetUserByID(user.ID); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	email := "new@example.com"
+	if _, err := svc.UpdateUser(user.ID, models.UpdateUserRequest{Email: &email, Version: &user.Version}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	fresh, _ := svc.GetUserByID(user.ID)
+	if fresh.Email != email {
+		t.Fatalf("expected updated email, got %q", fresh.Email)
+	}
+
+	// 缓存中的用户不含密码，更新时必须读取数据库，不能把密码覆盖为空
+	if _, err := svc.Authenticate("alice", "Correct-Horse-42"); err != nil {
+		t.Fatalf("password lost after cached update: %v", err)
+	}
+}
+
+// TestEnsureActiveBypassesCache 缓存只在本实例内失效，其他实例写入的封禁必须立即生效
+func TestEnsureActiveBypassesCache(t *testing.T) {
+	db := newTestDB(t)
+	svc := NewUserService(db, cache.NewLRU(100, time.Minute))
+	user, err := svc.CreateUser(models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "Correct-Horse-42"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := svc.GetUserByID(user.ID); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if err := svc.EnsureActive(user.ID); err != nil {
+		t.Fatalf("EnsureActive before ban: %v", err)
+	}
+
+	// 模拟其他实例封禁用户：直接写数据库，本实例的缓存没有失效
+	db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("banned_at", time.Now())
+	if cached, _ := svc.GetUserByID(user.ID); cached.BannedAt != nil {
+		t.Fatalf("expected stale cache entry, got %+v", cached)
+	}
+	if err := svc.EnsureActive(user.ID); err == nil || err.Code != 403 {
+		t.Fatalf("EnsureActive after ban = %v, want 403", err)
+	}
+}
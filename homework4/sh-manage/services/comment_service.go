@@ -1,9 +1,13 @@
 package services
 
 import (
+	"context"
+	"sh-manage/consts"
 	"sh-manage/dto"
+	"sh-manage/events"
 	"sh-manage/models"
-	"sh-manage/tools"
+	"sh-manage/moderation"
+	"sh-manage/repository"
 	"sh-manage/utils"
 	"strings"
 
@@ -13,88 +17,272 @@ import (
 
 type CommentService struct {
 	// 这里可以添加数据库连接等依赖
-	db          *gorm.DB
-	context     *gin.Context
-	userService *UserService
+	comments     repository.CommentRepository
+	posts        repository.PostRepository
+	context      *gin.Context
+	userService  *UserService
+	auditService AuditRecorder
+	publisher    events.Publisher
+	moderator    Moderator
 }
 
 func NewCommentService(db *gorm.DB, userService *UserService, c *gin.Context) *CommentService {
-	return &CommentService{db: db, userService: userService, context: c}
+	service := NewCommentServiceWithRepositories(repository.NewGorm(db), userService, NewAuditService(db))
+	service.context = c
+	return service
+}
+
+// NewCommentServiceWithRepositories 使用指定的存储和审计实现，文章存储用于检查评论的文章是否存在
+func NewCommentServiceWithRepositories(repos repository.Repositories, userService *UserService, audit AuditRecorder) *CommentService {
+	return &CommentService{comments: repos.Comments, posts: repos.Posts, userService: userService, auditService: audit, publisher: events.Nop{}}
+}
+
+// SetPublisher 设置评论事件的发布者
+func (p *CommentService) SetPublisher(publisher events.Publisher) {
+	p.publisher = publisher
+}
+
+// SetModerator 设置发布前的内容审核，为 nil 时不审核
+func (p *CommentService) SetModerator(moderator Moderator) {
+	p.moderator = moderator
+}
+
+// WithContext 返回绑定当前请求的副本，用于获取当前用户和记录审计日志
+func (p *CommentService) WithContext(c *gin.Context) *CommentService {
+	clone := *p
+	clone.context = c
+	return &clone
+}
+
+func (p *CommentService) currentUserID() uint {
+	if p.context == nil {
+		return 0
+	}
+	return utils.GetCurrentUserID(p.context)
+}
+
+func (p *CommentService) ctx() context.Context {
+	return requestContext(p.context)
 }
 
 func (p *CommentService) CreateComment(post *dto.CommentDto) (*models.Comment, *utils.AppError) {
 	if post == nil {
-		return nil, utils.NewAppError(500, "参数不能为空")
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
+	}
+	if post.PostID == nil {
+		return nil, utils.NewError(utils.ErrBadRequest, "参数文章ID不能为空")
 	}
 
 	if err := post.Validate(); err != nil {
 		return nil, err
 	}
 
+	if err := p.checkPostVisible(*post.PostID); err != nil {
+		return nil, err
+	}
+
 	commentModel := &models.Comment{
 		Content: *post.Content,
-		UserId:  utils.GetCurrentUserID(p.context),
+		UserId:  p.currentUserID(),
+		PostId:  *post.PostID,
+		Status:  consts.CommentStatusPublished,
+	}
+	result, appErr := moderate(p.ctx(), p.moderator, commentModerationRequest(commentModel))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if result.Verdict == moderation.Review {
+		commentModel.Status = consts.CommentStatusPendingReview
 	}
 
-	if err := p.db.Create(&commentModel).Error; err != nil {
-		return nil, utils.NewAppError(500, "Failed to create comment")
+	if err := p.comments.Create(p.ctx(), commentModel); err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create comment").Wrap(err)
+	}
+	if !commentModel.IsPublished() {
+		if err := enqueueForReview(p.ctx(), p.moderator, commentModerationRequest(commentModel), consts.ModerationActionCreate, result); err != nil {
+			return nil, err
+		}
+	}
+
+	p.auditService.Record(p.context, AuditEntry{
+		Action:     consts.AuditCommentCreate,
+		EntityType: consts.EntityComment,
+		EntityID:   commentModel.ID,
+		After:      commentModel.ToResponse(),
+	})
+	// 待审核的评论在审核通过后才推送
+	if commentModel.IsPublished() {
+		p.publisher.Publish(newEvent(p.ctx(), consts.EventCommentCreated, commentModel.ToResponse()))
 	}
 	return commentModel, nil
 
 }
+
+// GetCommentByID 待审核和被拒绝的评论只对作者可见，其他人返回 404
 func (p *CommentService) GetCommentByID(commentID uint) (*models.Comment, *utils.AppError) {
-	var comment models.Comment
-	if err := p.db.First(&comment, commentID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, utils.NewAppError(404, "comment not found")
+	comment, err := p.comments.FindByID(p.ctx(), commentID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrCommentNotFound, "Failed to retrieve comment")
+	}
+	if !comment.IsPublished() && comment.UserId != p.currentUserID() {
+		return nil, utils.NewError(utils.ErrCommentNotFound, "comment not found")
+	}
+	return comment, nil
+}
+
+// ListByPosts 返回多篇文章已发布的评论，调用方负责检查文章是否可见
+func (p *CommentService) ListByPosts(postIDs []uint) ([]models.Comment, *utils.AppError) {
+	comments, err := p.comments.FindByPosts(p.ctx(), postIDs)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve comment").Wrap(err)
+	}
+	published := comments[:0]
+	for _, comment := range comments {
+		if comment.IsPublished() {
+			published = append(published, comment)
 		}
-		return nil, utils.NewAppError(500, "Failed to retrieve comment")
 	}
-	return &comment, nil
+	return published, nil
+}
+
+// checkPostVisible 与 PostService.GetPostByID 相同的可见性规则：草稿、待审核和被拒绝的文章只对作者可见
+// 其他人评论或查询评论时返回 404，不暴露文章是否存在
+func (p *CommentService) checkPostVisible(postID uint) *utils.AppError {
+	post, err := p.posts.FindByID(p.ctx(), postID)
+	if err != nil {
+		return repositoryError(err, utils.ErrPostNotFound, "Failed to retrieve Post")
+	}
+	if !post.IsPublished() && post.UserId != p.currentUserID() {
+		return utils.NewError(utils.ErrPostNotFound, "Post not found")
+	}
+	return nil
 }
 
 func (p *CommentService) GetCommentByPage(commentPageDTO *dto.CommentPageDTO) (*dto.PageResult[models.Comment], *utils.AppError) {
 
-	db := p.db.Model(&models.Comment{})
-	if commentPageDTO.Content != nil || strings.TrimSpace(*commentPageDTO.Content) != "" {
-		db.Where("", commentPageDTO.Content)
+	filter := repository.CommentFilter{Status: consts.CommentStatusPublished}
+	if commentPageDTO.PostID != nil {
+		if err := p.checkPostVisible(*commentPageDTO.PostID); err != nil {
+			return nil, err
+		}
+		filter.PostID = *commentPageDTO.PostID
+	}
+	if commentPageDTO.Content != nil {
+		filter.Content = strings.TrimSpace(*commentPageDTO.Content)
 	}
 	// 执行分页查询
-	var comments []models.Comment
-	return tools.Paginate(db, commentPageDTO.BasePageQuery, &comments)
+	page, err := p.comments.Page(p.ctx(), filter, commentPageDTO.BasePageQuery)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrCommentNotFound, "Failed to retrieve comment")
+	}
+	return page, nil
 }
 
 func (p *CommentService) UpdateComment(comment *dto.CommentDto) (*models.Comment, *utils.AppError) {
 	if comment == nil {
-		return nil, utils.NewAppError(500, "参数不能为空")
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
 	}
 	if comment.ID == nil {
-		return nil, utils.NewAppError(500, "参数ID不能为空")
+		return nil, utils.NewError(utils.ErrBadRequest, "参数ID不能为空")
+	}
+	if comment.Version == nil {
+		return nil, utils.NewError(utils.ErrVersionRequired, "版本号不能为空")
 	}
 	existComment, err := p.GetCommentByID(*comment.ID)
 	if err != nil {
 		return nil, err
 	}
+	if existComment.UserId != p.currentUserID() {
+		return nil, utils.NewError(utils.ErrNotAuthor, "Only the author can modify this comment")
+	}
+	if existComment.Version != *comment.Version {
+		return nil, versionConflict(existComment.ToResponse())
+	}
 
 	if err := comment.Validate(); err != nil {
 		return nil, err
 	}
 
-	existComment.Content = *comment.Content
+	// 修改后的内容重新审核，待审核和被拒绝的评论修改后重新提交审核
+	changes := repository.CommentChanges{Content: comment.Content}
+	merged := *existComment
+	if comment.Content != nil {
+		merged.Content = *comment.Content
+	}
+	result, err := moderate(p.ctx(), p.moderator, commentModerationRequest(&merged))
+	if err != nil {
+		return nil, err
+	}
+	if p.moderator != nil && (result.Verdict == moderation.Review || !existComment.IsPublished()) {
+		pending := consts.CommentStatusPendingReview
+		changes.Status = &pending
+	}
+
+	before := existComment.ToResponse()
+	updated, e := p.comments.Update(p.ctx(), existComment.ID, *comment.Version, changes)
+	if e != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update Comment").Wrap(e)
+	}
+
+	existComment, err = p.GetCommentByID(existComment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, versionConflict(existComment.ToResponse())
+	}
+	if changes.Status != nil {
+		if err := enqueueForReview(p.ctx(), p.moderator, commentModerationRequest(existComment), consts.ModerationActionUpdate, result); err != nil {
+			return nil, err
+		}
+	}
 
-	if err := p.db.Save(&existComment).Error; err != nil {
-		return nil, utils.NewAppError(500, "Failed to create Comment")
+	p.auditService.Record(p.context, AuditEntry{
+		Action:     consts.AuditCommentUpdate,
+		EntityType: consts.EntityComment,
+		EntityID:   existComment.ID,
+		Before:     before,
+		After:      existComment.ToResponse(),
+	})
+	if existComment.IsPublished() {
+		p.publisher.Publish(newEvent(p.ctx(), consts.EventCommentUpdated, existComment.ToResponse()))
 	}
 	return existComment, nil
 
 }
 
 func (p *CommentService) DeleteByID(postID uint) *utils.AppError {
-	if err := p.db.Delete(&models.Comment{}, postID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return utils.NewAppError(404, "Comment not found")
-		}
-		return utils.NewAppError(500, "Failed to delete Comment")
+	existComment, err := p.GetCommentByID(postID)
+	if err != nil {
+		return err
+	}
+	if existComment.UserId != p.currentUserID() {
+		return utils.NewError(utils.ErrNotAuthor, "Only the author can delete this comment")
+	}
+
+	if err := p.comments.Delete(p.ctx(), postID); err != nil {
+		return repositoryError(err, utils.ErrCommentNotFound, "Failed to delete Comment")
+	}
+
+	p.auditService.Record(p.context, AuditEntry{
+		Action:     consts.AuditCommentDelete,
+		EntityType: consts.EntityComment,
+		EntityID:   postID,
+		Before:     existComment.ToResponse(),
+	})
+	if existComment.IsPublished() {
+		p.publisher.Publish(newEvent(p.ctx(), consts.EventCommentDeleted, existComment.ToResponse()))
+	} else {
+		withdrawReview(p.ctx(), p.moderator, consts.EntityComment, postID)
 	}
 	return nil
 }
+
+func commentModerationRequest(comment *models.Comment) moderationRequest {
+	return moderationRequest{
+		entityType: consts.EntityComment,
+		entityID:   comment.ID,
+		authorID:   comment.UserId,
+		text:       comment.Content,
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"testing"
+)
+
+func (f *memoryFixture) createComment(t *testing.T, userID, postID uint, content string) *models.Comment {
+	t.Helper()
+	comment, err := f.comments.WithContext(asUser(userID)).CreateComment(&dto.CommentDto{PostID: &postID, Content: &content})
+	if err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	return comment
+}
+
+func TestCommentServiceCreate(t *testing.T) {
+	f := newMemoryFixture(t)
+	post := f.createPost(t, 1, "title", "")
+
+	tests := []struct {
+		name     string
+		dto      *dto.CommentDto
+		wantCode int
+	}{
+		{"nil dto", nil, 400},
+		{"missing post id", &dto.CommentDto{Content: ptr("hi")}, 400},
+		{"empty content", &dto.CommentDto{PostID: &post.ID, Content: ptr("  ")}, 422},
+		{"missing post", &dto.CommentDto{PostID: ptr(uint(999)), Content: ptr("hi")}, 404},
+		{"ok", &dto.CommentDto{PostID: &post.ID, Content: ptr("hi")}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.events = nil
+			comment, err := f.comments.WithContext(asUser(2)).CreateComment(tt.dto)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode != 0 {
+				return
+			}
+			if comment.UserId != 2 || comment.PostId != post.ID || comment.Version != 1 {
+				t.Fatalf("comment = %+v", comment)
+			}
+			if len(f.events) != 1 || f.events[0].Type != consts.EventCommentCreated {
+				t.Fatalf("events = %+v", f.events)
+			}
+		})
+	}
+}
+
+func TestCommentServiceGetAndPage(t *testing.T) {
+	f := newMemoryFixture(t)
+	post := f.createPost(t, 1, "first", "")
+	other := f.createPost(t, 1, "second", "")
+	first := f.createComment(t, 2, post.ID, "nice post")
+	second := f.createComment(t, 3, post.ID, "thanks")
+	f.createComment(t, 2, other.ID, "nice too")
+
+	if got, err := f.comments.GetCommentByID(first.ID); err != nil || got.Content != "nice post" {
+		t.Fatalf("GetCommentByID = %+v, %v", got, err)
+	}
+	if _, err := f.comments.GetCommentByID(999); appErrorCode(err) != 404 {
+		t.Fatalf("missing comment = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		query   dto.CommentPageDTO
+		wantIDs string
+	}{
+		{"by post", dto.CommentPageDTO{BasePageQuery: *dto.NewBasePageQuery(), PostID: &post.ID}, fmt.Sprint([]uint{second.ID, first.ID})},
+		{"by content", dto.CommentPageDTO{BasePageQuery: *dto.NewBasePageQuery(), PostID: &post.ID, Content: ptr("nice")}, fmt.Sprint([]uint{first.ID})},
+		{"oldest first", dto.CommentPageDTO{BasePageQuery: dto.BasePageQuery{Page: 1, PageSize: 10, OrderBy: "id", Order: "asc"}, PostID: &post.ID}, fmt.Sprint([]uint{first.ID, second.ID})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := f.comments.GetCommentByPage(&tt.query)
+			if err != nil {
+				t.Fatalf("GetCommentByPage = %v", err)
+			}
+			var ids []uint
+			for _, comment := range page.Items {
+				ids = append(ids, comment.ID)
+			}
+			if fmt.Sprint(ids) != tt.wantIDs {
+				t.Fatalf("ids = %v, want %s", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestCommentServiceUpdate(t *testing.T) {
+	f := newMemoryFixture(t)
+	post := f.createPost(t, 1, "title", "")
+	comment := f.createComment(t, 2, post.ID, "first")
+
+	tests := []struct {
+		name     string
+		userID   uint
+		dto      *dto.CommentDto
+		wantCode int
+	}{
+		{"nil dto", 2, nil, 400},
+		{"missing id", 2, &dto.CommentDto{Content: ptr("x"), Version: ptr(uint(1))}, 400},
+		{"missing version", 2, &dto.CommentDto{ID: &comment.ID, Content: ptr("x")}, 400},
+		{"missing comment", 2, &dto.CommentDto{ID: ptr(uint(999)), Content: ptr("x"), Version: ptr(uint(1))}, 404},
+		{"not author", 1, &dto.CommentDto{ID: &comment.ID, Content: ptr("x"), Version: ptr(uint(1))}, 403},
+		{"stale version", 2, &dto.CommentDto{ID: &comment.ID, Content: ptr("x"), Version: ptr(uint(3))}, 409},
+		{"empty content", 2, &dto.CommentDto{ID: &comment.ID, Content: ptr(""), Version: ptr(uint(1))}, 422},
+		{"ok", 2, &dto.CommentDto{ID: &comment.ID, Content: ptr("edited"), Version: ptr(uint(1))}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := f.comments.WithContext(asUser(tt.userID)).UpdateComment(tt.dto)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode == 0 && (updated.Content != "edited" || updated.Version != 2) {
+				t.Fatalf("updated = %+v", updated)
+			}
+		})
+	}
+}
+
+func TestCommentServiceDelete(t *testing.T) {
+	f := newMemoryFixture(t)
+	post := f.createPost(t, 1, "title", "")
+	comment := f.createComment(t, 2, post.ID, "first")
+
+	tests := []struct {
+		name      string
+		userID    uint
+		commentID uint
+		wantCode  int
+	}{
+		{"missing", 2, 999, 404},
+		{"not author", 1, comment.ID, 403},
+		{"author", 2, comment.ID, 0},
+		{"already deleted", 2, comment.ID, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.comments.WithContext(asUser(tt.userID)).DeleteByID(tt.commentID)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+		})
+	}
+	if entry := f.audit.last(); entry.Action != consts.AuditCommentDelete || entry.EntityID != comment.ID {
+		t.Fatalf("audit = %+v", entry)
+	}
+}
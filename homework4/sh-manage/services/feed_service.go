@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"sh-manage/cache"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/feed"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 未配置时每个订阅源的文章数
+const defaultFeedLimit = 20
+
+// FeedQuery 订阅源的过滤条件，都为空时为全站订阅
+type FeedQuery struct {
+	Author string
+	Tag    string
+}
+
+// FeedDocument 生成好的订阅源，缓存中保存的也是它
+type FeedDocument struct {
+	Body         []byte    `json:"body"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+type FeedService struct {
+	db    *gorm.DB
+	cache cache.Cache
+	cfg   config.FeedConfig
+}
+
+// cacheStore 为 nil 时不使用缓存
+func NewFeedService(db *gorm.DB, cacheStore cache.Cache, cfg config.FeedConfig) *FeedService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultFeedLimit
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &FeedService{db: db, cache: cacheStore, cfg: cfg}
+}
+
+// WithContext 返回绑定当前请求的副本，订阅源只包含请求所属租户的文章
+func (s *FeedService) WithContext(c *gin.Context) *FeedService {
+	clone := *s
+	clone.db = s.db.WithContext(requestContext(c))
+	clone.cache = tenantCache(s.cache, requestContext(c))
+	return &clone
+}
+
+// GetFeed 生成已发布文章的订阅源，作者或标签不存在时返回 404
+func (s *FeedService) GetFeed(format feed.Format, query FeedQuery) (*FeedDocument, *utils.AppError) {
+	key := feedCacheKey(s.cache, string(format), query.Author, query.Tag)
+	return readThrough(s.cache, key, func() (*FeedDocument, *utils.AppError) {
+		return s.buildFeed(format, query)
+	})
+}
+
+func (s *FeedService) buildFeed(format feed.Format, query FeedQuery) (*FeedDocument, *utils.AppError) {
+	db := s.db.Model(&models.Post{}).Preload("User").Preload("Tags").
+		Where("posts.status = ?", consts.PostStatusPublished)
+
+	title := s.cfg.Title
+	path := "/feeds/" + string(format) + ".xml"
+	if query.Author != "" {
+		var author models.User
+		if err := s.db.Where("username = ?", query.Author).First(&author).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, utils.NewError(utils.ErrUserNotFound, "User not found")
+			}
+			return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve user").Wrap(err)
+		}
+		db = db.Where("posts.user_id = ?", author.ID)
+		title = fmt.Sprintf("%s - %s", title, author.Username)
+		path = fmt.Sprintf("/feeds/authors/%s/%s.xml", url.PathEscape(author.Username), format)
+	}
+	if query.Tag != "" {
+		var tag models.Tag
+		if err := s.db.Where("name = ?", strings.ToLower(query.Tag)).First(&tag).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, utils.NewError(utils.ErrTagNotFound, "Tag not found")
+			}
+			return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve tag").Wrap(err)
+		}
+		db = db.Where("posts.id IN (?)", s.db.Table("post_tags").Select("post_id").Where("tag_id = ?", tag.ID))
+		title = fmt.Sprintf("%s - #%s", title, tag.Name)
+		path = fmt.Sprintf("/feeds/tags/%s/%s.xml", url.PathEscape(tag.Name), format)
+	}
+
+	var posts []models.Post
+	if err := db.Order("posts.published_at desc").Order("posts.id desc").Limit(s.cfg.Limit).Find(&posts).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve posts").Wrap(err)
+	}
+
+	channel := &feed.Channel{
+		Title:       title,
+		Link:        s.cfg.BaseURL + "/",
+		FeedURL:     s.cfg.BaseURL + path,
+		Description: s.cfg.Description,
+		Items:       make([]feed.Item, 0, len(posts)),
+	}
+	for i := range posts {
+		post := &posts[i]
+		item := s.toFeedItem(post)
+		if item.Updated.After(channel.Updated) {
+			channel.Updated = item.Updated
+		}
+		channel.Items = append(channel.Items, item)
+	}
+
+	body, err := feed.Render(format, channel)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to render feed").Wrap(err)
+	}
+	return &FeedDocument{Body: body, LastModified: channel.Updated}, nil
+}
+
+func (s *FeedService) toFeedItem(post *models.Post) feed.Item {
+	published := post.CreatedAt
+	if post.PublishedAt != nil {
+		published = *post.PublishedAt
+	}
+	link := fmt.Sprintf("%s/api/v1/posts/%d", s.cfg.BaseURL, post.ID)
+	return feed.Item{
+		ID:         link,
+		Title:      post.Title,
+		Link:       link,
+		Author:     post.User.Username,
+		Categories: post.TagNames(),
+		Content:    post.Content,
+		Published:  published,
+		Updated:    post.UpdatedAt,
+	}
+}
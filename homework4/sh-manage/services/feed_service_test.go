@@ -0,0 +1,107 @@
+package services
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"sh-manage/cache"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/feed"
+	"sh-manage/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testRSS struct {
+	Channel struct {
+		Title         string `xml:"title"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		Items         []struct {
+			Title      string   `xml:"title"`
+			Categories []string `xml:"category"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+func TestFeedOnlyContainsPublishedPosts(t *testing.T) {
+	db := newTestDB(t)
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	db.Create(alice)
+	db.Create(bob)
+
+	store := cache.NewLRU(100, time.Minute)
+	postService := NewPostService(db, NewUserService(db, nil), store, nil)
+	feedService := NewFeedService(db, store, config.FeedConfig{Title: "blog", BaseURL: "http://example.com/"})
+
+	create := func(user *models.User, title, status string, tags ...string) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/", nil)
+		c.Set(consts.UserID, user.ID)
+		content := "content of " + title
+		if _, err := postService.WithContext(c).CreatePost(&dto.PostDto{Title: &title, Content: &content, Tags: tags, Status: &status}); err != nil {
+			t.Fatalf("create %s: %v", title, err)
+		}
+	}
+	create(alice, "alice go", consts.PostStatusPublished, "go")
+	create(alice, "alice draft", consts.PostStatusDraft, "go")
+	create(bob, "bob rust", consts.PostStatusPublished, "rust")
+
+	parse := func(query FeedQuery) testRSS {
+		t.Helper()
+		document, err := feedService.GetFeed(feed.FormatRSS, query)
+		if err != nil {
+			t.Fatalf("feed %+v: %v", query, err)
+		}
+		var rss testRSS
+		if err := xml.Unmarshal(document.Body, &rss); err != nil {
+			t.Fatalf("parse rss: %v\n%s", err, document.Body)
+		}
+		if rss.Channel.LastBuildDate == "" || document.LastModified.IsZero() {
+			t.Fatalf("missing lastBuildDate: %s", document.Body)
+		}
+		return rss
+	}
+
+	if rss := parse(FeedQuery{}); len(rss.Channel.Items) != 2 {
+		t.Fatalf("site feed items = %+v", rss.Channel.Items)
+	}
+	if rss := parse(FeedQuery{Author: "alice"}); len(rss.Channel.Items) != 1 || rss.Channel.Items[0].Title != "alice go" {
+		t.Fatalf("author feed items = %+v", rss.Channel.Items)
+	}
+	if rss := parse(FeedQuery{Tag: "rust"}); len(rss.Channel.Items) != 1 || rss.Channel.Items[0].Categories[0] != "rust" {
+		t.Fatalf("tag feed items = %+v", rss.Channel.Items)
+	}
+	if _, err := feedService.GetFeed(feed.FormatAtom, FeedQuery{Author: "nobody"}); err == nil || err.Code != 404 {
+		t.Fatalf("unknown author = %v", err)
+	}
+
+	// 新文章发布后缓存的订阅源失效
+	create(bob, "bob go", consts.PostStatusPublished, "go")
+	if rss := parse(FeedQuery{Tag: "go"}); len(rss.Channel.Items) != 2 {
+		t.Fatalf("tag feed after publish = %+v", rss.Channel.Items)
+	}
+}
+
+func TestEmptyAtomFeedUpdated(t *testing.T) {
+	db := newTestDB(t)
+	feedService := NewFeedService(db, nil, config.FeedConfig{Title: "blog", BaseURL: "http://example.com/"})
+
+	document, err := feedService.GetFeed(feed.FormatAtom, FeedQuery{})
+	if err != nil {
+		t.Fatalf("feed: %v", err)
+	}
+	var atom struct {
+		Updated string `xml:"updated"`
+	}
+	if err := xml.Unmarshal(document.Body, &atom); err != nil {
+		t.Fatalf("parse atom: %v\n%s", err, document.Body)
+	}
+	updated, e := time.Parse(time.RFC3339, atom.Updated)
+	if e != nil || time.Since(updated) > time.Minute {
+		t.Fatalf("updated = %q, want the current time", atom.Updated)
+	}
+}
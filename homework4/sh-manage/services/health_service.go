@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 单个依赖检查的超时时间
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck 检查一个依赖是否可用
+type HealthCheck func(ctx context.Context) error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthCheckResult 单个依赖的检查结果，Error 可能包含地址等内部信息，只记录日志不返回给调用方
+type HealthCheckResult struct {
+	Status  string `json:"status"`
+	Error   string `json:"-"`
+	Latency string `json:"latency"`
+}
+
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+// HealthService 汇总各依赖的就绪状态，进入停机流程后始终返回未就绪
+type HealthService struct {
+	mu       sync.RWMutex
+	checks   []namedHealthCheck
+	draining atomic.Bool
+}
+
+func NewHealthService() *HealthService {
+	return &HealthService{}
+}
+
+// AddCheck 注册一个依赖检查，name 会出现在就绪报告中
+func (s *HealthService) AddCheck(name string, check HealthCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks = append(s.checks, namedHealthCheck{name: name, check: check})
+}
+
+// SetDraining 标记服务正在停机，负载均衡据此摘除流量
+func (s *HealthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+// Ready 并发执行所有检查，全部通过且未在停机时返回 true
+func (s *HealthService) Ready(ctx context.Context) (*HealthReport, bool) {
+	s.mu.RLock()
+	checks := append([]namedHealthCheck(nil), s.checks...)
+	s.mu.RUnlock()
+
+	report := &HealthReport{Status: "ok", Checks: make(map[string]HealthCheckResult, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedHealthCheck) {
+			defer wg.Done()
+			result := runHealthCheck(ctx, c.check)
+			mu.Lock()
+			report.Checks[c.name] = result
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, result := range report.Checks {
+		if result.Status != "ok" {
+			ready = false
+		}
+	}
+	if s.draining.Load() {
+		report.Status = "draining"
+		return report, false
+	}
+	if !ready {
+		report.Status = "unavailable"
+	}
+	return report, ready
+}
+
+func runHealthCheck(ctx context.Context, check HealthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := HealthCheckResult{Status: "ok", Latency: time.Since(start).String()}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// DatabaseHealthCheck 通过 Ping 检查数据库连接
+func DatabaseHealthCheck(db *gorm.DB) HealthCheck {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return errors.New("database ping failed: " + err.Error())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHealthServiceReady(t *testing.T) {
+	db := newTestDB(t)
+	s := NewHealthService()
+	s.AddCheck("database", DatabaseHealthCheck(db))
+
+	report, ready := s.Ready(context.Background())
+	if !ready || report.Status != "ok" || report.Checks["database"].Status != "ok" {
+		t.Fatalf("Ready() = %+v, %v", report, ready)
+	}
+
+	s.AddCheck("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+	report, ready = s.Ready(context.Background())
+	if ready || report.Status != "unavailable" {
+		t.Fatalf("Ready() with failing check = %+v, %v", report, ready)
+	}
+	if got := report.Checks["cache"]; got.Status != "fail" || got.Error != "connection refused" {
+		t.Fatalf("cache check = %+v", got)
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	report, _ = s.Ready(context.Background())
+	if report.Checks["database"].Status != "fail" {
+		t.Fatalf("closed database reported as %+v", report.Checks["database"])
+	}
+}
+
+func TestHealthServiceDraining(t *testing.T) {
+	s := NewHealthService()
+	if _, ready := s.Ready(context.Background()); !ready {
+		t.Fatalf("service without checks should be ready")
+	}
+	s.SetDraining()
+	report, ready := s.Ready(context.Background())
+	if ready || report.Status != "draining" {
+		t.Fatalf("Ready() while draining = %+v, %v", report, ready)
+	}
+}
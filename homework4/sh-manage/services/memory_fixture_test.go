@@ -0,0 +1,116 @@
+package services
+
+import (
+	"net/http/httptest"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/events"
+	"sh-manage/models"
+	"sh-manage/repository"
+	"sh-manage/utils"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryAudit 把审计记录保存在内存中，便于断言
+type memoryAudit struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (a *memoryAudit) Record(_ *gin.Context, entry AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, entry)
+}
+
+func (a *memoryAudit) last() AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.entries) == 0 {
+		return AuditEntry{}
+	}
+	return a.entries[len(a.entries)-1]
+}
+
+// memoryFixture 基于内存存储的用户、文章、评论服务
+type memoryFixture struct {
+	repo     *repository.Memory
+	audit    *memoryAudit
+	events   []events.Event
+	users    *UserService
+	posts    *PostService
+	comments *CommentService
+}
+
+func newMemoryFixture(t *testing.T) *memoryFixture {
+	t.Helper()
+	f := &memoryFixture{repo: repository.NewMemory(), audit: &memoryAudit{}}
+	bus := events.NewBus()
+	bus.Subscribe(func(e events.Event) { f.events = append(f.events, e) })
+
+	f.users = NewUserServiceWithRepositories(f.repo.Repositories(), nil, f.audit)
+	f.posts = NewPostServiceWithRepositories(f.repo.Repositories(), f.users, nil, f.audit)
+	f.posts.SetPublisher(bus)
+	f.comments = NewCommentServiceWithRepositories(f.repo.Repositories(), f.users, f.audit)
+	f.comments.SetPublisher(bus)
+	return f
+}
+
+// asUser 返回已登录用户的请求上下文，userID 为 0 表示匿名
+func asUser(userID uint) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	if userID != 0 {
+		c.Set(consts.UserID, userID)
+	}
+	return c
+}
+
+func (f *memoryFixture) createUser(t *testing.T, username string) *models.User {
+	t.Helper()
+	user, err := f.users.CreateUser(models.CreateUserRequest{Username: username, Email: username + "@example.com", Password: "Correct-Horse-42"})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", username, err)
+	}
+	return user
+}
+
+func (f *memoryFixture) createPost(t *testing.T, authorID uint, title string, status string, tags ...string) *models.Post {
+	t.Helper()
+	content := "content of " + title
+	post, err := f.posts.WithContext(asUser(authorID)).CreatePost(postDto(nil, title, content, status, nil, tags))
+	if err != nil {
+		t.Fatalf("CreatePost(%s): %v", title, err)
+	}
+	return post
+}
+
+func postDto(id *uint, title, content, status string, version *uint, tags []string) *dto.PostDto {
+	d := &dto.PostDto{ID: id, Title: &title, Content: &content, Version: version, Tags: tags}
+	if status != "" {
+		d.Status = &status
+	}
+	return d
+}
+
+// appErrorCode 返回错误中的 AppError 状态码，没有错误时为 0
+// 服务方法返回 *utils.AppError，转换为 error 后的 nil 指针也视为没有错误
+func appErrorCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if appErr, ok := err.(*utils.AppError); ok {
+		if appErr == nil {
+			return 0
+		}
+		return appErr.Code
+	}
+	return -1
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sh-manage/cache"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/events"
+	"sh-manage/models"
+	"sh-manage/moderation"
+	"sh-manage/repository"
+	"sh-manage/tenant"
+	"sh-manage/tools"
+	"sh-manage/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// moderationTrainingLimit 启动时用于训练分类器的最近审核记录数
+const moderationTrainingLimit = 10000
+
+// Moderator 文章和评论发布前的内容审核，由 ModerationService 实现；PostService 和 CommentService 未设置时不审核
+type Moderator interface {
+	// Check 只检查内容，不修改数据
+	Check(ctx context.Context, content moderation.Content) moderation.Result
+	// Enqueue 把内容加入审核队列，同一内容已有待审核条目时更新该条目
+	Enqueue(ctx context.Context, item *models.ModerationItem) error
+	// Withdraw 作者把内容改为草稿或删除后撤回待审核条目
+	Withdraw(ctx context.Context, entityType string, entityID uint) error
+}
+
+// ModerationService 维护审核队列，审核员通过或拒绝后修改文章和评论的状态并训练垃圾内容分类器
+type ModerationService struct {
+	db           *gorm.DB
+	posts        repository.PostRepository
+	comments     repository.CommentRepository
+	tx           repository.UnitOfWork
+	pipeline     *moderation.Pipeline
+	cache        cache.Cache
+	context      *gin.Context
+	auditService *AuditService
+	publisher    events.Publisher
+}
+
+// cacheStore 为 nil 时不使用缓存，需要与 PostService 使用同一个缓存，审核后才能让文章缓存失效
+func NewModerationService(db *gorm.DB, pipeline *moderation.Pipeline, cacheStore cache.Cache) *ModerationService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	repos := repository.NewGorm(db)
+	return &ModerationService{
+		db:           db,
+		posts:        repos.Posts,
+		tx:           repos.Tx,
+		comments:     repos.Comments,
+		pipeline:     pipeline,
+		cache:        cacheStore,
+		auditService: NewAuditService(db),
+		publisher:    events.Nop{},
+	}
+}
+
+// SetPublisher 设置审核通过后文章和评论事件的发布者
+func (s *ModerationService) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// WithContext 返回绑定当前请求的副本，队列和审核限定在请求所属的租户
+func (s *ModerationService) WithContext(c *gin.Context) *ModerationService {
+	clone := *s
+	clone.context = c
+	clone.db = s.db.WithContext(requestContext(c))
+	clone.cache = tenantCache(s.cache, requestContext(c))
+	return &clone
+}
+
+func (s *ModerationService) currentUserID() uint {
+	if s.context == nil {
+		return 0
+	}
+	return utils.GetCurrentUserID(s.context)
+}
+
+func (s *ModerationService) ctx() context.Context {
+	return requestContext(s.context)
+}
+
+func (s *ModerationService) Check(_ context.Context, content moderation.Content) moderation.Result {
+	return s.pipeline.Check(content)
+}
+
+func (s *ModerationService) Enqueue(ctx context.Context, item *models.ModerationItem) error {
+	db := s.db.WithContext(ctx)
+	var existing models.ModerationItem
+	err := db.Where("entity_type = ? AND entity_id = ? AND status = ?", item.EntityType, item.EntityID, consts.ModerationPending).First(&existing).Error
+	switch {
+	case err == nil:
+		// 保留原条目的操作类型，新建后修改的评论审核通过时仍然是 comment.created
+		item.ID, item.Action = existing.ID, existing.Action
+		return db.Model(&existing).Updates(map[string]interface{}{
+			"content":    item.Content,
+			"reasons":    item.Reasons,
+			"spam_score": item.SpamScore,
+		}).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		item.Status = consts.ModerationPending
+		return db.Create(item).Error
+	default:
+		return err
+	}
+}
+
+func (s *ModerationService) Withdraw(ctx context.Context, entityType string, entityID uint) error {
+	return s.db.WithContext(ctx).Model(&models.ModerationItem{}).
+		Where("entity_type = ? AND entity_id = ? AND status = ?", entityType, entityID, consts.ModerationPending).
+		Update("status", consts.ModerationWithdrawn).Error
+}
+
+// GetQueue 分页查询审核队列，默认只返回待审核的条目
+func (s *ModerationService) GetQueue(query *dto.ModerationQueueDTO) (*dto.PageResult[models.ModerationItem], *utils.AppError) {
+	status := consts.ModerationPending
+	if query.Status != nil && *query.Status != "" {
+		status = *query.Status
+	}
+	db := s.db.Model(&models.ModerationItem{}).Where("status = ?", status)
+	if query.EntityType != nil && *query.EntityType != "" {
+		db = db.Where("entity_type = ?", *query.EntityType)
+	}
+	var items []models.ModerationItem
+	return tools.Paginate(db, query.BasePageQuery, &items)
+}
+
+func (s *ModerationService) getItem(itemID uint) (*models.ModerationItem, *utils.AppError) {
+	var item models.ModerationItem
+	if err := s.db.First(&item, itemID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewError(utils.ErrModerationItemNotFound, "Moderation item not found")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve moderation item").Wrap(err)
+	}
+	return &item, nil
+}
+
+// Approve 发布待审核的文章或评论
+func (s *ModerationService) Approve(itemID uint, note string) (*models.ModerationItem, *utils.AppError) {
+	return s.decide(itemID, true, note)
+}
+
+// Reject 把待审核的文章或评论标记为被拒绝，仍然只有作者可见
+func (s *ModerationService) Reject(itemID uint, note string) (*models.ModerationItem, *utils.AppError) {
+	return s.decide(itemID, false, note)
+}
+
+func (s *ModerationService) decide(itemID uint, approve bool, note string) (*models.ModerationItem, *utils.AppError) {
+	item, err := s.getItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != consts.ModerationPending {
+		return nil, utils.NewError(utils.ErrAlreadyDecided, "Moderation item has already been decided")
+	}
+	before := item.ToResponse()
+
+	status, action := consts.ModerationRejected, consts.AuditModerationReject
+	if approve {
+		status, action = consts.ModerationApproved, consts.AuditModerationApprove
+	}
+	now := time.Now()
+	// 领取条目和修改内容状态在同一个事务中，任一步失败时条目保持待审核
+	var event *events.Event
+	var withdraw bool
+	applyErr := inTransaction(s.ctx(), s.tx, "Failed to update moderation item", func(ctx context.Context) *utils.AppError {
+		// 按状态条件领取条目，并发审核同一条目时只有一个成功
+		result := repository.Conn(ctx, s.db).Model(&models.ModerationItem{}).Where("id = ? AND status = ?", item.ID, consts.ModerationPending).Updates(map[string]interface{}{
+			"status":       status,
+			"moderator_id": s.currentUserID(),
+			"decided_at":   now,
+			"note":         note,
+		})
+		if result.Error != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to update moderation item").Wrap(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewError(utils.ErrAlreadyDecided, "Moderation item has already been decided")
+		}
+
+		var err *utils.AppError
+		switch item.EntityType {
+		case consts.EntityPost:
+			event, err = s.applyToPost(ctx, item, approve)
+		case consts.EntityComment:
+			event, err = s.applyToComment(ctx, item, approve)
+		default:
+			err = utils.NewError(utils.ErrInternal, "Unknown moderation entity type")
+		}
+		// 内容已经删除或不再是待审核状态时，回滚后撤回条目
+		withdraw = err != nil && (err.Code == 404 || err.Code == 409)
+		return err
+	})
+	if applyErr != nil {
+		if withdraw {
+			if err := s.Withdraw(s.ctx(), item.EntityType, item.EntityID); err != nil {
+				log.Printf("Failed to withdraw moderation item %d: %v", item.ID, err)
+			}
+		}
+		return nil, applyErr
+	}
+
+	if item.EntityType == consts.EntityPost {
+		invalidate(s.cache, postCacheKey(item.EntityID))
+		invalidatePostList(s.cache)
+	}
+	if event != nil {
+		s.publisher.Publish(*event)
+	}
+	s.pipeline.Train(item.TenantID, item.Content, !approve)
+	item, err = s.getItem(item.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     action,
+		EntityType: consts.EntityModeration,
+		EntityID:   item.ID,
+		Before:     before,
+		After:      item.ToResponse(),
+	})
+	return item, nil
+}
+
+// applyToPost 在事务中修改文章状态，返回提交后需要发布的事件
+func (s *ModerationService) applyToPost(ctx context.Context, item *models.ModerationItem, approve bool) (*events.Event, *utils.AppError) {
+	post, err := s.posts.FindByID(ctx, item.EntityID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrPostNotFound, "Failed to retrieve Post")
+	}
+	if post.Status != consts.PostStatusPendingReview {
+		return nil, utils.NewError(utils.ErrAlreadyDecided, "Post is no longer pending review")
+	}
+
+	status := consts.PostStatusRejected
+	if approve {
+		status = consts.PostStatusPublished
+	}
+	changes := repository.PostChanges{Status: &status}
+	if approve && post.PublishedAt == nil {
+		now := time.Now()
+		changes.PublishedAt = &now
+	}
+	if _, err := s.posts.Update(ctx, post.ID, 0, changes); err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update post").Wrap(err)
+	}
+
+	post, err = s.posts.FindByID(ctx, post.ID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrPostNotFound, "Failed to retrieve Post")
+	}
+	event := newEvent(ctx, consts.EventPostUpdated, post.ToResponse())
+	event.PreviousStatus = consts.PostStatusPendingReview
+	return &event, nil
+}
+
+// applyToComment 在事务中修改评论状态，只有审核通过时返回需要发布的事件
+func (s *ModerationService) applyToComment(ctx context.Context, item *models.ModerationItem, approve bool) (*events.Event, *utils.AppError) {
+	comment, err := s.comments.FindByID(ctx, item.EntityID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrCommentNotFound, "Failed to retrieve comment")
+	}
+	if comment.Status != consts.CommentStatusPendingReview {
+		return nil, utils.NewError(utils.ErrAlreadyDecided, "Comment is no longer pending review")
+	}
+
+	status := consts.CommentStatusRejected
+	if approve {
+		status = consts.CommentStatusPublished
+	}
+	if _, err := s.comments.Update(ctx, comment.ID, 0, repository.CommentChanges{Status: &status}); err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update comment").Wrap(err)
+	}
+	if !approve {
+		return nil, nil
+	}
+
+	comment, err = s.comments.FindByID(ctx, comment.ID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrCommentNotFound, "Failed to retrieve comment")
+	}
+	eventType := consts.EventCommentCreated
+	if item.Action == consts.ModerationActionUpdate {
+		eventType = consts.EventCommentUpdated
+	}
+	event := newEvent(ctx, eventType, comment.ToResponse())
+	return &event, nil
+}
+
+// LoadTraining 用最近的审核记录训练各租户的分类器，服务启动时调用
+func (s *ModerationService) LoadTraining(ctx context.Context) error {
+	var items []models.ModerationItem
+	err := s.db.WithContext(ctx).
+		Where("status IN ?", []string{consts.ModerationApproved, consts.ModerationRejected}).
+		Order("id desc").Limit(moderationTrainingLimit).
+		Find(&items).Error
+	if err != nil {
+		return fmt.Errorf("load moderation decisions: %w", err)
+	}
+	for _, item := range items {
+		s.pipeline.Train(item.TenantID, item.Content, item.Status == consts.ModerationRejected)
+	}
+	return nil
+}
+
+// moderationRequest 文章和评论服务提交审核时使用
+type moderationRequest struct {
+	entityType string
+	entityID   uint // 新建时为 0
+	authorID   uint
+	text       string
+	preview    bool // 只检查不记录指纹，导入试运行时使用
+}
+
+// moderate 检查要发布的内容，屏蔽词命中时返回 422；moderator 为 nil 时总是通过
+func moderate(ctx context.Context, moderator Moderator, req moderationRequest) (moderation.Result, *utils.AppError) {
+	if moderator == nil {
+		return moderation.Result{}, nil
+	}
+	content := moderation.Content{AuthorID: req.authorID, Text: req.text, Preview: req.preview}
+	content.TenantID, _ = tenant.FromContext(ctx)
+	if req.entityID != 0 {
+		content.Key = fmt.Sprintf("%s:%d", req.entityType, req.entityID)
+	}
+	result := moderator.Check(ctx, content)
+	if result.Verdict == moderation.Reject {
+		return result, utils.NewError(utils.ErrContentRejected, "Content rejected by moderation").WithData(gin.H{"reasons": result.Reasons})
+	}
+	return result, nil
+}
+
+// enqueueForReview 把进入待审核状态的内容加入审核队列
+func enqueueForReview(ctx context.Context, moderator Moderator, req moderationRequest, action string, result moderation.Result) *utils.AppError {
+	item := &models.ModerationItem{
+		EntityType: req.entityType,
+		EntityID:   req.entityID,
+		AuthorID:   req.authorID,
+		Action:     action,
+		Content:    req.text,
+		Reasons:    strings.Join(result.Reasons, ","),
+		SpamScore:  result.SpamScore,
+	}
+	if err := moderator.Enqueue(ctx, item); err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to queue content for review").Wrap(err)
+	}
+	return nil
+}
+
+// withdrawReview 撤回失败只记录日志，审核时会发现内容已不是待审核状态
+func withdrawReview(ctx context.Context, moderator Moderator, entityType string, entityID uint) {
+	if moderator == nil {
+		return
+	}
+	if err := moderator.Withdraw(ctx, entityType, entityID); err != nil {
+		log.Printf("Failed to withdraw moderation item for %s#%d: %v", entityType, entityID, err)
+	}
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/events"
+	"sh-manage/models"
+	"sh-manage/moderation"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// moderationFixture 基于 sqlite 的文章、评论和审核服务，审核规则：casino 拒绝，crypto 进入审核
+type moderationFixture struct {
+	db         *gorm.DB
+	events     []events.Event
+	pipeline   *moderation.Pipeline
+	moderation *ModerationService
+	users      *UserService
+	posts      *PostService
+	comments   *CommentService
+}
+
+func newModerationFixture(t *testing.T) *moderationFixture {
+	t.Helper()
+	db := newTestDB(t)
+	f := &moderationFixture{db: db, pipeline: moderation.New(config.ModerationConfig{
+		Enabled:      true,
+		BlockedWords: []string{"casino"},
+		ReviewWords:  []string{"crypto"},
+	})}
+	bus := events.NewBus()
+	bus.Subscribe(func(e events.Event) { f.events = append(f.events, e) })
+
+	f.moderation = NewModerationService(db, f.pipeline, nil)
+	f.moderation.SetPublisher(bus)
+	f.users = NewUserService(db, nil)
+	f.posts = NewPostService(db, f.users, nil, nil)
+	f.posts.SetPublisher(bus)
+	f.posts.SetModerator(f.moderation)
+	f.comments = NewCommentService(db, f.users, nil)
+	f.comments.SetPublisher(bus)
+	f.comments.SetModerator(f.moderation)
+	return f
+}
+
+func (f *moderationFixture) createUser(t *testing.T, username string) *models.User {
+	t.Helper()
+	user, err := f.users.CreateUser(models.CreateUserRequest{Username: username, Email: username + "@example.com", Password: "Correct-Horse-42"})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", username, err)
+	}
+	return user
+}
+
+func (f *moderationFixture) queue(t *testing.T, status string) []models.ModerationItem {
+	t.Helper()
+	page, err := f.moderation.GetQueue(&dto.ModerationQueueDTO{BasePageQuery: *dto.NewBasePageQuery(), Status: &status})
+	if err != nil {
+		t.Fatalf("GetQueue: %v", err)
+	}
+	return page.Items
+}
+
+func (f *moderationFixture) lastEvent() string {
+	if len(f.events) == 0 {
+		return ""
+	}
+	return f.events[len(f.events)-1].Type
+}
+
+func TestModerationPostReview(t *testing.T) {
+	f := newModerationFixture(t)
+	author := f.createUser(t, "alice")
+	reader := f.createUser(t, "bob")
+	moderator := f.createUser(t, "carol")
+	posts := f.posts.WithContext(asUser(author.ID))
+
+	if _, err := posts.CreatePost(postDto(nil, "casino night", "come and play", "", nil, nil)); appErrorCode(err) != 422 {
+		t.Fatalf("blocked post: %v", err)
+	}
+	// 草稿不审核
+	if post, err := posts.CreatePost(postDto(nil, "crypto draft", "notes", consts.PostStatusDraft, nil, nil)); err != nil || post.Status != consts.PostStatusDraft {
+		t.Fatalf("draft = %+v, %v", post, err)
+	}
+
+	post, err := posts.CreatePost(postDto(nil, "about crypto", "my thoughts", "", nil, nil))
+	if err != nil || post.Status != consts.PostStatusPendingReview || post.PublishedAt != nil {
+		t.Fatalf("pending post = %+v, %v", post, err)
+	}
+	if _, err := f.posts.WithContext(asUser(reader.ID)).GetPostByID(post.ID); appErrorCode(err) != 404 {
+		t.Fatalf("pending post visible to others: %v", err)
+	}
+	items := f.queue(t, consts.ModerationPending)
+	if len(items) != 1 || items[0].EntityType != consts.EntityPost || items[0].EntityID != post.ID || items[0].Reasons != moderation.ReasonReviewWord {
+		t.Fatalf("queue = %+v", items)
+	}
+
+	// 作者不能绕过审核直接发布，修改后仍然使用同一个待审核条目
+	post, err = posts.UpdatePost(postDto(&post.ID, "about crypto", "revised", consts.PostStatusPublished, &post.Version, nil))
+	if err != nil || post.Status != consts.PostStatusPendingReview {
+		t.Fatalf("republish pending post = %+v, %v", post, err)
+	}
+	items = f.queue(t, consts.ModerationPending)
+	if len(items) != 1 || items[0].Content != "about crypto\nrevised" {
+		t.Fatalf("queue after edit = %+v", items)
+	}
+
+	item, err := f.moderation.WithContext(asUser(moderator.ID)).Approve(items[0].ID, "looks fine")
+	if err != nil || item.Status != consts.ModerationApproved || item.ModeratorID != moderator.ID || item.DecidedAt == nil {
+		t.Fatalf("approve = %+v, %v", item, err)
+	}
+	approved, err := f.posts.WithContext(asUser(reader.ID)).GetPostByID(post.ID)
+	if err != nil || approved.Status != consts.PostStatusPublished || approved.PublishedAt == nil {
+		t.Fatalf("approved post = %+v, %v", approved, err)
+	}
+	if f.lastEvent() != consts.EventPostUpdated {
+		t.Fatalf("last event = %s", f.lastEvent())
+	}
+	if _, err := f.moderation.Reject(item.ID, ""); appErrorCode(err) != 409 {
+		t.Fatalf("decide twice: %v", err)
+	}
+}
+
+func TestModerationRejectAndResubmit(t *testing.T) {
+	f := newModerationFixture(t)
+	author := f.createUser(t, "alice")
+	posts := f.posts.WithContext(asUser(author.ID))
+
+	post, err := posts.CreatePost(postDto(nil, "crypto", "tips", "", nil, nil))
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	items := f.queue(t, consts.ModerationPending)
+	if _, err := f.moderation.Reject(items[0].ID, "off topic"); err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	post, _ = posts.GetPostByID(post.ID)
+	if post.Status != consts.PostStatusRejected {
+		t.Fatalf("status = %s", post.Status)
+	}
+
+	// 不指定状态的修改保持被拒绝，重新发布时即使内容正常也需要再次审核
+	post, err = posts.UpdatePost(postDto(&post.ID, "go tips", "tips", "", &post.Version, nil))
+	if err != nil || post.Status != consts.PostStatusRejected {
+		t.Fatalf("edit rejected post = %+v, %v", post, err)
+	}
+	post, err = posts.UpdatePost(postDto(&post.ID, "go tips", "tips", consts.PostStatusPublished, &post.Version, nil))
+	if err != nil || post.Status != consts.PostStatusPendingReview {
+		t.Fatalf("resubmit = %+v, %v", post, err)
+	}
+	items = f.queue(t, consts.ModerationPending)
+	if len(items) != 1 || items[0].Action != consts.ModerationActionUpdate {
+		t.Fatalf("queue = %+v", items)
+	}
+
+	// 改为草稿后撤回待审核条目
+	if _, err := posts.UpdatePost(postDto(&post.ID, "go tips", "tips", consts.PostStatusDraft, &post.Version, nil)); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if pending, withdrawn := f.queue(t, consts.ModerationPending), f.queue(t, consts.ModerationWithdrawn); len(pending) != 0 || len(withdrawn) != 1 {
+		t.Fatalf("pending = %+v, withdrawn = %+v", pending, withdrawn)
+	}
+}
+
+// TestModerationDecideRollsBack 修改内容失败时领取条目一并回滚，条目保持待审核可以重试
+func TestModerationDecideRollsBack(t *testing.T) {
+	f := newModerationFixture(t)
+	author := f.createUser(t, "alice")
+	moderator := f.createUser(t, "carol")
+	post, err := f.posts.WithContext(asUser(author.ID)).CreatePost(postDto(nil, "crypto", "tips", "", nil, nil))
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	items := f.queue(t, consts.ModerationPending)
+
+	failing := true
+	f.db.Callback().Update().Before("gorm:update").Register("test:fail_post_update", func(db *gorm.DB) {
+		if failing && db.Statement.Table == "posts" {
+			db.AddError(errors.New("disk full"))
+		}
+	})
+	if _, err := f.moderation.WithContext(asUser(moderator.ID)).Approve(items[0].ID, "ok"); appErrorCode(err) != 500 {
+		t.Fatalf("approve with failing update: %v", err)
+	}
+	pending := f.queue(t, consts.ModerationPending)
+	if len(pending) != 1 || pending[0].ModeratorID != 0 || pending[0].DecidedAt != nil || pending[0].Note != "" {
+		t.Fatalf("queue after failure = %+v", pending)
+	}
+
+	failing = false
+	if _, err := f.moderation.WithContext(asUser(moderator.ID)).Approve(items[0].ID, "ok"); err != nil {
+		t.Fatalf("retry approve: %v", err)
+	}
+	if post, _ = f.posts.GetPostByID(post.ID); post.Status != consts.PostStatusPublished {
+		t.Fatalf("status = %s", post.Status)
+	}
+}
+
+func TestModerationComments(t *testing.T) {
+	f := newModerationFixture(t)
+	author := f.createUser(t, "alice")
+	commenter := f.createUser(t, "bob")
+	post, err := f.posts.WithContext(asUser(author.ID)).CreatePost(postDto(nil, "hello", "world", "", nil, nil))
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	comments := f.comments.WithContext(asUser(commenter.ID))
+	create := func(content string) (*models.Comment, error) {
+		comment, err := comments.CreateComment(&dto.CommentDto{PostID: &post.ID, Content: &content})
+		if err != nil {
+			return nil, err
+		}
+		return comment, nil
+	}
+
+	if _, err := create("play casino"); appErrorCode(err) != 422 {
+		t.Fatalf("blocked comment: %v", err)
+	}
+	events := len(f.events)
+	pending, e := create("buy crypto")
+	if e != nil || pending.Status != consts.CommentStatusPendingReview {
+		t.Fatalf("pending comment = %+v, %v", pending, e)
+	}
+	if len(f.events) != events {
+		t.Fatalf("pending comment published %s", f.lastEvent())
+	}
+	if _, err := create("nice post"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// 待审核的评论不出现在列表中，只有作者可以查看
+	listed, _ := f.comments.ListByPosts([]uint{post.ID})
+	page, _ := f.comments.GetCommentByPage(&dto.CommentPageDTO{BasePageQuery: *dto.NewBasePageQuery(), PostID: &post.ID})
+	if len(listed) != 1 || page.Total != 1 {
+		t.Fatalf("listed = %d, page total = %d", len(listed), page.Total)
+	}
+	if _, err := f.comments.WithContext(asUser(author.ID)).GetCommentByID(pending.ID); appErrorCode(err) != 404 {
+		t.Fatalf("pending comment visible to others: %v", err)
+	}
+	if _, err := comments.GetCommentByID(pending.ID); err != nil {
+		t.Fatalf("author cannot see pending comment: %v", err)
+	}
+
+	items := f.queue(t, consts.ModerationPending)
+	if len(items) != 1 || items[0].EntityType != consts.EntityComment {
+		t.Fatalf("queue = %+v", items)
+	}
+	if _, err := f.moderation.Approve(items[0].ID, ""); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if f.lastEvent() != consts.EventCommentCreated {
+		t.Fatalf("last event = %s", f.lastEvent())
+	}
+	if listed, _ := f.comments.ListByPosts([]uint{post.ID}); len(listed) != 2 {
+		t.Fatalf("listed after approve = %d", len(listed))
+	}
+
+	// 删除待审核的评论后条目被撤回
+	withdrawn, _ := create("more crypto")
+	if err := comments.DeleteByID(withdrawn.ID); err != nil {
+		t.Fatalf("DeleteByID: %v", err)
+	}
+	if items := f.queue(t, consts.ModerationWithdrawn); len(items) != 1 || items[0].EntityID != withdrawn.ID {
+		t.Fatalf("withdrawn = %+v", items)
+	}
+}
+
+func TestModerationLoadTraining(t *testing.T) {
+	f := newModerationFixture(t)
+	author := f.createUser(t, "alice")
+	posts := f.posts.WithContext(asUser(author.ID))
+	for i, text := range []string{"crypto pills", "crypto loans", "crypto money", "crypto deals", "crypto offer", "crypto history", "crypto research", "crypto paper", "crypto lecture", "crypto survey"} {
+		if _, err := posts.CreatePost(postDto(nil, text, "content", "", nil, nil)); err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+		items := f.queue(t, consts.ModerationPending)
+		decide := f.moderation.Approve
+		if i < 5 {
+			decide = f.moderation.Reject
+		}
+		if _, err := decide(items[0].ID, ""); err != nil {
+			t.Fatalf("decide: %v", err)
+		}
+	}
+
+	// 新的分类器从审核记录中学习
+	f.pipeline = moderation.New(config.ModerationConfig{Enabled: true})
+	f.moderation.pipeline = f.pipeline
+	if err := f.moderation.LoadTraining(context.Background()); err != nil {
+		t.Fatalf("LoadTraining: %v", err)
+	}
+	result := f.pipeline.Check(moderation.Content{AuthorID: author.ID, Text: "cheap pills and loans"})
+	if result.SpamScore < 0.5 {
+		t.Fatalf("score = %v", result.SpamScore)
+	}
+}
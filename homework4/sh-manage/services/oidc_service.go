@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// 登录流程中 state 的有效期
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCProvider 一个已完成服务发现的身份提供方
+type OIDCProvider struct {
+	Name     string
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+}
+
+// OIDCClaims ID Token 中用到的声明
+type OIDCClaims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	Nonce             string `json:"nonce"`
+}
+
+type oidcLoginState struct {
+	provider  string
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+type OIDCService struct {
+	db          *gorm.DB
+	userService *UserService
+
+	mu        sync.Mutex
+	providers map[string]*OIDCProvider
+	states    map[string]oidcLoginState
+}
+
+func NewOIDCService(db *gorm.DB, userService *UserService) *OIDCService {
+	return &OIDCService{
+		db:          db,
+		userService: userService,
+		providers:   make(map[string]*OIDCProvider),
+		states:      make(map[string]oidcLoginState),
+	}
+}
+
+// RegisterProvider 通过 issuer 的 .well-known/openid-configuration 完成服务发现并注册提供方
+// ctx 中可以通过 oidc.ClientContext 指定发现和换取令牌使用的 http.Client
+func (s *OIDCService) RegisterProvider(ctx context.Context, name string, cfg config.OIDCProviderConfig) error {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return fmt.Errorf("oidc discovery for %s failed: %w", name, err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.providers[name] = &OIDCProvider{
+		Name:     name,
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+	}
+	return nil
+}
+
+func (s *OIDCService) getProvider(name string) (*OIDCProvider, *utils.AppError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, utils.NewError(utils.ErrOIDCProviderNotFound, "OIDC provider not found")
+	}
+	return provider, nil
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址（授权码 + PKCE）
+func (s *OIDCService) AuthCodeURL(name string) (string, *utils.AppError) {
+	provider, err := s.getProvider(name)
+	if err != nil {
+		return "", err
+	}
+
+	state, e := randomToken()
+	if e != nil {
+		return "", utils.NewError(utils.ErrInternal, "Failed to generate state").Wrap(e)
+	}
+	nonce, e := randomToken()
+	if e != nil {
+		return "", utils.NewError(utils.ErrInternal, "Failed to generate nonce").Wrap(e)
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	s.mu.Lock()
+	s.purgeExpiredStates()
+	s.states[state] = oidcLoginState{
+		provider:  name,
+		verifier:  verifier,
+		nonce:     nonce,
+		expiresAt: time.Now().Add(oidcStateTTL),
+	}
+	s.mu.Unlock()
+
+	return provider.oauth2.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(verifier),
+	), nil
+}
+
+// Exchange 使用回调中的授权码换取令牌，校验 ID Token 并返回绑定或新建的本地用户
+func (s *OIDCService) Exchange(ctx context.Context, name, state, code string) (*models.User, *utils.AppError) {
+	provider, err := s.getProvider(name)
+	if err != nil {
+		return nil, err
+	}
+
+	loginState, ok := s.takeState(state)
+	if !ok || loginState.provider != name {
+		return nil, utils.NewError(utils.ErrOIDCStateInvalid, "Invalid or expired state")
+	}
+	if code == "" {
+		return nil, utils.NewError(utils.ErrOIDCStateInvalid, "Authorization code is missing")
+	}
+
+	token, e := provider.oauth2.Exchange(ctx, code, oauth2.VerifierOption(loginState.verifier))
+	if e != nil {
+		return nil, utils.NewError(utils.ErrOIDCExchangeFailed, "Failed to exchange authorization code")
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, utils.NewError(utils.ErrOIDCTokenInvalid, "ID token is missing")
+	}
+
+	idToken, e := provider.verifier.Verify(ctx, rawIDToken)
+	if e != nil {
+		return nil, utils.NewError(utils.ErrOIDCTokenInvalid, "Invalid ID token")
+	}
+
+	var claims OIDCClaims
+	if e := idToken.Claims(&claims); e != nil {
+		return nil, utils.NewError(utils.ErrOIDCTokenInvalid, "Invalid ID token claims")
+	}
+	if claims.Nonce != loginState.nonce {
+		return nil, utils.NewError(utils.ErrOIDCTokenInvalid, "Invalid ID token nonce")
+	}
+
+	user, appErr := s.linkOrCreateUser(s.db.WithContext(ctx), name, &claims)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if appErr := accountRestriction(user, time.Now()); appErr != nil {
+		return nil, appErr
+	}
+	return user, nil
+}
+
+// linkOrCreateUser 按 provider+sub 查找绑定关系；不存在时按已验证邮箱绑定已有用户，否则新建用户
+// db 携带请求的 context，绑定关系和用户都限定在请求所属的租户
+func (s *OIDCService) linkOrCreateUser(db *gorm.DB, provider string, claims *OIDCClaims) (*models.User, *utils.AppError) {
+	var identity models.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+	if err == nil {
+		user, e := s.userService.GetUserByID(identity.UserId)
+		if e != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve user").Wrap(e)
+		}
+		return user, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve identity").Wrap(err)
+	}
+
+	if claims.Email == "" {
+		return nil, utils.NewError(utils.ErrOIDCEmailMissing, "Email claim is required")
+	}
+	// 未验证的邮箱既不能绑定也不能注册，否则可以抢注他人邮箱，真正的主人之后用已验证邮箱登录时会被绑定到这个账号
+	if !claims.EmailVerified {
+		return nil, utils.NewError(utils.ErrEmailNotVerified, "")
+	}
+
+	var user *models.User
+	var existing models.User
+	err = db.Where("email = ?", claims.Email).First(&existing).Error
+	switch {
+	case err == nil:
+		user = &existing
+	case err == gorm.ErrRecordNotFound:
+		created, appErr := s.createUser(db, claims)
+		if appErr != nil {
+			return nil, appErr
+		}
+		user = created
+	default:
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve user").Wrap(err)
+	}
+
+	identity = models.UserIdentity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+		UserId:   user.ID,
+	}
+	if err := db.Create(&identity).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to link identity").Wrap(err)
+	}
+	return user, nil
+}
+
+func (s *OIDCService) createUser(db *gorm.DB, claims *OIDCClaims) (*models.User, *utils.AppError) {
+	username, appErr := s.availableUsername(db, claims)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	// 外部登录的用户没有本地密码，写入一个随机密码的哈希占位
+	randomPassword, err := randomToken()
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to generate password").Wrap(err)
+	}
+	hashedPassword, err := s.userService.passwords.Hash(randomPassword)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to hash password").Wrap(err)
+	}
+
+	user := &models.User{
+		Username: username,
+		Email:    claims.Email,
+		Password: hashedPassword,
+		Role:     consts.RoleUser,
+	}
+	if err := db.Create(user).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create user").Wrap(err)
+	}
+	return user, nil
+}
+
+// availableUsername 从 preferred_username 或邮箱前缀生成一个未被占用的用户名
+func (s *OIDCService) availableUsername(db *gorm.DB, claims *OIDCClaims) (string, *utils.AppError) {
+	base := sanitizeUsername(claims.PreferredUsername)
+	if len(base) < 3 {
+		base = sanitizeUsername(strings.SplitN(claims.Email, "@", 2)[0])
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 1; i <= 100; i++ {
+		var count int64
+		if err := db.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", utils.NewError(utils.ErrInternal, "Failed to retrieve user").Wrap(err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", utils.NewError(utils.ErrUserExists, "Username already exists")
+}
+
+func (s *OIDCService) takeState(state string) (oidcLoginState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loginState, ok := s.states[state]
+	if !ok {
+		return oidcLoginState{}, false
+	}
+	// state 只能使用一次
+	delete(s.states, state)
+	if time.Now().After(loginState.expiresAt) {
+		return oidcLoginState{}, false
+	}
+	return loginState, true
+}
+
+// purgeExpiredStates 清理过期的 state，调用方需持有锁
+func (s *OIDCService) purgeExpiredStates() {
+	now := time.Now()
+	for key, loginState := range s.states {
+		if now.After(loginState.expiresAt) {
+			delete(s.states, key)
+		}
+	}
+}
+
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, ch := range strings.ToLower(name) {
+		if (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '-' || ch == '.' {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
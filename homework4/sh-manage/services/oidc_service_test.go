@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sh-manage/config"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	fakeClientID     = "sh-manage"
+	fakeClientSecret = "secret"
+	fakeRedirectURL  = "http://localhost/callback"
+)
+
+// fakeOIDCProvider 进程内的 OIDC 提供方，支持服务发现、授权码 + PKCE 和 JWKS
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu       sync.Mutex
+	codes    map[string]fakeAuthRequest
+	claims   jwt.MapClaims
+	audience string
+}
+
+type fakeAuthRequest struct {
+	nonce     string
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := &fakeOIDCProvider{key: key, codes: make(map[string]fakeAuthRequest), audience: fakeClientID}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeOIDCProvider) setUser(claims jwt.MapClaims) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims = claims
+}
+
+func (p *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := p.server.URL
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *fakeOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != fakeClientID {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code := fmt.Sprintf("code-%d", time.Now().UnixNano())
+	p.mu.Lock()
+	p.codes[code] = fakeAuthRequest{nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), claims: p.claims}
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", q.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != fakeClientID || clientSecret != fakeClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	p.mu.Lock()
+	req, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   p.audience,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": req.nonce,
+	}
+	for k, v := range req.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, "server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (p *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test-key",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(models.GetModels()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func newTestOIDCService(t *testing.T) (*OIDCService, *fakeOIDCProvider, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	provider := newFakeOIDCProvider(t)
+	svc := NewOIDCService(db, NewUserService(db, nil))
+	err := svc.RegisterProvider(context.Background(), "fake", config.OIDCProviderConfig{
+		Issuer:       provider.server.URL,
+		ClientID:     fakeClientID,
+		ClientSecret: fakeClientSecret,
+		RedirectURL:  fakeRedirectURL,
+	})
+	if err != nil {
+		t.Fatalf("register provider: %v", err)
+	}
+	return svc, provider, db
+}
+
+// authorize 模拟浏览器访问授权地址，返回回调中的 code 和 state
+func authorize(t *testing.T, svc *OIDCService) (string, string) {
+	t.Helper()
+	authURL, appErr := svc.AuthCodeURL("fake")
+	if appErr != nil {
+		t.Fatalf("auth url: %v", appErr)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestOIDCLoginCreatesAndReusesUser(t *testing.T) {
+	svc, provider, db := newTestOIDCService(t)
+	provider.setUser(jwt.MapClaims{
+		"sub":                "alice-sub",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "Alice",
+	})
+
+	code, state := authorize(t, svc)
+	user, appErr := svc.Exchange(context.Background(), "fake", state, code)
+	if appErr != nil {
+		t.Fatalf("exchange: %v", appErr)
+	}
+	if user.Username != "alice" || user.Email != "alice@example.com" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	code, state = authorize(t, svc)
+	again, appErr := svc.Exchange(context.Background(), "fake", state, code)
+	if appErr != nil {
+		t.Fatalf("second exchange: %v", appErr)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("second login created user %d, want %d", again.ID, user.ID)
+	}
+
+	var identities int64
+	db.Model(&models.UserIdentity{}).Count(&identities)
+	if identities != 1 {
+		t.Fatalf("identities = %d, want 1", identities)
+	}
+}
+
+func TestOIDCLoginLinksVerifiedEmail(t *testing.T) {
+	svc, provider, db := newTestOIDCService(t)
+	existing := &models.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	db.Create(existing)
+
+	provider.setUser(jwt.MapClaims{"sub": "bob-sub", "email": "bob@example.com", "email_verified": true})
+	code, state := authorize(t, svc)
+	user, appErr := svc.Exchange(context.Background(), "fake", state, code)
+	if appErr != nil {
+		t.Fatalf("exchange: %v", appErr)
+	}
+	if user.ID != existing.ID {
+		t.Fatalf("linked user %d, want %d", user.ID, existing.ID)
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedExistingEmail(t *testing.T) {
+	svc, provider, db := newTestOIDCService(t)
+	db.Create(&models.User{Username: "carol", Email: "carol@example.com", Password: "x"})
+
+	provider.setUser(jwt.MapClaims{"sub": "carol-sub", "email": "carol@example.com", "email_verified": false})
+	code, state := authorize(t, svc)
+	if _, appErr := svc.Exchange(context.Background(), "fake", state, code); appErr == nil || appErr.ErrorCode != utils.ErrEmailNotVerified {
+		t.Fatalf("expected %s, got %v", utils.ErrEmailNotVerified, appErr)
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedNewEmail(t *testing.T) {
+	svc, provider, db := newTestOIDCService(t)
+
+	// 未验证的邮箱不能抢注账号
+	provider.setUser(jwt.MapClaims{"sub": "mallory-sub", "email": "victim@example.com", "email_verified": false})
+	code, state := authorize(t, svc)
+	if _, appErr := svc.Exchange(context.Background(), "fake", state, code); appErr == nil || appErr.ErrorCode != utils.ErrEmailNotVerified {
+		t.Fatalf("expected %s, got %v", utils.ErrEmailNotVerified, appErr)
+	}
+	var users, identities int64
+	db.Model(&models.User{}).Count(&users)
+	db.Model(&models.UserIdentity{}).Count(&identities)
+	if users != 0 || identities != 0 {
+		t.Fatalf("users = %d, identities = %d, want none", users, identities)
+	}
+
+	// 邮箱的主人之后用已验证的邮箱登录时创建自己的账号
+	provider.setUser(jwt.MapClaims{"sub": "victim-sub", "email": "victim@example.com", "email_verified": true})
+	code, state = authorize(t, svc)
+	user, appErr := svc.Exchange(context.Background(), "fake", state, code)
+	if appErr != nil || user.Email != "victim@example.com" {
+		t.Fatalf("exchange = %+v, %v", user, appErr)
+	}
+}
+
+func TestOIDCLoginGeneratesUniqueUsername(t *testing.T) {
+	svc, provider, db := newTestOIDCService(t)
+	db.Create(&models.User{Username: "dave", Email: "dave@other.com", Password: "x"})
+
+	provider.setUser(jwt.MapClaims{"sub": "dave-sub", "email": "dave@example.com", "email_verified": true, "preferred_username": "dave"})
+	code, state := authorize(t, svc)
+	user, appErr := svc.Exchange(context.Background(), "fake", state, code)
+	if appErr != nil {
+		t.Fatalf("exchange: %v", appErr)
+	}
+	if user.Username != "dave1" {
+		t.Fatalf("username = %s, want dave1", user.Username)
+	}
+}
+
+func TestOIDCExchangeFailures(t *testing.T) {
+	svc, provider, _ := newTestOIDCService(t)
+	provider.setUser(jwt.MapClaims{"sub": "eve-sub", "email": "eve@example.com", "email_verified": true})
+
+	tests := []struct {
+		name     string
+		prepare  func() (provider, state, code string)
+		wantCode int
+	}{
+		{
+			name: "unknown provider",
+			prepare: func() (string, string, string) {
+				code, state := authorize(t, svc)
+				return "missing", state, code
+			},
+			wantCode: 404,
+		},
+		{
+			name: "unknown state",
+			prepare: func() (string, string, string) {
+				code, _ := authorize(t, svc)
+				return "fake", "forged-state", code
+			},
+			wantCode: 400,
+		},
+		{
+			name: "state reused",
+			prepare: func() (string, string, string) {
+				code, state := authorize(t, svc)
+				if _, appErr := svc.Exchange(context.Background(), "fake", state, code); appErr != nil {
+					t.Fatalf("first exchange: %v", appErr)
+				}
+				code, _ = authorize(t, svc)
+				return "fake", state, code
+			},
+			wantCode: 400,
+		},
+		{
+			name: "code from another login (PKCE mismatch)",
+			prepare: func() (string, string, string) {
+				code, _ := authorize(t, svc)
+				_, state := authorize(t, svc)
+				return "fake", state, code
+			},
+			wantCode: 401,
+		},
+		{
+			name: "wrong audience",
+			prepare: func() (string, string, string) {
+				provider.mu.Lock()
+				provider.audience = "someone-else"
+				provider.mu.Unlock()
+				t.Cleanup(func() {
+					provider.mu.Lock()
+					provider.audience = fakeClientID
+					provider.mu.Unlock()
+				})
+				code, state := authorize(t, svc)
+				return "fake", state, code
+			},
+			wantCode: 401,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, state, code := tt.prepare()
+			_, appErr := svc.Exchange(context.Background(), name, state, code)
+			if appErr == nil || appErr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %v", tt.wantCode, appErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package services
+
+import "sh-manage/utils"
+
+// versionConflict 返回 409，并携带服务端当前状态供客户端合并
+func versionConflict(current interface{}) *utils.AppError {
+	return utils.NewError(utils.ErrVersionConflict, "Version conflict, resource has been modified").WithData(current)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"net/http/httptest"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServiceUpdatesReturnConflict(t *testing.T) {
+	db := newTestDB(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PUT", "/", nil)
+	c.Set(consts.UserID, uint(1))
+
+	userService := NewUserService(db, nil)
+	postService := NewPostService(db, userService, nil, c)
+	commentService := NewCommentService(db, userService, c)
+
+	post := &models.Post{Title: "title", Content: "content", UserId: 1}
+	db.Create(post)
+	comment := &models.Comment{Content: "content", UserId: 1, PostId: post.ID}
+	db.Create(comment)
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	db.Create(user)
+
+	text, stale, fresh := "changed", uint(0), uint(1)
+
+	if _, err := postService.UpdatePost(&dto.PostDto{ID: &post.ID, Title: &text, Content: &text, Version: &stale}); err == nil || err.Code != 409 {
+		t.Fatalf("post: expected 409, got %v", err)
+	} else if current, ok := err.Data.(models.PostResponse); !ok || current.Version != 1 {
+		t.Fatalf("post: conflict data = %#v", err.Data)
+	}
+	updatedPost, err := postService.UpdatePost(&dto.PostDto{ID: &post.ID, Title: &text, Content: &text, Version: &fresh})
+	if err != nil || updatedPost.Version != 2 || updatedPost.Title != text {
+		t.Fatalf("post: update = %+v, %v", updatedPost, err)
+	}
+
+	if _, err := commentService.UpdateComment(&dto.CommentDto{ID: &comment.ID, Content: &text, Version: &stale}); err == nil || err.Code != 409 {
+		t.Fatalf("comment: expected 409, got %v", err)
+	}
+	if _, err := commentService.UpdateComment(&dto.CommentDto{ID: &comment.ID, Content: &text}); err == nil || err.Code != 400 {
+		t.Fatalf("comment: expected 400 without version, got %v", err)
+	}
+
+	if _, err := userService.UpdateUser(user.ID, models.UpdateUserRequest{Email: &text, Version: &stale}); err == nil {
+		t.Fatalf("user: expected conflict")
+	}
+}
@@ -1,11 +1,17 @@
 package services
 
 import (
+	"context"
+	"sh-manage/cache"
+	"sh-manage/consts"
 	"sh-manage/dto"
+	"sh-manage/events"
 	"sh-manage/models"
-	"sh-manage/tools"
+	"sh-manage/moderation"
+	"sh-manage/repository"
 	"sh-manage/utils"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,18 +19,64 @@ import (
 
 type PostService struct {
 	// 这里可以添加数据库连接等依赖
-	db          *gorm.DB
-	context     *gin.Context
-	userService *UserService
+	posts        repository.PostRepository
+	comments     repository.CommentRepository
+	tx           repository.UnitOfWork
+	cache        cache.Cache
+	context      *gin.Context
+	userService  *UserService
+	auditService AuditRecorder
+	publisher    events.Publisher
+	moderator    Moderator
 }
 
-func NewPostService(db *gorm.DB, userService *UserService, c *gin.Context) *PostService {
-	return &PostService{db: db, userService: userService, context: c}
+// cacheStore 为 nil 时不使用缓存
+func NewPostService(db *gorm.DB, userService *UserService, cacheStore cache.Cache, c *gin.Context) *PostService {
+	service := NewPostServiceWithRepositories(repository.NewGorm(db), userService, cacheStore, NewAuditService(db))
+	service.context = c
+	return service
+}
+
+// NewPostServiceWithRepositories 使用指定的存储和审计实现，测试中传入内存实现
+func NewPostServiceWithRepositories(repos repository.Repositories, userService *UserService, cacheStore cache.Cache, audit AuditRecorder) *PostService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	return &PostService{posts: repos.Posts, comments: repos.Comments, tx: repos.Tx, userService: userService, cache: cacheStore, auditService: audit, publisher: events.Nop{}}
+}
+
+// SetPublisher 设置文章变更事件的发布者
+func (p *PostService) SetPublisher(publisher events.Publisher) {
+	p.publisher = publisher
+}
+
+// SetModerator 设置发布前的内容审核，为 nil 时不审核
+func (p *PostService) SetModerator(moderator Moderator) {
+	p.moderator = moderator
+}
+
+// WithContext 返回绑定当前请求的副本，用于获取当前用户和记录审计日志
+func (p *PostService) WithContext(c *gin.Context) *PostService {
+	clone := *p
+	clone.context = c
+	clone.cache = tenantCache(p.cache, requestContext(c))
+	return &clone
+}
+
+func (p *PostService) currentUserID() uint {
+	if p.context == nil {
+		return 0
+	}
+	return utils.GetCurrentUserID(p.context)
+}
+
+func (p *PostService) ctx() context.Context {
+	return requestContext(p.context)
 }
 
 func (p *PostService) CreatePost(post *dto.PostDto) (*models.Post, *utils.AppError) {
 	if post == nil {
-		return nil, utils.NewAppError(500, "参数不能为空")
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
 	}
 
 	if err := post.Validate(); err != nil {
@@ -34,72 +86,264 @@ func (p *PostService) CreatePost(post *dto.PostDto) (*models.Post, *utils.AppErr
 	postModel := &models.Post{
 		Title:   *post.Title,
 		Content: *post.Content,
-		UserId:  utils.GetCurrentUserID(p.context),
+		UserId:  p.currentUserID(),
+		Status:  consts.PostStatusPublished,
+	}
+	if post.Status != nil {
+		postModel.Status = *post.Status
+	}
+	// 草稿不审核，发布时命中审核规则的文章进入待审核状态
+	var review *moderation.Result
+	if postModel.IsPublished() {
+		result, err := moderate(p.ctx(), p.moderator, postModerationRequest(postModel))
+		if err != nil {
+			return nil, err
+		}
+		if result.Verdict == moderation.Review {
+			postModel.Status = consts.PostStatusPendingReview
+			review = &result
+		}
+	}
+	if postModel.IsPublished() {
+		now := time.Now()
+		postModel.PublishedAt = &now
 	}
 
-	if err := p.db.Create(&postModel).Error; err != nil {
-		return nil, utils.NewAppError(500, "Failed to create post")
+	if err := p.posts.Create(p.ctx(), postModel, normalizeTagNames(post.Tags)); err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create post").Wrap(err)
 	}
+	invalidatePostList(p.cache)
+	if review != nil {
+		if err := enqueueForReview(p.ctx(), p.moderator, postModerationRequest(postModel), consts.ModerationActionCreate, *review); err != nil {
+			return nil, err
+		}
+	}
+
+	p.auditService.Record(p.context, AuditEntry{
+		Action:     consts.AuditPostCreate,
+		EntityType: consts.EntityPost,
+		EntityID:   postModel.ID,
+		After:      postModel.ToResponse(),
+	})
+	p.publisher.Publish(newEvent(p.ctx(), consts.EventPostCreated, postModel.ToResponse()))
 	return postModel, nil
 
 }
+
+// GetPostByID 草稿只对作者可见，其他人返回 404
 func (p *PostService) GetPostByID(postID uint) (*models.Post, *utils.AppError) {
-	var post models.Post
-	if err := p.db.First(&post, postID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, utils.NewAppError(404, "Post not found")
-		}
-		return nil, utils.NewAppError(500, "Failed to retrieve Post")
+	post, err := readThrough(p.cache, postCacheKey(postID), func() (*models.Post, *utils.AppError) {
+		return p.findPostByID(postID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !post.IsPublished() && post.UserId != p.currentUserID() {
+		return nil, utils.NewError(utils.ErrPostNotFound, "Post not found")
 	}
-	return &post, nil
+	return post, nil
+}
+
+// findPostByID 直接查询数据库，写操作使用，避免基于缓存中的旧数据修改
+func (p *PostService) findPostByID(postID uint) (*models.Post, *utils.AppError) {
+	post, err := p.posts.FindByID(p.ctx(), postID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrPostNotFound, "Failed to retrieve Post")
+	}
+	return post, nil
 }
 
 func (p *PostService) GetPostByPage(postPageDTO *dto.PostPageDTO) (*dto.PageResult[models.Post], *utils.AppError) {
 
-	db := p.db.Model(&models.Post{})
-	if postPageDTO.Title != nil || strings.TrimSpace(*postPageDTO.Title) != "" {
-		db.Where("", postPageDTO.Title)
+	filter := repository.PostFilter{Status: consts.PostStatusPublished}
+	if postPageDTO.Title != nil {
+		filter.Title = strings.TrimSpace(*postPageDTO.Title)
 	}
-	if postPageDTO.Content != nil || strings.TrimSpace(*postPageDTO.Content) != "" {
-		db.Where("", postPageDTO.Content)
+	if postPageDTO.Content != nil {
+		filter.Content = strings.TrimSpace(*postPageDTO.Content)
 	}
 	// 执行分页查询
-	var posts []models.Post
-	return tools.Paginate(db, postPageDTO.BasePageQuery, &posts)
+	return readThrough(p.cache, postPageCacheKey(p.cache, postPageDTO), func() (*dto.PageResult[models.Post], *utils.AppError) {
+		page, err := p.posts.Page(p.ctx(), filter, postPageDTO.BasePageQuery)
+		if err != nil {
+			return nil, repositoryError(err, utils.ErrPostNotFound, "Failed to retrieve Post")
+		}
+		return page, nil
+	})
 }
 
 func (p *PostService) UpdatePost(post *dto.PostDto) (*models.Post, *utils.AppError) {
+	return p.UpdatePostIfMatch(post, "")
+}
+
+// UpdatePostIfMatch ifMatch 不为空时按 If-Match 条件修改：ETag 与数据库中的当前版本一致才允许修改，
+// 预期版本号取自匹配的版本并作为更新条件，检查后被其他请求修改时同样返回 412
+func (p *PostService) UpdatePostIfMatch(post *dto.PostDto, ifMatch string) (*models.Post, *utils.AppError) {
 	if post == nil {
-		return nil, utils.NewAppError(500, "参数不能为空")
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
 	}
 	if post.ID == nil {
-		return nil, utils.NewAppError(500, "参数ID不能为空")
+		return nil, utils.NewError(utils.ErrBadRequest, "参数ID不能为空")
 	}
-	existPost, err := p.GetPostByID(*post.ID)
+	if post.Version == nil && ifMatch == "" {
+		return nil, utils.NewError(utils.ErrVersionRequired, "版本号不能为空")
+	}
+	existPost, err := p.findPostByID(*post.ID)
 	if err != nil {
 		return nil, err
 	}
+	if existPost.UserId != p.currentUserID() {
+		return nil, utils.NewError(utils.ErrNotAuthor, "Only the author can modify this post")
+	}
+	if ifMatch != "" {
+		if !utils.IfMatchSatisfied(ifMatch, existPost.ToResponse()) {
+			return nil, utils.NewError(utils.ErrPreconditionFailed, "Resource has been modified")
+		}
+		if post.Version == nil {
+			post.Version = &existPost.Version
+		}
+	}
+	if existPost.Version != *post.Version {
+		return nil, versionConflict(existPost.ToResponse())
+	}
 
 	if err := post.Validate(); err != nil {
 		return nil, err
 	}
 
-	existPost.Title = *post.Title
-	existPost.Content = *post.Content
+	before := existPost.ToResponse()
+	changes := repository.PostChanges{
+		Title:   post.Title,
+		Content: post.Content,
+		Status:  post.Status,
+	}
+	review, err := p.moderateUpdate(existPost, post, &changes)
+	if err != nil {
+		return nil, err
+	}
+	// 第一次发布时记录发布时间，重新发布保留原时间
+	if changes.Status != nil && *changes.Status == consts.PostStatusPublished && existPost.PublishedAt == nil {
+		now := time.Now()
+		changes.PublishedAt = &now
+	}
+	if post.Tags != nil {
+		changes.Tags = normalizeTagNames(post.Tags)
+	}
+	updated, e := p.posts.Update(p.ctx(), existPost.ID, *post.Version, changes)
+	if e != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update post").Wrap(e)
+	}
+	invalidate(p.cache, postCacheKey(existPost.ID))
+	invalidatePostList(p.cache)
 
-	if err := p.db.Save(&existPost).Error; err != nil {
-		return nil, utils.NewAppError(500, "Failed to create post")
+	existPost, err = p.findPostByID(existPost.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		if ifMatch != "" {
+			return nil, utils.NewError(utils.ErrPreconditionFailed, "Resource has been modified")
+		}
+		return nil, versionConflict(existPost.ToResponse())
+	}
+	switch {
+	case review != nil:
+		if err := enqueueForReview(p.ctx(), p.moderator, postModerationRequest(existPost), consts.ModerationActionUpdate, *review); err != nil {
+			return nil, err
+		}
+	case before.Status == consts.PostStatusPendingReview:
+		withdrawReview(p.ctx(), p.moderator, consts.EntityPost, existPost.ID)
 	}
+
+	p.auditService.Record(p.context, AuditEntry{
+		Action:     consts.AuditPostUpdate,
+		EntityType: consts.EntityPost,
+		EntityID:   existPost.ID,
+		Before:     before,
+		After:      existPost.ToResponse(),
+	})
+	event := newEvent(p.ctx(), consts.EventPostUpdated, existPost.ToResponse())
+	event.PreviousStatus = before.Status
+	p.publisher.Publish(event)
 	return existPost, nil
 
 }
 
 func (p *PostService) DeleteByID(postID uint) *utils.AppError {
-	if err := p.db.Delete(&models.Post{}, postID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return utils.NewAppError(404, "Post not found")
+	existPost, err := p.findPostByID(postID)
+	if err != nil {
+		return err
+	}
+	if existPost.UserId != p.currentUserID() {
+		return utils.NewError(utils.ErrNotAuthor, "Only the author can delete this post")
+	}
+
+	// 文章和文章下的评论一起删除
+	if err := inTransaction(p.ctx(), p.tx, "Failed to delete Post", func(ctx context.Context) *utils.AppError {
+		if err := p.comments.DeleteByPosts(ctx, []uint{postID}); err != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to delete Post comments").Wrap(err)
+		}
+		if err := p.posts.Delete(ctx, postID); err != nil {
+			return repositoryError(err, utils.ErrPostNotFound, "Failed to delete Post")
 		}
-		return utils.NewAppError(500, "Failed to delete Post")
+		return nil
+	}); err != nil {
+		return err
+	}
+	invalidate(p.cache, postCacheKey(postID))
+	invalidatePostList(p.cache)
+	if existPost.Status == consts.PostStatusPendingReview {
+		withdrawReview(p.ctx(), p.moderator, consts.EntityPost, postID)
 	}
+
+	p.auditService.Record(p.context, AuditEntry{
+		Action:     consts.AuditPostDelete,
+		EntityType: consts.EntityPost,
+		EntityID:   postID,
+		Before:     existPost.ToResponse(),
+	})
+	p.publisher.Publish(newEvent(p.ctx(), consts.EventPostDeleted, existPost.ToResponse()))
 	return nil
 }
+
+// moderateUpdate 修改后要发布的文章重新审核，返回需要加入审核队列的结果
+// 待审核或被拒绝的文章不能直接发布，只能重新提交审核；未指定状态时待审核的文章修改后重新审核
+func (p *PostService) moderateUpdate(existPost *models.Post, post *dto.PostDto, changes *repository.PostChanges) (*moderation.Result, *utils.AppError) {
+	if p.moderator == nil {
+		return nil, nil
+	}
+	status := existPost.Status
+	if post.Status != nil {
+		status = *post.Status
+	}
+	if status != consts.PostStatusPublished && status != consts.PostStatusPendingReview {
+		return nil, nil
+	}
+
+	merged := *existPost
+	if post.Title != nil {
+		merged.Title = *post.Title
+	}
+	if post.Content != nil {
+		merged.Content = *post.Content
+	}
+	result, err := moderate(p.ctx(), p.moderator, postModerationRequest(&merged))
+	if err != nil {
+		return nil, err
+	}
+	if result.Verdict != moderation.Review && existPost.Status != consts.PostStatusPendingReview && existPost.Status != consts.PostStatusRejected {
+		return nil, nil
+	}
+	pending := consts.PostStatusPendingReview
+	changes.Status = &pending
+	return &result, nil
+}
+
+func postModerationRequest(post *models.Post) moderationRequest {
+	return moderationRequest{
+		entityType: consts.EntityPost,
+		entityID:   post.ID,
+		authorID:   post.UserId,
+		text:       post.Title + "\n" + post.Content,
+	}
+}
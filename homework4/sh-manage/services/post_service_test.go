@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/utils"
+	"testing"
+)
+
+func TestPostServiceCreate(t *testing.T) {
+	f := newMemoryFixture(t)
+
+	tests := []struct {
+		name          string
+		dto           *dto.PostDto
+		wantCode      int
+		wantStatus    string
+		wantPublished bool
+		wantTags      string
+	}{
+		{"published with tags", postDto(nil, "hello", "world", "", nil, []string{" Go ", "go", "Gin"}), 0, consts.PostStatusPublished, true, "[go gin]"},
+		{"draft", postDto(nil, "draft", "wip", consts.PostStatusDraft, nil, nil), 0, consts.PostStatusDraft, false, "[]"},
+		{"nil dto", nil, 400, "", false, ""},
+		{"empty title", postDto(nil, " ", "world", "", nil, nil), 422, "", false, ""},
+		{"bad status", postDto(nil, "hello", "world", "archived", nil, nil), 422, "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.events = nil
+			post, err := f.posts.WithContext(asUser(1)).CreatePost(tt.dto)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode != 0 {
+				if len(f.events) != 0 {
+					t.Fatalf("failed create published %d events", len(f.events))
+				}
+				return
+			}
+			if post.UserId != 1 || post.Status != tt.wantStatus || (post.PublishedAt != nil) != tt.wantPublished {
+				t.Fatalf("post = %+v", post)
+			}
+			if got := fmt.Sprint(post.TagNames()); got != tt.wantTags {
+				t.Fatalf("tags = %s, want %s", got, tt.wantTags)
+			}
+			if len(f.events) != 1 || f.events[0].Type != consts.EventPostCreated {
+				t.Fatalf("events = %+v", f.events)
+			}
+			if entry := f.audit.last(); entry.Action != consts.AuditPostCreate || entry.EntityID != post.ID {
+				t.Fatalf("audit = %+v", entry)
+			}
+		})
+	}
+}
+
+func TestPostServiceGet(t *testing.T) {
+	f := newMemoryFixture(t)
+	published := f.createPost(t, 1, "published", "")
+	draft := f.createPost(t, 1, "draft", consts.PostStatusDraft)
+
+	tests := []struct {
+		name     string
+		userID   uint
+		postID   uint
+		wantCode int
+	}{
+		{"published anonymous", 0, published.ID, 0},
+		{"draft by author", 1, draft.ID, 0},
+		{"draft by other user", 2, draft.ID, 404},
+		{"draft anonymous", 0, draft.ID, 404},
+		{"missing", 1, 999, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := f.posts.WithContext(asUser(tt.userID)).GetPostByID(tt.postID)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode == 0 && post.ID != tt.postID {
+				t.Fatalf("post = %+v", post)
+			}
+		})
+	}
+}
+
+func TestPostServicePage(t *testing.T) {
+	f := newMemoryFixture(t)
+	first := f.createPost(t, 1, "Learning Go", "")
+	f.createPost(t, 1, "Go draft", consts.PostStatusDraft)
+	second := f.createPost(t, 2, "Gin middleware", "")
+
+	tests := []struct {
+		name    string
+		query   dto.PostPageDTO
+		wantIDs string
+	}{
+		{"published only, newest first", dto.PostPageDTO{BasePageQuery: *dto.NewBasePageQuery()}, fmt.Sprint([]uint{second.ID, first.ID})},
+		{"title filter", dto.PostPageDTO{BasePageQuery: *dto.NewBasePageQuery(), Title: ptr(" go ")}, fmt.Sprint([]uint{first.ID})},
+		{"content filter", dto.PostPageDTO{BasePageQuery: *dto.NewBasePageQuery(), Content: ptr("middleware")}, fmt.Sprint([]uint{second.ID})},
+		{"page size", dto.PostPageDTO{BasePageQuery: dto.BasePageQuery{Page: 2, PageSize: 1, OrderBy: "id", Order: "desc"}}, fmt.Sprint([]uint{first.ID})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := f.posts.GetPostByPage(&tt.query)
+			if err != nil {
+				t.Fatalf("GetPostByPage = %v", err)
+			}
+			var ids []uint
+			for _, post := range page.Items {
+				ids = append(ids, post.ID)
+			}
+			if fmt.Sprint(ids) != tt.wantIDs {
+				t.Fatalf("ids = %v, want %s", ids, tt.wantIDs)
+			}
+		})
+	}
+
+	if _, err := f.posts.GetPostByPage(&dto.PostPageDTO{BasePageQuery: dto.BasePageQuery{Page: 0, PageSize: 10}}); err == nil {
+		t.Fatalf("invalid page accepted")
+	}
+}
+
+func TestPostServiceUpdate(t *testing.T) {
+	f := newMemoryFixture(t)
+	post := f.createPost(t, 1, "title", consts.PostStatusDraft, "go")
+
+	tests := []struct {
+		name     string
+		userID   uint
+		dto      *dto.PostDto
+		wantCode int
+		check    func(t *testing.T)
+	}{
+		{"nil dto", 1, nil, 400, nil},
+		{"missing id", 1, postDto(nil, "t", "c", "", ptr(uint(1)), nil), 400, nil},
+		{"missing version", 1, postDto(&post.ID, "t", "c", "", nil, nil), 400, nil},
+		{"missing post", 1, postDto(ptr(uint(999)), "t", "c", "", ptr(uint(1)), nil), 404, nil},
+		{"not author", 2, postDto(&post.ID, "t", "c", "", ptr(uint(1)), nil), 403, nil},
+		{"stale version", 1, postDto(&post.ID, "t", "c", "", ptr(uint(7)), nil), 409, nil},
+		{"invalid", 1, postDto(&post.ID, "", "c", "", ptr(uint(1)), nil), 422, nil},
+		{"publish and retag", 1, postDto(&post.ID, "new title", "new content", consts.PostStatusPublished, ptr(uint(1)), []string{"gin"}), 0, func(t *testing.T) {
+			got, _ := f.posts.WithContext(asUser(1)).GetPostByID(post.ID)
+			if got.Title != "new title" || got.Version != 2 || got.PublishedAt == nil || fmt.Sprint(got.TagNames()) != "[gin]" {
+				t.Fatalf("updated post = %+v", got)
+			}
+		}},
+		{"keep tags when nil", 1, postDto(&post.ID, "again", "content", "", ptr(uint(2)), nil), 0, func(t *testing.T) {
+			got, _ := f.posts.WithContext(asUser(1)).GetPostByID(post.ID)
+			if fmt.Sprint(got.TagNames()) != "[gin]" || got.Version != 3 {
+				t.Fatalf("updated post = %+v", got)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.events = nil
+			_, err := f.posts.WithContext(asUser(tt.userID)).UpdatePost(tt.dto)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode == 0 {
+				if len(f.events) != 1 || f.events[0].Type != consts.EventPostUpdated {
+					t.Fatalf("events = %+v", f.events)
+				}
+				tt.check(t)
+			}
+		})
+	}
+}
+
+func TestPostServiceUpdateIfMatch(t *testing.T) {
+	f := newMemoryFixture(t)
+	post := f.createPost(t, 1, "title", consts.PostStatusPublished)
+	alice := f.posts.WithContext(asUser(1))
+	etag := utils.ETag(post.ToResponse())
+
+	// 版本号取自匹配的 ETag，请求体可以不带版本号
+	updated, err := alice.UpdatePostIfMatch(postDto(&post.ID, "first", "content", "", nil, nil), etag)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("UpdatePostIfMatch = %+v, %v", updated, err)
+	}
+
+	// 其他请求修改后，旧的 ETag 不能再用于修改
+	if _, err := alice.UpdatePostIfMatch(postDto(&post.ID, "second", "content", "", nil, nil), etag); appErrorCode(err) != 412 {
+		t.Fatalf("stale etag = %v, want 412", err)
+	}
+	// ETag 匹配但请求体的版本号过期时按版本冲突处理
+	etag = utils.ETag(updated.ToResponse())
+	if _, err := alice.UpdatePostIfMatch(postDto(&post.ID, "second", "content", "", ptr(uint(1)), nil), etag); appErrorCode(err) != 409 {
+		t.Fatalf("stale body version = %v, want 409", err)
+	}
+	if _, err := alice.UpdatePostIfMatch(postDto(&post.ID, "second", "content", "", nil, nil), `W/`+etag); appErrorCode(err) != 412 {
+		t.Fatalf("weak etag = %v, want 412", err)
+	}
+	if _, err := alice.UpdatePostIfMatch(postDto(&post.ID, "second", "content", "", nil, nil), "*"); err != nil {
+		t.Fatalf("If-Match * = %v", err)
+	}
+}
+
+func TestPostServiceDelete(t *testing.T) {
+	f := newMemoryFixture(t)
+	post := f.createPost(t, 1, "title", "")
+	f.createComment(t, 2, post.ID, "comment")
+
+	tests := []struct {
+		name     string
+		userID   uint
+		postID   uint
+		wantCode int
+	}{
+		{"missing", 1, 999, 404},
+		{"not author", 2, post.ID, 403},
+		{"author", 1, post.ID, 0},
+		{"already deleted", 1, post.ID, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.posts.WithContext(asUser(tt.userID)).DeleteByID(tt.postID)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+		})
+	}
+	if entry := f.audit.last(); entry.Action != consts.AuditPostDelete || entry.EntityID != post.ID {
+		t.Fatalf("audit = %+v", entry)
+	}
+	if page, _ := f.comments.GetCommentByPage(&dto.CommentPageDTO{BasePageQuery: *dto.NewBasePageQuery()}); page.Total != 0 {
+		t.Fatalf("comments of deleted post = %d", page.Total)
+	}
+}
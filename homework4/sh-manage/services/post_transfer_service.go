@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sh-manage/cache"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/moderation"
+	"sh-manage/tenant"
+	"sh-manage/transfer"
+	"sh-manage/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 单次导入的最大文章数
+const maxImportRecords = 5000
+
+// errDryRun 用于在试运行结束时回滚事务
+var errDryRun = errors.New("dry run")
+
+// PostTransferService 文章的批量导入导出，供管理接口和命令行工具使用
+type PostTransferService struct {
+	db           *gorm.DB
+	cache        cache.Cache
+	context      *gin.Context
+	auditService *AuditService
+	moderator    Moderator
+}
+
+// cacheStore 为 nil 时不使用缓存
+func NewPostTransferService(db *gorm.DB, cacheStore cache.Cache) *PostTransferService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	return &PostTransferService{db: db, cache: cacheStore, auditService: NewAuditService(db)}
+}
+
+// SetModerator 设置导入内容的审核，与发布文章和评论使用同样的规则，为 nil 时不审核
+func (s *PostTransferService) SetModerator(moderator Moderator) {
+	s.moderator = moderator
+}
+
+// WithContext 返回绑定当前请求的副本，用于记录审计日志和按租户限定导入导出的范围
+func (s *PostTransferService) WithContext(c *gin.Context) *PostTransferService {
+	clone := *s
+	clone.context = c
+	clone.db = s.db.WithContext(requestContext(c))
+	clone.cache = tenantCache(s.cache, requestContext(c))
+	return &clone
+}
+
+// WithTenant 返回限定在指定租户内的副本，供没有 HTTP 请求的命令行使用
+func (s *PostTransferService) WithTenant(tenantID uint) *PostTransferService {
+	clone := *s
+	ctx := tenant.WithID(context.Background(), tenantID)
+	clone.db = s.db.WithContext(ctx)
+	clone.cache = tenantCache(s.cache, ctx)
+	clone.auditService = &AuditService{db: clone.db}
+	return &clone
+}
+
+// Export 导出全部文章及其作者、标签和评论，分批查询并逐条写出，不会一次加载全部文章
+func (s *PostTransferService) Export(w io.Writer, format transfer.Format) *utils.AppError {
+	encoder, err := transfer.NewEncoder(w, format)
+	if err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to export posts").Wrap(err)
+	}
+
+	var batch []models.Post
+	result := s.db.Preload("User").Preload("Tags").Preload("Comments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	}).Preload("Comments.User").Order("id asc").FindInBatches(&batch, 200, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := encoder.Encode(toPostRecord(&batch[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to export posts").Wrap(result.Error)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to export posts").Wrap(err)
+	}
+	return nil
+}
+
+// Import 导入文章，所有记录在一个事务中写入，单行失败只跳过该行并写入错误报告
+// 文章和评论经过内容审核，被拒绝的行写入错误报告，需要审核的内容以待审核状态导入并加入审核队列
+// dryRun 为 true 时只校验并回滚，不落库
+func (s *PostTransferService) Import(data []byte, format transfer.Format, dryRun bool) (*transfer.ImportResult, *utils.AppError) {
+	records, rowErrors, err := transfer.Decode(data, format)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInvalidImportFile, err.Error())
+	}
+	if len(records)+len(rowErrors) > maxImportRecords {
+		return nil, utils.NewError(utils.ErrTooManyRecords, fmt.Sprintf("Too many records, at most %d per import", maxImportRecords))
+	}
+
+	result := &transfer.ImportResult{
+		Format: format,
+		DryRun: dryRun,
+		Total:  len(records) + len(rowErrors),
+		Errors: rowErrors,
+	}
+
+	var createdIDs []uint
+	importer := &postImporter{ctx: s.db.Statement.Context, moderator: s.moderator, dryRun: dryRun}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		importer.tx, importer.authors, importer.reviews = tx, make(map[string]uint), nil
+		for i := range records {
+			record := &records[i]
+			postID, rowErr := importer.importRecord(record)
+			if rowErr != nil {
+				result.Errors = append(result.Errors, transfer.RowError{Row: record.Row, Title: record.Title, Message: rowErr.Error()})
+				continue
+			}
+			createdIDs = append(createdIDs, postID)
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to import posts").Wrap(err)
+	}
+
+	result.Imported = len(createdIDs)
+	result.Failed = len(result.Errors)
+	if result.Errors == nil {
+		result.Errors = []transfer.RowError{}
+	}
+
+	if !dryRun && len(createdIDs) > 0 {
+		invalidatePostList(s.cache)
+		s.auditService.Record(s.context, AuditEntry{
+			Action:     consts.AuditPostImport,
+			EntityType: consts.EntityPost,
+			After: gin.H{
+				"format":   format,
+				"imported": result.Imported,
+				"failed":   result.Failed,
+				"post_ids": createdIDs,
+			},
+		})
+		// 审核队列在事务外写入，与发布文章时一样在内容保存之后入队
+		for _, review := range importer.reviews {
+			if err := enqueueForReview(importer.ctx, s.moderator, review.request(), consts.ModerationActionCreate, review.result); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// postImporter 在同一个事务内导入多行，缓存已查到的作者
+type postImporter struct {
+	tx        *gorm.DB
+	ctx       context.Context
+	moderator Moderator
+	dryRun    bool
+	authors   map[string]uint
+	// reviews 已写入的待审核内容，事务提交后加入审核队列
+	reviews []importReview
+}
+
+// importReview 导入时进入待审核状态的文章或评论
+type importReview struct {
+	post    *models.Post
+	comment int // 评论在 post.Comments 中的下标，-1 表示文章本身
+	result  moderation.Result
+}
+
+func (r importReview) request() moderationRequest {
+	if r.comment < 0 {
+		return postModerationRequest(r.post)
+	}
+	return commentModerationRequest(&r.post.Comments[r.comment])
+}
+
+// importRecord 校验并写入一行，每行使用一个保存点，失败时只回滚该行
+func (im *postImporter) importRecord(record *transfer.PostRecord) (uint, error) {
+	post, reviews, err := im.buildPost(record)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := im.tx.SavePoint("import_row").Error; err != nil {
+		return 0, err
+	}
+	if err := im.createPost(post, record.Tags); err != nil {
+		im.tx.RollbackTo("import_row")
+		return 0, fmt.Errorf("failed to save post: %w", err)
+	}
+	im.reviews = append(im.reviews, reviews...)
+	return post.ID, nil
+}
+
+// buildPost 校验记录并转换为模型，作者和评论人必须是已存在的用户
+// 文章和评论按发布时的规则审核，返回需要加入审核队列的内容
+func (im *postImporter) buildPost(record *transfer.PostRecord) (*models.Post, []importReview, error) {
+	postDto := dto.PostDto{Title: &record.Title, Content: &record.Content, Tags: record.Tags}
+	if appErr := postDto.Validate(); appErr != nil {
+		return nil, nil, errors.New(appErr.Message)
+	}
+
+	authorID, err := im.authorID(record.Author)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	post := &models.Post{
+		Title:   strings.TrimSpace(record.Title),
+		Content: record.Content,
+		UserId:  authorID,
+		Status:  consts.PostStatusPublished,
+	}
+	post.CreatedAt = record.CreatedAt
+	post.UpdatedAt = record.UpdatedAt
+
+	var reviews []importReview
+	result, err := im.moderate(postModerationRequest(post))
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.Verdict == moderation.Review {
+		post.Status = consts.PostStatusPendingReview
+		reviews = append(reviews, importReview{post: post, comment: -1, result: result})
+	} else {
+		publishedAt := record.CreatedAt
+		if publishedAt.IsZero() {
+			publishedAt = time.Now()
+		}
+		post.PublishedAt = &publishedAt
+	}
+
+	for i, commentRecord := range record.Comments {
+		if strings.TrimSpace(commentRecord.Content) == "" {
+			return nil, nil, fmt.Errorf("comment %d: content is required", i+1)
+		}
+		commenterID, err := im.authorID(commentRecord.Author)
+		if err != nil {
+			return nil, nil, fmt.Errorf("comment %d: %w", i+1, err)
+		}
+		comment := models.Comment{Content: commentRecord.Content, UserId: commenterID, Status: consts.CommentStatusPublished}
+		comment.CreatedAt = commentRecord.CreatedAt
+		comment.UpdatedAt = commentRecord.CreatedAt
+		result, err := im.moderate(commentModerationRequest(&comment))
+		if err != nil {
+			return nil, nil, fmt.Errorf("comment %d: %w", i+1, err)
+		}
+		if result.Verdict == moderation.Review {
+			comment.Status = consts.CommentStatusPendingReview
+			reviews = append(reviews, importReview{post: post, comment: len(post.Comments), result: result})
+		}
+		post.Comments = append(post.Comments, comment)
+	}
+	return post, reviews, nil
+}
+
+// moderate 审核一条内容，被拒绝时返回包含原因的错误；试运行只检查不记录指纹
+func (im *postImporter) moderate(req moderationRequest) (moderation.Result, error) {
+	req.preview = im.dryRun
+	result, appErr := moderate(im.ctx, im.moderator, req)
+	if appErr != nil {
+		return result, fmt.Errorf("%s: %s", appErr.Message, strings.Join(result.Reasons, ","))
+	}
+	return result, nil
+}
+
+func (im *postImporter) createPost(post *models.Post, tagNames []string) error {
+	tags, err := findOrCreateTags(im.tx, tagNames)
+	if err != nil {
+		return err
+	}
+	post.Tags = tags
+	return im.tx.Create(post).Error
+}
+
+func (im *postImporter) authorID(username string) (uint, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return 0, errors.New("author is required")
+	}
+	if id, ok := im.authors[username]; ok {
+		return id, nil
+	}
+
+	var user models.User
+	if err := im.tx.Select("id").Where("username = ?", username).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, fmt.Errorf("author %q not found", username)
+		}
+		return 0, err
+	}
+	im.authors[username] = user.ID
+	return user.ID, nil
+}
+
+func toPostRecord(post *models.Post) transfer.PostRecord {
+	record := transfer.PostRecord{
+		ID:        post.ID,
+		Title:     post.Title,
+		Author:    post.User.Username,
+		Tags:      post.TagNames(),
+		CreatedAt: post.CreatedAt,
+		UpdatedAt: post.UpdatedAt,
+		Content:   post.Content,
+		Comments:  make([]transfer.CommentRecord, 0, len(post.Comments)),
+	}
+	for _, comment := range post.Comments {
+		record.Comments = append(record.Comments, transfer.CommentRecord{
+			Author:    comment.User.Username,
+			Content:   comment.Content,
+			CreatedAt: comment.CreatedAt,
+		})
+	}
+	return record
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/models"
+	"sh-manage/moderation"
+	"sh-manage/tenant"
+	"sh-manage/transfer"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestPostImportExport(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&models.User{Username: "alice", Email: "alice@example.com", Password: "x"})
+	db.Create(&models.User{Username: "bob", Email: "bob@example.com", Password: "x"})
+	svc := NewPostTransferService(db, nil)
+
+	created := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	records := []transfer.PostRecord{
+		{Title: "first", Author: "alice", Content: "body", Tags: []string{"Go", "go"}, CreatedAt: created,
+			Comments: []transfer.CommentRecord{{Author: "bob", Content: "hi"}}},
+		{Title: "unknown author", Author: "carol", Content: "body"},
+		{Title: "", Author: "alice", Content: "body"},
+		{Title: "bad comment", Author: "alice", Content: "body", Comments: []transfer.CommentRecord{{Author: "nobody", Content: "x"}}},
+	}
+	var input bytes.Buffer
+	if err := transfer.Encode(&input, transfer.FormatJSON, records); err != nil {
+		t.Fatal(err)
+	}
+
+	// 试运行不写入数据库
+	result, err := svc.Import(input.Bytes(), transfer.FormatJSON, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if result.Total != 4 || result.Imported != 1 || result.Failed != 3 {
+		t.Fatalf("dry run result = %+v", result)
+	}
+	var count int64
+	db.Model(&models.Post{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("dry run created %d posts", count)
+	}
+
+	result, err = svc.Import(input.Bytes(), transfer.FormatJSON, false)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if result.Imported != 1 || len(result.Errors) != 3 || result.Errors[0].Row != 2 {
+		t.Fatalf("import result = %+v", result)
+	}
+
+	var output bytes.Buffer
+	if err := svc.Export(&output, transfer.FormatJSON); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	exported, _, e := transfer.Decode(output.Bytes(), transfer.FormatJSON)
+	if e != nil || len(exported) != 1 {
+		t.Fatalf("exported = %+v, %v", exported, e)
+	}
+	post := exported[0]
+	if post.Author != "alice" || len(post.Tags) != 1 || post.Tags[0] != "go" || !post.CreatedAt.Equal(created) {
+		t.Fatalf("exported post = %+v", post)
+	}
+	if len(post.Comments) != 1 || post.Comments[0].Author != "bob" {
+		t.Fatalf("exported comments = %+v", post.Comments)
+	}
+}
+
+// TestPostImportModeration 导入的文章和评论与发布时一样审核，命中审核规则的内容待审核并进入审核队列
+func TestPostImportModeration(t *testing.T) {
+	f := newModerationFixture(t)
+	f.pipeline.Update(config.ModerationConfig{Enabled: true, BlockedWords: []string{"casino"}, ReviewWords: []string{"crypto"}, DuplicateWindow: "1h"})
+	f.createUser(t, "alice")
+	f.createUser(t, "bob")
+	svc := NewPostTransferService(f.db, nil)
+	svc.SetModerator(f.moderation)
+
+	records := []transfer.PostRecord{
+		{Title: "held", Author: "alice", Content: "buy crypto now"},
+		{Title: "clean", Author: "alice", Content: "hello", Comments: []transfer.CommentRecord{
+			{Author: "bob", Content: "nice"},
+			{Author: "bob", Content: "crypto tips"},
+		}},
+		{Title: "blocked", Author: "alice", Content: "visit the casino"},
+		{Title: "blocked comment", Author: "alice", Content: "fine", Comments: []transfer.CommentRecord{{Author: "bob", Content: "casino"}}},
+	}
+	var input bytes.Buffer
+	if err := transfer.Encode(&input, transfer.FormatJSON, records); err != nil {
+		t.Fatal(err)
+	}
+
+	// 试运行不记录指纹，随后的正式导入不会被当作重复内容
+	if result, err := svc.Import(input.Bytes(), transfer.FormatJSON, true); err != nil || result.Imported != 2 || result.Failed != 2 {
+		t.Fatalf("dry run = %+v, %v", result, err)
+	}
+	if items := f.queue(t, consts.ModerationPending); len(items) != 0 {
+		t.Fatalf("dry run queued %d items", len(items))
+	}
+
+	result, err := svc.Import(input.Bytes(), transfer.FormatJSON, false)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if result.Imported != 2 || result.Failed != 2 || !strings.Contains(result.Errors[0].Message, moderation.ReasonBlockedWord) ||
+		!strings.HasPrefix(result.Errors[1].Message, "comment 1:") {
+		t.Fatalf("import result = %+v", result)
+	}
+
+	var held, clean models.Post
+	f.db.Preload("Comments", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") }).Where("title = ?", "held").First(&held)
+	f.db.Preload("Comments", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") }).Where("title = ?", "clean").First(&clean)
+	if held.Status != consts.PostStatusPendingReview || held.PublishedAt != nil {
+		t.Fatalf("held post = %+v", held)
+	}
+	if clean.Status != consts.PostStatusPublished || clean.PublishedAt == nil || len(clean.Comments) != 2 ||
+		clean.Comments[0].Status != consts.CommentStatusPublished || clean.Comments[1].Status != consts.CommentStatusPendingReview {
+		t.Fatalf("clean post = %+v", clean)
+	}
+	// 队列按时间倒序
+	items := f.queue(t, consts.ModerationPending)
+	if len(items) != 2 || items[1].EntityType != consts.EntityPost || items[1].EntityID != held.ID ||
+		items[0].EntityType != consts.EntityComment || items[0].EntityID != clean.Comments[1].ID {
+		t.Fatalf("queue = %+v", items)
+	}
+}
+
+func TestPostTransferWithTenant(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tenantID := range []uint{1, 2} {
+		scoped := db.WithContext(tenant.WithID(context.Background(), tenantID))
+		user := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+		scoped.Create(user)
+		scoped.Create(&models.Post{Title: fmt.Sprintf("post of tenant %d", tenantID), Content: "body", UserId: user.ID, Status: consts.PostStatusPublished})
+	}
+	svc := NewPostTransferService(db, nil)
+
+	var output bytes.Buffer
+	if err := svc.WithTenant(2).Export(&output, transfer.FormatJSON); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	exported, _, _ := transfer.Decode(output.Bytes(), transfer.FormatJSON)
+	if len(exported) != 1 || exported[0].Title != "post of tenant 2" {
+		t.Fatalf("exported = %+v", exported)
+	}
+
+	// 作者按用户名在目标租户内匹配，导入的文章只属于该租户
+	result, err := svc.WithTenant(1).Import(output.Bytes(), transfer.FormatJSON, false)
+	if err != nil || result.Imported != 1 {
+		t.Fatalf("import = %+v, %v", result, err)
+	}
+	var posts []models.Post
+	db.Order("id asc").Find(&posts)
+	if len(posts) != 3 || posts[2].TenantID != 1 || posts[2].UserId != posts[0].UserId {
+		t.Fatalf("posts after import = %+v", posts)
+	}
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sh-manage/cache"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/events"
+	"sh-manage/models"
+	"sh-manage/repository"
+	"sh-manage/tools"
+	"sh-manage/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportService 处理用户对文章、评论和用户的举报，审核员处理时可以删除内容、警告、停用或封禁被举报的用户
+type ReportService struct {
+	db           *gorm.DB
+	posts        repository.PostRepository
+	comments     repository.CommentRepository
+	tx           repository.UnitOfWork
+	userService  *UserService
+	cache        cache.Cache
+	context      *gin.Context
+	auditService *AuditService
+	publisher    events.Publisher
+}
+
+// cacheStore 为 nil 时不使用缓存，需要与 PostService 使用同一个缓存，删除文章后才能让缓存失效
+func NewReportService(db *gorm.DB, userService *UserService, cacheStore cache.Cache) *ReportService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	repos := repository.NewGorm(db)
+	return &ReportService{
+		db:           db,
+		posts:        repos.Posts,
+		comments:     repos.Comments,
+		tx:           repos.Tx,
+		userService:  userService,
+		cache:        cacheStore,
+		auditService: NewAuditService(db),
+		publisher:    events.Nop{},
+	}
+}
+
+// SetPublisher 设置删除被举报内容后文章和评论事件的发布者
+func (s *ReportService) SetPublisher(publisher events.Publisher) {
+	s.publisher = publisher
+}
+
+// WithContext 返回绑定当前请求的副本，举报和处理限定在请求所属的租户
+func (s *ReportService) WithContext(c *gin.Context) *ReportService {
+	clone := *s
+	clone.context = c
+	clone.db = s.db.WithContext(requestContext(c))
+	clone.cache = tenantCache(s.cache, requestContext(c))
+	clone.userService = s.userService.WithContext(c)
+	return &clone
+}
+
+func (s *ReportService) currentUserID() uint {
+	if s.context == nil {
+		return 0
+	}
+	return utils.GetCurrentUserID(s.context)
+}
+
+func (s *ReportService) ctx() context.Context {
+	return requestContext(s.context)
+}
+
+// CreateReport 举报当前用户可见的文章、评论或其他用户，不能举报自己，同一对象未处理前不能重复举报
+func (s *ReportService) CreateReport(req *dto.CreateReportDto) (*models.Report, *utils.AppError) {
+	if req == nil {
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
+	}
+	if !slices.Contains(consts.ReportReasons, req.Reason) {
+		return nil, utils.NewError(utils.ErrInvalidReportReason, "Reason must be one of "+strings.Join(consts.ReportReasons, ", "))
+	}
+	details := strings.TrimSpace(req.Details)
+	if req.Reason == consts.ReportReasonOther && details == "" {
+		return nil, utils.NewError(utils.ErrInvalidReportReason, "Details are required when reason is other")
+	}
+
+	reporterID := s.currentUserID()
+	targetUserID, err := s.targetUser(req.EntityType, req.EntityID, reporterID)
+	if err != nil {
+		return nil, err
+	}
+	if targetUserID == reporterID {
+		return nil, utils.NewError(utils.ErrSelfReport, "You cannot report yourself or your own content")
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Report{}).
+		Where("reporter_id = ? AND entity_type = ? AND entity_id = ? AND status = ?", reporterID, req.EntityType, req.EntityID, consts.ReportStatusOpen).
+		Count(&count).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve report").Wrap(err)
+	}
+	if count > 0 {
+		return nil, utils.NewError(utils.ErrReportDuplicate, "You have already reported this")
+	}
+
+	report := &models.Report{
+		ReporterID:   reporterID,
+		EntityType:   req.EntityType,
+		EntityID:     req.EntityID,
+		TargetUserID: targetUserID,
+		Reason:       req.Reason,
+		Details:      details,
+		Status:       consts.ReportStatusOpen,
+	}
+	if err := s.db.Create(report).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create report").Wrap(err)
+	}
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditReportCreate,
+		EntityType: consts.EntityReport,
+		EntityID:   report.ID,
+		After:      report.ToResponse(),
+	})
+	return report, nil
+}
+
+// targetUser 返回被举报对象的作者，对举报人不可见的文章和评论返回 404
+func (s *ReportService) targetUser(entityType string, entityID, reporterID uint) (uint, *utils.AppError) {
+	switch entityType {
+	case consts.EntityPost:
+		post, err := s.posts.FindByID(s.ctx(), entityID)
+		if err != nil {
+			return 0, repositoryError(err, utils.ErrPostNotFound, "Failed to retrieve Post")
+		}
+		if !post.IsPublished() && post.UserId != reporterID {
+			return 0, utils.NewError(utils.ErrPostNotFound, "Post not found")
+		}
+		return post.UserId, nil
+	case consts.EntityComment:
+		comment, err := s.comments.FindByID(s.ctx(), entityID)
+		if err != nil {
+			return 0, repositoryError(err, utils.ErrCommentNotFound, "Failed to retrieve comment")
+		}
+		if !comment.IsPublished() && comment.UserId != reporterID {
+			return 0, utils.NewError(utils.ErrCommentNotFound, "comment not found")
+		}
+		return comment.UserId, nil
+	case consts.EntityUser:
+		user, err := s.userService.findUserByID(entityID)
+		if err != nil {
+			return 0, err
+		}
+		return user.ID, nil
+	default:
+		return 0, utils.NewError(utils.ErrInvalidEntityType, "Entity type must be post, comment or user")
+	}
+}
+
+// GetReports 分页查询举报，默认只返回未处理的举报
+func (s *ReportService) GetReports(query *dto.ReportPageDTO) (*dto.PageResult[models.Report], *utils.AppError) {
+	status := consts.ReportStatusOpen
+	if query.Status != nil && *query.Status != "" {
+		status = *query.Status
+	}
+	db := s.db.Model(&models.Report{}).Where("status = ?", status)
+	if query.EntityType != nil && *query.EntityType != "" {
+		db = db.Where("entity_type = ?", *query.EntityType)
+	}
+	if query.Reason != nil && *query.Reason != "" {
+		db = db.Where("reason = ?", *query.Reason)
+	}
+	var reports []models.Report
+	return tools.Paginate(db, query.BasePageQuery, &reports)
+}
+
+func (s *ReportService) GetReportByID(reportID uint) (*models.Report, *utils.AppError) {
+	var report models.Report
+	if err := s.db.First(&report, reportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewError(utils.ErrReportNotFound, "Report not found")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve report").Wrap(err)
+	}
+	return &report, nil
+}
+
+// Resolve 处理举报，同一对象的其他未处理举报按相同结果一起关闭
+// 审核员不能处罚自己，处罚审核员和管理员需要管理员角色
+func (s *ReportService) Resolve(reportID uint, req *dto.ResolveReportDto) (*models.Report, *utils.AppError) {
+	if req == nil {
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
+	}
+	report, err := s.GetReportByID(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != consts.ReportStatusOpen {
+		return nil, utils.NewError(utils.ErrAlreadyDecided, "Report has already been resolved")
+	}
+
+	removeContent := req.Action == consts.ReportActionRemoveContent || (req.RemoveContent && req.Action != consts.ReportActionDismiss)
+	if removeContent && report.EntityType == consts.EntityUser {
+		return nil, utils.NewError(utils.ErrNoContentToRemove, "Reported users have no content to remove")
+	}
+	var suspendUntil time.Time
+	if req.Action == consts.ReportActionSuspend {
+		duration, e := time.ParseDuration(req.Duration)
+		if e != nil || duration <= 0 {
+			return nil, utils.NewError(utils.ErrInvalidDuration, "Duration must be a positive duration such as 72h")
+		}
+		suspendUntil = time.Now().Add(duration)
+	}
+	if err := s.checkSanction(report, req.Action); err != nil {
+		return nil, err
+	}
+	before := report.ToResponse()
+
+	now := time.Now()
+	resolved := map[string]interface{}{
+		"status":      consts.ReportStatusResolved,
+		"resolution":  req.Action,
+		"resolver_id": s.currentUserID(),
+		"resolved_at": now,
+		"note":        req.Note,
+	}
+	// 领取举报、删除内容、处罚用户和关闭同一对象的其他举报在同一个事务中，任一步失败时举报保持未处理
+	var event *events.Event
+	var sanction *userSanction
+	if err := inTransaction(s.ctx(), s.tx, "Failed to resolve report", func(ctx context.Context) *utils.AppError {
+		db := repository.Conn(ctx, s.db)
+		// 按状态条件领取举报，并发处理同一举报时只有一个成功
+		result := db.Model(&models.Report{}).Where("id = ? AND status = ?", report.ID, consts.ReportStatusOpen).Updates(resolved)
+		if result.Error != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to update report").Wrap(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewError(utils.ErrAlreadyDecided, "Report has already been resolved")
+		}
+
+		var err *utils.AppError
+		if removeContent {
+			if event, err = s.removeContent(ctx, report); err != nil {
+				return err
+			}
+		}
+		if sanction, err = s.sanction(ctx, report, req, suspendUntil); err != nil {
+			return err
+		}
+
+		if e := db.Model(&models.Report{}).
+			Where("entity_type = ? AND entity_id = ? AND status = ?", report.EntityType, report.EntityID, consts.ReportStatusOpen).
+			Updates(resolved).Error; e != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to resolve related reports").Wrap(e)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if event != nil {
+		if event.Type == consts.EventPostDeleted {
+			invalidate(s.cache, postCacheKey(report.EntityID))
+			invalidatePostList(s.cache)
+		}
+		s.publisher.Publish(*event)
+	}
+	if sanction != nil {
+		if _, e := s.userService.finishSanction(sanction); e != nil {
+			log.Printf("Failed to reload sanctioned user %d: %v", report.TargetUserID, e)
+		}
+	}
+
+	report, err = s.GetReportByID(report.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditReportResolve,
+		EntityType: consts.EntityReport,
+		EntityID:   report.ID,
+		Before:     before,
+		After:      report.ToResponse(),
+	})
+	return report, nil
+}
+
+// checkSanction 警告、停用和封禁前检查处理人是否有权处罚被举报的用户
+func (s *ReportService) checkSanction(report *models.Report, action string) *utils.AppError {
+	switch action {
+	case consts.ReportActionWarn, consts.ReportActionSuspend, consts.ReportActionBan:
+	default:
+		return nil
+	}
+	resolverID := s.currentUserID()
+	if report.TargetUserID == resolverID {
+		return utils.NewError(utils.ErrSanctionNotAllowed, "You cannot sanction yourself")
+	}
+	target, err := s.userService.findUserByID(report.TargetUserID)
+	if err != nil {
+		return err
+	}
+	if !target.IsModerator() {
+		return nil
+	}
+	resolver, err := s.userService.findUserByID(resolverID)
+	if err != nil {
+		return err
+	}
+	if !resolver.IsAdmin() {
+		return utils.NewError(utils.ErrSanctionNotAllowed, "Only admins can sanction moderators and admins")
+	}
+	return nil
+}
+
+// sanction 在事务中按处理结果警告、停用或封禁被举报的用户，其他处理结果返回 nil
+func (s *ReportService) sanction(ctx context.Context, report *models.Report, req *dto.ResolveReportDto, suspendUntil time.Time) (*userSanction, *utils.AppError) {
+	switch req.Action {
+	case consts.ReportActionWarn:
+		return s.userService.warn(ctx, report.TargetUserID, req.Note)
+	case consts.ReportActionSuspend:
+		return s.userService.suspend(ctx, report.TargetUserID, suspendUntil, req.Note)
+	case consts.ReportActionBan:
+		return s.userService.ban(ctx, report.TargetUserID, req.Note)
+	}
+	return nil, nil
+}
+
+// removeContent 在事务中删除被举报的文章（连同评论）或评论，内容已被作者删除时视为成功
+// 返回提交后需要发布的事件，删除未发布的评论时为 nil
+func (s *ReportService) removeContent(ctx context.Context, report *models.Report) (*events.Event, *utils.AppError) {
+	switch report.EntityType {
+	case consts.EntityPost:
+		post, err := s.posts.FindByID(ctx, report.EntityID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve Post").Wrap(err)
+		}
+		if err := s.comments.DeleteByPosts(ctx, []uint{post.ID}); err != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to delete Post comments").Wrap(err)
+		}
+		if err := s.posts.Delete(ctx, post.ID); err != nil {
+			return nil, repositoryError(err, utils.ErrPostNotFound, "Failed to delete Post")
+		}
+		if err := s.withdrawReview(ctx, consts.EntityPost, post.ID); err != nil {
+			return nil, err
+		}
+		event := newEvent(ctx, consts.EventPostDeleted, post.ToResponse())
+		return &event, nil
+	case consts.EntityComment:
+		comment, err := s.comments.FindByID(ctx, report.EntityID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve comment").Wrap(err)
+		}
+		if err := s.comments.Delete(ctx, comment.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to delete Comment").Wrap(err)
+		}
+		if err := s.withdrawReview(ctx, consts.EntityComment, comment.ID); err != nil {
+			return nil, err
+		}
+		if comment.IsPublished() {
+			event := newEvent(ctx, consts.EventCommentDeleted, comment.ToResponse())
+			return &event, nil
+		}
+	}
+	return nil, nil
+}
+
+// withdrawReview 被删除的内容不再需要审核，撤回待审核条目
+func (s *ReportService) withdrawReview(ctx context.Context, entityType string, entityID uint) *utils.AppError {
+	if err := repository.Conn(ctx, s.db).Model(&models.ModerationItem{}).
+		Where("entity_type = ? AND entity_id = ? AND status = ?", entityType, entityID, consts.ModerationPending).
+		Update("status", consts.ModerationWithdrawn).Error; err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to withdraw moderation item").Wrap(err)
+	}
+	return nil
+}
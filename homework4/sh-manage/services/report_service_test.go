@@ -0,0 +1,210 @@
+package services
+
+import (
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/events"
+	"sh-manage/models"
+	"testing"
+	"time"
+)
+
+// reportFixture 基于 sqlite 的举报服务，moderator 是审核员
+type reportFixture struct {
+	events    []events.Event
+	users     *UserService
+	posts     *PostService
+	comments  *CommentService
+	reports   *ReportService
+	author    *models.User
+	reporter  *models.User
+	moderator *models.User
+}
+
+func newReportFixture(t *testing.T) *reportFixture {
+	t.Helper()
+	db := newTestDB(t)
+	f := &reportFixture{}
+	bus := events.NewBus()
+	bus.Subscribe(func(e events.Event) { f.events = append(f.events, e) })
+
+	f.users = NewUserService(db, nil)
+	f.posts = NewPostService(db, f.users, nil, nil)
+	f.comments = NewCommentService(db, f.users, nil)
+	f.reports = NewReportService(db, f.users, nil)
+	f.reports.SetPublisher(bus)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if _, err := f.users.CreateUser(models.CreateUserRequest{Username: name, Email: name + "@example.com", Password: "Correct-Horse-42"}); err != nil {
+			t.Fatalf("CreateUser(%s): %v", name, err)
+		}
+	}
+	if _, err := f.users.SetRole("carol", consts.RoleModerator); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	f.author, _ = f.users.GetUserByName("alice")
+	f.reporter, _ = f.users.GetUserByName("bob")
+	f.moderator, _ = f.users.GetUserByName("carol")
+	return f
+}
+
+func (f *reportFixture) report(entityType string, entityID uint, reporterID uint, reason, details string) (*models.Report, error) {
+	report, err := f.reports.WithContext(asUser(reporterID)).CreateReport(&dto.CreateReportDto{EntityType: entityType, EntityID: entityID, Reason: reason, Details: details})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func TestReportCreate(t *testing.T) {
+	f := newReportFixture(t)
+	posts := f.posts.WithContext(asUser(f.author.ID))
+	post, _ := posts.CreatePost(postDto(nil, "hello", "world", "", nil, nil))
+	draft, _ := posts.CreatePost(postDto(nil, "draft", "hidden", consts.PostStatusDraft, nil, nil))
+
+	tests := []struct {
+		name       string
+		entityType string
+		entityID   uint
+		reporterID uint
+		reason     string
+		details    string
+		wantCode   int
+	}{
+		{"unknown reason", consts.EntityPost, post.ID, f.reporter.ID, "boring", "", 400},
+		{"other without details", consts.EntityPost, post.ID, f.reporter.ID, consts.ReportReasonOther, " ", 400},
+		{"own post", consts.EntityPost, post.ID, f.author.ID, "spam", "", 400},
+		{"yourself", consts.EntityUser, f.reporter.ID, f.reporter.ID, "spam", "", 400},
+		{"draft of another user", consts.EntityPost, draft.ID, f.reporter.ID, "spam", "", 404},
+		{"missing user", consts.EntityUser, 999, f.reporter.ID, "spam", "", 404},
+		{"post", consts.EntityPost, post.ID, f.reporter.ID, "spam", "", 0},
+		{"post twice", consts.EntityPost, post.ID, f.reporter.ID, "harassment", "", 409},
+		{"same post by another user", consts.EntityPost, post.ID, f.moderator.ID, consts.ReportReasonOther, "looks like an ad", 0},
+		{"user", consts.EntityUser, f.author.ID, f.reporter.ID, "harassment", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := f.report(tt.entityType, tt.entityID, tt.reporterID, tt.reason, tt.details)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("CreateReport = %d, want %d (%v)", code, tt.wantCode, err)
+			}
+			if err == nil && (report.Status != consts.ReportStatusOpen || report.TargetUserID != f.author.ID) {
+				t.Fatalf("report = %+v", report)
+			}
+		})
+	}
+
+	page, err := f.reports.GetReports(&dto.ReportPageDTO{BasePageQuery: *dto.NewBasePageQuery(), EntityType: ptr(consts.EntityPost)})
+	if err != nil || page.Total != 2 {
+		t.Fatalf("GetReports = %+v, %v", page, err)
+	}
+}
+
+func TestReportResolveRemovesContent(t *testing.T) {
+	f := newReportFixture(t)
+	post, _ := f.posts.WithContext(asUser(f.author.ID)).CreatePost(postDto(nil, "buy now", "cheap", "", nil, nil))
+	content := "first"
+	if _, err := f.comments.WithContext(asUser(f.reporter.ID)).CreateComment(&dto.CommentDto{PostID: &post.ID, Content: &content}); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	first, _ := f.report(consts.EntityPost, post.ID, f.reporter.ID, "spam", "")
+	second, _ := f.report(consts.EntityPost, post.ID, f.moderator.ID, "spam", "")
+
+	reports := f.reports.WithContext(asUser(f.moderator.ID))
+	if _, err := reports.Resolve(first.ID, &dto.ResolveReportDto{Action: consts.ReportActionSuspend, Duration: "-1h"}); appErrorCode(err) != 400 {
+		t.Fatalf("negative duration: %v", err)
+	}
+	report, err := reports.Resolve(first.ID, &dto.ResolveReportDto{Action: consts.ReportActionWarn, RemoveContent: true, Note: "no ads"})
+	if err != nil || report.Status != consts.ReportStatusResolved || report.Resolution != consts.ReportActionWarn || report.ResolverID != f.moderator.ID || report.ResolvedAt == nil {
+		t.Fatalf("Resolve = %+v, %v", report, err)
+	}
+
+	// 文章和评论一起删除，同一文章的其他举报一起关闭
+	if _, err := f.posts.GetPostByID(post.ID); appErrorCode(err) != 404 {
+		t.Fatalf("removed post: %v", err)
+	}
+	if comments, _ := f.comments.ListByPosts([]uint{post.ID}); len(comments) != 0 {
+		t.Fatalf("comments of removed post = %d", len(comments))
+	}
+	if len(f.events) != 1 || f.events[0].Type != consts.EventPostDeleted {
+		t.Fatalf("events = %+v", f.events)
+	}
+	if other, _ := f.reports.GetReportByID(second.ID); other.Status != consts.ReportStatusResolved || other.Resolution != consts.ReportActionWarn {
+		t.Fatalf("related report = %+v", other)
+	}
+	if author, _ := f.users.GetUserByID(f.author.ID); author.Warnings != 1 {
+		t.Fatalf("warnings = %d", author.Warnings)
+	}
+	if _, err := reports.Resolve(first.ID, &dto.ResolveReportDto{Action: consts.ReportActionDismiss}); appErrorCode(err) != 409 {
+		t.Fatalf("resolve twice: %v", err)
+	}
+}
+
+// TestReportResolveRollsBack 处罚失败时已删除的内容一并回滚，举报保持未处理
+func TestReportResolveRollsBack(t *testing.T) {
+	f := newReportFixture(t)
+	post, _ := f.posts.WithContext(asUser(f.author.ID)).CreatePost(postDto(nil, "buy now", "cheap", "", nil, nil))
+	report, _ := f.report(consts.EntityPost, post.ID, f.reporter.ID, "spam", "")
+	if _, err := f.users.Ban(f.author.ID, ""); err != nil {
+		t.Fatalf("Ban: %v", err)
+	}
+
+	reports := f.reports.WithContext(asUser(f.moderator.ID))
+	if _, err := reports.Resolve(report.ID, &dto.ResolveReportDto{Action: consts.ReportActionBan, RemoveContent: true}); appErrorCode(err) != 409 {
+		t.Fatalf("ban banned author: %v", err)
+	}
+	if _, err := f.posts.GetPostByID(post.ID); err != nil {
+		t.Fatalf("post removed by failed resolve: %v", err)
+	}
+	if open, _ := f.reports.GetReportByID(report.ID); open.Status != consts.ReportStatusOpen || open.ResolverID != 0 {
+		t.Fatalf("report = %+v", open)
+	}
+	if len(f.events) != 0 {
+		t.Fatalf("events = %+v", f.events)
+	}
+}
+
+func TestReportSanctions(t *testing.T) {
+	f := newReportFixture(t)
+	report, _ := f.report(consts.EntityUser, f.author.ID, f.reporter.ID, "harassment", "")
+	reports := f.reports.WithContext(asUser(f.moderator.ID))
+
+	if _, err := reports.Resolve(report.ID, &dto.ResolveReportDto{Action: consts.ReportActionRemoveContent}); appErrorCode(err) != 400 {
+		t.Fatalf("remove content of user: %v", err)
+	}
+	if _, err := reports.Resolve(report.ID, &dto.ResolveReportDto{Action: consts.ReportActionSuspend, Duration: "72h"}); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if _, err := f.users.Authenticate("alice", "Correct-Horse-42"); appErrorCode(err) != 403 {
+		t.Fatalf("suspended login: %v", err)
+	}
+	if err := f.users.EnsureActive(f.author.ID); err == nil || err.Code != 403 {
+		t.Fatalf("EnsureActive = %v", err)
+	}
+
+	// 审核员不能处罚自己和其他审核员，管理员可以
+	own, _ := f.report(consts.EntityUser, f.moderator.ID, f.reporter.ID, "harassment", "")
+	if _, err := reports.Resolve(own.ID, &dto.ResolveReportDto{Action: consts.ReportActionBan}); appErrorCode(err) != 403 {
+		t.Fatalf("ban yourself: %v", err)
+	}
+	if _, err := f.users.SetRole("bob", consts.RoleAdmin); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	if _, err := f.reports.WithContext(asUser(f.reporter.ID)).Resolve(own.ID, &dto.ResolveReportDto{Action: consts.ReportActionBan}); err != nil {
+		t.Fatalf("admin bans moderator: %v", err)
+	}
+	if err := f.users.EnsureActive(f.moderator.ID); err == nil || err.Message != "Account is banned" {
+		t.Fatalf("EnsureActive = %v", err)
+	}
+	if _, err := f.users.Ban(f.moderator.ID, ""); appErrorCode(err) != 409 {
+		t.Fatalf("ban twice: %v", err)
+	}
+
+	// 停用到期后恢复
+	if _, err := f.users.Suspend(f.reporter.ID, time.Now().Add(50*time.Millisecond), ""); err != nil {
+		t.Fatalf("Suspend: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := f.users.EnsureActive(f.reporter.ID); err != nil {
+		t.Fatalf("expired suspension: %v", err)
+	}
+}
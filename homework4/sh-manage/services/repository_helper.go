@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sh-manage/events"
+	"sh-manage/repository"
+	"sh-manage/tenant"
+	"sh-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestContext 返回请求的 context，没有绑定请求时（如命令行调用）使用 Background
+func requestContext(c *gin.Context) context.Context {
+	if c == nil || c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// repositoryError 把存储层错误转换为 AppError，记录不存在时返回 notFound 错误码，其他错误保留原因返回 500
+func repositoryError(err error, notFound utils.ErrorCode, failed string) *utils.AppError {
+	var appErr *utils.AppError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, repository.ErrNotFound):
+		return utils.NewError(notFound, "")
+	default:
+		return utils.NewError(utils.ErrInternal, failed).Wrap(err)
+	}
+}
+
+// inTransaction 在事务中执行 fn，fn 返回 AppError 时回滚并原样返回，提交失败时返回 500
+// fn 中需要使用传入的 ctx 调用存储，已在事务中时内层使用保存点
+func inTransaction(ctx context.Context, tx repository.UnitOfWork, failed string, fn func(ctx context.Context) *utils.AppError) *utils.AppError {
+	var appErr *utils.AppError
+	err := tx.Do(ctx, func(ctx context.Context) error {
+		// 不能直接返回 appErr：值为 nil 的 *AppError 转为 error 后不等于 nil，会导致误回滚
+		if appErr = fn(ctx); appErr != nil {
+			return appErr
+		}
+		return nil
+	})
+	if appErr != nil {
+		return appErr
+	}
+	if err != nil {
+		return utils.NewError(utils.ErrInternal, failed).Wrap(err)
+	}
+	return nil
+}
+
+// newEvent 创建事件并记录 ctx 中的租户
+func newEvent(ctx context.Context, eventType string, data interface{}) events.Event {
+	event := events.New(eventType, data)
+	event.TenantID, _ = tenant.FromContext(ctx)
+	return event
+}
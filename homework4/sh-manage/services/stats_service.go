@@ -0,0 +1,360 @@
+package services
+
+import (
+	"fmt"
+	"sh-manage/cache"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultStatsRange = 30 * 24 * time.Hour
+	// 按天统计时允许的最大时间跨度
+	maxStatsRange     = 366 * 24 * time.Hour
+	defaultStatsLimit = 10
+	dateLayout        = "2006-01-02"
+	// 排行榜最多返回的条数，与分页查询的上限一致
+	maxStatsLimit = 100
+)
+
+// StatsService 管理后台的统计数据，结果按查询条件缓存，允许有缓存TTL内的延迟
+type StatsService struct {
+	db    *gorm.DB
+	cache cache.Cache
+	now   func() time.Time
+}
+
+// cacheStore 为 nil 时不使用缓存
+func NewStatsService(db *gorm.DB, cacheStore cache.Cache) *StatsService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	return &StatsService{db: db, cache: cacheStore, now: time.Now}
+}
+
+// WithContext 返回绑定当前请求的副本，统计范围和缓存限定在请求所属的租户
+func (s *StatsService) WithContext(c *gin.Context) *StatsService {
+	clone := *s
+	clone.db = s.db.WithContext(requestContext(c))
+	clone.cache = tenantCache(s.cache, requestContext(c))
+	return &clone
+}
+
+func (s *StatsService) Overview() (*dto.StatsOverview, *utils.AppError) {
+	return readThrough(s.cache, "stats:overview", func() (*dto.StatsOverview, *utils.AppError) {
+		var overview dto.StatsOverview
+		since := s.now().Add(-7 * 24 * time.Hour)
+		counts := []struct {
+			target *int64
+			query  *gorm.DB
+		}{
+			{&overview.Users, s.db.Model(&models.User{})},
+			{&overview.Admins, s.db.Model(&models.User{}).Where("role = ?", consts.RoleAdmin)},
+			{&overview.Posts, s.db.Model(&models.Post{})},
+			{&overview.PublishedPosts, s.db.Model(&models.Post{}).Where("status = ?", consts.PostStatusPublished)},
+			{&overview.DraftPosts, s.db.Model(&models.Post{}).Where("status = ?", consts.PostStatusDraft)},
+			{&overview.Comments, s.db.Model(&models.Comment{})},
+			{&overview.Tags, s.db.Model(&models.Tag{})},
+			{&overview.Attachments, s.db.Model(&models.Attachment{})},
+			{&overview.NewUsers, s.db.Model(&models.User{}).Where("created_at >= ?", since)},
+			{&overview.NewPosts, s.db.Model(&models.Post{}).Where("created_at >= ?", since)},
+			{&overview.NewComments, s.db.Model(&models.Comment{}).Where("created_at >= ?", since)},
+		}
+		for _, count := range counts {
+			if err := count.query.Count(count.target).Error; err != nil {
+				return nil, utils.NewError(utils.ErrInternal, "Failed to count statistics").Wrap(err)
+			}
+		}
+		return &overview, nil
+	})
+}
+
+// TimeSeries 按天或按周（周一开始）统计新增数量，没有数据的区间补 0
+func (s *StatsService) TimeSeries(query *dto.StatsQueryDTO) (*dto.TimeSeries, *utils.AppError) {
+	from, to, err := s.statsRange(query)
+	if err != nil {
+		return nil, err
+	}
+	metric := query.Metric
+	if metric == "" {
+		metric = "posts"
+	}
+	interval := query.Interval
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "day" && interval != "week" {
+		return nil, utils.NewError(utils.ErrInvalidInterval, "Unknown interval: "+interval)
+	}
+
+	var model interface{}
+	switch metric {
+	case "users":
+		model = &models.User{}
+	case "posts":
+		model = &models.Post{}
+	case "comments":
+		model = &models.Comment{}
+	default:
+		return nil, utils.NewError(utils.ErrUnknownMetric, "Unknown metric: "+metric)
+	}
+
+	key := fmt.Sprintf("stats:series:%s:%s:%d:%d", metric, interval, from.Unix(), to.Unix())
+	return readThrough(s.cache, key, func() (*dto.TimeSeries, *utils.AppError) {
+		// 数据库只按天分组，按周汇总在内存中完成，避免依赖各数据库不同的周函数
+		var rows []struct {
+			Day   string
+			Count int64
+		}
+		err := s.db.Model(model).
+			Select("DATE(created_at) AS day, COUNT(*) AS count").
+			Where("created_at >= ? AND created_at < ?", from, to).
+			Group("DATE(created_at)").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to query statistics").Wrap(err)
+		}
+
+		byBucket := make(map[string]int64, len(rows))
+		var total int64
+		for _, row := range rows {
+			if len(row.Day) < len(dateLayout) {
+				continue
+			}
+			day, err := time.Parse(dateLayout, row.Day[:len(dateLayout)])
+			if err != nil {
+				continue
+			}
+			byBucket[bucketStart(day, interval).Format(dateLayout)] += row.Count
+			total += row.Count
+		}
+
+		series := &dto.TimeSeries{Metric: metric, Interval: interval, From: from, To: to, Total: total}
+		start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+		for bucket := bucketStart(start, interval); bucket.Before(to); bucket = nextBucket(bucket, interval) {
+			date := bucket.Format(dateLayout)
+			series.Points = append(series.Points, dto.TimeSeriesPoint{Date: date, Count: byBucket[date]})
+		}
+		return series, nil
+	})
+}
+
+// TopAuthors 时间范围内发文和评论最多的用户，按两者之和排序
+func (s *StatsService) TopAuthors(query *dto.StatsQueryDTO) (*[]dto.AuthorStat, *utils.AppError) {
+	from, to, err := s.statsRange(query)
+	if err != nil {
+		return nil, err
+	}
+	limit := statsLimit(query)
+
+	key := fmt.Sprintf("stats:authors:%d:%d:%d", from.Unix(), to.Unix(), limit)
+	return readThrough(s.cache, key, func() (*[]dto.AuthorStat, *utils.AppError) {
+		type countRow struct {
+			UserID uint
+			Count  int64
+		}
+		countBy := func(model interface{}) ([]countRow, error) {
+			var rows []countRow
+			err := s.db.Model(model).
+				Select("user_id, COUNT(*) AS count").
+				Where("created_at >= ? AND created_at < ?", from, to).
+				Group("user_id").
+				Scan(&rows).Error
+			return rows, err
+		}
+
+		postRows, e := countBy(&models.Post{})
+		if e != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to query statistics").Wrap(e)
+		}
+		commentRows, e := countBy(&models.Comment{})
+		if e != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to query statistics").Wrap(e)
+		}
+
+		stats := make(map[uint]*dto.AuthorStat)
+		statFor := func(userID uint) *dto.AuthorStat {
+			if stat, ok := stats[userID]; ok {
+				return stat
+			}
+			stat := &dto.AuthorStat{UserID: userID}
+			stats[userID] = stat
+			return stat
+		}
+		for _, row := range postRows {
+			statFor(row.UserID).Posts = row.Count
+		}
+		for _, row := range commentRows {
+			statFor(row.UserID).Comments = row.Count
+		}
+
+		result := make([]dto.AuthorStat, 0, len(stats))
+		for _, stat := range stats {
+			result = append(result, *stat)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			a, b := result[i].Posts+result[i].Comments, result[j].Posts+result[j].Comments
+			if a != b {
+				return a > b
+			}
+			return result[i].UserID < result[j].UserID
+		})
+		if len(result) > limit {
+			result = result[:limit]
+		}
+
+		if err := s.fillUsernames(result); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	})
+}
+
+// TopCommentedPosts 时间范围内评论最多的文章
+func (s *StatsService) TopCommentedPosts(query *dto.StatsQueryDTO) (*[]dto.PostStat, *utils.AppError) {
+	from, to, err := s.statsRange(query)
+	if err != nil {
+		return nil, err
+	}
+	limit := statsLimit(query)
+
+	key := fmt.Sprintf("stats:posts:%d:%d:%d", from.Unix(), to.Unix(), limit)
+	return readThrough(s.cache, key, func() (*[]dto.PostStat, *utils.AppError) {
+		var rows []struct {
+			PostID uint
+			Count  int64
+		}
+		err := s.db.Model(&models.Comment{}).
+			Select("post_id, COUNT(*) AS count").
+			Where("created_at >= ? AND created_at < ?", from, to).
+			Group("post_id").
+			Order("count DESC").Order("post_id ASC").
+			Limit(limit).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to query statistics").Wrap(err)
+		}
+
+		ids := make([]uint, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.PostID)
+		}
+		var posts []models.Post
+		if len(ids) > 0 {
+			if err := s.db.Select("id", "title", "user_id").Where("id IN ?", ids).Find(&posts).Error; err != nil {
+				return nil, utils.NewError(utils.ErrInternal, "Failed to query statistics").Wrap(err)
+			}
+		}
+		postsByID := make(map[uint]models.Post, len(posts))
+		for _, post := range posts {
+			postsByID[post.ID] = post
+		}
+
+		result := make([]dto.PostStat, 0, len(rows))
+		for _, row := range rows {
+			post, ok := postsByID[row.PostID]
+			if !ok {
+				// 文章已删除
+				continue
+			}
+			result = append(result, dto.PostStat{PostID: row.PostID, Title: post.Title, UserID: post.UserId, Comments: row.Count})
+		}
+		return &result, nil
+	})
+}
+
+// StorageUsage 附件占用的存储空间，按类型汇总
+func (s *StatsService) StorageUsage() (*dto.StorageUsage, *utils.AppError) {
+	return readThrough(s.cache, "stats:storage", func() (*dto.StorageUsage, *utils.AppError) {
+		var rows []dto.StorageStat
+		err := s.db.Model(&models.Attachment{}).
+			Select("content_type, COUNT(*) AS files, COALESCE(SUM(size), 0) AS bytes").
+			Group("content_type").
+			Order("bytes DESC").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to query statistics").Wrap(err)
+		}
+
+		usage := &dto.StorageUsage{ByType: rows}
+		if usage.ByType == nil {
+			usage.ByType = []dto.StorageStat{}
+		}
+		for _, row := range rows {
+			usage.Files += row.Files
+			usage.Bytes += row.Bytes
+		}
+		return usage, nil
+	})
+}
+
+func (s *StatsService) fillUsernames(stats []dto.AuthorStat) *utils.AppError {
+	if len(stats) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(stats))
+	for _, stat := range stats {
+		ids = append(ids, stat.UserID)
+	}
+	var users []models.User
+	if err := s.db.Unscoped().Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to query statistics").Wrap(err)
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+	for i := range stats {
+		stats[i].Username = names[stats[i].UserID]
+	}
+	return nil
+}
+
+// statsRange 解析时间范围，默认最近30天；起始时间按分钟取整以便缓存命中
+func (s *StatsService) statsRange(query *dto.StatsQueryDTO) (time.Time, time.Time, *utils.AppError) {
+	to := s.now().UTC().Truncate(time.Minute).Add(time.Minute)
+	if query.To != nil {
+		to = query.To.UTC()
+	}
+	from := to.Add(-defaultStatsRange)
+	if query.From != nil {
+		from = query.From.UTC()
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, utils.NewError(utils.ErrInvalidTimeRange, "from must be earlier than to")
+	}
+	if to.Sub(from) > maxStatsRange {
+		return time.Time{}, time.Time{}, utils.NewError(utils.ErrInvalidTimeRange, "Time range must not exceed 366 days")
+	}
+	return from, to, nil
+}
+
+func statsLimit(query *dto.StatsQueryDTO) int {
+	if query.Limit <= 0 {
+		return defaultStatsLimit
+	}
+	return min(query.Limit, maxStatsLimit)
+}
+
+func bucketStart(day time.Time, interval string) time.Time {
+	if interval != "week" {
+		return day
+	}
+	// 周一为一周的开始
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func nextBucket(bucket time.Time, interval string) time.Time {
+	if interval == "week" {
+		return bucket.AddDate(0, 0, 7)
+	}
+	return bucket.AddDate(0, 0, 1)
+}
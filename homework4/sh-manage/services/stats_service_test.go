@@ -0,0 +1,111 @@
+package services
+
+import (
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"testing"
+	"time"
+)
+
+func TestStatsService(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Date(2024, 6, 12, 15, 0, 0, 0, time.UTC) // 周三
+	day := func(offset int) time.Time { return now.AddDate(0, 0, offset) }
+
+	alice := &models.User{Username: "alice", Email: "a@example.com", Password: "x", Role: consts.RoleAdmin}
+	bob := &models.User{Username: "bob", Email: "b@example.com", Password: "x", Role: consts.RoleUser}
+	alice.CreatedAt, bob.CreatedAt = day(-20), day(-1)
+	db.Create(alice)
+	db.Create(bob)
+
+	newPost := func(user *models.User, createdAt time.Time, status string) *models.Post {
+		post := &models.Post{Title: "p", Content: "c", UserId: user.ID, Status: status}
+		post.CreatedAt = createdAt
+		db.Create(post)
+		return post
+	}
+	p1 := newPost(alice, day(-2), consts.PostStatusPublished)
+	p2 := newPost(alice, day(-2), consts.PostStatusPublished)
+	newPost(bob, day(0), consts.PostStatusDraft)
+	for i := 0; i < 3; i++ {
+		comment := &models.Comment{Content: "c", UserId: bob.ID, PostId: p2.ID}
+		comment.CreatedAt = day(-1)
+		db.Create(comment)
+	}
+	comment := &models.Comment{Content: "c", UserId: alice.ID, PostId: p1.ID}
+	comment.CreatedAt = day(0)
+	db.Create(comment)
+	db.Create(&models.Attachment{PostId: p1.ID, FileName: "a.png", StorageKey: "a.png", ContentType: "image/png", Size: 100})
+	db.Create(&models.Attachment{PostId: p1.ID, FileName: "b.png", StorageKey: "b.png", ContentType: "image/png", Size: 50})
+	db.Create(&models.Attachment{PostId: p2.ID, FileName: "c.pdf", StorageKey: "c.pdf", ContentType: "application/pdf", Size: 10})
+
+	svc := NewStatsService(db, nil)
+	svc.now = func() time.Time { return now }
+
+	overview, err := svc.Overview()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overview.Users != 2 || overview.Admins != 1 || overview.Posts != 3 || overview.DraftPosts != 1 ||
+		overview.Comments != 4 || overview.NewUsers != 1 || overview.Attachments != 3 {
+		t.Fatalf("overview = %+v", overview)
+	}
+
+	from := day(-7)
+	series, err := svc.TimeSeries(&dto.StatsQueryDTO{Metric: "posts", From: &from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	for _, point := range series.Points {
+		counts[point.Date] = point.Count
+	}
+	if len(series.Points) != 8 || series.Total != 3 || counts["2024-06-10"] != 2 || counts["2024-06-12"] != 1 || counts["2024-06-11"] != 0 {
+		t.Fatalf("daily series = %+v", series)
+	}
+
+	weekly, err := svc.TimeSeries(&dto.StatsQueryDTO{Metric: "comments", Interval: "week", From: &from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weekly.Points) != 2 || weekly.Points[0].Date != "2024-06-03" || weekly.Points[1].Date != "2024-06-10" || weekly.Points[1].Count != 4 {
+		t.Fatalf("weekly series = %+v", weekly)
+	}
+
+	authors, err := svc.TopAuthors(&dto.StatsQueryDTO{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*authors) != 2 || (*authors)[0].Username != "bob" || (*authors)[0].Comments != 3 || (*authors)[1].Posts != 2 {
+		t.Fatalf("top authors = %+v", *authors)
+	}
+
+	posts, err := svc.TopCommentedPosts(&dto.StatsQueryDTO{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*posts) != 1 || (*posts)[0].PostID != p2.ID || (*posts)[0].Comments != 3 {
+		t.Fatalf("top posts = %+v", *posts)
+	}
+
+	usage, err := svc.StorageUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Files != 3 || usage.Bytes != 160 || usage.ByType[0].ContentType != "image/png" {
+		t.Fatalf("storage = %+v", usage)
+	}
+
+	to := from
+	if _, err := svc.TimeSeries(&dto.StatsQueryDTO{From: &from, To: &to}); err == nil || err.Code != 400 {
+		t.Fatalf("empty range = %v", err)
+	}
+	if _, err := svc.TimeSeries(&dto.StatsQueryDTO{Interval: "hour"}); err == nil || err.StableCode() != utils.ErrInvalidInterval || err.Code != 422 {
+		t.Fatalf("unknown interval = %v", err)
+	}
+	if limit := statsLimit(&dto.StatsQueryDTO{Limit: 100000}); limit != maxStatsLimit {
+		t.Fatalf("statsLimit = %d, want %d", limit, maxStatsLimit)
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"sh-manage/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// normalizeTagNames 去除空白、转小写并去重，保持原有顺序
+func normalizeTagNames(names []string) []string {
+	result := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// findOrCreateTags 按名称查找标签，不存在的自动创建
+func findOrCreateTags(db *gorm.DB, names []string) ([]models.Tag, error) {
+	names = normalizeTagNames(names)
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		var tag models.Tag
+		if err := db.Where(models.Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"sh-manage/cache"
+	"sh-manage/models"
+	"sh-manage/utils"
+
+	"gorm.io/gorm"
+)
+
+// TenantService 管理租户，每个请求都会解析租户，查询结果会缓存
+type TenantService struct {
+	db    *gorm.DB
+	cache cache.Cache
+}
+
+// cacheStore 为 nil 时不使用缓存
+func NewTenantService(db *gorm.DB, cacheStore cache.Cache) *TenantService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	return &TenantService{db: db, cache: cacheStore}
+}
+
+// Resolve 按标识查找租户，不存在时返回 404
+func (s *TenantService) Resolve(slug string) (*models.Tenant, *utils.AppError) {
+	if !models.ValidTenantSlug(slug) {
+		return nil, utils.NewError(utils.ErrTenantNotFound, "Tenant not found")
+	}
+	return readThrough(s.cache, tenantSlugCacheKey(slug), func() (*models.Tenant, *utils.AppError) {
+		var t models.Tenant
+		if err := s.db.Where("slug = ?", slug).First(&t).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, utils.NewError(utils.ErrTenantNotFound, "Tenant not found")
+			}
+			return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve tenant").Wrap(err)
+		}
+		return &t, nil
+	})
+}
+
+func (s *TenantService) CreateTenant(slug, name string) (*models.Tenant, *utils.AppError) {
+	if !models.ValidTenantSlug(slug) {
+		return nil, utils.NewError(utils.ErrValidation, "Tenant slug must be lowercase letters, digits or hyphens")
+	}
+	if name == "" {
+		name = slug
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Tenant{}).Where("slug = ?", slug).Count(&count).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve tenant").Wrap(err)
+	}
+	if count > 0 {
+		return nil, utils.NewError(utils.ErrTenantExists, "Tenant already exists")
+	}
+
+	t := &models.Tenant{Slug: slug, Name: name}
+	if err := s.db.Create(t).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create tenant").Wrap(err)
+	}
+	invalidate(s.cache, tenantSlugCacheKey(slug))
+	return t, nil
+}
+
+func (s *TenantService) ListTenants() ([]models.Tenant, *utils.AppError) {
+	var tenants []models.Tenant
+	if err := s.db.Order("id asc").Find(&tenants).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve tenants").Wrap(err)
+	}
+	return tenants, nil
+}
@@ -1,123 +1,560 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"log"
+	"sh-manage/cache"
+	"sh-manage/consts"
+	"sh-manage/logging"
 	"sh-manage/models"
+	"sh-manage/passwords"
+	"sh-manage/repository"
+	"sh-manage/storage"
+	"sh-manage/tenant"
 	"sh-manage/utils"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type UserService struct {
 	// 这里可以添加数据库连接等依赖
-	db *gorm.DB
+	users        repository.UserRepository
+	posts        repository.PostRepository
+	comments     repository.CommentRepository
+	attachments  repository.AttachmentRepository
+	tx           repository.UnitOfWork
+	storage      storage.Storage
+	cache        cache.Cache
+	context      *gin.Context
+	tenantID     uint
+	auditService AuditRecorder
+	passwords    *passwords.Manager
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+// cacheStore 为 nil 时不使用缓存
+func NewUserService(db *gorm.DB, cacheStore cache.Cache) *UserService {
+	return NewUserServiceWithRepositories(repository.NewGorm(db), cacheStore, NewAuditService(db))
+}
+
+// NewUserServiceWithRepositories 使用指定的存储和审计实现，测试中传入内存实现
+// 删除用户时需要级联删除文章、评论和附件，所以依赖全部存储
+func NewUserServiceWithRepositories(repos repository.Repositories, cacheStore cache.Cache, audit AuditRecorder) *UserService {
+	if cacheStore == nil {
+		cacheStore = cache.Nop{}
+	}
+	return &UserService{users: repos.Users, posts: repos.Posts, comments: repos.Comments, attachments: repos.Attachments, tx: repos.Tx, cache: cacheStore, auditService: audit, passwords: passwords.Default()}
+}
+
+// SetPasswords 设置密码哈希算法和密码策略，默认使用 passwords.Default
+func (s *UserService) SetPasswords(manager *passwords.Manager) {
+	s.passwords = manager
+}
+
+// SetStorage 设置附件文件所在的存储，删除用户时一并删除其文章附件的文件，为 nil 时只删除记录
+func (s *UserService) SetStorage(store storage.Storage) {
+	s.storage = store
+}
+
+// WithContext 返回绑定当前请求的副本，审计日志从中读取操作人、IP和请求ID
+func (s *UserService) WithContext(c *gin.Context) *UserService {
+	clone := *s
+	clone.context = c
+	clone.cache = tenantCache(s.cache, requestContext(c))
+	return &clone
+}
+
+// WithTenant 返回限定在指定租户内的副本，供没有 HTTP 请求的命令行使用
+func (s *UserService) WithTenant(tenantID uint) *UserService {
+	clone := *s
+	clone.tenantID = tenantID
+	clone.cache = tenantCache(s.cache, tenant.WithID(context.Background(), tenantID))
+	return &clone
+}
+
+func (s *UserService) ctx() context.Context {
+	ctx := requestContext(s.context)
+	if s.tenantID != 0 {
+		ctx = tenant.WithID(ctx, s.tenantID)
+	}
+	return ctx
 }
 
 // 在这里添加用户相关的方法，例如创建用户、获取用户信息等
 func (s *UserService) CreateUser(req models.CreateUserRequest) (*models.User, error) {
-	var existingUser models.User
-	if err := s.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		return nil, utils.NewAppError(409, "Username already exists")
+	return s.createUser(req, consts.RoleUser, consts.AuditUserRegister)
+}
+
+// CreateAdmin 创建管理员账号，只供命令行使用，不对外提供接口
+func (s *UserService) CreateAdmin(req models.CreateUserRequest) (*models.User, error) {
+	return s.createUser(req, consts.RoleAdmin, consts.AuditUserCreateAdmin)
+}
+
+func (s *UserService) createUser(req models.CreateUserRequest, role, action string) (*models.User, error) {
+	if _, err := s.users.FindByUsername(s.ctx(), req.Username); err == nil {
+		return nil, utils.NewError(utils.ErrUserExists, "Username already exists")
 	}
 
-	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		return nil, utils.NewAppError(409, "Email already exists")
+	if _, err := s.users.FindByEmail(s.ctx(), req.Email); err == nil {
+		return nil, utils.NewError(utils.ErrUserExists, "Email already exists")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, utils.NewAppError(500, "Failed to hash password")
+	hashedPassword, appErr := s.newPasswordHash(req.Password, req.Username, req.Email)
+	if appErr != nil {
+		return nil, appErr
 	}
 
 	user := &models.User{
 		Username: req.Username,
 		Email:    req.Email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
+		Role:     role,
 	}
 
-	if err := s.db.Create(&user).Error; err != nil {
-		return nil, utils.NewAppError(500, "Failed to create user")
+	if err := s.users.Create(s.ctx(), user); err != nil {
+		if errors.Is(err, repository.ErrDuplicated) {
+			return nil, utils.NewError(utils.ErrUserExists, "Username or email already exists")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create user").Wrap(err)
 	}
 
+	s.auditService.Record(s.context, AuditEntry{
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+		Action:     action,
+		EntityType: consts.EntityUser,
+		EntityID:   user.ID,
+		After:      user.ToResponse(),
+	})
 	return user, nil
 }
+
+// GetUserByID 读取时经过缓存，缓存中的用户不含密码哈希，不能用于写操作
 func (s *UserService) GetUserByID(userID uint) (*models.User, error) {
-	var user models.User
-	if err := s.db.First(&user, userID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, utils.NewAppError(404, "User not found")
-		}
-		return nil, utils.NewAppError(500, "Failed to retrieve user")
+	user, err := readThrough(s.cache, userCacheKey(userID), func() (*models.User, *utils.AppError) {
+		return s.findUserByID(userID)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return &user, nil
+	return user, nil
+}
+
+// GetUserByIDUncached 直接查询数据库，权限检查使用
+// 缓存是进程内的，其他实例修改角色后本地缓存在过期前不会更新
+func (s *UserService) GetUserByIDUncached(userID uint) (*models.User, error) {
+	user, err := s.findUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *UserService) findUserByID(userID uint) (*models.User, *utils.AppError) {
+	user, err := s.users.FindByID(s.ctx(), userID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrUserNotFound, "Failed to retrieve user")
+	}
+	return user, nil
+}
+
+// GetUsersByIDs 批量查询用户，不经过缓存，不存在的 ID 被忽略
+func (s *UserService) GetUsersByIDs(userIDs []uint) ([]models.User, error) {
+	users, err := s.users.FindByIDs(s.ctx(), userIDs)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve user").Wrap(err)
+	}
+	return users, nil
 }
 
 func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
-	var user models.User
-	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, utils.NewAppError(404, "User not found")
-		}
-		return nil, utils.NewAppError(500, "Failed to retrieve user")
+	user, err := s.users.FindByEmail(s.ctx(), email)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrUserNotFound, "Failed to retrieve user")
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (s *UserService) GetUserByName(username string) (*models.User, error) {
-	var user models.User
-	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, utils.NewAppError(404, "User not found")
-		}
-		return nil, utils.NewAppError(500, "Failed to retrieve user")
+	user, err := s.users.FindByUsername(s.ctx(), username)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrUserNotFound, "Failed to retrieve user")
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (s *UserService) UpdateUser(userID uint, req models.UpdateUserRequest) (*models.User, error) {
 
-	user, err := s.GetUserByID(userID)
+	if req.Version == nil {
+		return nil, utils.NewError(utils.ErrVersionRequired, "版本号不能为空")
+	}
+
+	user, err := s.findUserByID(userID)
 	if err != nil {
 		return nil, err
 	}
-
-	if req.Email != nil {
-		user.Email = *req.Email
+	if user.Version != *req.Version {
+		return nil, versionConflict(user.ToResponse())
 	}
+	before := user.ToResponse()
+
+	changes := repository.UserChanges{Email: req.Email}
 	if req.Password != nil {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, utils.NewAppError(500, "Failed to hash password")
+		email := user.Email
+		if req.Email != nil {
+			email = *req.Email
 		}
-		user.Password = string(hashedPassword)
+		hashed, appErr := s.newPasswordHash(*req.Password, user.Username, email)
+		if appErr != nil {
+			return nil, appErr
+		}
+		changes.Password = &hashed
 	}
 
-	if err := s.db.Save(user).Error; err != nil {
-		return nil, utils.NewAppError(500, "Failed to update user")
+	updated, e := s.users.Update(s.ctx(), userID, *req.Version, changes)
+	if errors.Is(e, repository.ErrDuplicated) {
+		return nil, utils.NewError(utils.ErrUserExists, "Email already exists")
+	}
+	if e != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update user").Wrap(e)
 	}
+	invalidate(s.cache, userCacheKey(userID))
 
+	user, err = s.findUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, versionConflict(user.ToResponse())
+	}
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditUserUpdate,
+		EntityType: consts.EntityUser,
+		EntityID:   user.ID,
+		Before:     before,
+		After:      gin.H{"user": user.ToResponse(), "password_changed": req.Password != nil},
+	})
 	return user, nil
 }
+
+// DeleteUser 在同一个事务中删除用户、用户的评论、用户的文章以及这些文章的评论、标签关联和附件，
+// 用户的第三方身份绑定和 API Key 随用户一起删除；附件文件在事务提交后从存储中删除
 func (s *UserService) DeleteUser(userID uint) error {
-	if err := s.db.Delete(&models.User{}, userID).Error; err != nil {
-		return utils.NewAppError(500, "Failed to delete user")
+	user, err := s.findUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	var postIDs []uint
+	var attachments []models.Attachment
+	if err := inTransaction(s.ctx(), s.tx, "Failed to delete user", func(ctx context.Context) *utils.AppError {
+		if err := s.comments.DeleteByUser(ctx, userID); err != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to delete user comments").Wrap(err)
+		}
+		ids, err := s.posts.DeleteByUser(ctx, userID)
+		if err != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to delete user posts").Wrap(err)
+		}
+		if err := s.comments.DeleteByPosts(ctx, ids); err != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to delete user posts").Wrap(err)
+		}
+		if attachments, err = s.attachments.DeleteByPosts(ctx, ids); err != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to delete post attachments").Wrap(err)
+		}
+		if err := s.users.Delete(ctx, userID); err != nil {
+			return utils.NewError(utils.ErrInternal, "Failed to delete user").Wrap(err)
+		}
+		postIDs = ids
+		return nil
+	}); err != nil {
+		return err
+	}
+	s.deleteAttachmentObjects(attachments)
+	invalidate(s.cache, userCacheKey(userID))
+	for _, postID := range postIDs {
+		invalidate(s.cache, postCacheKey(postID))
 	}
+	if len(postIDs) > 0 {
+		invalidatePostList(s.cache)
+	}
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditUserDelete,
+		EntityType: consts.EntityUser,
+		EntityID:   userID,
+		Before:     gin.H{"user": user.ToResponse(), "deleted_posts": postIDs, "deleted_attachments": len(attachments)},
+	})
 	return nil
 }
 
+// deleteAttachmentObjects 删除附件及缩略图文件，失败只记录日志，记录已经删除
+func (s *UserService) deleteAttachmentObjects(attachments []models.Attachment) {
+	if s.storage == nil {
+		return
+	}
+	for i := range attachments {
+		for _, key := range attachments[i].StorageKeys() {
+			if err := s.storage.Delete(s.ctx(), key); err != nil {
+				log.Printf("Failed to delete attachment object %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// ResetPassword 直接重置指定用户的密码，不校验旧密码和版本号，只供命令行使用
+func (s *UserService) ResetPassword(username, password string) (*models.User, error) {
+	user, err := s.GetUserByName(username)
+	if err != nil {
+		return nil, err
+	}
+
+	hashed, appErr := s.newPasswordHash(password, user.Username, user.Email)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if _, e := s.users.Update(s.ctx(), user.ID, 0, repository.UserChanges{Password: &hashed}); e != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update user").Wrap(e)
+	}
+	invalidate(s.cache, userCacheKey(user.ID))
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditUserResetPasswd,
+		EntityType: consts.EntityUser,
+		EntityID:   user.ID,
+	})
+
+	updated, appErr := s.findUserByID(user.ID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return updated, nil
+}
+
+// SetRole 修改用户的角色，只供命令行使用
+func (s *UserService) SetRole(username, role string) (*models.User, error) {
+	switch role {
+	case consts.RoleUser, consts.RoleModerator, consts.RoleAdmin:
+	default:
+		return nil, utils.NewError(utils.ErrInvalidRole, "Role must be user, moderator or admin")
+	}
+	user, err := s.GetUserByName(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, e := s.users.Update(s.ctx(), user.ID, 0, repository.UserChanges{Role: &role}); e != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update user").Wrap(e)
+	}
+	invalidate(s.cache, userCacheKey(user.ID))
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditUserSetRole,
+		EntityType: consts.EntityUser,
+		EntityID:   user.ID,
+		Before:     map[string]string{"role": user.Role},
+		After:      map[string]string{"role": role},
+	})
+
+	updated, appErr := s.findUserByID(user.ID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return updated, nil
+}
+
+// newPasswordHash 按密码策略检查新密码后生成哈希
+func (s *UserService) newPasswordHash(password, username, email string) (string, *utils.AppError) {
+	if reasons := s.passwords.Check(password, username, email); len(reasons) > 0 {
+		return "", utils.NewError(utils.ErrWeakPassword, "").WithData(gin.H{"reasons": reasons})
+	}
+	hashed, err := s.passwords.Hash(password)
+	if err != nil {
+		return "", utils.NewError(utils.ErrInternal, "Failed to hash password").Wrap(err)
+	}
+	return hashed, nil
+}
+
+// rehashPassword 登录成功后按当前算法和参数重新生成哈希，失败时只记录日志，不影响登录
+func (s *UserService) rehashPassword(user *models.User, password string) {
+	hashed, err := s.passwords.Hash(password)
+	if err != nil {
+		logging.Errorf("rehash password of user %d: %v", user.ID, err)
+		return
+	}
+	// 密码在此期间被修改时 ReplacePassword 不生效，保留新密码
+	replaced, err := s.users.ReplacePassword(s.ctx(), user.ID, user.Password, hashed)
+	if err != nil {
+		logging.Errorf("rehash password of user %d: %v", user.ID, err)
+		return
+	}
+	if replaced {
+		user.Password = hashed
+	}
+}
+
 func (s *UserService) Authenticate(username, password string) (*models.User, error) {
 	user, err := s.GetUserByName(username)
 	if err != nil {
-		return nil, utils.NewAppError(401, "Invalid username or password")
+		s.recordLoginFailed(0, username)
+		return nil, utils.NewError(utils.ErrInvalidCredentials, "Invalid username or password")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, utils.NewAppError(401, "Invalid username or password")
+	ok, rehash, e := s.passwords.Verify(password, user.Password)
+	if e != nil {
+		logging.Warnf("user %d has an unreadable password hash: %v", user.ID, e)
+	}
+	if !ok {
+		s.recordLoginFailed(user.ID, username)
+		return nil, utils.NewError(utils.ErrInvalidCredentials, "Invalid username or password")
+	}
+	if rehash {
+		s.rehashPassword(user, password)
+	}
+	// 密码正确后才提示账号被停用，避免泄露账号状态
+	if err := accountRestriction(user, time.Now()); err != nil {
+		return nil, err
 	}
 
+	s.auditService.Record(s.context, AuditEntry{
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+		Action:     consts.AuditUserLogin,
+		EntityType: consts.EntityUser,
+		EntityID:   user.ID,
+	})
 	return user, nil
 }
+
+// EnsureActive 认证成功后检查账号没有被停用或封禁，用户已删除时返回 401
+// 与角色检查一样直接查询数据库，其他实例的封禁和停用立即生效
+func (s *UserService) EnsureActive(userID uint) *utils.AppError {
+	user, err := s.findUserByID(userID)
+	if err != nil {
+		if err.Code == 404 {
+			return utils.NewError(utils.ErrTokenInvalid, "User no longer exists")
+		}
+		return err
+	}
+	return accountRestriction(user, time.Now())
+}
+
+// accountRestriction 被封禁或停用未到期的账号返回 403，停用时附带截止时间
+func accountRestriction(user *models.User, now time.Time) *utils.AppError {
+	if user.IsBanned() {
+		return utils.NewError(utils.ErrAccountBanned, "Account is banned")
+	}
+	if user.IsSuspended(now) {
+		return utils.NewError(utils.ErrAccountSuspended, "Account is suspended").WithData(gin.H{"suspended_until": user.SuspendedUntil})
+	}
+	return nil
+}
+
+// Warn 记录一次警告，警告次数用于审核员决定后续处理
+func (s *UserService) Warn(userID uint, note string) (*models.User, error) {
+	sanction, err := s.warn(s.ctx(), userID, note)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishSanction(sanction)
+}
+
+// Suspend 停用账号到 until，期间不能登录，已签发的令牌和 API Key 也不能使用
+func (s *UserService) Suspend(userID uint, until time.Time, note string) (*models.User, error) {
+	sanction, err := s.suspend(s.ctx(), userID, until, note)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishSanction(sanction)
+}
+
+// Ban 永久封禁账号，只能通过数据库解除
+func (s *UserService) Ban(userID uint, note string) (*models.User, error) {
+	sanction, err := s.ban(s.ctx(), userID, note)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishSanction(sanction)
+}
+
+// userSanction 已经写入但还没有记录审计日志的处罚
+type userSanction struct {
+	user   *models.User
+	action string
+	after  map[string]interface{}
+}
+
+// warn、suspend 和 ban 使用 ctx 修改账号，可以加入调用方的事务，提交后需要调用 finishSanction
+func (s *UserService) warn(ctx context.Context, userID uint, note string) (*userSanction, *utils.AppError) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrUserNotFound, "Failed to retrieve user")
+	}
+	warnings := user.Warnings + 1
+	return s.sanction(ctx, user, consts.AuditUserWarn, repository.UserChanges{Warnings: &warnings}, map[string]interface{}{"warnings": warnings, "note": note})
+}
+
+func (s *UserService) suspend(ctx context.Context, userID uint, until time.Time, note string) (*userSanction, *utils.AppError) {
+	if !until.After(time.Now()) {
+		return nil, utils.NewError(utils.ErrInvalidDuration, "Suspension must end in the future")
+	}
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrUserNotFound, "Failed to retrieve user")
+	}
+	return s.sanction(ctx, user, consts.AuditUserSuspend, repository.UserChanges{SuspendedUntil: &until}, map[string]interface{}{"suspended_until": until, "note": note})
+}
+
+func (s *UserService) ban(ctx context.Context, userID uint, note string) (*userSanction, *utils.AppError) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, repositoryError(err, utils.ErrUserNotFound, "Failed to retrieve user")
+	}
+	if user.IsBanned() {
+		return nil, utils.NewError(utils.ErrUserAlreadyBanned, "User is already banned")
+	}
+	now := time.Now()
+	return s.sanction(ctx, user, consts.AuditUserBan, repository.UserChanges{BannedAt: &now}, map[string]interface{}{"banned_at": now, "note": note})
+}
+
+func (s *UserService) sanction(ctx context.Context, user *models.User, action string, changes repository.UserChanges, after map[string]interface{}) (*userSanction, *utils.AppError) {
+	if _, err := s.users.Update(ctx, user.ID, 0, changes); err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update user").Wrap(err)
+	}
+	return &userSanction{user: user, action: action, after: after}, nil
+}
+
+// finishSanction 处罚生效后使用户缓存失效并记录审计日志，返回处罚后的用户
+func (s *UserService) finishSanction(sanction *userSanction) (*models.User, error) {
+	user := sanction.user
+	invalidate(s.cache, userCacheKey(user.ID))
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     sanction.action,
+		EntityType: consts.EntityUser,
+		EntityID:   user.ID,
+		Before: map[string]interface{}{
+			"warnings":        user.Warnings,
+			"suspended_until": user.SuspendedUntil,
+			"banned_at":       user.BannedAt,
+		},
+		After: sanction.after,
+	})
+
+	updated, appErr := s.findUserByID(user.ID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return updated, nil
+}
+
+func (s *UserService) recordLoginFailed(userID uint, username string) {
+	s.auditService.Record(s.context, AuditEntry{
+		ActorName:  username,
+		Action:     consts.AuditUserLoginFailed,
+		EntityType: consts.EntityUser,
+		EntityID:   userID,
+	})
+}
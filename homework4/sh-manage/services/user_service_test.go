@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/models"
+	"sh-manage/passwords"
+	"sh-manage/repository"
+	"sh-manage/storage"
+	"sh-manage/utils"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserServiceCreate(t *testing.T) {
+	f := newMemoryFixture(t)
+	f.createUser(t, "alice")
+
+	tests := []struct {
+		name     string
+		req      models.CreateUserRequest
+		admin    bool
+		wantCode int
+		wantRole string
+	}{
+		{"user", models.CreateUserRequest{Username: "bob", Email: "bob@example.com", Password: "Correct-Horse-42"}, false, 0, consts.RoleUser},
+		{"admin", models.CreateUserRequest{Username: "root", Email: "root@example.com", Password: "Correct-Horse-42"}, true, 0, consts.RoleAdmin},
+		{"duplicate username", models.CreateUserRequest{Username: "alice", Email: "other@example.com", Password: "Correct-Horse-42"}, false, 409, ""},
+		{"duplicate email", models.CreateUserRequest{Username: "carol", Email: "alice@example.com", Password: "Correct-Horse-42"}, false, 409, ""},
+		{"breached password", models.CreateUserRequest{Username: "dave", Email: "dave@example.com", Password: "password123"}, false, 422, ""},
+		{"password is username", models.CreateUserRequest{Username: "erin-the-admin", Email: "erin@example.com", Password: "Erin-The-Admin"}, false, 422, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create := f.users.CreateUser
+			if tt.admin {
+				create = f.users.CreateAdmin
+			}
+			user, err := create(tt.req)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode != 0 {
+				return
+			}
+			if user.Role != tt.wantRole || user.Password == tt.req.Password {
+				t.Fatalf("user = %+v", user)
+			}
+			wantAction := consts.AuditUserRegister
+			if tt.admin {
+				wantAction = consts.AuditUserCreateAdmin
+			}
+			if entry := f.audit.last(); entry.Action != wantAction || entry.EntityID != user.ID {
+				t.Fatalf("audit = %+v", entry)
+			}
+		})
+	}
+}
+
+func TestUserServiceGet(t *testing.T) {
+	f := newMemoryFixture(t)
+	alice := f.createUser(t, "alice")
+
+	tests := []struct {
+		name     string
+		get      func() (*models.User, error)
+		wantCode int
+	}{
+		{"by id", func() (*models.User, error) { return f.users.GetUserByID(alice.ID) }, 0},
+		{"by id missing", func() (*models.User, error) { return f.users.GetUserByID(999) }, 404},
+		{"by email", func() (*models.User, error) { return f.users.GetUserByEmail("alice@example.com") }, 0},
+		{"by email missing", func() (*models.User, error) { return f.users.GetUserByEmail("x@example.com") }, 404},
+		{"by name", func() (*models.User, error) { return f.users.GetUserByName("alice") }, 0},
+		{"by name missing", func() (*models.User, error) { return f.users.GetUserByName("nobody") }, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := tt.get()
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode == 0 && user.ID != alice.ID {
+				t.Fatalf("user = %+v", user)
+			}
+		})
+	}
+}
+
+func TestUserServiceUpdate(t *testing.T) {
+	f := newMemoryFixture(t)
+	alice := f.createUser(t, "alice")
+	f.createUser(t, "bob")
+
+	tests := []struct {
+		name     string
+		userID   uint
+		req      models.UpdateUserRequest
+		wantCode int
+	}{
+		{"missing version", alice.ID, models.UpdateUserRequest{Email: ptr("a@example.com")}, 400},
+		{"missing user", 999, models.UpdateUserRequest{Version: ptr(uint(1))}, 404},
+		{"stale version", alice.ID, models.UpdateUserRequest{Email: ptr("a@example.com"), Version: ptr(uint(5))}, 409},
+		{"email taken", alice.ID, models.UpdateUserRequest{Email: ptr("bob@example.com"), Version: ptr(uint(1))}, 409},
+		{"weak password", alice.ID, models.UpdateUserRequest{Password: ptr("short"), Version: ptr(uint(1))}, 422},
+		{"email and password", alice.ID, models.UpdateUserRequest{Email: ptr("a@example.com"), Password: ptr("newpassword"), Version: ptr(uint(1))}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := f.users.UpdateUser(tt.userID, tt.req)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode == 0 && (user.Email != *tt.req.Email || user.Version != 2) {
+				t.Fatalf("user = %+v", user)
+			}
+		})
+	}
+
+	if _, err := f.users.Authenticate("alice", "newpassword"); err != nil {
+		t.Fatalf("login with updated password: %v", err)
+	}
+}
+
+func TestUserServiceDelete(t *testing.T) {
+	f := newMemoryFixture(t)
+	alice := f.createUser(t, "alice")
+	bob := f.createUser(t, "bob")
+	alicePost := f.createPost(t, alice.ID, "alice post", "")
+	bobPost := f.createPost(t, bob.ID, "bob post", "")
+	f.createComment(t, bob.ID, alicePost.ID, "bob on alice")
+	f.createComment(t, alice.ID, bobPost.ID, "alice on bob")
+	bobComment := f.createComment(t, bob.ID, bobPost.ID, "bob on bob")
+
+	if err := f.users.DeleteUser(999); appErrorCode(err) != 404 {
+		t.Fatalf("delete missing = %v", err)
+	}
+	if err := f.users.DeleteUser(alice.ID); err != nil {
+		t.Fatalf("DeleteUser = %v", err)
+	}
+	if _, err := f.users.GetUserByID(alice.ID); appErrorCode(err) != 404 {
+		t.Fatalf("deleted user still found: %v", err)
+	}
+	if _, err := f.posts.GetPostByID(alicePost.ID); appErrorCode(err) != 404 {
+		t.Fatalf("alice's post still found: %v", err)
+	}
+	if _, err := f.posts.GetPostByID(bobPost.ID); err != nil {
+		t.Fatalf("bob's post deleted: %v", err)
+	}
+	// 只剩 bob 在自己文章下的评论
+	page, _ := f.comments.GetCommentByPage(&dto.CommentPageDTO{BasePageQuery: *dto.NewBasePageQuery()})
+	if page.Total != 1 || page.Items[0].ID != bobComment.ID {
+		t.Fatalf("remaining comments = %+v", page.Items)
+	}
+	if entry := f.audit.last(); entry.Action != consts.AuditUserDelete || entry.EntityID != alice.ID {
+		t.Fatalf("audit = %+v", entry)
+	}
+}
+
+func TestUserServiceDeleteCascadesRelatedRows(t *testing.T) {
+	db := newTestDB(t)
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir, "/static/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := NewUserService(db, nil)
+	users.SetStorage(store)
+	posts := NewPostService(db, users, nil, nil)
+
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "x"}
+	db.Create(alice)
+	db.Create(bob)
+	db.Create(&models.ApiKey{Name: "ci", Prefix: "shm_alice", KeyHash: "hash", UserId: alice.ID})
+	db.Create(&models.UserIdentity{Provider: "fake", Subject: "alice-sub", UserId: alice.ID})
+	title, content := "alice post", "content"
+	post, appErr := posts.WithContext(asUser(alice.ID)).CreatePost(&dto.PostDto{Title: &title, Content: &content, Tags: []string{"go"}})
+	if appErr != nil {
+		t.Fatalf("create post: %v", appErr)
+	}
+	attachment := &models.Attachment{PostId: post.ID, UserId: alice.ID, FileName: "a.png", StorageKey: "attachments/a.png",
+		Thumbnails: []models.AttachmentThumbnail{{Name: "small", StorageKey: "attachments/a-small.png"}}}
+	db.Create(attachment)
+	for _, key := range attachment.StorageKeys() {
+		if err := store.Put(context.Background(), key, strings.NewReader("png"), 3, "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := users.DeleteUser(alice.ID); err != nil {
+		t.Fatalf("DeleteUser = %v", err)
+	}
+
+	counts := map[string]int64{}
+	for name, model := range map[string]interface{}{"api keys": &models.ApiKey{}, "identities": &models.UserIdentity{}, "attachments": &models.Attachment{}} {
+		var count int64
+		db.Model(model).Where("user_id = ?", alice.ID).Count(&count)
+		counts[name] = count
+	}
+	var postTags int64
+	db.Table("post_tags").Where("post_id = ?", post.ID).Count(&postTags)
+	counts["post_tags"] = postTags
+	for name, count := range counts {
+		if count != 0 {
+			t.Fatalf("%d %s left after delete", count, name)
+		}
+	}
+	for _, key := range attachment.StorageKeys() {
+		if _, err := os.Stat(filepath.Join(dir, key)); !os.IsNotExist(err) {
+			t.Fatalf("file %s still exists after delete: %v", key, err)
+		}
+	}
+	if _, err := users.GetUserByID(bob.ID); err != nil {
+		t.Fatalf("other user deleted: %v", err)
+	}
+}
+
+// failingComments 删除文章评论时失败，用于验证级联删除整体回滚
+type failingComments struct {
+	repository.CommentRepository
+}
+
+func (failingComments) DeleteByPosts(context.Context, []uint) error {
+	return errors.New("connection lost")
+}
+
+func TestUserServiceDeleteRollsBack(t *testing.T) {
+	f := newMemoryFixture(t)
+	alice := f.createUser(t, "alice")
+	post := f.createPost(t, alice.ID, "alice post", "")
+	f.createComment(t, alice.ID, post.ID, "comment")
+
+	repos := f.repo.Repositories()
+	repos.Comments = failingComments{repos.Comments}
+	users := NewUserServiceWithRepositories(repos, nil, f.audit)
+	if err := users.DeleteUser(alice.ID); appErrorCode(err) != 500 {
+		t.Fatalf("DeleteUser = %v, want 500", err)
+	}
+
+	if _, err := f.users.GetUserByID(alice.ID); err != nil {
+		t.Fatalf("user deleted despite rollback: %v", err)
+	}
+	if _, err := f.posts.GetPostByID(post.ID); err != nil {
+		t.Fatalf("post deleted despite rollback: %v", err)
+	}
+	page, _ := f.comments.GetCommentByPage(&dto.CommentPageDTO{BasePageQuery: *dto.NewBasePageQuery()})
+	if page.Total != 1 {
+		t.Fatalf("comments after rollback = %d, want 1", page.Total)
+	}
+}
+
+func TestUserServiceAuthenticateAndResetPassword(t *testing.T) {
+	f := newMemoryFixture(t)
+	alice := f.createUser(t, "alice")
+
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		wantCode   int
+		wantAction string
+	}{
+		{"success", "alice", "Correct-Horse-42", 0, consts.AuditUserLogin},
+		{"wrong password", "alice", "wrong-password", 401, consts.AuditUserLoginFailed},
+		{"unknown user", "nobody", "Correct-Horse-42", 401, consts.AuditUserLoginFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.users.Authenticate(tt.username, tt.password)
+			if code := appErrorCode(err); code != tt.wantCode {
+				t.Fatalf("err = %v, want code %d", err, tt.wantCode)
+			}
+			if entry := f.audit.last(); entry.Action != tt.wantAction {
+				t.Fatalf("audit action = %s, want %s", entry.Action, tt.wantAction)
+			}
+		})
+	}
+
+	if _, err := f.users.ResetPassword("nobody", "password456"); appErrorCode(err) != 404 {
+		t.Fatalf("reset missing user = %v", err)
+	}
+	user, err := f.users.ResetPassword("alice", "password456")
+	if err != nil || user.Version != alice.Version+1 {
+		t.Fatalf("ResetPassword = %+v, %v", user, err)
+	}
+	if _, err := f.users.Authenticate("alice", "Correct-Horse-42"); appErrorCode(err) != 401 {
+		t.Fatalf("old password still accepted")
+	}
+	if _, err := f.users.Authenticate("alice", "password456"); err != nil {
+		t.Fatalf("new password rejected: %v", err)
+	}
+}
+
+func TestUserServicePasswordPolicy(t *testing.T) {
+	f := newMemoryFixture(t)
+	_, err := f.users.CreateUser(models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "alice@example.com"})
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) || appErr.ErrorCode != utils.ErrWeakPassword {
+		t.Fatalf("err = %v, want %s", err, utils.ErrWeakPassword)
+	}
+	if reasons := appErr.Data.(gin.H)["reasons"]; !reflect.DeepEqual(reasons, []string{passwords.ReasonMatchesIdentity}) {
+		t.Fatalf("reasons = %v", reasons)
+	}
+}
+
+func TestUserServiceRehashOnLogin(t *testing.T) {
+	f := newMemoryFixture(t)
+	alice := f.createUser(t, "alice")
+
+	// 模拟升级前用 bcrypt 保存的密码
+	legacy, err := passwords.Bcrypt{Cost: 4}.Hash("Correct-Horse-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := f.repo.Repositories().Users
+	if ok, err := users.ReplacePassword(context.Background(), alice.ID, alice.Password, legacy); !ok || err != nil {
+		t.Fatalf("ReplacePassword = %v %v", ok, err)
+	}
+
+	if _, err := f.users.Authenticate("alice", "wrong-password"); appErrorCode(err) != 401 {
+		t.Fatalf("wrong password = %v", err)
+	}
+	if user, _ := f.users.GetUserByID(alice.ID); user.Password != legacy {
+		t.Fatal("failed login rehashed the password")
+	}
+
+	if _, err := f.users.Authenticate("alice", "Correct-Horse-42"); err != nil {
+		t.Fatalf("login with bcrypt hash: %v", err)
+	}
+	user, _ := f.users.GetUserByID(alice.ID)
+	if !strings.HasPrefix(user.Password, "$argon2id$") {
+		t.Fatalf("password not rehashed: %s", user.Password)
+	}
+	// 重新哈希不修改版本号，避免客户端持有的版本失效
+	if user.Version != alice.Version {
+		t.Fatalf("version = %d, want %d", user.Version, alice.Version)
+	}
+	if _, err := f.users.Authenticate("alice", "Correct-Horse-42"); err != nil {
+		t.Fatalf("login after rehash: %v", err)
+	}
+}
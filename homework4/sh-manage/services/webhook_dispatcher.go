@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sh-manage/config"
+	"sh-manage/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// webhook 请求头
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// 每轮最多处理的投递数和并发数
+	webhookBatchSize   = 100
+	webhookConcurrency = 8
+	// 保存的响应体最大长度
+	webhookResponseLimit = 2048
+)
+
+// webhookDispatcher 负责投递和按指数退避重试，投递状态都保存在数据库中，进程重启后继续
+type webhookDispatcher struct {
+	db           *gorm.DB
+	client       *http.Client
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	now          func() time.Time
+	wake         chan struct{}
+	startOnce    sync.Once
+}
+
+func newWebhookDispatcher(db *gorm.DB, cfg config.WebhookConfig) *webhookDispatcher {
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	return &webhookDispatcher{
+		db:           db,
+		client:       &http.Client{Timeout: durationOrDefault(cfg.Timeout, 10*time.Second)},
+		maxAttempts:  maxAttempts,
+		baseBackoff:  durationOrDefault(cfg.BaseBackoff, 30*time.Second),
+		maxBackoff:   durationOrDefault(cfg.MaxBackoff, 6*time.Hour),
+		pollInterval: durationOrDefault(cfg.PollInterval, 10*time.Second),
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// SignWebhookPayload 计算签名：HMAC-SHA256(secret, "<timestamp>.<body>")
+// 请求头格式为 X-Webhook-Signature: t=<unix 秒>,v1=<十六进制签名>，接收方应校验时间戳防止重放
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Start 在后台循环投递，直到 ctx 结束
+func (s *WebhookService) Start(ctx context.Context) {
+	s.dispatcher.startOnce.Do(func() {
+		go s.dispatcher.run(ctx)
+	})
+}
+
+// DispatchDue 立即投递所有到期的记录，返回处理的数量
+func (s *WebhookService) DispatchDue(ctx context.Context) int {
+	return s.dispatcher.dispatchDue(ctx)
+}
+
+func (d *webhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *webhookDispatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		d.dispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *webhookDispatcher) dispatchDue(ctx context.Context) int {
+	var deliveries []models.WebhookDelivery
+	err := d.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, d.now()).
+		Order("next_attempt_at asc").Limit(webhookBatchSize).Find(&deliveries).Error
+	if err != nil {
+		log.Printf("Failed to load webhook deliveries: %v", err)
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookConcurrency)
+	processed := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		if !d.claim(delivery) {
+			continue
+		}
+		processed++
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return processed
+}
+
+// claim 把下次投递时间推后占用该记录，多个实例同时扫描时只有一个能投递成功
+func (d *webhookDispatcher) claim(delivery *models.WebhookDelivery) bool {
+	lease := d.now().Add(d.client.Timeout + time.Minute)
+	result := d.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, models.DeliveryPending, delivery.NextAttemptAt).
+		Update("next_attempt_at", lease)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+func (d *webhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	var webhook models.Webhook
+	if err := d.db.First(&webhook, delivery.WebhookId).Error; err != nil || !webhook.Active {
+		d.finish(delivery, map[string]interface{}{
+			"status":          models.DeliveryFailed,
+			"error":           "webhook is deleted or inactive",
+			"next_attempt_at": nil,
+		})
+		return
+	}
+
+	started := d.now()
+	statusCode, body, err := d.send(ctx, &webhook, delivery)
+	attempts := delivery.Attempts + 1
+	values := map[string]interface{}{
+		"attempts":        attempts,
+		"response_status": statusCode,
+		"response_body":   body,
+		"duration_ms":     d.now().Sub(started).Milliseconds(),
+		"error":           "",
+	}
+
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		values["status"] = models.DeliverySucceeded
+		values["delivered_at"] = d.now()
+		values["next_attempt_at"] = nil
+	case attempts >= d.maxAttempts:
+		values["status"] = models.DeliveryFailed
+		values["next_attempt_at"] = nil
+	default:
+		values["next_attempt_at"] = d.now().Add(d.backoff(attempts))
+	}
+	if err != nil {
+		values["error"] = truncate(err.Error(), 500)
+	} else if statusCode < 200 || statusCode >= 300 {
+		values["error"] = fmt.Sprintf("unexpected status %d", statusCode)
+	}
+	d.finish(delivery, values)
+}
+
+func (d *webhookDispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sh-manage-webhook/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, SignWebhookPayload(webhook.Secret, timestamp, payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	return resp.StatusCode, string(body), nil
+}
+
+// backoff 第 n 次失败后的等待时间：base * 2^(n-1)，不超过 maxBackoff
+func (d *webhookDispatcher) backoff(attempts int) time.Duration {
+	wait := d.baseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= d.maxBackoff {
+			return d.maxBackoff
+		}
+	}
+	return wait
+}
+
+func (d *webhookDispatcher) finish(delivery *models.WebhookDelivery, values map[string]interface{}) {
+	if err := d.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(values).Error; err != nil {
+		log.Printf("Failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	return value[:limit]
+}
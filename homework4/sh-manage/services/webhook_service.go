@@ -0,0 +1,336 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/events"
+	"sh-manage/models"
+	"sh-manage/tools"
+	"sh-manage/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 自动生成的签名密钥前缀
+const webhookSecretPrefix = "whsec_"
+
+type WebhookService struct {
+	db           *gorm.DB
+	dispatcher   *webhookDispatcher
+	context      *gin.Context
+	auditService *AuditService
+}
+
+func NewWebhookService(db *gorm.DB, cfg config.WebhookConfig) *WebhookService {
+	return &WebhookService{db: db, dispatcher: newWebhookDispatcher(db, cfg), auditService: NewAuditService(db)}
+}
+
+// WithContext 返回绑定当前请求的副本，用于获取当前用户、记录审计日志和按租户限定查询
+func (s *WebhookService) WithContext(c *gin.Context) *WebhookService {
+	clone := *s
+	clone.context = c
+	clone.db = s.db.WithContext(requestContext(c))
+	return &clone
+}
+
+func (s *WebhookService) currentUserID() uint {
+	if s.context == nil {
+		return 0
+	}
+	return utils.GetCurrentUserID(s.context)
+}
+
+// CreateWebhook 创建订阅，未指定密钥时自动生成；密钥只在创建时返回
+func (s *WebhookService) CreateWebhook(req *dto.CreateWebhookDto) (*models.Webhook, *utils.AppError) {
+	if req == nil {
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
+	}
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	eventTypes, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, e := randomHex(24)
+		if e != nil {
+			return nil, utils.NewError(utils.ErrInternal, "Failed to generate secret").Wrap(e)
+		}
+		secret = webhookSecretPrefix + generated
+	}
+
+	webhook := &models.Webhook{
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      strings.Join(eventTypes, ","),
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+		UserId:      s.currentUserID(),
+	}
+	if err := s.db.Create(webhook).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create webhook").Wrap(err)
+	}
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditWebhookCreate,
+		EntityType: consts.EntityWebhook,
+		EntityID:   webhook.ID,
+		After:      webhook.ToResponse(),
+	})
+	return webhook, nil
+}
+
+func (s *WebhookService) ListWebhooks() ([]models.Webhook, *utils.AppError) {
+	var webhooks []models.Webhook
+	if err := s.db.Order("id asc").Find(&webhooks).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve webhooks").Wrap(err)
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(webhookID uint) (*models.Webhook, *utils.AppError) {
+	var webhook models.Webhook
+	if err := s.db.First(&webhook, webhookID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewError(utils.ErrWebhookNotFound, "Webhook not found")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve webhook").Wrap(err)
+	}
+	return &webhook, nil
+}
+
+func (s *WebhookService) UpdateWebhook(webhookID uint, req *dto.UpdateWebhookDto) (*models.Webhook, *utils.AppError) {
+	if req == nil {
+		return nil, utils.NewError(utils.ErrBadRequest, "参数不能为空")
+	}
+	webhook, err := s.GetWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	before := webhook.ToResponse()
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		eventTypes, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Events = strings.Join(eventTypes, ",")
+	}
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	if req.Description != nil {
+		webhook.Description = *req.Description
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if err := s.db.Save(webhook).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to update webhook").Wrap(err)
+	}
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditWebhookUpdate,
+		EntityType: consts.EntityWebhook,
+		EntityID:   webhook.ID,
+		Before:     before,
+		After:      gin.H{"webhook": webhook.ToResponse(), "secret_changed": req.Secret != nil},
+	})
+	return webhook, nil
+}
+
+func (s *WebhookService) DeleteWebhook(webhookID uint) *utils.AppError {
+	webhook, err := s.GetWebhook(webhookID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(&models.Webhook{}, webhookID).Error; err != nil {
+		return utils.NewError(utils.ErrInternal, "Failed to delete webhook").Wrap(err)
+	}
+
+	s.auditService.Record(s.context, AuditEntry{
+		Action:     consts.AuditWebhookDelete,
+		EntityType: consts.EntityWebhook,
+		EntityID:   webhookID,
+		Before:     webhook.ToResponse(),
+	})
+	return nil
+}
+
+// GetDeliveryByPage 分页查询某个订阅的投递日志
+func (s *WebhookService) GetDeliveryByPage(webhookID uint, query *dto.WebhookDeliveryPageDTO) (*dto.PageResult[models.WebhookDelivery], *utils.AppError) {
+	if _, err := s.GetWebhook(webhookID); err != nil {
+		return nil, err
+	}
+	db := s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if query.Status != nil && *query.Status != "" {
+		db = db.Where("status = ?", *query.Status)
+	}
+	if query.EventType != nil && *query.EventType != "" {
+		db = db.Where("event_type = ?", *query.EventType)
+	}
+	var deliveries []models.WebhookDelivery
+	return tools.Paginate(db, query.BasePageQuery, &deliveries)
+}
+
+// Redeliver 按原内容重新投递一次，生成新的投递记录，原记录保留
+func (s *WebhookService) Redeliver(deliveryID uint) (*models.WebhookDelivery, *utils.AppError) {
+	var original models.WebhookDelivery
+	if err := s.db.First(&original, deliveryID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewError(utils.ErrWebhookNotFound, "Delivery not found")
+		}
+		return nil, utils.NewError(utils.ErrInternal, "Failed to retrieve delivery").Wrap(err)
+	}
+	if _, err := s.GetWebhook(original.WebhookId); err != nil {
+		return nil, err
+	}
+
+	now := s.dispatcher.now()
+	delivery := &models.WebhookDelivery{
+		TenantID:      original.TenantID,
+		WebhookId:     original.WebhookId,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+	}
+	if err := s.db.Create(delivery).Error; err != nil {
+		return nil, utils.NewError(utils.ErrInternal, "Failed to create delivery").Wrap(err)
+	}
+	s.dispatcher.notify()
+	return delivery, nil
+}
+
+// HandleEvent 作为事件总线的订阅者，为事件所属租户中订阅了该事件的 webhook 生成待投递记录
+func (s *WebhookService) HandleEvent(event events.Event) {
+	if post, ok := event.Data.(models.PostResponse); ok {
+		if event.Type = webhookPostEventType(event, post); event.Type == "" {
+			return
+		}
+	}
+	if !slices.Contains(consts.WebhookEvents, event.Type) {
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := s.db.Where("active = ? AND tenant_id = ?", true, event.TenantID).Find(&webhooks).Error; err != nil {
+		log.Printf("Failed to load webhooks for %s: %v", event.Type, err)
+		return
+	}
+
+	var payload []byte
+	now := s.dispatcher.now()
+	created := 0
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				log.Printf("Failed to marshal event %s: %v", event.Type, err)
+				return
+			}
+		}
+		delivery := &models.WebhookDelivery{
+			TenantID:      webhook.TenantID,
+			WebhookId:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := s.db.Create(delivery).Error; err != nil {
+			log.Printf("Failed to create webhook delivery for webhook %d: %v", webhook.ID, err)
+			continue
+		}
+		created++
+	}
+	if created > 0 {
+		s.dispatcher.notify()
+	}
+}
+
+// webhookPostEventType 只投递已发布文章的事件，草稿、待审核和被拒绝的文章不发送给外部订阅者
+// 文章首次对外可见时作为 post.created 投递，已发布的文章下线时作为 post.deleted 投递，返回空字符串表示不投递
+func webhookPostEventType(event events.Event, post models.PostResponse) string {
+	published := post.Status == consts.PostStatusPublished
+	wasPublished := event.PreviousStatus == consts.PostStatusPublished
+	switch event.Type {
+	case consts.EventPostUpdated:
+		switch {
+		case published && event.PreviousStatus != "" && !wasPublished:
+			return consts.EventPostCreated
+		case published:
+			return consts.EventPostUpdated
+		case wasPublished:
+			return consts.EventPostDeleted
+		}
+		return ""
+	case consts.EventPostCreated, consts.EventPostDeleted:
+		if published {
+			return event.Type
+		}
+		return ""
+	}
+	return event.Type
+}
+
+func validateWebhookURL(rawURL string) *utils.AppError {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return utils.NewError(utils.ErrInvalidWebhookURL, "Webhook url must be an absolute http(s) url")
+	}
+	return nil
+}
+
+func normalizeWebhookEvents(eventTypes []string) ([]string, *utils.AppError) {
+	result := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if !slices.Contains(consts.WebhookEvents, eventType) {
+			return nil, utils.NewError(utils.ErrInvalidWebhookEvents, "Unknown event type: "+eventType)
+		}
+		if !slices.Contains(result, eventType) {
+			result = append(result, eventType)
+		}
+	}
+	if len(result) == 0 {
+		return nil, utils.NewError(utils.ErrInvalidWebhookEvents, "At least one event type is required")
+	}
+	return result, nil
+}
+
+func durationOrDefault(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Invalid duration %q, using %s", value, fallback)
+		return fallback
+	}
+	return parsed
+}
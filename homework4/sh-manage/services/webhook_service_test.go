@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sh-manage/config"
+	"sh-manage/consts"
+	"sh-manage/dto"
+	"sh-manage/events"
+	"sh-manage/models"
+	"sh-manage/utils"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	failures int // 前几次请求返回 500
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+func TestWebhookDeliveryWithRetries(t *testing.T) {
+	db := newTestDB(t)
+	receiver := &webhookReceiver{failures: 1}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	svc := NewWebhookService(db, config.WebhookConfig{MaxAttempts: 3, BaseBackoff: "1m"})
+	now := time.Now().UTC().Truncate(time.Second)
+	svc.dispatcher.now = func() time.Time { return now }
+
+	webhook, appErr := svc.CreateWebhook(&dto.CreateWebhookDto{URL: server.URL, Events: []string{consts.EventPostCreated}})
+	if appErr != nil {
+		t.Fatalf("create webhook: %v", appErr)
+	}
+	if !strings.HasPrefix(webhook.Secret, webhookSecretPrefix) {
+		t.Fatalf("generated secret = %q", webhook.Secret)
+	}
+	if _, appErr := svc.CreateWebhook(&dto.CreateWebhookDto{URL: server.URL, Events: []string{"user.created"}}); appErr == nil || appErr.StableCode() != utils.ErrInvalidWebhookEvents {
+		t.Fatalf("unknown event type = %v", appErr)
+	}
+	if _, appErr := svc.CreateWebhook(&dto.CreateWebhookDto{URL: "/relative", Events: []string{consts.EventPostCreated}}); appErr == nil || appErr.StableCode() != utils.ErrInvalidWebhookURL {
+		t.Fatalf("relative url = %v", appErr)
+	}
+
+	bus := events.NewBus()
+	bus.Subscribe(svc.HandleEvent)
+	postService := NewPostService(db, NewUserService(db, nil), nil, nil)
+	postService.SetPublisher(bus)
+	commentService := NewCommentService(db, NewUserService(db, nil), nil)
+	commentService.SetPublisher(bus)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	c.Set(consts.UserID, uint(1))
+	title, content := "hello", "world"
+	post, appErr := postService.WithContext(c).CreatePost(&dto.PostDto{Title: &title, Content: &content})
+	if appErr != nil {
+		t.Fatalf("create post: %v", appErr)
+	}
+	// 未订阅的事件不产生投递
+	if _, appErr := commentService.WithContext(c).CreateComment(&dto.CommentDto{PostID: &post.ID, Content: &content}); appErr != nil {
+		t.Fatalf("create comment: %v", appErr)
+	}
+
+	// 第一次投递失败，按退避时间等待重试
+	if n := svc.DispatchDue(context.Background()); n != 1 {
+		t.Fatalf("first dispatch processed %d", n)
+	}
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != 500 ||
+		!delivery.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("after failure = %+v", delivery)
+	}
+	if n := svc.DispatchDue(context.Background()); n != 0 {
+		t.Fatalf("dispatch before backoff processed %d", n)
+	}
+
+	now = now.Add(time.Minute)
+	if n := svc.DispatchDue(context.Background()); n != 1 {
+		t.Fatalf("retry processed %d", n)
+	}
+	db.First(&delivery, delivery.ID)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 2 || delivery.DeliveredAt == nil {
+		t.Fatalf("after retry = %+v", delivery)
+	}
+
+	// 校验签名和请求头
+	req := receiver.requests[1]
+	body := receiver.bodies[1]
+	timestamp := now.Unix()
+	wantSignature := fmt.Sprintf("t=%d,v1=%s", timestamp, SignWebhookPayload(webhook.Secret, timestamp, []byte(body)))
+	if req.Header.Get(WebhookSignatureHeader) != wantSignature || req.Header.Get(WebhookEventHeader) != consts.EventPostCreated {
+		t.Fatalf("headers = %v", req.Header)
+	}
+	if !strings.Contains(body, `"type":"post.created"`) || !strings.Contains(body, `"title":"hello"`) {
+		t.Fatalf("payload = %s", body)
+	}
+
+	// 手动重新投递生成新的记录
+	redelivery, appErr := svc.Redeliver(delivery.ID)
+	if appErr != nil {
+		t.Fatalf("redeliver: %v", appErr)
+	}
+	svc.DispatchDue(context.Background())
+	db.First(redelivery, redelivery.ID)
+	if redelivery.Status != models.DeliverySucceeded || *redelivery.RedeliveryOf != delivery.ID || len(receiver.bodies) != 3 || receiver.bodies[2] != body {
+		t.Fatalf("redelivery = %+v", redelivery)
+	}
+
+	var count int64
+	db.Model(&models.WebhookDelivery{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("deliveries = %d, want 2", count)
+	}
+}
+
+// TestWebhookPostEventsOnlyForPublishedPosts 草稿不投递，发布时作为 post.created，下线时作为 post.deleted
+func TestWebhookPostEventsOnlyForPublishedPosts(t *testing.T) {
+	db := newTestDB(t)
+	svc := NewWebhookService(db, config.WebhookConfig{})
+	if _, appErr := svc.CreateWebhook(&dto.CreateWebhookDto{URL: "https://example.com/hook", Events: []string{consts.EventPostCreated, consts.EventPostUpdated, consts.EventPostDeleted}}); appErr != nil {
+		t.Fatalf("create webhook: %v", appErr)
+	}
+	bus := events.NewBus()
+	bus.Subscribe(svc.HandleEvent)
+	postService := NewPostService(db, NewUserService(db, nil), nil, nil)
+	postService.SetPublisher(bus)
+	posts := postService.WithContext(asUser(1))
+
+	post, appErr := posts.CreatePost(postDto(nil, "draft", "secret", consts.PostStatusDraft, nil, nil))
+	if appErr != nil {
+		t.Fatalf("CreatePost: %v", appErr)
+	}
+	steps := []struct {
+		status string
+		title  string
+	}{
+		{consts.PostStatusDraft, "still draft"},
+		{consts.PostStatusPublished, "published"},
+		{consts.PostStatusPublished, "edited"},
+		{consts.PostStatusDraft, "unpublished"},
+	}
+	for _, step := range steps {
+		if post, appErr = posts.UpdatePost(postDto(&post.ID, step.title, "secret", step.status, &post.Version, nil)); appErr != nil {
+			t.Fatalf("UpdatePost(%s): %v", step.title, appErr)
+		}
+	}
+	if appErr := posts.DeleteByID(post.ID); appErr != nil {
+		t.Fatalf("DeletePost: %v", appErr)
+	}
+
+	var deliveries []models.WebhookDelivery
+	db.Order("id asc").Find(&deliveries)
+	var got []string
+	for _, delivery := range deliveries {
+		if strings.Contains(delivery.Payload, "still draft") || strings.Contains(delivery.Payload, `"title":"draft"`) {
+			t.Fatalf("draft delivered: %s", delivery.Payload)
+		}
+		got = append(got, delivery.EventType)
+	}
+	want := []string{consts.EventPostCreated, consts.EventPostUpdated, consts.EventPostDeleted}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("delivered events = %v, want %v", got, want)
+	}
+	if !strings.Contains(deliveries[0].Payload, `"type":"post.created"`) {
+		t.Fatalf("payload type = %s", deliveries[0].Payload)
+	}
+}
+
+func TestWebhookDeliveryGivesUp(t *testing.T) {
+	db := newTestDB(t)
+	receiver := &webhookReceiver{failures: 100}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	svc := NewWebhookService(db, config.WebhookConfig{MaxAttempts: 2, BaseBackoff: "1s"})
+	now := time.Now().UTC()
+	svc.dispatcher.now = func() time.Time { return now }
+	svc.CreateWebhook(&dto.CreateWebhookDto{URL: server.URL, Events: []string{consts.EventPostDeleted}})
+	svc.HandleEvent(events.New(consts.EventPostDeleted, gin.H{"id": 1}))
+
+	svc.DispatchDue(context.Background())
+	now = now.Add(time.Second)
+	svc.DispatchDue(context.Background())
+
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 2 || delivery.NextAttemptAt != nil {
+		t.Fatalf("delivery = %+v", delivery)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	d := newWebhookDispatcher(nil, config.WebhookConfig{BaseBackoff: "30s", MaxBackoff: "5m"})
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := d.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"fmt"
+	"sh-manage/config"
+)
+
+// New 根据配置创建存储后端，driver 为空时使用本地目录
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		dir := cfg.LocalDir
+		if dir == "" {
+			dir = "web/static/uploads"
+		}
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = "/static/uploads"
+		}
+		return NewLocal(dir, baseURL)
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PathStyle: cfg.S3.PathStyle,
+			PublicURL: cfg.S3.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
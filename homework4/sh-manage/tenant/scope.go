@@ -0,0 +1,81 @@
+package tenant
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// FieldName 需要按租户隔离的模型中的字段名
+const FieldName = "TenantID"
+
+// Plugin 注册后按 Statement.Context 中的租户限定带 TenantID 字段的模型
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenant"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	if err := callback.Create().Before("gorm:create").Register("tenant:assign", assign); err != nil {
+		return err
+	}
+	if err := callback.Query().Before("gorm:query").Register("tenant:scope", scope); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:update").Register("tenant:scope", scope); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("gorm:delete").Register("tenant:scope", scope); err != nil {
+		return err
+	}
+	return callback.Row().Before("gorm:row").Register("tenant:scope", scope)
+}
+
+func tenantField(db *gorm.DB) (*schema.Field, uint, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, 0, false
+	}
+	field := db.Statement.Schema.LookUpField(FieldName)
+	if field == nil {
+		return nil, 0, false
+	}
+	tenantID, ok := FromContext(db.Statement.Context)
+	return field, tenantID, ok
+}
+
+// scope 追加 tenant_id 条件，使用当前表名避免联表查询时字段名冲突
+func scope(db *gorm.DB) {
+	field, tenantID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// assign 为没有指定租户的新记录填充当前租户
+func assign(db *gorm.DB) {
+	field, tenantID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	ctx := db.Statement.Context
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if _, zero := field.ValueOf(ctx, reflect.Indirect(rv.Index(i))); zero {
+				db.AddError(field.Set(ctx, reflect.Indirect(rv.Index(i)), tenantID))
+			}
+		}
+	case reflect.Struct:
+		if _, zero := field.ValueOf(ctx, rv); zero {
+			db.AddError(field.Set(ctx, rv, tenantID))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package tenant 在 context 中传递当前租户，并通过 GORM 插件自动限定租户范围
+//
+// 带有 TenantID 字段的模型在查询、更新、删除时自动追加 tenant_id 条件，创建时自动填充；
+// context 中没有租户时（命令行、后台任务）不做限定。原生 SQL 不经过模型，不会被限定
+package tenant
+
+import "context"
+
+type contextKey struct{}
+
+// WithID 返回携带租户 ID 的 context
+func WithID(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext 返回 context 中的租户 ID，没有租户时 ok 为 false
+func FromContext(ctx context.Context) (tenantID uint, ok bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok = ctx.Value(contextKey{}).(uint)
+	return tenantID, ok && tenantID != 0
+}
+
+// Visible 记录属于 ctx 中的租户，或 ctx 中没有租户时返回 true
+func Visible(ctx context.Context, tenantID uint) bool {
+	current, ok := FromContext(ctx)
+	return !ok || current == tenantID
+}
@@ -0,0 +1,111 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"sort"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 允许解码的最大像素数，防止小文件解压出超大图片耗尽内存
+const maxPixels = 40_000_000
+
+var ErrTooLarge = errors.New("thumbnail: image dimensions are too large")
+
+// Size 缩略图规格，图片等比缩放到 MaxSide x MaxSide 以内
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+type Thumbnail struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+// SizesFromMap 把配置中的名称到边长映射转换为按边长排序的规格列表
+func SizesFromMap(m map[string]int) []Size {
+	sizes := make([]Size, 0, len(m))
+	for name, side := range m {
+		if side > 0 {
+			sizes = append(sizes, Size{Name: name, MaxSide: side})
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].MaxSide < sizes[j].MaxSide })
+	return sizes
+}
+
+// Generate 解码图片并按规格生成缩略图，返回原图尺寸
+// 原图比规格小时不放大，直接按原尺寸重新编码
+func Generate(data []byte, sizes []Size) (int, int, []Thumbnail, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return 0, 0, nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	thumbnails := make([]Thumbnail, 0, len(sizes))
+	for _, size := range sizes {
+		width, height := fit(config.Width, config.Height, size.MaxSide)
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+		encoded, contentType, err := encode(dst, format)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("thumbnail %s: %w", size.Name, err)
+		}
+		thumbnails = append(thumbnails, Thumbnail{
+			Name:        size.Name,
+			Width:       width,
+			Height:      height,
+			ContentType: contentType,
+			Data:        encoded,
+		})
+	}
+	return config.Width, config.Height, thumbnails, nil
+}
+
+// fit 计算等比缩放到 maxSide 以内的尺寸
+func fit(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		return maxSide, max(1, height*maxSide/width)
+	}
+	return max(1, width*maxSide/height), maxSide
+}
+
+// encode 有透明通道的格式输出 PNG，其余输出 JPEG
+func encode(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "png", "gif":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	default:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+}
@@ -0,0 +1,119 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x*height/width, color.RGBA{R: 255, A: 255})
+	}
+	return img
+}
+
+func encodeTestImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, testImage(width, height))
+	case "jpeg":
+		err = jpeg.Encode(&buf, testImage(width, height), nil)
+	case "gif":
+		err = gif.Encode(&buf, testImage(width, height), nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSizesFromMap(t *testing.T) {
+	sizes := SizesFromMap(map[string]int{"large": 1024, "small": 150, "off": 0, "medium": 480})
+	want := []Size{{"small", 150}, {"medium", 480}, {"large", 1024}}
+	if !reflect.DeepEqual(sizes, want) {
+		t.Fatalf("SizesFromMap = %v, want %v", sizes, want)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		width, height, maxSide int
+		wantW, wantH           int
+	}{
+		{200, 100, 50, 50, 25},
+		{100, 200, 50, 25, 50},
+		{100, 100, 50, 50, 50},
+		{40, 30, 50, 40, 30},
+		{1000, 1, 50, 50, 1},
+	}
+	for _, tt := range tests {
+		if w, h := fit(tt.width, tt.height, tt.maxSide); w != tt.wantW || h != tt.wantH {
+			t.Errorf("fit(%d, %d, %d) = %d, %d; want %d, %d", tt.width, tt.height, tt.maxSide, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	sizes := []Size{{"small", 50}, {"large", 400}}
+	tests := []struct {
+		format          string
+		wantContentType string
+	}{
+		{"png", "image/png"},
+		{"gif", "image/png"},
+		{"jpeg", "image/jpeg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			width, height, thumbnails, err := Generate(encodeTestImage(t, tt.format, 200, 100), sizes)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if width != 200 || height != 100 || len(thumbnails) != 2 {
+				t.Fatalf("Generate = %d x %d, %d thumbnails", width, height, len(thumbnails))
+			}
+			// 小于规格的图片不放大
+			wantSizes := [][2]int{{50, 25}, {200, 100}}
+			for i, thumbnail := range thumbnails {
+				if thumbnail.Name != sizes[i].Name || thumbnail.ContentType != tt.wantContentType {
+					t.Fatalf("thumbnail %d = %s %s", i, thumbnail.Name, thumbnail.ContentType)
+				}
+				if [2]int{thumbnail.Width, thumbnail.Height} != wantSizes[i] {
+					t.Fatalf("thumbnail %s = %d x %d, want %v", thumbnail.Name, thumbnail.Width, thumbnail.Height, wantSizes[i])
+				}
+				decoded, _, err := image.DecodeConfig(bytes.NewReader(thumbnail.Data))
+				if err != nil || decoded.Width != thumbnail.Width || decoded.Height != thumbnail.Height {
+					t.Fatalf("decode thumbnail %s = %+v, %v", thumbnail.Name, decoded, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsInvalidImages(t *testing.T) {
+	if _, _, _, err := Generate([]byte("plain text"), []Size{{"small", 50}}); err == nil {
+		t.Fatal("Generate accepted a non-image")
+	}
+
+	// 只修改 PNG 头中声明的尺寸，在解码像素前拒绝
+	data := encodeTestImage(t, "png", 2, 2)
+	binary.BigEndian.PutUint32(data[16:], 10000)
+	binary.BigEndian.PutUint32(data[20:], 5000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	if _, _, _, err := Generate(data, []Size{{"small", 50}}); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Generate huge image = %v, want ErrTooLarge", err)
+	}
+}
@@ -18,7 +18,7 @@ func Paginate[T any](db *gorm.DB, query dto.BasePageQuery, result *[]T) (*dto.Pa
 	// 查询总数
 	var total int64
 	if err := db.Count(&total).Error; err != nil {
-		return nil, utils.NewAppError(500, err.Error())
+		return nil, utils.NewError(utils.ErrInternal, "Failed to query page").Wrap(err)
 	}
 
 	// 如果有总数才查询数据
@@ -33,7 +33,7 @@ func Paginate[T any](db *gorm.DB, query dto.BasePageQuery, result *[]T) (*dto.Pa
 			Offset(query.GetOffset()).
 			Limit(query.GetLimit()).
 			Find(result).Error; err != nil {
-			return nil, utils.NewAppError(500, err.Error())
+			return nil, utils.NewError(utils.ErrInternal, "Failed to query page").Wrap(err)
 		}
 	} else {
 		*result = []T{}
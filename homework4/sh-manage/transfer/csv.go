@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSV 中多个标签用 | 分隔，评论为 JSON 数组
+const csvTagSeparator = "|"
+
+var csvHeader = []string{"id", "title", "author", "tags", "created_at", "updated_at", "content", "comments"}
+
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+// newCSVEncoder 创建时写出表头
+func newCSVEncoder(w io.Writer) (*csvEncoder, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvEncoder{writer: writer}, nil
+}
+
+func (e *csvEncoder) Encode(record PostRecord) error {
+	comments, err := json.Marshal(record.Comments)
+	if err != nil {
+		return err
+	}
+	row := []string{
+		strconv.FormatUint(uint64(record.ID), 10),
+		record.Title,
+		record.Author,
+		strings.Join(record.Tags, csvTagSeparator),
+		formatTime(record.CreatedAt),
+		formatTime(record.UpdatedAt),
+		record.Content,
+		string(comments),
+	}
+	return e.writer.Write(row)
+}
+
+func (e *csvEncoder) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func decodeCSV(data []byte) ([]PostRecord, []RowError, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"title", "content"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("csv column %q is required", required)
+		}
+	}
+
+	var records []PostRecord
+	var rowErrors []RowError
+	// 第1行是表头
+	for row := 2; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Message: err.Error()})
+			continue
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		record, err := parseCSVRow(get)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Title: get("title"), Message: err.Error()})
+			continue
+		}
+		record.Row = row
+		records = append(records, record)
+	}
+	return records, rowErrors, nil
+}
+
+func parseCSVRow(get func(string) string) (PostRecord, error) {
+	record := PostRecord{
+		Title:   get("title"),
+		Author:  get("author"),
+		Content: get("content"),
+	}
+
+	if id := get("id"); id != "" {
+		value, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return record, fmt.Errorf("invalid id %q", id)
+		}
+		record.ID = uint(value)
+	}
+	if tags := get("tags"); tags != "" {
+		record.Tags = strings.Split(tags, csvTagSeparator)
+	}
+
+	var err error
+	if record.CreatedAt, err = parseTime(get("created_at")); err != nil {
+		return record, fmt.Errorf("invalid created_at: %w", err)
+	}
+	if record.UpdatedAt, err = parseTime(get("updated_at")); err != nil {
+		return record, fmt.Errorf("invalid updated_at: %w", err)
+	}
+	if comments := get("comments"); comments != "" {
+		if err := json.Unmarshal([]byte(comments), &record.Comments); err != nil {
+			return record, fmt.Errorf("invalid comments: %w", err)
+		}
+	}
+	return record, nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,63 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonEncoder 逐条写出 JSON 数组的元素，输出与一次性编码整个数组相同
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) *jsonEncoder {
+	return &jsonEncoder{w: w}
+}
+
+func (e *jsonEncoder) Encode(record PostRecord) error {
+	data, err := json.MarshalIndent(record, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	prefix := ",\n  "
+	if e.count == 0 {
+		prefix = "[\n  "
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+func decodeJSON(data []byte) ([]PostRecord, []RowError, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	records := make([]PostRecord, 0, len(items))
+	var rowErrors []RowError
+	for i, item := range items {
+		var record PostRecord
+		if err := json.Unmarshal(item, &record); err != nil {
+			rowErrors = append(rowErrors, RowError{Row: i + 1, Message: err.Error()})
+			continue
+		}
+		record.Row = i + 1
+		records = append(records, record)
+	}
+	return records, rowErrors, nil
+}
@@ -0,0 +1,135 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// 单个 Markdown 文件解压后的最大字节数，防止压缩炸弹
+const maxMarkdownFileSize = 5 << 20
+
+const frontMatterDelimiter = "---"
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// markdownEncoder 每篇文章写入 zip 包中的一个文件，文件名带序号避免重名
+type markdownEncoder struct {
+	archive *zip.Writer
+	count   int
+}
+
+func newMarkdownEncoder(w io.Writer) *markdownEncoder {
+	return &markdownEncoder{archive: zip.NewWriter(w)}
+}
+
+func (e *markdownEncoder) Encode(record PostRecord) error {
+	e.count++
+	name := fmt.Sprintf("posts/%04d-%s.md", e.count, slugify(record.Title))
+	file, err := e.archive.Create(name)
+	if err != nil {
+		return err
+	}
+	return writeMarkdown(file, record)
+}
+
+func (e *markdownEncoder) Close() error {
+	return e.archive.Close()
+}
+
+func writeMarkdown(w io.Writer, record PostRecord) error {
+	frontMatter, err := yaml.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n%s%s\n\n%s\n", frontMatterDelimiter, frontMatter, frontMatterDelimiter, record.Content)
+	return err
+}
+
+func decodeMarkdown(data []byte) ([]PostRecord, []RowError, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid zip: %w", err)
+	}
+
+	var records []PostRecord
+	var rowErrors []RowError
+	row := 0
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(file.Name), ".md") {
+			continue
+		}
+		row++
+
+		record, err := readMarkdownFile(file)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Message: file.Name + ": " + err.Error()})
+			continue
+		}
+		record.Row = row
+		records = append(records, record)
+	}
+	return records, rowErrors, nil
+}
+
+func readMarkdownFile(file *zip.File) (PostRecord, error) {
+	var record PostRecord
+
+	reader, err := file.Open()
+	if err != nil {
+		return record, err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, maxMarkdownFileSize+1))
+	if err != nil {
+		return record, err
+	}
+	if len(content) > maxMarkdownFileSize {
+		return record, fmt.Errorf("file is larger than %d bytes", maxMarkdownFileSize)
+	}
+
+	frontMatter, body, err := splitFrontMatter(string(content))
+	if err != nil {
+		return record, err
+	}
+	if err := yaml.Unmarshal([]byte(frontMatter), &record); err != nil {
+		return record, fmt.Errorf("invalid front matter: %w", err)
+	}
+	record.Content = strings.TrimSpace(body)
+	return record, nil
+}
+
+// splitFrontMatter 拆分开头由 --- 包围的 YAML 与正文
+func splitFrontMatter(content string) (string, string, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, frontMatterDelimiter+"\n") {
+		return "", "", fmt.Errorf("front matter is missing")
+	}
+	rest := content[len(frontMatterDelimiter)+1:]
+	end := strings.Index(rest, "\n"+frontMatterDelimiter+"\n")
+	if end < 0 {
+		if strings.HasSuffix(rest, "\n"+frontMatterDelimiter) {
+			return rest[:len(rest)-len(frontMatterDelimiter)-1], "", nil
+		}
+		return "", "", fmt.Errorf("front matter is not closed")
+	}
+	return rest[:end], rest[end+len(frontMatterDelimiter)+2:], nil
+}
+
+func slugify(title string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.Trim(slug[:50], "-")
+	}
+	if slug == "" {
+		slug = "post"
+	}
+	return slug
+}
@@ -0,0 +1,132 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format 导入导出的文件格式
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown" // zip 包，每篇文章一个带 YAML front matter 的 Markdown 文件
+)
+
+// ParseFormat 解析格式名称，也接受文件扩展名
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "markdown", "md", "zip":
+		return FormatMarkdown, nil
+	}
+	return "", fmt.Errorf("unsupported format %q", name)
+}
+
+// ContentType 导出文件的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatMarkdown:
+		return "application/zip"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Extension 导出文件的扩展名
+func (f Format) Extension() string {
+	if f == FormatMarkdown {
+		return "zip"
+	}
+	return string(f)
+}
+
+// PostRecord 导入导出的一篇文章，作者和评论人用用户名表示
+type PostRecord struct {
+	Row       int             `json:"-" yaml:"-"` // 导入时的行号或文件序号，用于错误报告
+	ID        uint            `json:"id,omitempty" yaml:"id,omitempty"`
+	Title     string          `json:"title" yaml:"title"`
+	Author    string          `json:"author" yaml:"author"`
+	Tags      []string        `json:"tags" yaml:"tags"`
+	CreatedAt time.Time       `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at" yaml:"updated_at"`
+	Comments  []CommentRecord `json:"comments" yaml:"comments"`
+	Content   string          `json:"content" yaml:"-"` // Markdown 格式中作为正文
+}
+
+type CommentRecord struct {
+	Author    string    `json:"author" yaml:"author"`
+	Content   string    `json:"content" yaml:"content"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// RowError 单行导入错误
+type RowError struct {
+	Row     int    `json:"row"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult 导入结果报告
+type ImportResult struct {
+	Format   Format     `json:"format"`
+	DryRun   bool       `json:"dry_run"`
+	Total    int        `json:"total"`
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors"`
+}
+
+// Encoder 逐条写出文章，导出时可以边查询边写入，不需要把全部记录放进内存
+type Encoder interface {
+	Encode(record PostRecord) error
+	// Close 写出文件结尾并刷新缓冲，不关闭底层的 io.Writer
+	Close() error
+}
+
+// NewEncoder 按格式创建 Encoder
+func NewEncoder(w io.Writer, format Format) (Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return newJSONEncoder(w), nil
+	case FormatCSV:
+		return newCSVEncoder(w)
+	case FormatMarkdown:
+		return newMarkdownEncoder(w), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// Encode 按格式写出文章
+func Encode(w io.Writer, format Format, records []PostRecord) error {
+	encoder, err := NewEncoder(w, format)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return encoder.Close()
+}
+
+// Decode 解析导入文件；无法解析的单条记录放入 RowError，整个文件无法解析时返回 error
+func Decode(data []byte, format Format) ([]PostRecord, []RowError, error) {
+	switch format {
+	case FormatJSON:
+		return decodeJSON(data)
+	case FormatCSV:
+		return decodeCSV(data)
+	case FormatMarkdown:
+		return decodeMarkdown(data)
+	}
+	return nil, nil, fmt.Errorf("unsupported format %q", format)
+}
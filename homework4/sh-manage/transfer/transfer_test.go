@@ -0,0 +1,99 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func sampleRecords() []PostRecord {
+	created := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	return []PostRecord{
+		{
+			Title:     "Hello, World",
+			Author:    "alice",
+			Tags:      []string{"go", "web"},
+			CreatedAt: created,
+			UpdatedAt: created.Add(time.Hour),
+			Content:   "# Title\n\nfirst line, with \"quotes\"\n---\nnot front matter",
+			Comments:  []CommentRecord{{Author: "bob", Content: "nice", CreatedAt: created.Add(2 * time.Hour)}},
+		},
+		{
+			Title:     "第二篇",
+			Author:    "bob",
+			Tags:      []string{},
+			CreatedAt: created,
+			UpdatedAt: created,
+			Content:   "内容",
+			Comments:  []CommentRecord{},
+		},
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatCSV, FormatMarkdown} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			want := sampleRecords()
+			if err := Encode(&buf, format, want); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			got, rowErrors, err := Decode(buf.Bytes(), format)
+			if err != nil || len(rowErrors) > 0 {
+				t.Fatalf("decode: %v %v", err, rowErrors)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("decoded %d records, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i].Row != i+1 && got[i].Row != i+2 {
+					t.Errorf("record %d row = %d", i, got[i].Row)
+				}
+				got[i].Row = 0
+				if len(got[i].Tags) == 0 {
+					got[i].Tags = []string{}
+				}
+				if len(got[i].Comments) == 0 {
+					got[i].Comments = []CommentRecord{}
+				}
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Errorf("record %d:\n got %+v\nwant %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestJSONEncoderMatchesArray(t *testing.T) {
+	for _, records := range [][]PostRecord{sampleRecords(), {}} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, FormatJSON, records); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		want, _ := json.MarshalIndent(records, "", "  ")
+		if buf.String() != string(want)+"\n" {
+			t.Fatalf("streamed json:\n%s\nwant:\n%s", buf.String(), want)
+		}
+	}
+}
+
+func TestDecodeCollectsRowErrors(t *testing.T) {
+	data := "title,content,created_at\nok,body,\nbad,body,yesterday\n"
+	records, rowErrors, err := Decode([]byte(data), FormatCSV)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(records) != 1 || records[0].Row != 2 {
+		t.Fatalf("records = %+v", records)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Row != 3 || rowErrors[0].Title != "bad" {
+		t.Fatalf("row errors = %+v", rowErrors)
+	}
+
+	if _, _, err := Decode([]byte("id,author\n1,alice\n"), FormatCSV); err == nil {
+		t.Fatal("missing required columns should fail")
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v3"
+)
+
+// ErrUnsupportedMediaType 请求体的 Content-Type 不是支持的格式，处理函数应返回 415
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// BindBody 按 Content-Type 解析请求体并按 binding 标签校验，没有 Content-Type 时按 JSON 解析
+// 各格式使用与 JSON 相同的字段名；XML 中的值都是文本，按目标字段的类型转换
+func BindBody(c *gin.Context, obj interface{}) error {
+	format := MIMEJSON
+	if contentType := c.ContentType(); contentType != "" {
+		if format = formatOf(contentType); format == "" {
+			return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+		}
+	}
+	if format == MIMEJSON {
+		return c.ShouldBindJSON(obj)
+	}
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+
+	var err error
+	switch format {
+	case MIMEXML:
+		err = decodeXMLBody(c, obj)
+	case MIMEMsgPack:
+		var tree interface{}
+		if err = msgpack.NewDecoder(c.Request.Body).Decode(&tree); err == nil {
+			err = remarshalJSON(tree, obj)
+		}
+	case MIMEYAML:
+		var tree interface{}
+		if err = yaml.NewDecoder(c.Request.Body).Decode(&tree); err == nil {
+			err = remarshalJSON(tree, obj)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// remarshalJSON MessagePack 和 YAML 的值带有类型，经 JSON 转换后使用 json 标签
+func remarshalJSON(tree interface{}, obj interface{}) error {
+	body, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, obj)
+}
+
+func decodeXMLBody(c *gin.Context, obj interface{}) error {
+	tree, err := decodeXMLTree(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "json",
+		WeaklyTypedInput: true,
+		Result:           obj,
+		// 时间字段与 JSON 一样使用 RFC 3339 格式
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			emptyXMLSlice,
+			mapstructure.StringToTimeHookFunc(time.RFC3339),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(tree)
+}
+
+// emptyXMLSlice 没有子元素的空元素解析为空字符串，目标是切片时转换为空切片
+func emptyXMLSlice(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() == reflect.String && to.Kind() == reflect.Slice && data == "" {
+		return reflect.MakeSlice(to, 0, 0).Interface(), nil
+	}
+	return data, nil
+}
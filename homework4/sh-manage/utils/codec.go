@@ -0,0 +1,309 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v3"
+)
+
+// 其他格式都先编码为 JSON 再转换，字段名、省略规则和时间格式与 JSON 响应完全一致
+
+// field、object 是 JSON 对象的有序表示，转换后的字段顺序与 JSON 相同
+type field struct {
+	key   string
+	value interface{}
+}
+
+type object []field
+
+// xmlRoot XML 响应和请求体的根元素，数组元素使用 xmlItem
+const (
+	xmlRoot = "response"
+	xmlItem = "item"
+)
+
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// encode 把响应编码为 format 指定的格式
+func encode(format string, obj interface{}) ([]byte, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := parseJSONTree(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case MIMEXML:
+		err = encodeXML(&buf, tree)
+	case MIMEMsgPack:
+		err = encodeMsgPack(msgpack.NewEncoder(&buf), tree)
+	case MIMEYAML:
+		err = encodeYAML(&buf, tree)
+	default:
+		return body, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseJSONTree 解析为 object、[]interface{}、string、json.Number、bool 和 nil 组成的树
+func parseJSONTree(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	return parseJSONValue(decoder)
+}
+
+func parseJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		obj := object{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, field{key: key.(string), value: value})
+		}
+		_, err = decoder.Token()
+		return obj, err
+	case '[':
+		items := []interface{}{}
+		for decoder.More() {
+			value, err := parseJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		_, err = decoder.Token()
+		return items, err
+	default:
+		return nil, fmt.Errorf("unexpected json delimiter %v", delim)
+	}
+}
+
+// encodeXML 对象的字段编码为子元素，数组的元素编码为 <item>，null 编码为空元素
+// 字段名不是合法的 XML 名称时使用 <entry key="...">
+func encodeXML(w io.Writer, tree interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	if err := encodeXMLElement(encoder, xml.StartElement{Name: xml.Name{Local: xmlRoot}}, tree); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func encodeXMLElement(encoder *xml.Encoder, start xml.StartElement, value interface{}) error {
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case object:
+		for _, f := range v {
+			child := xml.StartElement{Name: xml.Name{Local: f.key}}
+			if !xmlName.MatchString(f.key) || strings.HasPrefix(strings.ToLower(f.key), "xml") {
+				child = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: f.key}}}
+			}
+			if err := encodeXMLElement(encoder, child, f.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := encodeXMLElement(encoder, xml.StartElement{Name: xml.Name{Local: xmlItem}}, item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func encodeMsgPack(encoder *msgpack.Encoder, value interface{}) error {
+	switch v := value.(type) {
+	case object:
+		if err := encoder.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for _, f := range v {
+			if err := encoder.EncodeString(f.key); err != nil {
+				return err
+			}
+			if err := encodeMsgPack(encoder, f.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		if err := encoder.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := encodeMsgPack(encoder, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return encoder.EncodeInt(i)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return encoder.EncodeFloat64(f)
+	default:
+		return encoder.Encode(v)
+	}
+}
+
+func encodeYAML(w io.Writer, tree interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(yamlNode(tree)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func yamlNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case object:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, f := range v {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.key}, yamlNode(f.value))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case json.Number:
+		tag := "!!int"
+		if _, err := v.Int64(); err != nil {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+	}
+}
+
+// decodeXMLTree 把请求体解析为 map，与 encodeXML 的结构对应
+// 子元素全部是 <item> 时解析为数组，同名子元素重复出现时也解析为数组，没有子元素时解析为文本
+func decodeXMLTree(r io.Reader) (interface{}, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("xml: empty document")
+			}
+			return nil, err
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			return decodeXMLElement(decoder)
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder) (interface{}, error) {
+	var text strings.Builder
+	var names []string
+	var values []interface{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := decodeXMLElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			for _, attr := range t.Attr {
+				if name == "entry" && attr.Name.Local == "key" {
+					name = attr.Value
+				}
+			}
+			names = append(names, name)
+			values = append(values, value)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(names) == 0 {
+				return strings.TrimSpace(text.String()), nil
+			}
+			return xmlChildren(names, values), nil
+		}
+	}
+}
+
+func xmlChildren(names []string, values []interface{}) interface{} {
+	allItems := true
+	for _, name := range names {
+		if name != xmlItem {
+			allItems = false
+			break
+		}
+	}
+	if allItems {
+		return values
+	}
+
+	result := make(map[string]interface{}, len(names))
+	for i, name := range names {
+		existing, ok := result[name]
+		if !ok {
+			result[name] = values[i]
+			continue
+		}
+		if list, ok := existing.([]interface{}); ok {
+			result[name] = append(list, values[i])
+		} else {
+			result[name] = []interface{}{existing, values[i]}
+		}
+	}
+	return result
+}
@@ -9,3 +9,20 @@ import (
 func GetCurrentUserID(c *gin.Context) uint {
 	return c.GetUint(consts.UserID)
 }
+
+func GetCurrentUserName(c *gin.Context) string {
+	return c.GetString(consts.UserName)
+}
+
+// GetCurrentTenantID 返回请求所属的租户，未启用租户解析时为 0
+func GetCurrentTenantID(c *gin.Context) uint {
+	return c.GetUint(consts.TenantID)
+}
+
+func GetCurrentTenantSlug(c *gin.Context) string {
+	return c.GetString(consts.TenantSlug)
+}
+
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(consts.RequestID)
+}
@@ -0,0 +1,234 @@
+package utils
+
+import "net/http"
+
+// ErrorCode 返回给客户端的稳定错误码，客户端应按错误码而不是提示信息处理错误
+// 已发布的错误码不能删除或修改含义，新增错误码后运行 go generate ./cli 更新 docs/errors.md
+type ErrorCode string
+
+// 通用错误码，没有指定错误码的 AppError 按 HTTP 状态码使用
+const (
+	ErrBadRequest         ErrorCode = "BAD_REQUEST"
+	ErrUnauthorized       ErrorCode = "UNAUTHORIZED"
+	ErrForbidden          ErrorCode = "FORBIDDEN"
+	ErrNotFound           ErrorCode = "NOT_FOUND"
+	ErrNotAcceptable      ErrorCode = "NOT_ACCEPTABLE"
+	ErrConflict           ErrorCode = "CONFLICT"
+	ErrPreconditionFailed ErrorCode = "PRECONDITION_FAILED"
+	ErrPayloadTooLarge    ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrUnsupportedMedia   ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrValidation         ErrorCode = "VALIDATION_FAILED"
+	ErrRateLimited        ErrorCode = "RATE_LIMITED"
+	ErrInternal           ErrorCode = "INTERNAL_ERROR"
+	ErrUnavailable        ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrVersionRequired    ErrorCode = "VERSION_REQUIRED"
+)
+
+// 认证与账号
+const (
+	ErrAuthMissing        ErrorCode = "AUTH_MISSING"
+	ErrTokenInvalid       ErrorCode = "TOKEN_INVALID"
+	ErrApiKeyInvalid      ErrorCode = "API_KEY_INVALID"
+	ErrInvalidCredentials ErrorCode = "INVALID_CREDENTIALS"
+	ErrEmailNotVerified   ErrorCode = "EMAIL_NOT_VERIFIED"
+	ErrWeakPassword       ErrorCode = "WEAK_PASSWORD"
+	ErrScopeMissing       ErrorCode = "SCOPE_MISSING"
+	ErrRoleRequired       ErrorCode = "ROLE_REQUIRED"
+	ErrAccountSuspended   ErrorCode = "ACCOUNT_SUSPENDED"
+	ErrAccountBanned      ErrorCode = "ACCOUNT_BANNED"
+	ErrUserExists         ErrorCode = "USER_EXISTS"
+	ErrUserNotFound       ErrorCode = "USER_NOT_FOUND"
+	ErrApiKeyNotFound     ErrorCode = "API_KEY_NOT_FOUND"
+	ErrTenantNotFound     ErrorCode = "TENANT_NOT_FOUND"
+	ErrTenantExists       ErrorCode = "TENANT_EXISTS"
+	ErrInvalidRole        ErrorCode = "INVALID_ROLE"
+	ErrUserAlreadyBanned  ErrorCode = "USER_ALREADY_BANNED"
+	ErrInvalidScope       ErrorCode = "INVALID_SCOPE"
+	ErrInvalidExpiry      ErrorCode = "INVALID_EXPIRY"
+)
+
+// 第三方登录
+const (
+	ErrOIDCProviderNotFound ErrorCode = "OIDC_PROVIDER_NOT_FOUND"
+	ErrOIDCStateInvalid     ErrorCode = "OIDC_STATE_INVALID"
+	ErrOIDCExchangeFailed   ErrorCode = "OIDC_EXCHANGE_FAILED"
+	ErrOIDCTokenInvalid     ErrorCode = "OIDC_TOKEN_INVALID"
+	ErrOIDCEmailMissing     ErrorCode = "OIDC_EMAIL_MISSING"
+)
+
+// 内容
+const (
+	ErrPostNotFound       ErrorCode = "POST_NOT_FOUND"
+	ErrCommentNotFound    ErrorCode = "COMMENT_NOT_FOUND"
+	ErrAttachmentNotFound ErrorCode = "ATTACHMENT_NOT_FOUND"
+	ErrTagNotFound        ErrorCode = "TAG_NOT_FOUND"
+	ErrNotAuthor          ErrorCode = "NOT_AUTHOR"
+	ErrVersionConflict    ErrorCode = "VERSION_CONFLICT"
+	ErrFileTypeNotAllowed ErrorCode = "FILE_TYPE_NOT_ALLOWED"
+	ErrContentRejected    ErrorCode = "CONTENT_REJECTED"
+	ErrInvalidUpload      ErrorCode = "INVALID_UPLOAD"
+	ErrUnsupportedFormat  ErrorCode = "UNSUPPORTED_FORMAT"
+	ErrInvalidImportFile  ErrorCode = "INVALID_IMPORT_FILE"
+	ErrTooManyRecords     ErrorCode = "TOO_MANY_RECORDS"
+)
+
+// 审核、举报与 webhook
+const (
+	ErrModerationItemNotFound ErrorCode = "MODERATION_ITEM_NOT_FOUND"
+	ErrAlreadyDecided         ErrorCode = "ALREADY_DECIDED"
+	ErrReportNotFound         ErrorCode = "REPORT_NOT_FOUND"
+	ErrReportDuplicate        ErrorCode = "REPORT_DUPLICATE"
+	ErrSelfReport             ErrorCode = "SELF_REPORT"
+	ErrSanctionNotAllowed     ErrorCode = "SANCTION_NOT_ALLOWED"
+	ErrWebhookNotFound        ErrorCode = "WEBHOOK_NOT_FOUND"
+	ErrInvalidReportReason    ErrorCode = "INVALID_REPORT_REASON"
+	ErrInvalidEntityType      ErrorCode = "INVALID_ENTITY_TYPE"
+	ErrNoContentToRemove      ErrorCode = "NO_CONTENT_TO_REMOVE"
+	ErrInvalidDuration        ErrorCode = "INVALID_DURATION"
+	ErrInvalidWebhookURL      ErrorCode = "INVALID_WEBHOOK_URL"
+	ErrInvalidWebhookEvents   ErrorCode = "INVALID_WEBHOOK_EVENTS"
+)
+
+// 统计
+const (
+	ErrUnknownMetric    ErrorCode = "UNKNOWN_METRIC"
+	ErrInvalidTimeRange ErrorCode = "INVALID_TIME_RANGE"
+	ErrInvalidInterval  ErrorCode = "INVALID_INTERVAL"
+)
+
+// ErrorDefinition 错误码对应的 HTTP 状态码和说明，Title 同时作为没有提示信息时的默认提示
+type ErrorDefinition struct {
+	Code        ErrorCode
+	Status      int
+	Title       string
+	Description string
+}
+
+var errorCatalog = []ErrorDefinition{
+	{ErrBadRequest, http.StatusBadRequest, "Bad request", "请求参数不合法，具体原因见 detail。"},
+	{ErrUnauthorized, http.StatusUnauthorized, "Unauthorized", "需要登录或认证失败。"},
+	{ErrForbidden, http.StatusForbidden, "Forbidden", "没有执行该操作的权限。"},
+	{ErrNotFound, http.StatusNotFound, "Not found", "资源不存在或对当前用户不可见。"},
+	{ErrNotAcceptable, http.StatusNotAcceptable, "Not acceptable", "Accept 头中没有支持的响应格式，支持 JSON、XML、MessagePack 和 YAML。"},
+	{ErrConflict, http.StatusConflict, "Conflict", "请求与资源的当前状态冲突。"},
+	{ErrPreconditionFailed, http.StatusPreconditionFailed, "Precondition failed", "If-Match 与资源当前的 ETag 不一致，重新读取后再修改。"},
+	{ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, "Payload too large", "请求体或上传的文件超过大小限制。"},
+	{ErrUnsupportedMedia, http.StatusUnsupportedMediaType, "Unsupported media type", "请求体的 Content-Type 不是支持的格式。"},
+	{ErrValidation, http.StatusUnprocessableEntity, "Validation failed", "请求体解析或字段校验失败，字段错误见 errors。"},
+	{ErrRateLimited, http.StatusTooManyRequests, "Too many requests", "超过请求频率限制，按 Retry-After 头等待后重试。"},
+	{ErrInternal, http.StatusInternalServerError, "Internal server error", "服务端错误，原因只记录在服务端日志中，可以用 request_id 查询。"},
+	{ErrUnavailable, http.StatusServiceUnavailable, "Service unavailable", "依赖的服务暂时不可用。"},
+	{ErrVersionRequired, http.StatusBadRequest, "Version is required", "修改时必须提供读取时的 version，用于检测并发修改。"},
+
+	{ErrAuthMissing, http.StatusUnauthorized, "Authorization header is missing", "请求没有 Authorization 头，或格式不是 Bearer {token} / ApiKey {key}。"},
+	{ErrTokenInvalid, http.StatusUnauthorized, "Invalid token", "令牌无效、已过期或不是当前租户签发的。"},
+	{ErrApiKeyInvalid, http.StatusUnauthorized, "Invalid api key", "API Key 不存在、已删除或已过期。"},
+	{ErrInvalidCredentials, http.StatusUnauthorized, "Invalid username or password", "用户名或密码错误，不区分用户是否存在。"},
+	{ErrEmailNotVerified, http.StatusForbidden, "Email is not verified", "第三方登录返回的邮箱未经身份提供方验证，不能用来注册或绑定账号。"},
+	{ErrWeakPassword, http.StatusUnprocessableEntity, "Password does not meet the policy", "密码不符合密码策略，data.reasons 为违反的规则：too_short、too_long、too_few_character_classes、breached、matches_username_or_email。"},
+	{ErrScopeMissing, http.StatusForbidden, "Api key scope missing", "API Key 没有访问该接口需要的 scope。"},
+	{ErrRoleRequired, http.StatusForbidden, "Role required", "需要审核员或管理员角色。"},
+	{ErrAccountSuspended, http.StatusForbidden, "Account is suspended", "账号被停用，data.suspended_until 为恢复时间。"},
+	{ErrAccountBanned, http.StatusForbidden, "Account is banned", "账号被永久封禁。"},
+	{ErrUserExists, http.StatusConflict, "User already exists", "用户名或邮箱已被使用。"},
+	{ErrUserNotFound, http.StatusNotFound, "User not found", "用户不存在。"},
+	{ErrApiKeyNotFound, http.StatusNotFound, "Api key not found", "API Key 不存在或不属于当前用户。"},
+	{ErrTenantNotFound, http.StatusNotFound, "Tenant not found", "子域名或 X-Tenant-ID 指定的租户不存在。"},
+	{ErrTenantExists, http.StatusConflict, "Tenant already exists", "租户标识已被使用。"},
+	{ErrInvalidRole, http.StatusBadRequest, "Invalid role", "角色只能是 user、moderator 或 admin。"},
+	{ErrUserAlreadyBanned, http.StatusConflict, "User is already banned", "用户已被封禁，不能重复封禁。"},
+	{ErrInvalidScope, http.StatusBadRequest, "Invalid scope", "API Key 的 scope 为空或不在可授予的范围内。"},
+	{ErrInvalidExpiry, http.StatusBadRequest, "Invalid expiry", "API Key 的过期时间早于当前时间。"},
+
+	{ErrOIDCProviderNotFound, http.StatusNotFound, "OIDC provider not found", "没有配置该第三方登录提供方。"},
+	{ErrOIDCStateInvalid, http.StatusBadRequest, "Invalid OIDC callback", "回调的 state 无效或已过期，或缺少授权码，需要重新发起登录。"},
+	{ErrOIDCExchangeFailed, http.StatusUnauthorized, "Failed to exchange authorization code", "身份提供方拒绝了授权码。"},
+	{ErrOIDCTokenInvalid, http.StatusUnauthorized, "Invalid ID token", "身份提供方返回的 ID token 缺失、签名或声明无效，或 nonce 不匹配。"},
+	{ErrOIDCEmailMissing, http.StatusBadRequest, "Email claim is required", "身份提供方没有返回邮箱，无法注册或绑定账号。"},
+
+	{ErrPostNotFound, http.StatusNotFound, "Post not found", "文章不存在，或是其他用户的草稿、待审核文章。"},
+	{ErrCommentNotFound, http.StatusNotFound, "Comment not found", "评论不存在，或是其他用户待审核的评论。"},
+	{ErrAttachmentNotFound, http.StatusNotFound, "Attachment not found", "附件不存在。"},
+	{ErrTagNotFound, http.StatusNotFound, "Tag not found", "标签不存在。"},
+	{ErrNotAuthor, http.StatusForbidden, "Only the author can do this", "只有作者可以修改或删除。"},
+	{ErrVersionConflict, http.StatusConflict, "Version conflict", "版本号与服务端不一致，data 为服务端当前状态，合并后用新的版本号重试。"},
+	{ErrFileTypeNotAllowed, http.StatusUnsupportedMediaType, "File type not allowed", "上传的文件类型不在允许范围内，或图片文件已损坏。"},
+	{ErrContentRejected, http.StatusUnprocessableEntity, "Content rejected by moderation", "内容命中屏蔽规则，data.reasons 为命中的规则。"},
+	{ErrInvalidUpload, http.StatusBadRequest, "Invalid upload", "没有上传文件、文件为空或读取失败。"},
+	{ErrUnsupportedFormat, http.StatusBadRequest, "Unsupported format", "导入导出格式只支持 json、csv 和 markdown。"},
+	{ErrInvalidImportFile, http.StatusBadRequest, "Invalid import file", "没有上传导入文件、文件读取失败或无法按指定格式解析。"},
+	{ErrTooManyRecords, http.StatusBadRequest, "Too many records", "单次导入的记录数超过上限，拆分文件后分批导入。"},
+
+	{ErrModerationItemNotFound, http.StatusNotFound, "Moderation item not found", "审核条目不存在。"},
+	{ErrAlreadyDecided, http.StatusConflict, "Already decided", "审核条目或举报已经处理过，或内容已不是待审核状态。"},
+	{ErrReportNotFound, http.StatusNotFound, "Report not found", "举报不存在。"},
+	{ErrReportDuplicate, http.StatusConflict, "Already reported", "同一对象已有当前用户未处理的举报。"},
+	{ErrSelfReport, http.StatusBadRequest, "Cannot report yourself", "不能举报自己或自己的内容。"},
+	{ErrSanctionNotAllowed, http.StatusForbidden, "Sanction not allowed", "审核员不能处罚自己，处罚审核员和管理员需要管理员角色。"},
+	{ErrWebhookNotFound, http.StatusNotFound, "Webhook not found", "Webhook 或投递记录不存在。"},
+	{ErrInvalidReportReason, http.StatusBadRequest, "Invalid report reason", "举报原因不在可选范围内，或原因为 other 时没有填写说明。"},
+	{ErrInvalidEntityType, http.StatusBadRequest, "Invalid entity type", "举报对象只能是 post、comment 或 user。"},
+	{ErrNoContentToRemove, http.StatusBadRequest, "No content to remove", "被举报的是用户，没有可以删除的内容。"},
+	{ErrInvalidDuration, http.StatusBadRequest, "Invalid duration", "停用时长必须为正数（如 72h），停用必须在将来结束。"},
+	{ErrInvalidWebhookURL, http.StatusBadRequest, "Invalid webhook url", "Webhook 地址必须是绝对的 http 或 https 地址。"},
+	{ErrInvalidWebhookEvents, http.StatusBadRequest, "Invalid webhook events", "事件类型为空或不在可订阅的范围内。"},
+
+	{ErrUnknownMetric, http.StatusBadRequest, "Unknown metric", "统计指标不存在。"},
+	{ErrInvalidTimeRange, http.StatusBadRequest, "Invalid time range", "开始时间必须早于结束时间，且范围不能超过 366 天。"},
+	{ErrInvalidInterval, http.StatusUnprocessableEntity, "Invalid interval", "统计区间只能是 day 或 week。"},
+}
+
+var errorDefinitions = func() map[ErrorCode]ErrorDefinition {
+	definitions := make(map[ErrorCode]ErrorDefinition, len(errorCatalog))
+	for _, definition := range errorCatalog {
+		definitions[definition.Code] = definition
+	}
+	return definitions
+}()
+
+// ErrorCatalog 返回全部错误码，顺序与参考文档一致
+func ErrorCatalog() []ErrorDefinition {
+	return append([]ErrorDefinition(nil), errorCatalog...)
+}
+
+// LookupError 返回错误码的定义，未登记的错误码按 500 处理
+func LookupError(code ErrorCode) ErrorDefinition {
+	if definition, ok := errorDefinitions[code]; ok {
+		return definition
+	}
+	return errorDefinitions[ErrInternal]
+}
+
+// defaultErrorCode 没有指定错误码时按 HTTP 状态码选择通用错误码
+func defaultErrorCode(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusNotAcceptable:
+		return ErrNotAcceptable
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return ErrPayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrUnsupportedMedia
+	case http.StatusUnprocessableEntity:
+		return ErrValidation
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return ErrBadRequest
+	}
+	return ErrInternal
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteErrorReference 按错误码目录生成 Markdown 格式的错误参考文档，docs/errors.md 由它生成
+func WriteErrorReference(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# 错误码参考\n\n")
+	b.WriteString("<!-- 由 `go run . errors --output docs/errors.md` 生成，不要手动修改 -->\n\n")
+	b.WriteString("REST 接口的错误响应使用 [RFC 7807](https://www.rfc-editor.org/rfc/rfc7807) 定义的 problem 格式，")
+	b.WriteString("JSON 响应的 Content-Type 为 `" + MIMEProblemJSON + "`，XML 响应为 `" + MIMEProblemXML + "`。\n")
+	b.WriteString("客户端应按 `code` 处理错误，`detail` 只用于展示，内容可能变化。\n\n")
+	b.WriteString("```json\n")
+	b.WriteString("{\n")
+	b.WriteString("  \"type\": \"" + ProblemTypePrefix + string(ErrPostNotFound) + "\",\n")
+	b.WriteString("  \"title\": \"Post not found\",\n")
+	b.WriteString("  \"status\": 404,\n")
+	b.WriteString("  \"detail\": \"Post not found\",\n")
+	b.WriteString("  \"instance\": \"/api/v1/posts/42\",\n")
+	b.WriteString("  \"code\": \"" + string(ErrPostNotFound) + "\",\n")
+	b.WriteString("  \"request_id\": \"9f2c1e7a4b6d8c0e\"\n")
+	b.WriteString("}\n")
+	b.WriteString("```\n\n")
+	b.WriteString("| 字段 | 说明 |\n")
+	b.WriteString("| --- | --- |\n")
+	b.WriteString("| `type` | `" + ProblemTypePrefix + "` 加错误码 |\n")
+	b.WriteString("| `title` | 错误码的标题 |\n")
+	b.WriteString("| `status` | HTTP 状态码 |\n")
+	b.WriteString("| `detail` | 本次错误的提示信息，5xx 错误不包含内部原因 |\n")
+	b.WriteString("| `instance` | 请求路径 |\n")
+	b.WriteString("| `code` | 稳定错误码，见下表 |\n")
+	b.WriteString("| `request_id` | 与 `X-Request-ID` 响应头一致，服务端日志按它记录错误原因 |\n")
+	b.WriteString("| `errors` | 字段校验错误，字段名到错误信息的映射，只在 `VALIDATION_FAILED` 时出现 |\n")
+	b.WriteString("| `data` | 随错误返回的数据，例如版本冲突时的服务端当前状态 |\n\n")
+	b.WriteString("GraphQL 错误的 `extensions.error_code`、gRPC 状态中 `ErrorInfo` 的 `reason` 使用相同的错误码。\n\n")
+	b.WriteString("## 错误码\n\n")
+	b.WriteString("| 错误码 | HTTP 状态码 | 标题 | 说明 |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, definition := range errorCatalog {
+		fmt.Fprintf(&b, "| `%s` | %d | %s | %s |\n", definition.Code, definition.Status, definition.Title, definition.Description)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
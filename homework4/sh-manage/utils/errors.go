@@ -4,13 +4,21 @@ import (
 	"errors"
 	"fmt"
 
+	"sh-manage/logging"
+
 	"github.com/gin-gonic/gin"
 )
 
 type AppError struct {
-	Code    int
-	Message string
-	Err     error
+	// Code HTTP 状态码
+	Code int
+	// ErrorCode 返回给客户端的稳定错误码，为空时按 Code 使用通用错误码
+	ErrorCode ErrorCode
+	Message   string
+	// Err 错误原因，只记录在服务端日志中，不返回给客户端
+	Err error
+	// Data 随错误返回给客户端的数据，例如版本冲突时的服务端当前状态
+	Data interface{}
 }
 
 func (e *AppError) Error() string {
@@ -20,6 +28,43 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// StableCode 返回错误码，没有指定时按 HTTP 状态码返回通用错误码
+func (e *AppError) StableCode() ErrorCode {
+	if e.ErrorCode != "" {
+		return e.ErrorCode
+	}
+	return defaultErrorCode(e.Code)
+}
+
+// Wrap 记录错误原因，返回原错误便于链式调用
+func (e *AppError) Wrap(err error) *AppError {
+	e.Err = err
+	return e
+}
+
+// WithData 附加返回给客户端的数据
+func (e *AppError) WithData(data interface{}) *AppError {
+	e.Data = data
+	return e
+}
+
+// NewError 按错误码创建错误，HTTP 状态码取自错误码目录，message 为空时使用错误码的标题
+func NewError(code ErrorCode, message string) *AppError {
+	definition := LookupError(code)
+	if message == "" {
+		message = definition.Title
+	}
+	return &AppError{
+		Code:      definition.Status,
+		ErrorCode: code,
+		Message:   message,
+	}
+}
+
 func NewAppError(code int, message string) *AppError {
 	return &AppError{
 		Code:    code,
@@ -27,17 +72,29 @@ func NewAppError(code int, message string) *AppError {
 	}
 }
 
+func NewAppErrorWithData(code int, message string, data interface{}) *AppError {
+	return &AppError{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	}
+}
+
+// HandleError 以 problem+json 输出错误
+// 错误原因和未知错误只记录日志，5xx 错误不向客户端暴露具体信息
 func HandleError(c *gin.Context, err error) {
 	if err == nil {
 		return
 	}
 
 	var appErr *AppError
-	if errors.As(err, &appErr) {
-		Error(c, appErr.Code, appErr.Message)
+	if !errors.As(err, &appErr) {
+		logging.Errorf("request %s %s failed: request_id=%s err=%v", c.Request.Method, c.Request.URL.Path, GetRequestID(c), err)
+		Fail(c, NewError(ErrInternal, ""))
 		return
 	}
-
-	// 未知错误，记录日志但不暴露给客户端
-	Error(c, 500, "Internal server error")
+	if appErr.Err != nil || appErr.Code >= 500 {
+		logging.Errorf("request %s %s failed: request_id=%s code=%s err=%v", c.Request.Method, c.Request.URL.Path, GetRequestID(c), appErr.StableCode(), appErr)
+	}
+	Fail(c, appErr)
 }
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sh-manage/consts"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorCatalog(t *testing.T) {
+	seen := map[ErrorCode]bool{}
+	for _, definition := range ErrorCatalog() {
+		if seen[definition.Code] {
+			t.Fatalf("duplicate error code %s", definition.Code)
+		}
+		seen[definition.Code] = true
+		if definition.Status < 400 || definition.Title == "" || definition.Description == "" {
+			t.Fatalf("incomplete definition %+v", definition)
+		}
+	}
+	// 通用错误码都要登记在目录中
+	for _, status := range []int{400, 401, 403, 404, 406, 409, 412, 413, 415, 418, 422, 429, 500, 502, 503} {
+		if code := defaultErrorCode(status); !seen[code] {
+			t.Fatalf("default code %s for %d is not in catalog", code, status)
+		}
+	}
+
+	err := NewError(ErrPostNotFound, "")
+	if err.Code != http.StatusNotFound || err.Message != "Post not found" || err.StableCode() != ErrPostNotFound {
+		t.Fatalf("NewError = %+v", err)
+	}
+	if code := NewAppError(http.StatusConflict, "x").StableCode(); code != ErrConflict {
+		t.Fatalf("StableCode = %s", code)
+	}
+}
+
+func decodeProblem(t *testing.T, body []byte) Problem {
+	t.Helper()
+	var problem Problem
+	if err := json.Unmarshal(body, &problem); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return problem
+}
+
+func TestHandleErrorProblem(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.1:3306: connection refused")
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   ErrorCode
+		wantDetail string
+	}{
+		{"catalog code", NewError(ErrVersionConflict, "").WithData(gin.H{"version": 3}), http.StatusConflict, ErrVersionConflict, "Version conflict"},
+		{"status only", NewAppError(http.StatusBadRequest, "Invalid id"), http.StatusBadRequest, ErrBadRequest, "Invalid id"},
+		{"wrapped cause", NewError(ErrInternal, "Failed to create post").Wrap(cause), http.StatusInternalServerError, ErrInternal, "Failed to create post"},
+		{"unknown error", cause, http.StatusInternalServerError, ErrInternal, "Internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(http.MethodGet, nil, func(c *gin.Context) {
+				c.Set(consts.RequestID, "req-1")
+				HandleError(c, tt.err)
+			})
+			if w.Code != tt.wantStatus || w.Header().Get("Content-Type") != MIMEProblemJSON {
+				t.Fatalf("status = %d, content type = %s", w.Code, w.Header().Get("Content-Type"))
+			}
+			// 错误原因只记录日志，不返回给客户端
+			if strings.Contains(w.Body.String(), "connection refused") {
+				t.Fatalf("cause leaked: %s", w.Body)
+			}
+			problem := decodeProblem(t, w.Body.Bytes())
+			if problem.Code != tt.wantCode || problem.Type != ProblemTypePrefix+string(tt.wantCode) || problem.Status != tt.wantStatus ||
+				problem.Detail != tt.wantDetail || problem.Instance != "/" || problem.RequestID != "req-1" {
+				t.Fatalf("problem = %+v", problem)
+			}
+		})
+	}
+
+	wrapped := NewError(ErrInternal, "").Wrap(cause)
+	if !errors.Is(wrapped, cause) {
+		t.Fatal("wrapped error does not unwrap to its cause")
+	}
+}
+
+func TestValidationProblem(t *testing.T) {
+	w := serve(http.MethodPost, nil, func(c *gin.Context) { ValidationError(c, map[string]string{"title": "required"}) })
+	problem := decodeProblem(t, w.Body.Bytes())
+	if w.Code != http.StatusUnprocessableEntity || problem.Code != ErrValidation || problem.Errors["title"] != "required" {
+		t.Fatalf("status = %d, problem = %+v", w.Code, problem)
+	}
+
+	w = serve(http.MethodGet, map[string]string{"Accept": "application/xml"}, func(c *gin.Context) { HandleError(c, NewError(ErrUserNotFound, "")) })
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != MIMEProblemXML+"; charset=utf-8" ||
+		!strings.Contains(w.Body.String(), "<code>USER_NOT_FOUND</code>") {
+		t.Fatalf("xml problem: %d %s %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+}
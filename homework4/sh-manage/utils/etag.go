@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LastModifier 响应数据实现该接口时输出 Last-Modified 头
+type LastModifier interface {
+	LastModified() time.Time
+}
+
+// ETag 根据响应数据内容计算强校验 ETag
+func ETag(data interface{}) string {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	return BytesETag(body)
+}
+
+// BytesETag 根据原始响应体计算强校验 ETag
+func BytesETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeValidators 写入 ETag/Last-Modified；GET/HEAD 请求命中条件时返回 304 并返回 true
+// 同一数据的不同格式是不同的表示，非 JSON 格式的 ETag 带有格式后缀
+func writeValidators(c *gin.Context, data interface{}, format string) bool {
+	var lastModified time.Time
+	if lm, ok := data.(LastModifier); ok {
+		lastModified = lm.LastModified()
+	}
+	etag := ETag(data)
+	if etag != "" && format != MIMEJSON {
+		etag = strings.TrimSuffix(etag, `"`) + "-" + strings.TrimPrefix(format, "application/") + `"`
+	}
+	return checkNotModified(c, etag, lastModified)
+}
+
+// checkNotModified 写入校验头并处理 If-None-Match/If-Modified-Since
+func checkNotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	method := c.Request.Method
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+
+	// If-None-Match 优先于 If-Modified-Since
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if etag != "" && etagListMatches(inm, etag, true) {
+			notModified(c)
+			return true
+		}
+		return false
+	}
+
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			notModified(c)
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIfMatch 校验 If-Match 与当前资源的 ETag，不匹配时返回 412 并返回 false
+// 请求没有 If-Match 头时直接通过
+func CheckIfMatch(c *gin.Context, current interface{}) bool {
+	if IfMatchSatisfied(c.GetHeader("If-Match"), current) {
+		return true
+	}
+
+	c.Header("ETag", ETag(current))
+	Error(c, http.StatusPreconditionFailed, "Resource has been modified")
+	c.Abort()
+	return false
+}
+
+// IfMatchSatisfied 判断 If-Match 头是否包含 current 的 ETag，使用强比较，header 为空时视为满足
+// 各种格式的表示对应同一个资源状态，比较时忽略非 JSON 格式 ETag 的格式后缀
+func IfMatchSatisfied(header string, current interface{}) bool {
+	if header == "" {
+		return true
+	}
+	candidates := strings.Split(header, ",")
+	for i, candidate := range candidates {
+		candidates[i] = stripFormatSuffix(strings.TrimSpace(candidate))
+	}
+	return etagListMatches(strings.Join(candidates, ","), ETag(current), false)
+}
+
+// stripFormatSuffix 去掉 writeValidators 添加的格式后缀，例如 "abc-xml" 变为 "abc"；哈希部分不含 -
+func stripFormatSuffix(etag string) string {
+	if i := strings.Index(etag, "-"); i >= 0 && strings.HasSuffix(etag, `"`) {
+		return etag[:i] + `"`
+	}
+	return etag
+}
+
+func notModified(c *gin.Context) {
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	c.Abort()
+}
+
+// etagListMatches 判断逗号分隔的 ETag 列表是否包含 etag；weak 为 true 时忽略 W/ 前缀（If-None-Match 使用弱比较）
+func etagListMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testResource struct {
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r testResource) LastModified() time.Time {
+	return r.UpdatedAt
+}
+
+func serve(method string, headers map[string]string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/", nil)
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+	handler(c)
+	return w
+}
+
+func TestSuccessConditionalGet(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	resource := testResource{Name: "a", UpdatedAt: updated}
+	etag := ETag(resource)
+
+	tests := []struct {
+		name       string
+		method     string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"no validators", http.MethodGet, nil, http.StatusOK},
+		{"matching etag", http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"matching weak etag in list", http.MethodGet, map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"wildcard", http.MethodGet, map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"different etag", http.MethodGet, map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"not modified since", http.MethodGet, map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"modified since", http.MethodGet, map[string]string{"If-Modified-Since": updated.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK},
+		{"etag takes precedence", http.MethodGet, map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": updated.Format(http.TimeFormat)}, http.StatusOK},
+		{"post ignores validators", http.MethodPost, map[string]string{"If-None-Match": etag}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.method, tt.headers, func(c *gin.Context) { Success(c, resource) })
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Fatalf("ETag = %q, want %q", w.Header().Get("ETag"), etag)
+			}
+			if w.Header().Get("Last-Modified") != updated.Format(http.TimeFormat) {
+				t.Fatalf("Last-Modified = %q", w.Header().Get("Last-Modified"))
+			}
+			if tt.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Fatalf("304 must not have a body")
+			}
+		})
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	resource := testResource{Name: "a"}
+	etag := ETag(resource)
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		wantOK  bool
+	}{
+		{"absent", "", true},
+		{"matching", etag, true},
+		{"wildcard", "*", true},
+		{"stale", ETag(testResource{Name: "b"}), false},
+		{"weak never matches", "W/" + etag, false},
+		{"format suffix", strings.TrimSuffix(etag, `"`) + `-xml"`, true},
+		{"stale with format suffix", strings.TrimSuffix(ETag(testResource{Name: "b"}), `"`) + `-yaml"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ok bool
+			w := serve(http.MethodPut, map[string]string{"If-Match": tt.ifMatch}, func(c *gin.Context) {
+				ok = CheckIfMatch(c, resource)
+			})
+			if ok != tt.wantOK {
+				t.Fatalf("CheckIfMatch = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok && w.Code != http.StatusPreconditionFailed {
+				t.Fatalf("status = %d, want 412", w.Code)
+			}
+		})
+	}
+}
+
+func TestRawConditionalGet(t *testing.T) {
+	body := []byte("<rss></rss>")
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	handler := func(c *gin.Context) { Raw(c, "application/rss+xml", body, updated) }
+
+	w := serve(http.MethodGet, nil, handler)
+	if w.Code != http.StatusOK || w.Body.String() != string(body) || w.Header().Get("ETag") != BytesETag(body) {
+		t.Fatalf("first response = %d %q etag %q", w.Code, w.Body.String(), w.Header().Get("ETag"))
+	}
+	if w := serve(http.MethodGet, map[string]string{"If-None-Match": BytesETag(body)}, handler); w.Code != http.StatusNotModified {
+		t.Fatalf("matching etag status = %d", w.Code)
+	}
+	if w := serve(http.MethodGet, map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}, handler); w.Code != http.StatusNotModified {
+		t.Fatalf("not modified since status = %d", w.Code)
+	}
+}
@@ -8,22 +8,32 @@ import (
 )
 
 type Claims struct {
-	UserId   uint   `json:"user_id"`
+	UserId uint `json:"user_id"`
+	// TenantId 签发令牌的租户，只能在同一个租户下使用
+	TenantId uint   `json:"tenant_id,omitempty"`
 	Username string `json:"username"`
 	jwt.RegisteredClaims
 }
 
 func GenerateToken(secret []byte, userID uint, username string) (string, error) {
+	return generateToken(secret, "", userID, 0, username, time.Now(), 24*time.Hour)
+}
+
+func generateToken(secret []byte, kid string, userID, tenantID uint, username string, now time.Time, expire time.Duration) (string, error) {
 	claims := Claims{
 		UserId:   userID,
+		TenantId: tenantID,
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expiration time
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()), // Token issued at time
+			ExpiresAt: jwt.NewNumericDate(now.Add(expire)), // Token expiration time
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now), // Token issued at time
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
 	return token.SignedString(secret)
 }
 
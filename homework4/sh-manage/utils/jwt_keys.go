@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKeys 可在运行时轮换的 JWT 签名密钥
+// 轮换后新令牌使用新密钥签名，旧密钥签发的令牌在宽限期内仍然有效
+type JWTKeys struct {
+	mu            sync.RWMutex
+	current       []byte
+	previous      []byte
+	previousUntil time.Time
+	expire        time.Duration
+	now           func() time.Time
+}
+
+func NewJWTKeys(secret []byte, expire time.Duration) *JWTKeys {
+	if expire <= 0 {
+		expire = 24 * time.Hour
+	}
+	return &JWTKeys{current: secret, expire: expire, now: time.Now}
+}
+
+// Rotate 切换到新密钥，grace 为旧密钥继续用于校验的时长；密钥未变化时不做处理
+func (k *JWTKeys) Rotate(secret []byte, grace time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if string(secret) == string(k.current) {
+		return
+	}
+	k.previous = k.current
+	k.previousUntil = k.now().Add(grace)
+	k.current = secret
+}
+
+// SetExpire 修改之后签发的令牌有效期
+func (k *JWTKeys) SetExpire(expire time.Duration) {
+	if expire <= 0 {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.expire = expire
+}
+
+// Sign 使用当前密钥签发令牌，header 中的 kid 标识签名密钥，tenantID 为用户所属租户
+func (k *JWTKeys) Sign(userID, tenantID uint, username string) (string, error) {
+	k.mu.RLock()
+	secret, expire, now := k.current, k.expire, k.now()
+	k.mu.RUnlock()
+	return generateToken(secret, keyID(secret), userID, tenantID, username, now, expire)
+}
+
+// Parse 校验令牌，按 kid 选择密钥；没有 kid 的旧令牌依次尝试当前和宽限期内的旧密钥
+func (k *JWTKeys) Parse(tokenString string) (*Claims, error) {
+	k.mu.RLock()
+	candidates := [][]byte{k.current}
+	if k.previous != nil && k.now().Before(k.previousUntil) {
+		candidates = append(candidates, k.previous)
+	}
+	k.mu.RUnlock()
+
+	kid := tokenKeyID(tokenString)
+	var lastErr error
+	for _, secret := range candidates {
+		if kid != "" && kid != keyID(secret) {
+			continue
+		}
+		claims, err := ParseToken(tokenString, secret)
+		if err == nil {
+			return claims, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("signing key is unknown or expired")
+	}
+	return nil, lastErr
+}
+
+// keyID 密钥的摘要，只用于区分密钥，不能反推密钥
+func keyID(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:4])
+}
+
+func tokenKeyID(tokenString string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+	if err != nil {
+		return ""
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
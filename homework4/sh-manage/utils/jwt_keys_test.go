@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJWTKeysRotation(t *testing.T) {
+	// 令牌的过期时间按真实时间校验，这里只推进宽限期使用的时钟
+	now := time.Now()
+	keys := NewJWTKeys([]byte("old-secret-0123456789"), time.Hour)
+	keys.now = func() time.Time { return now }
+
+	oldToken, err := keys.Sign(1, 0, "alice")
+	if err != nil {
+		t.Fatalf("Sign() = %v", err)
+	}
+	legacyToken, err := GenerateToken([]byte("old-secret-0123456789"), 1, "alice")
+	if err != nil {
+		t.Fatalf("GenerateToken() = %v", err)
+	}
+
+	// 密钥未变化时不轮换
+	keys.Rotate([]byte("old-secret-0123456789"), time.Minute)
+	if keys.previous != nil {
+		t.Fatalf("rotating to the same secret should be a no-op")
+	}
+
+	keys.Rotate([]byte("new-secret-0123456789"), 30*time.Minute)
+	newToken, err := keys.Sign(2, 0, "bob")
+	if err != nil {
+		t.Fatalf("Sign() = %v", err)
+	}
+
+	for name, token := range map[string]string{"old kid": oldToken, "legacy without kid": legacyToken, "new": newToken} {
+		if _, err := keys.Parse(token); err != nil {
+			t.Fatalf("%s token rejected within grace period: %v", name, err)
+		}
+	}
+
+	now = now.Add(31 * time.Minute)
+	if _, err := keys.Parse(oldToken); err == nil {
+		t.Fatalf("old token accepted after grace period")
+	}
+	if _, err := keys.Parse(legacyToken); err == nil {
+		t.Fatalf("legacy token accepted after grace period")
+	}
+	claims, err := keys.Parse(newToken)
+	if err != nil || claims.Username != "bob" {
+		t.Fatalf("Parse(new) = %v, %v", claims, err)
+	}
+}
+
+func TestJWTKeysRejectsForeignToken(t *testing.T) {
+	keys := NewJWTKeys([]byte("secret-a-0123456789"), time.Hour)
+	other := NewJWTKeys([]byte("secret-b-0123456789"), time.Hour)
+	token, err := other.Sign(1, 0, "mallory")
+	if err != nil {
+		t.Fatalf("Sign() = %v", err)
+	}
+	if _, err := keys.Parse(token); err == nil {
+		t.Fatalf("token signed by another key accepted")
+	}
+}
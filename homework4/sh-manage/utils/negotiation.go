@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 响应和请求体支持的格式，其他格式的响应体通过 Raw 输出
+const (
+	MIMEJSON    = "application/json"
+	MIMEXML     = "application/xml"
+	MIMEMsgPack = "application/msgpack"
+	MIMEYAML    = "application/yaml"
+)
+
+// SupportedFormats 按优先顺序排列，通配符匹配时选择第一个
+var SupportedFormats = []string{MIMEJSON, MIMEXML, MIMEMsgPack, MIMEYAML}
+
+// formatAliases 常见的别名，结构化后缀（如 application/problem+json）在 formatOf 中处理
+var formatAliases = map[string]string{
+	"application/json":        MIMEJSON,
+	"text/json":               MIMEJSON,
+	"application/xml":         MIMEXML,
+	"text/xml":                MIMEXML,
+	"application/msgpack":     MIMEMsgPack,
+	"application/x-msgpack":   MIMEMsgPack,
+	"application/vnd.msgpack": MIMEMsgPack,
+	"application/yaml":        MIMEYAML,
+	"application/x-yaml":      MIMEYAML,
+	"text/yaml":               MIMEYAML,
+	"text/x-yaml":             MIMEYAML,
+}
+
+// negotiatedFormat 保存在 gin.Context 中的协商结果，避免每次输出都重新解析 Accept
+const negotiatedFormat = "NegotiatedFormat"
+
+// formatOf 返回媒体类型对应的格式，不支持时返回空字符串
+func formatOf(mediaType string) string {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if format, ok := formatAliases[mediaType]; ok {
+		return format
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return MIMEJSON
+	case strings.HasSuffix(mediaType, "+xml"):
+		return MIMEXML
+	case strings.HasSuffix(mediaType, "+msgpack"):
+		return MIMEMsgPack
+	case strings.HasSuffix(mediaType, "+yaml"):
+		return MIMEYAML
+	}
+	return ""
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// NegotiateFormat 按 Accept 头的 q 值选择响应格式，q 相同时按出现顺序
+// 没有 Accept 头或接受任意类型时使用 JSON，没有可接受的格式时返回 false
+func NegotiateFormat(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return MIMEJSON, true
+	}
+
+	var ranges []acceptRange
+	excluded := map[string]bool{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		// q=0 表示明确不接受，通配符匹配时跳过这些格式
+		if q <= 0 {
+			if format := formatOf(mediaType); format != "" {
+				excluded[format] = true
+			}
+			continue
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		if format := formatOf(r.mediaType); format != "" {
+			if !excluded[format] {
+				return format, true
+			}
+			continue
+		}
+		for _, format := range SupportedFormats {
+			if !excluded[format] && matchesRange(r.mediaType, format) {
+				return format, true
+			}
+		}
+	}
+	return "", false
+}
+
+// matchesRange 处理 */* 和 type/* 形式的通配符，text/* 可以匹配 XML 和 YAML 的 text/ 别名
+func matchesRange(mediaRange, format string) bool {
+	if mediaRange == "*/*" {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	if !ok {
+		return false
+	}
+	for alias, target := range formatAliases {
+		if target == format && strings.HasPrefix(alias, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseFormat 返回当前请求协商出的响应格式，结果缓存在 gin.Context 中
+func ResponseFormat(c *gin.Context) (string, bool) {
+	if value, exists := c.Get(negotiatedFormat); exists {
+		format, _ := value.(string)
+		return format, format != ""
+	}
+	format, ok := NegotiateFormat(c.GetHeader("Accept"))
+	c.Set(negotiatedFormat, format)
+	return format, ok
+}
+
+// NotAcceptable 客户端不接受任何支持的格式时返回 406，响应体使用 JSON
+func NotAcceptable(c *gin.Context) {
+	c.Header("Vary", "Accept")
+	RenderProblem(c, NewProblem(c, NewError(ErrNotAcceptable, "Supported types are "+strings.Join(SupportedFormats, ", "))))
+}
+
+// Render 按协商的格式输出响应
+// 成功响应在客户端不接受任何支持的格式时返回 406；错误响应保留原状态码，改用 JSON 输出
+func Render(c *gin.Context, code int, obj interface{}) {
+	render(c, code, obj, false)
+}
+
+// RenderProblem 按协商的格式输出 problem，JSON 和 XML 使用 problem+json、problem+xml 类型
+func RenderProblem(c *gin.Context, problem Problem) {
+	render(c, problem.Status, problem, true)
+}
+
+func render(c *gin.Context, code int, obj interface{}, problem bool) {
+	format, ok := ResponseFormat(c)
+	if !ok {
+		if code < http.StatusBadRequest {
+			NotAcceptable(c)
+			return
+		}
+		format = MIMEJSON
+	}
+	c.Header("Vary", "Accept")
+	if format == MIMEJSON {
+		// gin 只在没有 Content-Type 时设置 application/json
+		if problem {
+			c.Header("Content-Type", MIMEProblemJSON)
+		}
+		c.JSON(code, obj)
+		return
+	}
+
+	body, err := encode(format, obj)
+	if err != nil {
+		c.Error(err)
+		c.Header("Content-Type", MIMEProblemJSON)
+		c.JSON(http.StatusInternalServerError, NewProblem(c, NewError(ErrInternal, "")))
+		return
+	}
+	contentType := format
+	if problem && format == MIMEXML {
+		contentType = MIMEProblemXML
+	}
+	if format != MIMEMsgPack {
+		contentType += "; charset=utf-8"
+	}
+	c.Data(code, contentType, body)
+}
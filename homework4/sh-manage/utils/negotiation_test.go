@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"", MIMEJSON, true},
+		{"*/*", MIMEJSON, true},
+		{"application/xml", MIMEXML, true},
+		{"text/xml", MIMEXML, true},
+		{"application/x-msgpack", MIMEMsgPack, true},
+		{"application/yaml", MIMEYAML, true},
+		{"application/problem+json", MIMEJSON, true},
+		{"text/html, application/yaml;q=0.5, application/xml;q=0.8", MIMEXML, true},
+		{"application/*", MIMEJSON, true},
+		{"text/*", MIMEJSON, true},
+		{"*/*, application/json;q=0", MIMEXML, true},
+		{"text/html", "", false},
+		{"image/png, text/plain", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NegotiateFormat(tt.accept)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NegotiateFormat(%q) = %q, %v, want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+type negotiationResource struct {
+	ID        uint              `json:"id"`
+	Title     string            `json:"title"`
+	Tags      []string          `json:"tags"`
+	Score     float64           `json:"score"`
+	Draft     bool              `json:"draft"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	DeletedAt *time.Time        `json:"deleted_at"`
+}
+
+func TestSuccessFormats(t *testing.T) {
+	resource := negotiationResource{ID: 7, Title: "true", Tags: []string{"go", "web"}, Score: 1.5}
+	want := map[string]interface{}{
+		"code": 200,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"id": 7, "title": "true", "tags": []interface{}{"go", "web"}, "score": 1.5, "draft": false, "deleted_at": nil,
+		},
+	}
+
+	for _, format := range []string{MIMEXML, MIMEMsgPack, MIMEYAML} {
+		t.Run(format, func(t *testing.T) {
+			w := serve(http.MethodGet, map[string]string{"Accept": format}, func(c *gin.Context) { Success(c, resource) })
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), format) || w.Header().Get("Vary") != "Accept" {
+				t.Fatalf("status = %d, headers = %v", w.Code, w.Header())
+			}
+			// 不同格式的 ETag 不同
+			if w.Header().Get("ETag") == ETag(resource) {
+				t.Fatalf("etag of %s equals json etag", format)
+			}
+
+			switch format {
+			case MIMEXML:
+				body := w.Body.String()
+				for _, part := range []string{"<response><code>200</code><msg>success</msg><data><id>7</id><title>true</title>", "<tags><item>go</item><item>web</item></tags>", "<deleted_at></deleted_at>"} {
+					if !strings.Contains(body, part) {
+						t.Fatalf("xml body %s does not contain %s", body, part)
+					}
+				}
+			case MIMEMsgPack:
+				var got map[string]interface{}
+				if err := msgpack.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("msgpack: %v", err)
+				}
+				data := got["data"].(map[string]interface{})
+				if got["msg"] != "success" || data["title"] != "true" || data["score"] != 1.5 || data["deleted_at"] != nil {
+					t.Fatalf("msgpack = %v", got)
+				}
+			case MIMEYAML:
+				var got map[string]interface{}
+				if err := yaml.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("yaml: %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("yaml = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderNotAcceptable(t *testing.T) {
+	headers := map[string]string{"Accept": "text/html"}
+	if w := serve(http.MethodGet, headers, func(c *gin.Context) { Success(c, gin.H{"a": 1}) }); w.Code != http.StatusNotAcceptable {
+		t.Fatalf("success status = %d", w.Code)
+	}
+	// 错误响应保留状态码，使用 problem+json 输出
+	w := serve(http.MethodGet, headers, func(c *gin.Context) { HandleError(c, NewAppError(http.StatusNotFound, "Post not found")) })
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Header().Get("Content-Type"), MIMEProblemJSON) {
+		t.Fatalf("error status = %d, content type = %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+type bindRequest struct {
+	Title   *string  `json:"title" binding:"required,min=1"`
+	Version *uint    `json:"version" binding:"required"`
+	Tags    []string `json:"tags"`
+	Active  bool     `json:"active"`
+}
+
+func TestBindBody(t *testing.T) {
+	msgpackBody, _ := msgpack.Marshal(map[string]interface{}{"title": "hello", "version": 2, "tags": []string{"go", "web"}, "active": true})
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		wantErr     bool
+		wantTags    []string
+	}{
+		{"json", "application/json", []byte(`{"title":"hello","version":2,"tags":["go","web"],"active":true}`), false, []string{"go", "web"}},
+		{"no content type", "", []byte(`{"title":"hello","version":2,"tags":["go","web"],"active":true}`), false, []string{"go", "web"}},
+		{"xml", "application/xml; charset=utf-8", []byte(`<request><title>hello</title><version>2</version><tags><item>go</item><item>web</item></tags><active>true</active></request>`), false, []string{"go", "web"}},
+		{"xml empty list", "text/xml", []byte(`<request><title>hello</title><version>2</version><tags></tags><active>true</active></request>`), false, []string{}},
+		{"msgpack", "application/x-msgpack", msgpackBody, false, []string{"go", "web"}},
+		{"yaml", "application/yaml", []byte("title: hello\nversion: 2\ntags: [go, web]\nactive: true\n"), false, []string{"go", "web"}},
+		{"yaml fails validation", "application/yaml", []byte("title: \"\"\nversion: 2\n"), true, nil},
+		{"malformed xml", "application/xml", []byte(`<request><title>`), true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			}
+			var req bindRequest
+			err := BindBody(c, &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindBody = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *req.Title != "hello" || *req.Version != 2 || !req.Active || !reflect.DeepEqual(req.Tags, tt.wantTags) {
+				t.Fatalf("req = %+v", req)
+			}
+		})
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("a=b"))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := BindBody(c, &bindRequest{}); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Fatalf("form body: %v", err)
+	}
+}
+
+// bindExpiryRequest 与 dto.CreateApiKeyDto 相同的时间字段
+type bindExpiryRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func TestBindBodyXMLTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    *time.Time
+		wantErr bool
+	}{
+		{"rfc3339", `<request><name>ci</name><expires_at>2030-01-02T03:04:05+08:00</expires_at></request>`, ptrTime(time.Date(2030, 1, 1, 19, 4, 5, 0, time.UTC)), false},
+		{"omitted", `<request><name>ci</name></request>`, nil, false},
+		{"invalid", `<request><name>ci</name><expires_at>tomorrow</expires_at></request>`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/xml")
+			var req bindExpiryRequest
+			err := BindBody(c, &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindBody = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (req.ExpiresAt == nil) != (tt.want == nil) || (tt.want != nil && !req.ExpiresAt.Equal(*tt.want)) {
+				t.Fatalf("expires_at = %v, want %v", req.ExpiresAt, tt.want)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,26 +14,82 @@ type Response struct {
 	Error   interface{} `json:"error,omitempty"`
 }
 
+// 错误响应使用 RFC 7807 定义的 problem 格式
+const (
+	MIMEProblemJSON = "application/problem+json"
+	MIMEProblemXML  = "application/problem+xml"
+	// ProblemTypePrefix problem 的 type 字段前缀，后接错误码，说明见 docs/errors.md
+	ProblemTypePrefix = "urn:sh-manage:error:"
+)
+
+// Problem 错误响应体，code 为稳定错误码，errors 为字段校验错误，data 为随错误返回的数据
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      ErrorCode         `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	Data      interface{}       `json:"data,omitempty"`
+}
+
+// NewProblem 按错误生成 problem，title 取自错误码目录，detail 为错误的提示信息
+func NewProblem(c *gin.Context, err *AppError) Problem {
+	code := err.StableCode()
+	problem := Problem{
+		Type:      ProblemTypePrefix + string(code),
+		Title:     LookupError(code).Title,
+		Status:    err.Code,
+		Detail:    err.Message,
+		Code:      code,
+		RequestID: GetRequestID(c),
+		Data:      err.Data,
+	}
+	if c.Request != nil {
+		problem.Instance = c.Request.URL.Path
+	}
+	return problem
+}
+
+// Success 按 Accept 协商的格式输出成功响应，并根据数据生成 ETag/Last-Modified，满足条件请求时返回 304
 func Success(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusOK, Response{
+	format, ok := ResponseFormat(c)
+	if !ok {
+		NotAcceptable(c)
+		return
+	}
+	if data != nil && writeValidators(c, data, format) {
+		return
+	}
+	Render(c, http.StatusOK, Response{
 		Code:    200,
 		Message: "success",
 		Data:    data,
 	})
 }
 
+// Raw 输出非 JSON 响应体（如 XML 订阅源），同样支持条件请求
+func Raw(c *gin.Context, contentType string, body []byte, lastModified time.Time) {
+	if checkNotModified(c, BytesETag(body), lastModified) {
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// Fail 输出错误响应，不记录日志，需要记录错误原因时使用 HandleError
+func Fail(c *gin.Context, err *AppError) {
+	RenderProblem(c, NewProblem(c, err))
+}
+
+// Error 按 HTTP 状态码输出错误，错误码使用状态码对应的通用错误码
 func Error(c *gin.Context, code int, message string) {
-	c.JSON(code, Response{
-		Code:    code,
-		Message: message,
-		Error:   message,
-	})
+	Fail(c, NewAppError(code, message))
 }
 
 func ValidationError(c *gin.Context, errors map[string]string) {
-	c.JSON(http.StatusUnprocessableEntity, Response{
-		Code:    422,
-		Message: "validation failed",
-		Error:   errors,
-	})
+	problem := NewProblem(c, NewError(ErrValidation, "validation failed"))
+	problem.Errors = errors
+	RenderProblem(c, problem)
 }